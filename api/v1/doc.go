@@ -0,0 +1,23 @@
+// Package v1 is the stable Go API of kaput-not for programs that embed the sync or build on its Netmaker client
+//
+// Compatibility: within v1, exported identifiers are neither removed nor changed incompatibly. Interfaces do not
+// gain methods, and options structs only gain fields whose zero value keeps the previous behavior. Anything that
+// needs a breaking change goes into a new api/v2 package, with v1 kept alongside it.
+//
+// The packages under pkg/ implement this API and may change in any release; import them directly only if you
+// can follow kaput-not's releases. The interfaces here are declared independently of their pkg/ counterparts and
+// checked against them at compile time, so a change in pkg/ that would break v1 fails the kaput-not build instead
+// of downstream builds.
+//
+// Typical use (see the README section "Embedding in Another Operator"):
+//
+//	client, err := kaputnotv1.NewNetmakerClient("https://api.netmaker.example.com", username, password)
+//	if err != nil {
+//		return err
+//	}
+//	return kaputnotv1.Run(ctx, kaputnotv1.Options{
+//		KubeClient:     kubeClient,
+//		NetmakerClient: client,
+//		Reconciler:     kaputnotv1.ReconcilerOptions{ClusterName: "prod-eu"},
+//	})
+package v1
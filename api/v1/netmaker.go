@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// Netmaker API types (only the fields kaput-not uses; unknown API fields are ignored)
+type (
+	// Host is a Netmaker host (a machine running netclient)
+	Host = netmaker.Host
+
+	// Node is a Netmaker node (a host's membership in one network)
+	Node = netmaker.Node
+
+	// Network is a Netmaker network
+	Network = netmaker.Network
+
+	// Egress is a Netmaker egress rule
+	Egress = netmaker.Egress
+
+	// EgressReq creates or updates an egress rule
+	EgressReq = netmaker.EgressReq
+
+	// ExtClient is a Netmaker external client
+	ExtClient = netmaker.ExtClient
+)
+
+// ErrNotFound is wrapped by errors for Netmaker objects that don't exist
+var ErrNotFound = netmaker.ErrNotFound
+
+// NetmakerClient is the Netmaker API used by kaput-not
+// The client works with all networks - the network is passed as parameter where needed
+type NetmakerClient interface {
+	// Authenticate obtains a token from the Netmaker API
+	Authenticate(ctx context.Context) error
+
+	// ListHosts returns all hosts (global, not per network)
+	ListHosts(ctx context.Context) ([]Host, error)
+
+	// ListNodes returns all nodes across all networks
+	ListNodes(ctx context.Context) ([]Node, error)
+
+	// ListEgress returns all egress rules of a network
+	ListEgress(ctx context.Context, network string) ([]Egress, error)
+
+	// CreateEgress creates an egress rule in req.Network
+	CreateEgress(ctx context.Context, req EgressReq) (*Egress, error)
+
+	// UpdateEgress updates an egress rule in req.Network
+	UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error)
+
+	// DeleteEgress removes an egress rule by ID
+	DeleteEgress(ctx context.Context, egressID string) error
+
+	// ListExtClients returns all external clients of a network
+	ListExtClients(ctx context.Context, network string) ([]ExtClient, error)
+
+	// UpdateExtClientAllowedIPs replaces the extra allowed IPs (routes) of an external client
+	UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error
+
+	// GetNetwork returns a network by name (the error wraps ErrNotFound if it doesn't exist)
+	GetNetwork(ctx context.Context, netID string) (*Network, error)
+
+	// CreateNetwork creates a network
+	CreateNetwork(ctx context.Context, network Network) (*Network, error)
+}
+
+// NetworkLister is optionally implemented by NetmakerClients (the built-in client does): the networks' address ranges
+// refine IP family selection, CIDR validation and logs; without it kaput-not works without network metadata
+type NetworkLister interface {
+	// ListNetworks returns all networks with their address ranges and default settings
+	ListNetworks(ctx context.Context) ([]Network, error)
+}
+
+// Authenticator obtains a bearer token for the Netmaker API
+// Implementations must be safe for concurrent use
+type Authenticator interface {
+	// Authenticate returns a fresh bearer token, using client for any HTTP calls
+	Authenticate(ctx context.Context, client *http.Client) (string, error)
+}
+
+// Ensure the interfaces stay interchangeable with their implementations in pkg/netmaker
+// (netmaker.Client is NetmakerClient plus NetworkLister)
+var (
+	_ NetmakerClient         = netmaker.BasicClient(nil)
+	_ netmaker.BasicClient   = NetmakerClient(nil)
+	_ NetworkLister          = netmaker.NetworkLister(nil)
+	_ netmaker.NetworkLister = NetworkLister(nil)
+	_ NetmakerClient         = (*netmaker.HTTPClient)(nil)
+	_ NetworkLister          = (*netmaker.HTTPClient)(nil)
+
+	_ Authenticator          = netmaker.Authenticator(nil)
+	_ netmaker.Authenticator = Authenticator(nil)
+	_ Authenticator          = (*netmaker.PasswordAuthenticator)(nil)
+	_ Authenticator          = (*netmaker.TokenExchangeAuthenticator)(nil)
+)
+
+// NewNetmakerClient creates a Netmaker API client logging in with a username and password
+// Returns error for validation failures, never panics
+func NewNetmakerClient(baseURL, username, password string) (NetmakerClient, error) {
+	client, err := netmaker.NewHTTPClient(baseURL, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewNetmakerClientWithAuthenticator creates a Netmaker API client with a custom authentication flow
+// Returns error for validation failures, never panics
+func NewNetmakerClientWithAuthenticator(baseURL string, authenticator Authenticator) (NetmakerClient, error) {
+	client, err := netmaker.NewHTTPClientWithAuthenticator(baseURL, authenticator)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewTokenExchangeAuthenticator creates an authenticator exchanging the pod's projected service account token
+// (RFC 8693) for a Netmaker session; audience is optional
+// Returns error for validation failures, never panics
+func NewTokenExchangeAuthenticator(exchangeURL, tokenFile, audience string) (Authenticator, error) {
+	authenticator, err := netmaker.NewTokenExchangeAuthenticator(exchangeURL, tokenFile, audience)
+	if err != nil {
+		return nil, err
+	}
+	return authenticator, nil
+}
@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// Reconciliation types
+type (
+	// Topology holds the cluster-wide inputs for reconciling a single node (gateways, publishers, ...)
+	Topology = reconciler.Topology
+
+	// NodeRequest is a node to reconcile in a resync, with its topology
+	NodeRequest = reconciler.NodeRequest
+
+	// NodeResult describes what reconciling a node did
+	NodeResult = reconciler.NodeResult
+
+	// Mutation is a change made to a Netmaker egress rule while reconciling a node
+	Mutation = reconciler.Mutation
+
+	// NetworkCounts are the egress rule outcomes of a node reconcile in one network
+	NetworkCounts = reconciler.NetworkCounts
+
+	// Collision is an egress rule not managed by kaput-not overlapping a managed one in the same network
+	Collision = reconciler.Collision
+
+	// EgressRule is a ClusterEgressRule or node pool: CIDRs routed via selected nodes
+	EgressRule = reconciler.EgressRule
+
+	// ExtClientGrant exposes a node's pod CIDRs to Netmaker external clients
+	ExtClientGrant = reconciler.ExtClientGrant
+
+	// Plan is the desired node egress state of the cluster and the Netmaker changes needed to reach it
+	Plan = reconciler.Plan
+)
+
+// Mutation actions
+const (
+	ChangeCreate = reconciler.ChangeCreate
+	ChangeUpdate = reconciler.ChangeUpdate
+	ChangeDelete = reconciler.ChangeDelete
+)
+
+// Reconciler is the reconciliation logic driven by the controller
+// Implemented by NewReconciler; alternate implementations and test doubles can be set as ControllerOptions.Reconciler
+type Reconciler interface {
+	// ReconcileNode syncs a node's pod CIDRs to Netmaker egress rules
+	// Returns the networks touched and the mutations made
+	ReconcileNode(ctx context.Context, node *corev1.Node, topology Topology) (NodeResult, error)
+
+	// DeleteNode removes the egress rules of a deleted node
+	DeleteNode(ctx context.Context, nodeName string) error
+
+	// CleanupOrphanedEgresses removes egress rules of Netmaker nodes not in validNodeIDs
+	CleanupOrphanedEgresses(ctx context.Context, validNodeIDs map[string]bool) error
+
+	// CleanupExpiredEgresses removes egress rules whose lease expired
+	CleanupExpiredEgresses(ctx context.Context) error
+
+	// CleanupRecordedEgresses removes recorded egress rules of nodes not in managedNodes
+	CleanupRecordedEgresses(ctx context.Context, managedNodes map[string]bool) error
+
+	// RenamedHostNodeIDs returns the Netmaker node IDs of a node whose host was renamed in Netmaker (nil if none)
+	RenamedHostNodeIDs(ctx context.Context, nodeName string) ([]string, error)
+
+	// ResyncNodes reconciles many nodes against a single snapshot of Netmaker state
+	// Returns per-node results and errors, or an error if the snapshot could not be taken
+	ResyncNodes(ctx context.Context, requests []NodeRequest) (map[string]NodeResult, map[string]error, error)
+
+	// AggregatesClusterCIDRs reports whether nodes publish cluster-wide CIDRs instead of their own
+	AggregatesClusterCIDRs() bool
+
+	// SyncExtClients publishes granted pod CIDRs to Netmaker external clients
+	SyncExtClients(ctx context.Context, grants []ExtClientGrant) error
+
+	// ReconcileRule syncs a ClusterEgressRule to Netmaker egress rules
+	ReconcileRule(ctx context.Context, rule EgressRule) error
+
+	// DeleteRule removes the egress rules of a deleted ClusterEgressRule
+	DeleteRule(ctx context.Context, name string) error
+
+	// CleanupRules removes egress rules of ClusterEgressRules not in validRules
+	CleanupRules(ctx context.Context, validRules map[string]bool) error
+
+	// ReconcilePool syncs the shared egress rules of a node pool to Netmaker
+	ReconcilePool(ctx context.Context, pool EgressRule) error
+
+	// DeletePool removes the egress rules of a node pool without nodes
+	DeletePool(ctx context.Context, name string) error
+
+	// CleanupPools removes egress rules of node pools not in validPools
+	CleanupPools(ctx context.Context, validPools map[string]bool) error
+
+	// EnsureNetworks creates the configured Netmaker networks that don't exist yet
+	EnsureNetworks(ctx context.Context) error
+
+	// PlanNodes computes the egress rules of the given nodes and the changes to reach them, without mutating
+	PlanNodes(ctx context.Context, requests []NodeRequest, validNodeIDs map[string]bool) (*Plan, error)
+
+	// VerifyNode reads a node's egress rules back from Netmaker and fails if they differ from the desired state
+	VerifyNode(ctx context.Context, node *corev1.Node, topology Topology) error
+}
+
+// Ensure the interface stays interchangeable with the controller's and its implementation
+var (
+	_ Reconciler            = controller.Reconciler(nil)
+	_ controller.Reconciler = Reconciler(nil)
+	_ Reconciler            = (*reconciler.Reconciler)(nil)
+)
+
+// NewReconciler creates the default reconciler
+// opts.NetmakerClient is required (see NewCachedNetmakerClient); defaults are applied to opts
+// Returns error for invalid options, never panics
+func NewReconciler(opts *ReconcilerOptions) (Reconciler, error) {
+	rec, err := reconciler.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
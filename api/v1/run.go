@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/kaputnot"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// Options structs; fields are documented on the aliased types
+type (
+	// Options configures an embedded kaput-not instance, see Run
+	Options = kaputnot.Options
+
+	// ReconcilerOptions configures the egress rule logic
+	ReconcilerOptions = reconciler.Options
+
+	// ControllerOptions configures node watching
+	ControllerOptions = controller.Options
+
+	// ReconcileResult is passed to ControllerOptions.OnReconcileResult after every node reconcile
+	ReconcileResult = controller.ReconcileResult
+)
+
+// CachedNetmakerClient is a NetmakerClient with a TTL cache, as required by ReconcilerOptions.NetmakerClient
+type CachedNetmakerClient = netmaker.CachedClient
+
+// NewCachedNetmakerClient wraps client in the caching layer; a ttl of 0 uses the default of 30 seconds
+// Clients without NetworkLister are served without network metadata
+func NewCachedNetmakerClient(client NetmakerClient, ttl time.Duration) *CachedNetmakerClient {
+	return netmaker.NewCachedClient(netmaker.AsClient(client), ttl)
+}
+
+// Run syncs node pod CIDRs to Netmaker egress rules and blocks until ctx is canceled
+// Only the leader work is done: the embedding process owns leader election, health probes and metrics
+// Returns an error for invalid options or if the instance fails to start, never panics
+func Run(ctx context.Context, opts Options) error {
+	return kaputnot.Run(ctx, opts)
+}
@@ -62,3 +62,74 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Name of the Secret holding Netmaker credentials
+*/}}
+{{- define "kaput-not.secretName" -}}
+{{- default (include "kaput-not.fullname" .) .Values.netmaker.existingSecret }}
+{{- end }}
+
+{{/*
+Leader election lease name (suffixed with the instance ID unless a custom id is set)
+*/}}
+{{- define "kaput-not.leaderElectionID" -}}
+{{- if and .Values.instanceId (eq .Values.leaderElection.id "kaput-not") }}
+{{- printf "kaput-not-%s" .Values.instanceId }}
+{{- else }}
+{{- .Values.leaderElection.id }}
+{{- end }}
+{{- end }}
+
+{{/*
+Namespaces of the Roles: the release namespace (lease, state and trash ConfigMaps) and kube-system
+*/}}
+{{- define "kaput-not.roleNamespaces" -}}
+{{- list .Release.Namespace "kube-system" | uniq | sortAlpha | join " " }}
+{{- end }}
+
+{{/*
+Role rules of one namespace, mirroring "kaput-not rbac" (empty when the namespace needs none)
+Expects a dict with the chart context as "root" and the Role's "namespace"
+*/}}
+{{- define "kaput-not.roleRules" -}}
+{{- $root := .root }}
+{{- $values := .root.Values }}
+{{- $fullname := include "kaput-not.fullname" .root }}
+{{- if eq .namespace "kube-system" }}
+{{- if and $values.publishers.aggregateClusterCIDR (not $values.publishers.clusterCIDRs) }}
+# kube-controller-manager pods (read-only) - cluster CIDR auto-detection
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["list"]
+{{- end }}
+{{- if $values.clusterNetworks.watch }}
+# kubeadm-config and kube-proxy ConfigMaps (read-only) - cluster network watcher
+- apiGroups: [""]
+  resources: ["configmaps"]
+  verbs: ["list", "watch"]
+{{- end }}
+{{- end }}
+{{- if eq .namespace $root.Release.Namespace }}
+{{- range $configMap := list (ternary "state" "" $values.stateStore.enabled) (ternary "trash" "" $values.trash.enabled) | compact }}
+# {{ ternary "Persistent state store" "Trash bin" (eq $configMap "state") }} ConfigMap
+- apiGroups: [""]
+  resources: ["configmaps"]
+  verbs: ["create"]
+- apiGroups: [""]
+  resources: ["configmaps"]
+  resourceNames: [{{ printf "%s-%s" $fullname $configMap | quote }}]
+  verbs: ["get", "update"]
+{{- end }}
+{{- if $values.leaderElection.enabled }}
+# Leader election using Leases
+- apiGroups: ["coordination.k8s.io"]
+  resources: ["leases"]
+  verbs: ["create"]
+- apiGroups: ["coordination.k8s.io"]
+  resources: ["leases"]
+  resourceNames: [{{ include "kaput-not.leaderElectionID" $root | quote }}]
+  verbs: ["get", "update"]
+{{- end }}
+{{- end }}
+{{- end }}
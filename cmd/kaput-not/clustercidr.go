@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// controllerManagerNamespace is where kubeadm-style clusters run kube-controller-manager
+	controllerManagerNamespace = "kube-system"
+	// controllerManagerSelector matches the kube-controller-manager static pods
+	controllerManagerSelector = "component=kube-controller-manager"
+	// clusterCIDRFlag is the kube-controller-manager flag holding the cluster pod CIDRs
+	clusterCIDRFlag = "--cluster-cidr"
+)
+
+// detectClusterCIDRs reads the cluster pod CIDRs from the kube-controller-manager command line
+// Only works where the control plane runs as pods (e.g. kubeadm) - managed clusters need CLUSTER_CIDRS
+func detectClusterCIDRs(ctx context.Context, kubeClient kubernetes.Interface) ([]string, error) {
+	pods, err := kubeClient.CoreV1().Pods(controllerManagerNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: controllerManagerSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kube-controller-manager pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			args := append(append([]string{}, container.Command...), container.Args...)
+			if cidrs := parseClusterCIDRFlag(args); len(cidrs) > 0 {
+				return cidrs, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s not found on kube-controller-manager pods in %s (set CLUSTER_CIDRS explicitly)",
+		clusterCIDRFlag, controllerManagerNamespace)
+}
+
+// parseClusterCIDRFlag extracts the comma-separated CIDRs from --cluster-cidr
+// Supports both "--cluster-cidr=a,b" and "--cluster-cidr a,b"
+func parseClusterCIDRFlag(args []string) []string {
+	for i, arg := range args {
+		var value string
+		switch {
+		case strings.HasPrefix(arg, clusterCIDRFlag+"="):
+			value = strings.TrimPrefix(arg, clusterCIDRFlag+"=")
+		case arg == clusterCIDRFlag && i+1 < len(args):
+			value = args[i+1]
+		default:
+			continue
+		}
+		return splitList(value)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, trimming whitespace and dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// complianceReport lists every Kubernetes node with its egress rules and their deviations from the desired state
+type complianceReport struct {
+	GeneratedAt time.Time        `json:"generatedAt"`
+	Cluster     string           `json:"cluster,omitempty"`
+	Nodes       []complianceNode `json:"nodes"`
+	Compliant   int              `json:"compliant"`     // Nodes without discrepancies
+	Discrepant  int              `json:"discrepancies"` // Nodes with discrepancies
+}
+
+// complianceNode is a Kubernetes node (or the owner of rules without one) in the compliance report
+type complianceNode struct {
+	Node          string           `json:"node"`
+	PodCIDRs      []string         `json:"podCIDRs"`
+	Managed       bool             `json:"managed"`        // Supported publisher node, its egress rules are managed
+	Host          string           `json:"host,omitempty"` // Netmaker host ID
+	Rules         []complianceRule `json:"rules"`
+	Discrepancies []string         `json:"discrepancies"`
+}
+
+// complianceRule is a current Netmaker egress rule owned by a node
+type complianceRule struct {
+	Network string `json:"network"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Range   string `json:"range"`
+	Enabled bool   `json:"enabled"`
+}
+
+// runComplianceReport implements "kaput-not report --format json|csv" and returns the process exit code
+// The exit code does not reflect discrepancies, so a failing export is always an error (e.g. in a CronJob)
+func runComplianceReport(cfg *Config, format string) int {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	ctrl, rec, err := planningController(ctx, cfg)
+	if err != nil {
+		log.Printf("Failed to build compliance report: %v", err)
+		return 1
+	}
+	report, err := buildComplianceReport(ctx, cfg, ctrl, rec)
+	if err != nil {
+		log.Printf("Failed to build compliance report: %v", err)
+		return 1
+	}
+	if err := writeComplianceReport(os.Stdout, report, format); err != nil {
+		log.Printf("Failed to write compliance report: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// buildComplianceReport joins the nodes in the informer cache, their current egress rules and the planned changes
+// Only reads: the planned changes are the discrepancies (description-only updates like lease refreshes are not)
+func buildComplianceReport(ctx context.Context, cfg *Config, ctrl *controller.Controller, rec *reconciler.Reconciler) (*complianceReport, error) {
+	plan, err := ctrl.Plan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan: %w", err)
+	}
+	current, err := rec.NodeEgresses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list egress rules: %w", err)
+	}
+
+	nodes := make(map[string]*complianceNode)
+	for _, state := range ctrl.State().Nodes {
+		node := &complianceNode{Node: state.Name, PodCIDRs: state.PodCIDRs, Managed: state.Supported && state.Publisher}
+		if state.Netmaker != nil {
+			node.Host = state.Netmaker.ID
+		}
+		if node.Managed && node.Host == "" {
+			node.Discrepancies = append(node.Discrepancies, "no Netmaker host")
+		}
+		if node.Managed && len(node.PodCIDRs) == 0 {
+			node.Discrepancies = append(node.Discrepancies, "no pod CIDRs")
+		}
+		nodes[state.Name] = node
+	}
+	nodeFor := func(name string) *complianceNode {
+		if node, ok := nodes[name]; ok {
+			return node
+		}
+		node := &complianceNode{Node: name, Discrepancies: []string{"no Kubernetes node"}}
+		nodes[name] = node
+		return node
+	}
+
+	for name, egresses := range current {
+		node := nodeFor(name)
+		for _, egress := range egresses {
+			node.Rules = append(node.Rules, complianceRule{
+				Network: egress.Network,
+				ID:      egress.ID,
+				Name:    egress.Name,
+				Range:   egress.Range,
+				Enabled: egress.Status,
+			})
+		}
+	}
+
+	for _, network := range plan.Networks {
+		for _, change := range network.Changes {
+			if change.Action == reconciler.ChangeUpdate && change.PreviousRange == "" && change.PreviousGateways == nil {
+				continue // Description only
+			}
+			node := nodeFor(changeOwner(change))
+			node.Discrepancies = append(node.Discrepancies, describeChange(network.Network, change))
+		}
+	}
+
+	report := &complianceReport{GeneratedAt: time.Now().UTC(), Cluster: cfg.ClusterName, Nodes: make([]complianceNode, 0, len(nodes))}
+	for _, name := range sortedNames(nodes) {
+		node := nodes[name]
+		if node.PodCIDRs == nil {
+			node.PodCIDRs = []string{}
+		}
+		if node.Rules == nil {
+			node.Rules = []complianceRule{}
+		}
+		if node.Discrepancies == nil {
+			node.Discrepancies = []string{}
+			report.Compliant++
+		} else {
+			report.Discrepant++
+		}
+		report.Nodes = append(report.Nodes, *node)
+	}
+	return report, nil
+}
+
+// changeOwner returns the node a planned change belongs to: its primary gateway
+func changeOwner(change reconciler.PlannedChange) string {
+	for _, gateways := range [][]reconciler.Gateway{change.Gateways, change.PreviousGateways} {
+		for _, gateway := range gateways {
+			if gateway.Primary {
+				return gateway.Node
+			}
+		}
+	}
+	return "-" // Rules without a primary gateway
+}
+
+// describeChange describes what a planned change corrects, e.g. "missing rule \"x\" (10.0.0.0/24) in network n"
+func describeChange(network string, change reconciler.PlannedChange) string {
+	switch change.Action {
+	case reconciler.ChangeCreate:
+		return fmt.Sprintf("missing rule %q (%s) in network %s", change.Name, change.Range, network)
+	case reconciler.ChangeDelete:
+		return fmt.Sprintf("surplus rule %q (%s) in network %s", change.Name, change.Range, network)
+	}
+	var drift []string
+	if change.PreviousRange != "" {
+		drift = append(drift, fmt.Sprintf("range %s instead of %s", change.PreviousRange, change.Range))
+	}
+	if change.PreviousGateways != nil {
+		drift = append(drift, fmt.Sprintf("gateways %s instead of %s", gatewayList(change.PreviousGateways), gatewayList(change.Gateways)))
+	}
+	return fmt.Sprintf("outdated rule %q in network %s: %s", change.Name, network, strings.Join(drift, ", "))
+}
+
+// gatewayList formats gateways as "node:metric" entries, preferred first
+func gatewayList(gateways []reconciler.Gateway) string {
+	entries := make([]string, len(gateways))
+	for i, gateway := range gateways {
+		entries[i] = fmt.Sprintf("%s:%d", gateway.Node, gateway.Metric)
+	}
+	return strings.Join(entries, " ")
+}
+
+// sortedNames returns the node names of the report, sorted
+func sortedNames(nodes map[string]*complianceNode) []string {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeComplianceReport writes the report as JSON, or as CSV with one row per node (lists joined with ";")
+func writeComplianceReport(w io.Writer, report *complianceReport, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"node", "pod_cidrs", "managed", "netmaker_host", "rules", "compliant", "discrepancies"})
+	for _, node := range report.Nodes {
+		rules := make([]string, len(node.Rules))
+		for i, rule := range node.Rules {
+			rules[i] = fmt.Sprintf("%s/%s=%s", rule.Network, rule.Name, rule.Range)
+			if !rule.Enabled {
+				rules[i] += " (off)"
+			}
+		}
+		_ = writer.Write([]string{
+			node.Node,
+			strings.Join(node.PodCIDRs, ";"),
+			fmt.Sprint(node.Managed),
+			node.Host,
+			strings.Join(rules, ";"),
+			fmt.Sprint(len(node.Discrepancies) == 0),
+			strings.Join(node.Discrepancies, ";"),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -2,68 +2,724 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/leaderelection"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
 )
 
 const (
 	// serviceAccountNamespaceFile is the path to the namespace file mounted in pods
 	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	// authModePassword logs in with NETMAKER_USERNAME/NETMAKER_PASSWORD
+	authModePassword = "password"
+	// authModeTokenExchange exchanges the projected service account token for a Netmaker session
+	authModeTokenExchange = "token-exchange"
+	// authModeVault reads the Netmaker username/password from a HashiCorp Vault secret
+	authModeVault = "vault"
+
+	// clusterNetworkPod advertises the cluster pod subnets on HA gateways
+	clusterNetworkPod = "pod"
+	// clusterNetworkService advertises the cluster service subnets on HA gateways
+	clusterNetworkService = "service"
 )
 
 // Config holds all configuration loaded from environment variables
 type Config struct {
 	// Netmaker configuration
-	NetmakerAPIURL   string
+	NetmakerAPIURL   string `mask:"url"`
 	NetmakerUsername string
-	NetmakerPassword string
+	NetmakerPassword string `mask:"secret"`
+	// Credential files take precedence over the values above and are re-read on every login
+	NetmakerUsernameFile string
+	NetmakerPasswordFile string
+	// Optional - directory with a <network>/username and <network>/password pair per network with its own user
+	NetmakerNetworkCredentialsDir string
 	// Networks are auto-discovered by looking up Netmaker host nodes
 
+	// Netmaker authentication configuration
+	NetmakerAuthMode              string        // "password" (default), "token-exchange" or "vault"
+	NetmakerTokenExchangeURL      string        `mask:"url"` // Required for token-exchange mode
+	NetmakerTokenExchangeAudience string        // Optional RFC 8693 audience
+	NetmakerServiceAccountToken   string        // Path to the projected service account token
+	NetmakerAuthHeader            string        // "bearer" (default) or "x-api-key"
+	NetmakerLoginPath             string        // Optional - login path for password/vault mode (default: stock Netmaker)
+	NetmakerLoginUsernameField    string        // Optional - JSON key of the username in the login payload
+	NetmakerLoginPasswordField    string        // Optional - JSON key of the password in the login payload
+	NetmakerLoginTokenField       string        // Optional - dot-separated path of the token in the login response
+	NetmakerCacheTTL              time.Duration // 0 uses the client default (30s)
+	NetmakerCacheFlushToken       string        `mask:"secret"` // Bearer token for POST /admin/cache/flush (empty disables the endpoint)
+	NetmakerTokenRefreshMargin    time.Duration // Refresh JWTs this long before exp; 0 disables proactive refresh
+	NetmakerHostPollInterval      time.Duration // List hosts this often to reconcile newly enrolled nodes; 0 disables
+	NetmakerCreateNetworks        []string      // Optional - "name=cidr" entries, networks created when missing
+	NetmakerProxyURL              string        `mask:"url"` // Optional - explicit http(s)/socks5 proxy; HTTPS_PROXY applies otherwise
+	NetmakerNoProxy               string        // Hosts bypassing NetmakerProxyURL (NO_PROXY)
+	NetmakerReadOnlyNetworks      []string      // Optional - networks whose drift is reported but never corrected
+	NetmakerDefaultNetwork        string        // Optional - network hosts in no network are joined to
+	NetmakerNetworkTimeouts       []string      // Optional - "network=timeout[/retries]" entries for slow networks
+	NetmakerMaxMutations          int           // Concurrent Netmaker writes allowed; 0 = unlimited
+	NetmakerMaxResponseBytes      int           // Size limit of Netmaker API responses; 0 uses the client default (64 MiB)
+	NetmakerTLSMinVersion         string        // Optional - "1.2" (default) or "1.3"
+	NetmakerTLSCipherSuites       []string      // Optional - allowed TLS 1.2 cipher suites by IANA name
+	NetmakerTLSFIPS               bool          // Restrict TLS to FIPS 140-3 approved settings
+	NetmakerPermissionCheck       bool          // Verify the Netmaker users' roles at startup, fail fast when rights are missing
+
+	// Mutation hook configuration
+	HookCommand    []string      // Optional - command run before and after every egress rule mutation
+	HookWebhookURL string        `mask:"url"` // Optional - URL receiving a POST before and after every egress rule mutation
+	HookTimeout    time.Duration // 0 uses the hook default (10s)
+
+	// Vault configuration (vault mode only)
+	VaultAddress     string `mask:"url"`
+	VaultRole        string
+	VaultSecretPath  string // e.g. "secret/data/kaput-not" for KV v2
+	VaultAuthMount   string // Optional - defaults to "kubernetes"
+	VaultNamespace   string // Optional - Vault Enterprise namespace
+	VaultTokenFile   string // Optional - defaults to the pod's service account token
+	VaultUsernameKey string // Optional - defaults to "username"
+	VaultPasswordKey string // Optional - defaults to "password"
+
 	// Kubernetes configuration
 	Kubeconfig  string // Optional - empty means in-cluster
 	ClusterName string // Optional - for multi-cluster deployments sharing a Netmaker network
+	InstanceID  string // Optional - for several kaput-not instances in one cluster
+
+	// Kubernetes API client tuning (for congested API servers in large clusters)
+	KubeClientQPS           float32 // 0 uses the client-go default (5)
+	KubeClientBurst         int     // 0 uses the client-go default (10)
+	KubeWatchBookmark       bool    // Watch bookmarks enabled by default
+	KubeInformerMaxRestarts int     // Node informer restarts during watch failure storms; 0 disables
+
+	// Node selection configuration
+	IncludeWindowsNodes   bool          // Windows nodes are skipped by default
+	NodeDeletionDelay     time.Duration // 0 uses the controller default (10s)
+	HAGatewaySelector     string        // Optional - label selector for HA backup gateway nodes
+	HostNotFoundThreshold time.Duration // 0 uses the controller default (15m)
+	CanaryNode            string        // Optional - reconciled and verified alone before all other nodes
+	GatingTaints          []string      // Optional - taint keys that turn a node's egress rules off
+	AutoscalerScaleDown   string        // Optional - gate nodes cluster-autoscaler removes ("deleting" or "candidates")
+	KarpenterNodeClaims   bool          // Gate nodes whose Karpenter NodeClaim is being deleted
+	GatewayHealthCheck    bool          // Turn rules off while none of their gateways is healthy in Netmaker
+	GatewayStaleAfter     time.Duration // 0 uses the reconciler default (5m)
+
+	// Mass node churn configuration
+	ChurnThreshold int           // Node add/delete events per ChurnWindow switching to bulk reconciliation; 0 disables
+	ChurnWindow    time.Duration // 0 uses the controller default (1m)
+
+	// Quarantine configuration
+	QuarantineThreshold     int           // Consecutive failures before a node is quarantined; 0 disables
+	QuarantineRetryInterval time.Duration // 0 uses the controller default (10m)
+
+	// Topology-aware publisher configuration
+	PublisherSelector    string   // Optional - only matching nodes publish egress rules
+	AggregateClusterCIDR bool     // Publishers advertise the cluster CIDRs instead of their own
+	ClusterCIDRs         []string // Optional - auto-detected from kube-controller-manager if empty
+	SummarizePodCIDRs    bool     // Publishers advertise the summarized node pod CIDRs instead of their own
+	PoolLabel            string   // Optional - nodes with the same value of this label share egress rules
+
+	// Cilium IP pool configuration
+	CiliumIPPools        bool   // Publish the CIDRs allocated from CiliumPodIPPools instead of spec.podCIDRs
+	CiliumIPPoolSelector string // Optional - label selector for the advertised pools (empty = all)
+
+	// External client configuration
+	ManageExtClients bool // Expose annotated nodes' pod CIDRs to Netmaker external clients
+
+	// ClusterEgressRule configuration
+	ManageEgressRules bool // Route the CIDRs of ClusterEgressRule resources through their selected nodes
+
+	// Cluster network configuration
+	WatchClusterNetworks     bool     // Watch kubeadm-config / kube-proxy for the pod and service subnets
+	AdvertiseClusterNetworks []string // Optional - subnet kinds ("pod", "service") published by HA gateways
+
+	// Adoption of hand-made egress rules
+	AdoptExisting bool // Take over unmanaged rules matching a node's pod CIDR and Netmaker node
+
+	// Host matching
+	MatchHostsByAddress bool // Fall back to matching hosts by node IP when no host has the node's name
+
+	// Out-of-band change detection
+	DetectExternalChanges      bool // Log, count and emit events for managed rules changed outside kaput-not
+	DetectClusterNameConflicts bool // Pause orphan cleanup when another kaput-not writes rules as this cluster
+
+	// Cost and ownership metadata
+	DescriptionLabels []string // Optional - "key=node-label" entries, node label values embedded in rule descriptions
+	EgressNameMarker  bool     // Also record the ownership key in egress names, for UIs stripping descriptions
+
+	// Gateway preference
+	EgressMetrics string // Optional - ";"-separated "selector:metric" entries, primary metrics of matching nodes' rules
+
+	// Egress lease configuration
+	EgressLeaseDuration    time.Duration // 0 disables leases
+	EgressLeaseGracePeriod time.Duration // 0 uses the reconciler default
+
+	// Orphan cleanup bounds
+	CleanupBatchSize         int           // 0 uses the reconciler default
+	CleanupTimeBudget        time.Duration // 0 uses the reconciler default
+	CleanupDeleteConcurrency int           // 0 uses the reconciler default
+
+	// Resync snapshot listing
+	ResyncListConcurrency int // Networks listed in parallel, 0 uses the reconciler default
+
+	// Runtime configuration
+	WatchKaputNotConfig bool // Apply the KaputNotConfig custom resource's settings without restarts
+
+	// State store configuration
+	StateConfigMap string // Optional - empty disables the persistent state store
+
+	// Trash bin configuration
+	TrashConfigMap  string        // Optional - empty disables keeping copies of deleted egress rules
+	TrashMaxEntries int           // 0 uses the trash default
+	TrashTTL        time.Duration // 0 uses the trash default
 
 	// Leader election configuration
 	LeaderElectionEnabled   bool
 	LeaderElectionNamespace string
 	LeaderElectionID        string
+	LeaderElectionIdentity  string // POD_NAME_POD_UID from the downward API, or the hostname
+	LeaderElectionHandoff   bool   // Leave the cleanup state on the lease at shutdown, so the next leader skips its initial cleanup
+
+	// Observability configuration
+	MetricsBindAddress         string // Empty disables the metrics server
+	DashboardEnabled           bool   // Serve the read-only status page on /dashboard/ of the metrics server
+	InformerCacheWarnThreshold int    // 0 disables the warning
+	EgressCacheWarnThreshold   int    // 0 disables the warning
+
+	// Admin gRPC service configuration (optional)
+	AdminGRPCBindAddress   string   // Empty disables the admin service
+	AdminGRPCAllowedUsers  []string // Usernames allowed to call the service
+	AdminGRPCAllowedGroups []string // Groups whose members may call the service
+	AdminGRPCAudiences     []string // Audiences the callers' tokens must be issued for (empty: the API server's)
+	AdminGRPCTLSCertFile   string   // Empty serves plaintext
+	AdminGRPCTLSKeyFile    string
 }
 
-// LoadConfig loads configuration from environment variables
+// LoadConfig loads configuration from environment variables and validates it
 // Following twelve-factor app principles, all configuration comes from env vars
 // Auto-detects in-cluster vs local environment for smart defaults
+// All problems are reported together (see configErrors) instead of one per attempt
 func LoadConfig() (*Config, error) {
+	cfg, errs := readConfig()
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, configErrors(errs)
+	}
+	return cfg, nil
+}
+
+// readConfig reads configuration from environment variables without validating it
+// Returns the values that failed to parse (their defaults are used instead)
+// Commands that don't talk to Netmaker (e.g. rbac) only need the feature toggles
+func readConfig() (*Config, []error) {
 	// Detect if running in-cluster
 	inCluster := isInCluster()
+	env := &envReader{}
 
 	cfg := &Config{
 		// Netmaker configuration (required)
-		NetmakerAPIURL:   os.Getenv("NETMAKER_API_URL"),
-		NetmakerUsername: os.Getenv("NETMAKER_USERNAME"),
-		NetmakerPassword: os.Getenv("NETMAKER_PASSWORD"),
+		NetmakerAPIURL:   getenv("NETMAKER_API_URL"),
+		NetmakerUsername: getenv("NETMAKER_USERNAME"),
+		NetmakerPassword: getenv("NETMAKER_PASSWORD"),
+		// Credential files (optional, e.g. mounted Secrets or CSI secret stores)
+		NetmakerUsernameFile: getenv("NETMAKER_USERNAME_FILE"),
+		NetmakerPasswordFile: getenv("NETMAKER_PASSWORD_FILE"),
+		// Per-network credentials (optional, least-privilege users per network)
+		NetmakerNetworkCredentialsDir: getenv("NETMAKER_NETWORK_CREDENTIALS_DIR"),
 		// Networks are auto-discovered by querying Netmaker
 
+		// Netmaker authentication configuration (optional)
+		NetmakerAuthMode:              getEnvWithDefault("NETMAKER_AUTH_MODE", authModePassword),
+		NetmakerTokenExchangeURL:      getenv("NETMAKER_TOKEN_EXCHANGE_URL"),
+		NetmakerTokenExchangeAudience: getenv("NETMAKER_TOKEN_EXCHANGE_AUDIENCE"),
+		NetmakerServiceAccountToken:   getEnvWithDefault("NETMAKER_SA_TOKEN_FILE", "/var/run/secrets/tokens/netmaker-token"),
+		NetmakerAuthHeader:            getEnvWithDefault("NETMAKER_AUTH_HEADER", netmaker.AuthHeaderBearer),
+		NetmakerLoginPath:             getenv("NETMAKER_LOGIN_PATH"),
+		NetmakerLoginUsernameField:    getenv("NETMAKER_LOGIN_USERNAME_FIELD"),
+		NetmakerLoginPasswordField:    getenv("NETMAKER_LOGIN_PASSWORD_FIELD"),
+		NetmakerLoginTokenField:       getenv("NETMAKER_LOGIN_TOKEN_FIELD"),
+		NetmakerCacheTTL:              env.duration("NETMAKER_CACHE_TTL", 0),
+		NetmakerCacheFlushToken:       getenv("NETMAKER_CACHE_FLUSH_TOKEN"),
+		NetmakerTokenRefreshMargin:    env.duration("NETMAKER_TOKEN_REFRESH_MARGIN", time.Minute),
+		NetmakerHostPollInterval:      env.duration("NETMAKER_HOST_POLL_INTERVAL", 0),
+		NetmakerCreateNetworks:        splitList(getenv("NETMAKER_CREATE_NETWORKS")),
+		NetmakerProxyURL:              getenv("NETMAKER_PROXY_URL"),
+		NetmakerNoProxy:               getEnvWithDefault("NO_PROXY", getenv("no_proxy")),
+		NetmakerReadOnlyNetworks:      splitList(getenv("NETMAKER_READ_ONLY_NETWORKS")),
+		NetmakerDefaultNetwork:        getenv("NETMAKER_DEFAULT_NETWORK"),
+		NetmakerNetworkTimeouts:       splitList(getenv("NETMAKER_NETWORK_TIMEOUTS")),
+		NetmakerMaxMutations:          env.integer("NETMAKER_MAX_CONCURRENT_MUTATIONS", 0),
+		NetmakerMaxResponseBytes:      env.integer("NETMAKER_MAX_RESPONSE_BYTES", 0),
+		NetmakerTLSMinVersion:         getenv("NETMAKER_TLS_MIN_VERSION"),
+		NetmakerTLSCipherSuites:       splitList(getenv("NETMAKER_TLS_CIPHER_SUITES")),
+		NetmakerTLSFIPS:               env.boolean("NETMAKER_TLS_FIPS", false),
+		NetmakerPermissionCheck:       env.boolean("NETMAKER_PERMISSION_CHECK", true),
+
+		// Mutation hook configuration (optional)
+		HookCommand:    strings.Fields(getenv("HOOK_COMMAND")),
+		HookWebhookURL: getenv("HOOK_WEBHOOK_URL"),
+		HookTimeout:    env.duration("HOOK_TIMEOUT", 0),
+
+		// Vault configuration (optional)
+		VaultAddress:     getenv("VAULT_ADDR"),
+		VaultRole:        getenv("VAULT_ROLE"),
+		VaultSecretPath:  getenv("VAULT_SECRET_PATH"),
+		VaultAuthMount:   getenv("VAULT_AUTH_MOUNT"),
+		VaultNamespace:   getenv("VAULT_NAMESPACE"),
+		VaultTokenFile:   getenv("VAULT_SA_TOKEN_FILE"),
+		VaultUsernameKey: getenv("VAULT_USERNAME_KEY"),
+		VaultPasswordKey: getenv("VAULT_PASSWORD_KEY"),
+
 		// Kubernetes configuration (optional)
-		Kubeconfig:  os.Getenv("KUBECONFIG"),
-		ClusterName: os.Getenv("K8S_CLUSTER_NAME"), // Optional - for multi-cluster deployments
+		Kubeconfig:  getenv("KUBECONFIG"),
+		ClusterName: getenv("K8S_CLUSTER_NAME"), // Optional - for multi-cluster deployments
+		InstanceID:  getenv("INSTANCE_ID"),      // Optional - for several instances in one cluster
+
+		// Kubernetes API client tuning (optional)
+		KubeClientQPS:           float32(env.float("KUBE_CLIENT_QPS", 0)),
+		KubeClientBurst:         env.integer("KUBE_CLIENT_BURST", 0),
+		KubeWatchBookmark:       env.boolean("KUBE_WATCH_BOOKMARKS", true),
+		KubeInformerMaxRestarts: env.integer("KUBE_INFORMER_MAX_RESTARTS", 3),
+
+		// Node selection configuration (optional)
+		IncludeWindowsNodes:   env.boolean("INCLUDE_WINDOWS_NODES", false),
+		NodeDeletionDelay:     env.duration("NODE_DELETION_DELAY", 0),
+		HAGatewaySelector:     getenv("HA_GATEWAY_SELECTOR"),
+		HostNotFoundThreshold: env.duration("HOST_NOT_FOUND_THRESHOLD", 0),
+		CanaryNode:            getenv("CANARY_NODE"),
+		GatingTaints:          splitList(getenv("GATING_TAINTS")),
+		AutoscalerScaleDown:   getenv("AUTOSCALER_SCALE_DOWN"),
+		KarpenterNodeClaims:   env.boolean("KARPENTER_NODECLAIMS", false),
+		GatewayHealthCheck:    env.boolean("GATEWAY_HEALTH_CHECK", false),
+		GatewayStaleAfter:     env.duration("GATEWAY_STALE_AFTER", 0),
+
+		// Mass node churn configuration (optional)
+		ChurnThreshold: env.integer("CHURN_THRESHOLD", 0),
+		ChurnWindow:    env.duration("CHURN_WINDOW", 0),
+
+		// Quarantine configuration (optional)
+		QuarantineThreshold:     env.integer("QUARANTINE_FAILURE_THRESHOLD", 10),
+		QuarantineRetryInterval: env.duration("QUARANTINE_RETRY_INTERVAL", 0),
+
+		// Topology-aware publisher configuration (optional)
+		PublisherSelector:    getenv("PUBLISHER_SELECTOR"),
+		AggregateClusterCIDR: env.boolean("AGGREGATE_CLUSTER_CIDR", false),
+		ClusterCIDRs:         splitList(getenv("CLUSTER_CIDRS")),
+		SummarizePodCIDRs:    env.boolean("SUMMARIZE_POD_CIDRS", false),
+		PoolLabel:            getenv("POOL_LABEL"),
+
+		// Cilium IP pool configuration (optional, requires Cilium multi-pool IPAM)
+		CiliumIPPools:        env.boolean("CILIUM_IP_POOLS", false),
+		CiliumIPPoolSelector: getenv("CILIUM_IP_POOL_SELECTOR"),
+
+		// External client configuration (optional)
+		ManageExtClients: env.boolean("MANAGE_EXTCLIENTS", false),
+
+		// ClusterEgressRule configuration (optional, requires the CRD)
+		ManageEgressRules: env.boolean("MANAGE_EGRESS_RULES", false),
+
+		// Cluster network configuration (optional)
+		WatchClusterNetworks:     env.boolean("WATCH_CLUSTER_NETWORKS", false),
+		AdvertiseClusterNetworks: splitList(getenv("ADVERTISE_CLUSTER_NETWORKS")),
+
+		// Adoption of hand-made egress rules (optional)
+		AdoptExisting: env.boolean("ADOPT_EXISTING", false),
+
+		// Host matching (optional)
+		MatchHostsByAddress: env.boolean("MATCH_HOSTS_BY_ADDRESS", false),
+
+		// Out-of-band change detection (optional)
+		DetectExternalChanges:      env.boolean("DETECT_EXTERNAL_CHANGES", false),
+		DetectClusterNameConflicts: env.boolean("DETECT_CLUSTER_NAME_CONFLICTS", false),
+
+		// Cost and ownership metadata (optional)
+		DescriptionLabels: splitList(getenv("DESCRIPTION_LABELS")),
+		EgressNameMarker:  env.boolean("EGRESS_NAME_MARKER", false),
+
+		EgressMetrics: getenv("EGRESS_METRICS"),
+
+		// Egress lease configuration (optional)
+		EgressLeaseDuration:    env.duration("EGRESS_LEASE_DURATION", 0),
+		EgressLeaseGracePeriod: env.duration("EGRESS_LEASE_GRACE_PERIOD", 0),
+
+		// Orphan cleanup bounds (optional)
+		CleanupBatchSize:         env.integer("CLEANUP_BATCH_SIZE", 0),
+		CleanupTimeBudget:        env.duration("CLEANUP_TIME_BUDGET", 0),
+		CleanupDeleteConcurrency: env.integer("CLEANUP_DELETE_CONCURRENCY", 0),
+
+		// Resync snapshot listing (optional)
+		ResyncListConcurrency: env.integer("RESYNC_LIST_CONCURRENCY", 0),
+
+		// Runtime configuration (optional, requires the CRD)
+		WatchKaputNotConfig: env.boolean("WATCH_KAPUT_NOT_CONFIG", false),
+
+		// State store configuration (optional)
+		StateConfigMap: getenv("STATE_CONFIGMAP"),
+
+		// Trash bin configuration (optional)
+		TrashConfigMap:  getenv("TRASH_CONFIGMAP"),
+		TrashMaxEntries: env.integer("TRASH_MAX_ENTRIES", 0),
+		TrashTTL:        env.duration("TRASH_TTL", 0),
 
 		// Leader election configuration (auto-detected with overrides)
-		LeaderElectionEnabled:   detectLeaderElection(inCluster),
+		LeaderElectionEnabled:   detectLeaderElection(env, inCluster),
 		LeaderElectionNamespace: detectNamespace(inCluster),
-		LeaderElectionID:        getEnvWithDefault("LEADER_ELECTION_ID", "kaput-not"),
+		LeaderElectionID:        getEnvWithDefault("LEADER_ELECTION_ID", instanceName("kaput-not", getenv("INSTANCE_ID"))),
+		LeaderElectionIdentity:  leaderelection.Identity(getenv("POD_NAME"), getenv("POD_UID")),
+		LeaderElectionHandoff:   env.boolean("LEADER_ELECTION_HANDOFF", false),
+
+		// Observability configuration (optional)
+		MetricsBindAddress:         getEnvWithDefault("METRICS_BIND_ADDRESS", ":8080"),
+		DashboardEnabled:           env.boolean("DASHBOARD_ENABLED", false),
+		InformerCacheWarnThreshold: env.integer("CACHE_WARN_INFORMER_OBJECTS", 0),
+		EgressCacheWarnThreshold:   env.integer("CACHE_WARN_EGRESS_ENTRIES", 0),
+
+		// Admin gRPC service configuration (optional)
+		AdminGRPCBindAddress:   getenv("ADMIN_GRPC_BIND_ADDRESS"),
+		AdminGRPCAllowedUsers:  splitList(getenv("ADMIN_GRPC_ALLOWED_USERS")),
+		AdminGRPCAllowedGroups: splitList(getenv("ADMIN_GRPC_ALLOWED_GROUPS")),
+		AdminGRPCAudiences:     splitList(getenv("ADMIN_GRPC_AUDIENCES")),
+		AdminGRPCTLSCertFile:   getenv("ADMIN_GRPC_TLS_CERT_FILE"),
+		AdminGRPCTLSKeyFile:    getenv("ADMIN_GRPC_TLS_KEY_FILE"),
 	}
 
-	// Validate required fields
+	return cfg, env.errors
+}
+
+// validate checks required fields, URLs, enums and incompatible settings
+// Returns every problem found, not just the first
+func (cfg *Config) validate() []error {
+	var errs []error
 	if cfg.NetmakerAPIURL == "" {
-		return nil, fmt.Errorf("NETMAKER_API_URL is required")
+		errs = append(errs, fmt.Errorf("NETMAKER_API_URL is required"))
+	} else if err := validateURL("NETMAKER_API_URL", cfg.NetmakerAPIURL, "http", "https"); err != nil {
+		errs = append(errs, err)
 	}
-	if cfg.NetmakerUsername == "" {
-		return nil, fmt.Errorf("NETMAKER_USERNAME is required")
+	if cfg.NetmakerProxyURL != "" {
+		if err := validateURL("NETMAKER_PROXY_URL", cfg.NetmakerProxyURL, "http", "https", "socks5"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.HookWebhookURL != "" {
+		if err := validateURL("HOOK_WEBHOOK_URL", cfg.HookWebhookURL, "http", "https"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.AggregateClusterCIDR && cfg.PublisherSelector == "" {
+		errs = append(errs, fmt.Errorf("PUBLISHER_SELECTOR is required when AGGREGATE_CLUSTER_CIDR is enabled"))
+	}
+	if cfg.SummarizePodCIDRs && cfg.PublisherSelector == "" {
+		errs = append(errs, fmt.Errorf("PUBLISHER_SELECTOR is required when SUMMARIZE_POD_CIDRS is enabled"))
+	}
+	if cfg.AggregateClusterCIDR && cfg.SummarizePodCIDRs {
+		errs = append(errs, fmt.Errorf("AGGREGATE_CLUSTER_CIDR and SUMMARIZE_POD_CIDRS are mutually exclusive"))
 	}
-	if cfg.NetmakerPassword == "" {
-		return nil, fmt.Errorf("NETMAKER_PASSWORD is required")
+	if cfg.AutoscalerScaleDown != "" && cfg.AutoscalerScaleDown != controller.ScaleDownDeleting && cfg.AutoscalerScaleDown != controller.ScaleDownCandidates {
+		errs = append(errs, fmt.Errorf("AUTOSCALER_SCALE_DOWN must be %q or %q, got %q", controller.ScaleDownDeleting, controller.ScaleDownCandidates, cfg.AutoscalerScaleDown))
+	}
+	if cfg.PoolLabel != "" && (cfg.AggregateClusterCIDR || cfg.SummarizePodCIDRs) {
+		errs = append(errs, fmt.Errorf("POOL_LABEL cannot be combined with AGGREGATE_CLUSTER_CIDR or SUMMARIZE_POD_CIDRS"))
+	}
+	if cfg.CiliumIPPoolSelector != "" && !cfg.CiliumIPPools {
+		errs = append(errs, fmt.Errorf("CILIUM_IP_POOLS is required when CILIUM_IP_POOL_SELECTOR is set"))
+	}
+	if cfg.CiliumIPPools && cfg.AggregateClusterCIDR {
+		errs = append(errs, fmt.Errorf("AGGREGATE_CLUSTER_CIDR would publish the pods of unselected IP pools, it cannot be combined with CILIUM_IP_POOLS"))
+	}
+	if len(cfg.AdvertiseClusterNetworks) > 0 {
+		if !cfg.WatchClusterNetworks || cfg.HAGatewaySelector == "" {
+			errs = append(errs, fmt.Errorf("WATCH_CLUSTER_NETWORKS and HA_GATEWAY_SELECTOR are required when ADVERTISE_CLUSTER_NETWORKS is set"))
+		}
+		for _, kind := range cfg.AdvertiseClusterNetworks {
+			if kind != clusterNetworkPod && kind != clusterNetworkService {
+				errs = append(errs, fmt.Errorf("ADVERTISE_CLUSTER_NETWORKS entries must be %q or %q, got %q", clusterNetworkPod, clusterNetworkService, kind))
+			}
+		}
+	}
+	if cfg.AdminGRPCBindAddress != "" {
+		if len(cfg.AdminGRPCAllowedUsers) == 0 && len(cfg.AdminGRPCAllowedGroups) == 0 {
+			errs = append(errs, fmt.Errorf("ADMIN_GRPC_ALLOWED_USERS or ADMIN_GRPC_ALLOWED_GROUPS is required when ADMIN_GRPC_BIND_ADDRESS is set"))
+		}
+		if (cfg.AdminGRPCTLSCertFile == "") != (cfg.AdminGRPCTLSKeyFile == "") {
+			errs = append(errs, fmt.Errorf("ADMIN_GRPC_TLS_CERT_FILE and ADMIN_GRPC_TLS_KEY_FILE must be set together"))
+		}
+	}
+	if err := reconciler.ValidateInstanceID(cfg.InstanceID); err != nil {
+		errs = append(errs, fmt.Errorf("invalid INSTANCE_ID: %w", err))
+	}
+	for _, setting := range []struct {
+		key   string
+		value int
+	}{
+		{"NETMAKER_MAX_CONCURRENT_MUTATIONS", cfg.NetmakerMaxMutations},
+		{"NETMAKER_MAX_RESPONSE_BYTES", cfg.NetmakerMaxResponseBytes},
+		{"KUBE_CLIENT_BURST", cfg.KubeClientBurst},
+		{"KUBE_INFORMER_MAX_RESTARTS", cfg.KubeInformerMaxRestarts},
+		{"QUARANTINE_FAILURE_THRESHOLD", cfg.QuarantineThreshold},
+		{"CHURN_THRESHOLD", cfg.ChurnThreshold},
+		{"CLEANUP_BATCH_SIZE", cfg.CleanupBatchSize},
+		{"CLEANUP_DELETE_CONCURRENCY", cfg.CleanupDeleteConcurrency},
+		{"RESYNC_LIST_CONCURRENCY", cfg.ResyncListConcurrency},
+		{"CACHE_WARN_INFORMER_OBJECTS", cfg.InformerCacheWarnThreshold},
+		{"CACHE_WARN_EGRESS_ENTRIES", cfg.EgressCacheWarnThreshold},
+	} {
+		if setting.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", setting.key, setting.value))
+		}
+	}
+	if cfg.KubeClientQPS < 0 {
+		errs = append(errs, fmt.Errorf("KUBE_CLIENT_QPS must not be negative, got %g", cfg.KubeClientQPS))
+	}
+	if _, err := cfg.createNetworks(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid NETMAKER_CREATE_NETWORKS: %w", err))
+	}
+	if _, err := cfg.descriptionLabels(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid DESCRIPTION_LABELS: %w", err))
+	}
+	if _, err := cfg.egressMetrics(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid EGRESS_METRICS: %w", err))
+	}
+	if _, err := cfg.networkTimeouts(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid NETMAKER_NETWORK_TIMEOUTS: %w", err))
+	}
+	if err := cfg.tlsPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid NETMAKER_TLS_*: %w", err))
+	}
+	if cfg.NetmakerAuthHeader != netmaker.AuthHeaderBearer && cfg.NetmakerAuthHeader != netmaker.AuthHeaderAPIKey {
+		errs = append(errs, fmt.Errorf("NETMAKER_AUTH_HEADER must be %q or %q, got %q", netmaker.AuthHeaderBearer, netmaker.AuthHeaderAPIKey, cfg.NetmakerAuthHeader))
+	}
+	if endpoint := cfg.loginEndpoint(); !endpoint.IsZero() {
+		if cfg.NetmakerAuthMode == authModeTokenExchange {
+			errs = append(errs, fmt.Errorf("NETMAKER_LOGIN_* only apply to NETMAKER_AUTH_MODE=%s or %s", authModePassword, authModeVault))
+		} else {
+			endpoint.ApplyDefaults()
+			if err := endpoint.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("invalid NETMAKER_LOGIN_*: %w", err))
+			}
+		}
+	}
+	switch cfg.NetmakerAuthMode {
+	case authModePassword:
+		if cfg.NetmakerUsername == "" && cfg.NetmakerUsernameFile == "" {
+			errs = append(errs, fmt.Errorf("NETMAKER_USERNAME or NETMAKER_USERNAME_FILE is required"))
+		}
+		if cfg.NetmakerPassword == "" && cfg.NetmakerPasswordFile == "" {
+			errs = append(errs, fmt.Errorf("NETMAKER_PASSWORD or NETMAKER_PASSWORD_FILE is required"))
+		}
+	case authModeTokenExchange:
+		if cfg.NetmakerTokenExchangeURL == "" {
+			errs = append(errs, fmt.Errorf("NETMAKER_TOKEN_EXCHANGE_URL is required when NETMAKER_AUTH_MODE=%s", authModeTokenExchange))
+		} else if err := validateURL("NETMAKER_TOKEN_EXCHANGE_URL", cfg.NetmakerTokenExchangeURL, "http", "https"); err != nil {
+			errs = append(errs, err)
+		}
+	case authModeVault:
+		if cfg.VaultAddress == "" || cfg.VaultRole == "" || cfg.VaultSecretPath == "" {
+			errs = append(errs, fmt.Errorf("VAULT_ADDR, VAULT_ROLE and VAULT_SECRET_PATH are required when NETMAKER_AUTH_MODE=%s", authModeVault))
+		}
+		if cfg.VaultAddress != "" {
+			if err := validateURL("VAULT_ADDR", cfg.VaultAddress, "http", "https"); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("NETMAKER_AUTH_MODE must be %q, %q or %q, got %q", authModePassword, authModeTokenExchange, authModeVault, cfg.NetmakerAuthMode))
 	}
 
-	return cfg, nil
+	return errs
+}
+
+// loginEndpoint returns the configured Netmaker login (zero for the stock login)
+func (cfg *Config) loginEndpoint() netmaker.LoginEndpoint {
+	return netmaker.LoginEndpoint{
+		Path:          cfg.NetmakerLoginPath,
+		UsernameField: cfg.NetmakerLoginUsernameField,
+		PasswordField: cfg.NetmakerLoginPasswordField,
+		TokenField:    cfg.NetmakerLoginTokenField,
+	}
+}
+
+// tlsPolicy returns the TLS policy for the Netmaker API
+func (cfg *Config) tlsPolicy() netmaker.TLSPolicy {
+	return netmaker.TLSPolicy{
+		MinVersion:   cfg.NetmakerTLSMinVersion,
+		CipherSuites: cfg.NetmakerTLSCipherSuites,
+		FIPS:         cfg.NetmakerTLSFIPS,
+	}
+}
+
+// credentialFiles are the paths of a username and a password file
+type credentialFiles struct {
+	Username string
+	Password string
+}
+
+// networkCredentials lists the per-network credential files in NetmakerNetworkCredentialsDir
+// Every subdirectory is a network and must hold a username and a password file (e.g. a mounted Secret)
+func (cfg *Config) networkCredentials() (map[string]credentialFiles, error) {
+	entries, err := os.ReadDir(cfg.NetmakerNetworkCredentialsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NETMAKER_NETWORK_CREDENTIALS_DIR: %w", err)
+	}
+
+	credentials := make(map[string]credentialFiles)
+	for _, entry := range entries {
+		// Skip the ..data and timestamped directories of Kubernetes volume mounts
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(cfg.NetmakerNetworkCredentialsDir, entry.Name())
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue // Follows symlinks, unlike entry.IsDir()
+		}
+		files := credentialFiles{
+			Username: filepath.Join(dir, "username"),
+			Password: filepath.Join(dir, "password"),
+		}
+		for _, file := range []string{files.Username, files.Password} {
+			if _, err := os.Stat(file); err != nil {
+				return nil, fmt.Errorf("credentials of network %s: %w", entry.Name(), err)
+			}
+		}
+		credentials[entry.Name()] = files
+	}
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("NETMAKER_NETWORK_CREDENTIALS_DIR %s holds no network directories", cfg.NetmakerNetworkCredentialsDir)
+	}
+	return credentials, nil
+}
+
+// createNetworks parses NetmakerCreateNetworks ("name=cidr" entries) into networks
+// A name listed with an IPv4 and an IPv6 CIDR becomes a dual-stack network
+func (cfg *Config) createNetworks() ([]netmaker.Network, error) {
+	var networks []netmaker.Network
+	index := make(map[string]int) // name -> position in networks
+	for _, entry := range cfg.NetmakerCreateNetworks {
+		name, cidr, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("entry %q must be name=cidr", entry)
+		}
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", entry, err)
+		}
+
+		i, exists := index[name]
+		if !exists {
+			i = len(networks)
+			index[name] = i
+			networks = append(networks, netmaker.Network{NetID: name})
+		}
+		addressRange := &networks[i].AddressRange
+		if ip.To4() == nil {
+			addressRange = &networks[i].AddressRange6
+		}
+		if *addressRange != "" {
+			return nil, fmt.Errorf("network %s has more than one address range of the same IP family", name)
+		}
+		*addressRange = cidr
+	}
+	return networks, nil
+}
+
+// networkTimeouts parses NetmakerNetworkTimeouts ("network=timeout[/retries]" entries, e.g. "satellite=1m/6")
+// Without retries a network keeps the default rate limit retry budget
+func (cfg *Config) networkTimeouts() (map[string]netmaker.NetworkTimeout, error) {
+	if len(cfg.NetmakerNetworkTimeouts) == 0 {
+		return nil, nil
+	}
+	timeouts := make(map[string]netmaker.NetworkTimeout, len(cfg.NetmakerNetworkTimeouts))
+	for _, entry := range cfg.NetmakerNetworkTimeouts {
+		network, value, ok := strings.Cut(entry, "=")
+		if !ok || network == "" {
+			return nil, fmt.Errorf("entry %q must be network=timeout[/retries]", entry)
+		}
+		if _, exists := timeouts[network]; exists {
+			return nil, fmt.Errorf("network %q is listed more than once", network)
+		}
+
+		timeout := netmaker.NetworkTimeout{Retries: netmaker.DefaultRateLimitRetries}
+		value, retries, hasRetries := strings.Cut(value, "/")
+		var err error
+		if timeout.Timeout, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("entry %q: %w", entry, err)
+		}
+		if hasRetries {
+			if timeout.Retries, err = strconv.Atoi(retries); err != nil {
+				return nil, fmt.Errorf("entry %q: invalid retries: %w", entry, err)
+			}
+		}
+		if err := timeout.Validate(); err != nil {
+			return nil, fmt.Errorf("entry %q: %w", entry, err)
+		}
+		timeouts[network] = timeout
+	}
+	return timeouts, nil
+}
+
+// descriptionLabels parses DescriptionLabels ("key=node-label" entries) into description key -> node label key
+func (cfg *Config) descriptionLabels() (map[string]string, error) {
+	if len(cfg.DescriptionLabels) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(cfg.DescriptionLabels))
+	for _, entry := range cfg.DescriptionLabels {
+		key, label, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || label == "" {
+			return nil, fmt.Errorf("entry %q must be key=node-label", entry)
+		}
+		if _, exists := labels[key]; exists {
+			return nil, fmt.Errorf("key %q is listed more than once", key)
+		}
+		labels[key] = label
+	}
+	if err := reconciler.ValidateDescriptionLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// egressMetrics parses EgressMetrics ("selector:metric" entries separated by ";", so selectors may contain commas)
+// The metric follows the last ':' - label selectors never contain one
+func (cfg *Config) egressMetrics() ([]reconciler.NodeMetric, error) {
+	var nodeMetrics []reconciler.NodeMetric
+	for _, entry := range strings.Split(cfg.EgressMetrics, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		separator := strings.LastIndex(entry, ":")
+		if separator < 0 {
+			return nil, fmt.Errorf("entry %q must be selector:metric", entry)
+		}
+		metric, err := strconv.Atoi(strings.TrimSpace(entry[separator+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: invalid metric: %w", entry, err)
+		}
+		nodeMetrics = append(nodeMetrics, reconciler.NodeMetric{Selector: strings.TrimSpace(entry[:separator]), Metric: metric})
+	}
+	if err := reconciler.ValidateNodeMetrics(nodeMetrics); err != nil {
+		return nil, err
+	}
+	return nodeMetrics, nil
+}
+
+// descriptionKeys returns the configured description keys, sorted
+func (cfg *Config) descriptionKeys() []string {
+	keys := make([]string, 0, len(cfg.DescriptionLabels))
+	for _, entry := range cfg.DescriptionLabels {
+		key, _, _ := strings.Cut(entry, "=")
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // isInCluster checks if the process is running inside a Kubernetes cluster
@@ -78,7 +734,7 @@ func isInCluster() bool {
 // Local: uses LEADER_ELECTION_NAMESPACE env var or "kube-system" as fallback
 func detectNamespace(inCluster bool) string {
 	// Check for explicit override first
-	if envNamespace := os.Getenv("LEADER_ELECTION_NAMESPACE"); envNamespace != "" {
+	if envNamespace := getenv("LEADER_ELECTION_NAMESPACE"); envNamespace != "" {
 		return envNamespace
 	}
 
@@ -97,36 +753,127 @@ func detectNamespace(inCluster bool) string {
 // In-cluster: enabled by default (HA)
 // Local: disabled by default (single dev instance)
 // Can be overridden via LEADER_ELECTION_ENABLED env var
-func detectLeaderElection(inCluster bool) bool {
-	// Check for explicit override first
-	if envValue := os.Getenv("LEADER_ELECTION_ENABLED"); envValue != "" {
-		return parseBool(envValue, inCluster)
+func detectLeaderElection(env *envReader, inCluster bool) bool {
+	// An explicit override wins, otherwise auto-detect based on environment
+	return env.boolean("LEADER_ELECTION_ENABLED", inCluster)
+}
+
+// instanceName suffixes a default object name with the instance ID, so instances don't share objects
+func instanceName(name, instanceID string) string {
+	if instanceID == "" {
+		return name
 	}
+	return name + "-" + instanceID
+}
+
+// readEnvVars holds every environment variable looked up by LoadConfig (see unknownEnvVars)
+var readEnvVars = make(map[string]bool)
 
-	// Auto-detect based on environment
-	return inCluster
+// getenv returns an environment variable and records it as known
+func getenv(key string) string {
+	readEnvVars[key] = true
+	return os.Getenv(key)
 }
 
 // getEnvWithDefault returns the environment variable value or a default if not set
 func getEnvWithDefault(key, defaultValue string) string {
-	value := os.Getenv(key)
+	value := getenv(key)
 	if value == "" {
 		return defaultValue
 	}
 	return value
 }
 
-// parseBool parses a boolean environment variable
-// Accepts: "true", "false", "1", "0" (case-insensitive)
-// Returns defaultValue if the value is invalid
-func parseBool(value string, defaultValue bool) bool {
-	switch value {
-	case "true", "True", "TRUE", "1":
+// envReader reads typed environment variables, collecting the values that fail to parse
+// Invalid values fall back to the default, so commands that ignore the errors (e.g. rbac) still get a Config
+type envReader struct {
+	errors []error
+}
+
+// boolean reads a boolean environment variable
+// Accepts: "true", "false", "1", "0" (case-insensitive); returns defaultValue if unset
+func (e *envReader) boolean(key string, defaultValue bool) bool {
+	switch value := getenv(key); strings.ToLower(value) {
+	case "":
+		return defaultValue
+	case "true", "1":
 		return true
-	case "false", "False", "FALSE", "0":
+	case "false", "0":
 		return false
 	default:
-		// Invalid value - return default
+		e.errors = append(e.errors, fmt.Errorf("%s must be true or false, got %q", key, value))
+		return defaultValue
+	}
+}
+
+// integer reads an integer environment variable, returns defaultValue if unset
+func (e *envReader) integer(key string, defaultValue int) int {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errors = append(e.errors, fmt.Errorf("%s must be an integer, got %q", key, value))
 		return defaultValue
 	}
+	return parsed
+}
+
+// duration reads a non-negative duration environment variable (e.g. "30s", "24h"), returns defaultValue if unset
+func (e *envReader) duration(key string, defaultValue time.Duration) time.Duration {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errors = append(e.errors, fmt.Errorf("%s must be a duration like 30s or 5m, got %q", key, value))
+		return defaultValue
+	}
+	if parsed < 0 {
+		e.errors = append(e.errors, fmt.Errorf("%s must not be negative, got %s", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+// float reads a floating point environment variable, returns defaultValue if unset
+func (e *envReader) float(key string, defaultValue float64) float64 {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errors = append(e.errors, fmt.Errorf("%s must be a number, got %q", key, value))
+		return defaultValue
+	}
+	return parsed
+}
+
+// configErrors aggregates every configuration problem, so all of them are reported at once
+type configErrors []error
+
+// Error implements error, one problem per line
+func (e configErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = "\n  - " + err.Error()
+	}
+	return fmt.Sprintf("%d configuration error(s):%s", len(e), strings.Join(messages, ""))
+}
+
+// Unwrap returns the individual errors
+func (e configErrors) Unwrap() []error {
+	return e
+}
+
+// validateURL checks that a URL setting is absolute and uses one of the schemes
+func validateURL(key, value string, schemes ...string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" || !slices.Contains(schemes, u.Scheme) {
+		return fmt.Errorf("%s must be a URL with scheme %s, got %q", key, strings.Join(schemes, " or "), redactURL(value))
+	}
+	return nil
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unknownEnvPrefix is the prefix of environment variables reserved for kaput-not
+// Variables with this prefix that LoadConfig never reads are most likely typos
+const unknownEnvPrefix = "KAPUT_NOT_"
+
+// maskedValue replaces secrets in the configuration dump
+const maskedValue = "***"
+
+// configDump returns the effective configuration as JSON with secrets masked
+// Fields tagged mask:"secret" are replaced when set, fields tagged mask:"url" lose their password and query
+func configDump(cfg *Config) ([]byte, error) {
+	value := reflect.ValueOf(cfg).Elem()
+	typ := value.Type()
+
+	dump := make(map[string]any, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldValue := value.Field(i)
+
+		switch field.Tag.Get("mask") {
+		case "secret":
+			if !fieldValue.IsZero() {
+				dump[field.Name] = maskedValue
+				continue
+			}
+		case "url":
+			if fieldValue.String() != "" {
+				dump[field.Name] = redactURL(fieldValue.String())
+				continue
+			}
+		}
+
+		if duration, ok := fieldValue.Interface().(time.Duration); ok {
+			dump[field.Name] = duration.String() // "30s" instead of nanoseconds
+			continue
+		}
+		dump[field.Name] = fieldValue.Interface()
+	}
+
+	return json.Marshal(dump)
+}
+
+// redactURL masks the password and query string of a URL (query parameters often carry tokens)
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid"
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = maskedValue
+	}
+	return u.Redacted()
+}
+
+// unknownEnvVars returns the KAPUT_NOT_ environment variables that were never read by LoadConfig, sorted
+func unknownEnvVars() []string {
+	var unknown []string
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(key, unknownEnvPrefix) && !readEnvVars[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// doctorStatus is the outcome of a doctor check
+type doctorStatus int
+
+const (
+	doctorOK doctorStatus = iota
+	doctorWarn
+	doctorFail
+	doctorSkip
+)
+
+// doctorMaxListed caps the nodes listed in a check message
+const doctorMaxListed = 10
+
+// doctor prints check results as they complete and remembers whether any failed
+type doctor struct {
+	out    io.Writer
+	color  bool
+	failed bool
+}
+
+// report prints a check result, followed by the remediation hint for anything but OK
+func (d *doctor) report(status doctorStatus, check, message, hint string) {
+	label, color := "OK", "\033[32m"
+	switch status {
+	case doctorWarn:
+		label, color = "WARN", "\033[33m"
+	case doctorFail:
+		label, color = "FAIL", "\033[31m"
+		d.failed = true
+	case doctorSkip:
+		label, color = "SKIP", "\033[90m"
+	}
+	label = fmt.Sprintf("%-4s", label)
+	if d.color {
+		label = color + label + "\033[0m"
+	}
+	fmt.Fprintf(d.out, "[%s] %s: %s\n", label, check, message)
+	if hint != "" && status != doctorOK {
+		fmt.Fprintf(d.out, "       → %s\n", hint)
+	}
+}
+
+// runDoctor implements "kaput-not doctor": checks Netmaker connectivity and credentials, Kubernetes RBAC,
+// how many nodes have a Netmaker host, and the pod CIDRs, printing a remediation hint for each problem
+// Only reads; configuration comes from the usual environment variables; returns the process exit code
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	noColor := flags.Bool("no-color", false, "Disable colored output (also disabled by NO_COLOR and when stdout is not a terminal)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not doctor [--no-color]\n\n")
+		fmt.Fprintf(flags.Output(), "Diagnoses the configuration, Netmaker and Kubernetes access of the controller.\n")
+		fmt.Fprintf(flags.Output(), "RBAC is checked for the current credentials - run it in the controller pod to check its service account.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	d := &doctor{out: os.Stdout, color: !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)}
+
+	// Client setup logs through the standard logger, which would interleave with the report
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		d.report(doctorFail, "Configuration", err.Error(), "fix the environment variables listed in the README")
+		return 1
+	}
+	if unknown := unknownEnvVars(); len(unknown) > 0 {
+		d.report(doctorWarn, "Configuration", "unknown environment variables: "+strings.Join(unknown, ", "),
+			"check them for typos, they are ignored")
+	} else {
+		d.report(doctorOK, "Configuration", "valid", "")
+	}
+
+	hosts, networks := d.checkNetmaker(ctx, cfg)
+	nodes := d.checkKubernetes(ctx, cfg)
+	d.checkHostMatching(cfg, nodes, hosts)
+	d.checkCIDRs(cfg, nodes, networks)
+
+	if d.failed {
+		return 1
+	}
+	return 0
+}
+
+// checkNetmaker checks Netmaker connectivity and credentials
+// Returns the hosts and the networks hosts participate in, nil if Netmaker could not be read
+func (d *doctor) checkNetmaker(ctx context.Context, cfg *Config) ([]netmaker.Host, []netmaker.Network) {
+	httpClient, err := createNetmakerClient(cfg)
+	if err != nil {
+		d.report(doctorFail, "Netmaker connectivity", err.Error(), "check NETMAKER_API_URL and the NETMAKER_TLS_* / proxy settings")
+		d.report(doctorSkip, "Netmaker credentials", "Netmaker client could not be created", "")
+		return nil, nil
+	}
+
+	if err := httpClient.Authenticate(ctx); err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			d.report(doctorFail, "Netmaker connectivity", err.Error(),
+				"check that NETMAKER_API_URL is reachable from here (DNS, firewall, NETMAKER_PROXY_URL, TLS)")
+			d.report(doctorSkip, "Netmaker credentials", "Netmaker is unreachable", "")
+			return nil, nil
+		}
+		d.report(doctorOK, "Netmaker connectivity", "reached "+redactURL(cfg.NetmakerAPIURL), "")
+		d.report(doctorFail, "Netmaker credentials", err.Error(),
+			fmt.Sprintf("check the credentials of the %s auth mode (NETMAKER_USERNAME/NETMAKER_PASSWORD, Vault or token exchange settings)", cfg.NetmakerAuthMode))
+		return nil, nil
+	}
+	d.report(doctorOK, "Netmaker connectivity", "reached "+redactURL(cfg.NetmakerAPIURL), "")
+
+	hosts, err := httpClient.ListHosts(ctx)
+	if err != nil {
+		d.report(doctorFail, "Netmaker credentials", "authenticated, but listing hosts failed: "+err.Error(),
+			"the Netmaker user needs (super) admin access to hosts, nodes and egress rules")
+		return nil, nil
+	}
+	nodes, err := httpClient.ListNodes(ctx)
+	if err != nil {
+		d.report(doctorFail, "Netmaker credentials", "authenticated, but listing nodes failed: "+err.Error(),
+			"the Netmaker user needs (super) admin access to hosts, nodes and egress rules")
+		return nil, nil
+	}
+	d.report(doctorOK, "Netmaker credentials", fmt.Sprintf("authenticated, %d host(s) and %d node(s) visible", len(hosts), len(nodes)), "")
+	d.checkPermissions(ctx, cfg, httpClient)
+
+	var networks []netmaker.Network
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		if seen[node.Network] {
+			continue
+		}
+		seen[node.Network] = true
+		network, err := httpClient.GetNetwork(ctx, node.Network)
+		if err != nil {
+			d.report(doctorWarn, "Netmaker networks", fmt.Sprintf("failed to read network %s: %v", node.Network, err),
+				"CIDR overlaps with this network are not checked")
+			continue
+		}
+		networks = append(networks, *network)
+	}
+	if hosts == nil {
+		hosts = []netmaker.Host{} // Read, but empty
+	}
+	return hosts, networks
+}
+
+// checkPermissions checks the roles of the Netmaker users (see auditNetmakerPermissions)
+func (d *doctor) checkPermissions(ctx context.Context, cfg *Config, client netmakerClient) {
+	audit, err := auditNetmakerPermissions(ctx, cfg, client)
+	if err != nil {
+		d.report(doctorWarn, "Netmaker permissions", err.Error(), "permissions are checked when the controller starts")
+		return
+	}
+	const hint = "grant the platform role admin, or the network-admin role in every managed network"
+	switch {
+	case len(audit.Missing) > 0:
+		d.report(doctorFail, "Netmaker permissions", "missing "+strings.Join(audit.Missing, "; "), hint)
+	case len(audit.Unverified) > 0:
+		d.report(doctorWarn, "Netmaker permissions", "not verified for "+strings.Join(audit.Unverified, "; "), hint)
+	default:
+		d.report(doctorOK, "Netmaker permissions", fmt.Sprintf("verified for %d user(s)", audit.Users), "")
+	}
+}
+
+// checkKubernetes checks the Kubernetes connection and the RBAC permissions of the enabled features
+// Returns the publisher nodes, nil if they could not be listed
+func (d *doctor) checkKubernetes(ctx context.Context, cfg *Config) []corev1.Node {
+	restConfig, err := createRestConfig(cfg)
+	if err != nil {
+		d.report(doctorFail, "Kubernetes connectivity", err.Error(), "check KUBECONFIG (leave it unset in the cluster)")
+		d.report(doctorSkip, "Kubernetes RBAC", "no Kubernetes configuration", "")
+		return nil
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		d.report(doctorFail, "Kubernetes connectivity", err.Error(), "check the kubeconfig")
+		d.report(doctorSkip, "Kubernetes RBAC", "no Kubernetes client", "")
+		return nil
+	}
+	serverVersion, err := kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		d.report(doctorFail, "Kubernetes connectivity", err.Error(), "check that the API server is reachable with the kubeconfig")
+		d.report(doctorSkip, "Kubernetes RBAC", "API server is unreachable", "")
+		return nil
+	}
+	d.report(doctorOK, "Kubernetes connectivity", "API server "+serverVersion.GitVersion, "")
+
+	d.checkRBAC(ctx, cfg, kubeClient)
+
+	nodeList, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: cfg.PublisherSelector})
+	if err != nil {
+		d.report(doctorFail, "Kubernetes nodes", err.Error(), "check PUBLISHER_SELECTOR and the nodes list permission")
+		return nil
+	}
+	var nodes []corev1.Node
+	for _, node := range nodeList.Items {
+		if node.Labels[corev1.LabelOSStable] == "windows" && !cfg.IncludeWindowsNodes {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		d.report(doctorWarn, "Kubernetes nodes", "no node publishes egress rules", "check PUBLISHER_SELECTOR and INCLUDE_WINDOWS_NODES")
+		return nil
+	}
+	return nodes
+}
+
+// checkRBAC asks the API server whether the current credentials are granted every permission the enabled
+// features need (see permissions)
+func (d *doctor) checkRBAC(ctx context.Context, cfg *Config, kubeClient kubernetes.Interface) {
+	var missing []string
+	checked := 0
+	for _, permission := range permissions(cfg) {
+		rule := permission.Rule
+		name := ""
+		if len(rule.ResourceNames) > 0 {
+			name = rule.ResourceNames[0]
+		}
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				for _, verb := range rule.Verbs {
+					checked++
+					review := &authorizationv1.SelfSubjectAccessReview{
+						Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+							ResourceAttributes: &authorizationv1.ResourceAttributes{
+								Namespace: permission.Namespace,
+								Verb:      verb,
+								Group:     group,
+								Resource:  resource,
+								Name:      name,
+							},
+						},
+					}
+					result, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+					if err != nil {
+						d.report(doctorFail, "Kubernetes RBAC", "access review failed: "+err.Error(), "")
+						return
+					}
+					if !result.Status.Allowed {
+						missing = append(missing, describeAccess(permission.Namespace, group, resource, verb))
+					}
+				}
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		d.report(doctorFail, "Kubernetes RBAC", fmt.Sprintf("%d of %d permission(s) missing: %s", len(missing), checked, strings.Join(missing, ", ")),
+			"apply the output of \"kaput-not rbac\" (or upgrade the Helm release)")
+		return
+	}
+	d.report(doctorOK, "Kubernetes RBAC", fmt.Sprintf("all %d permission(s) granted", checked), "")
+}
+
+// describeAccess formats a resource access like "list pods in kube-system"
+func describeAccess(namespace, group, resource, verb string) string {
+	if group != "" {
+		resource += "." + group
+	}
+	if namespace != "" {
+		return fmt.Sprintf("%s %s in %s", verb, resource, namespace)
+	}
+	return verb + " " + resource
+}
+
+// checkHostMatching reports how many publisher nodes have a Netmaker host of the same name
+// (or, with MATCH_HOSTS_BY_ADDRESS, a host sharing one of their addresses)
+func (d *doctor) checkHostMatching(cfg *Config, nodes []corev1.Node, hosts []netmaker.Host) {
+	if nodes == nil || hosts == nil {
+		d.report(doctorSkip, "Host matching", "needs both the Kubernetes nodes and the Netmaker hosts", "")
+		return
+	}
+
+	hostNames := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		hostNames[host.Name] = true
+	}
+	var unmatched []string
+	byAddress := 0
+	for i := range nodes {
+		if hostNames[nodes[i].Name] {
+			continue
+		}
+		if cfg.MatchHostsByAddress {
+			if _, ok := reconciler.MatchHostByAddress(hosts, reconciler.NodeAddresses(&nodes[i])); ok {
+				byAddress++
+				continue
+			}
+		}
+		unmatched = append(unmatched, nodes[i].Name)
+	}
+	matched := len(nodes) - len(unmatched)
+
+	message := fmt.Sprintf("%d of %d node(s) have a Netmaker host", matched, len(nodes))
+	if byAddress > 0 {
+		message += fmt.Sprintf(" (%d matched by IP address)", byAddress)
+	}
+	hint := "install netclient on the nodes and make sure the Netmaker host names equal the Kubernetes node names"
+	if !cfg.MatchHostsByAddress {
+		hint += ", or set MATCH_HOSTS_BY_ADDRESS=true"
+	}
+	switch {
+	case len(unmatched) == 0:
+		d.report(doctorOK, "Host matching", message, "")
+	case matched == 0:
+		d.report(doctorFail, "Host matching", message+" (none: "+listNames(unmatched)+")", hint)
+	default:
+		d.report(doctorWarn, "Host matching", message+", missing: "+listNames(unmatched), hint)
+	}
+}
+
+// checkCIDRs checks the publisher nodes' pod CIDRs: present, valid, not overlapping each other or a Netmaker
+// network's address range, and inside CLUSTER_CIDRS when configured
+func (d *doctor) checkCIDRs(cfg *Config, nodes []corev1.Node, networks []netmaker.Network) {
+	if nodes == nil {
+		d.report(doctorSkip, "Pod CIDRs", "no Kubernetes nodes", "")
+		return
+	}
+	if cfg.CiliumIPPools {
+		d.report(doctorSkip, "Pod CIDRs", "CILIUM_IP_POOLS publishes the IP pool CIDRs instead of spec.podCIDRs", "")
+		return
+	}
+
+	type nodePrefix struct {
+		node   string
+		prefix netip.Prefix
+	}
+	var prefixes []nodePrefix
+	var withoutCIDR, problems []string
+	for _, node := range nodes {
+		podCIDRs := node.Spec.PodCIDRs
+		if len(podCIDRs) == 0 && node.Spec.PodCIDR != "" {
+			podCIDRs = []string{node.Spec.PodCIDR}
+		}
+		if len(podCIDRs) == 0 {
+			withoutCIDR = append(withoutCIDR, node.Name)
+			continue
+		}
+		for _, cidr := range podCIDRs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("node %s has invalid pod CIDR %q", node.Name, cidr))
+				continue
+			}
+			prefixes = append(prefixes, nodePrefix{node: node.Name, prefix: prefix.Masked()})
+		}
+	}
+
+	for i := range prefixes {
+		for j := i + 1; j < len(prefixes); j++ {
+			if prefixes[i].node != prefixes[j].node && prefixes[i].prefix.Overlaps(prefixes[j].prefix) {
+				problems = append(problems, fmt.Sprintf("pod CIDR %s of node %s overlaps %s of node %s",
+					prefixes[i].prefix, prefixes[i].node, prefixes[j].prefix, prefixes[j].node))
+			}
+		}
+	}
+
+	for _, network := range networks {
+		for _, addressRange := range []string{network.AddressRange, network.AddressRange6} {
+			networkPrefix, err := netip.ParsePrefix(addressRange)
+			if err != nil {
+				continue // No range of this family
+			}
+			for _, p := range prefixes {
+				if p.prefix.Overlaps(networkPrefix) {
+					problems = append(problems, fmt.Sprintf("pod CIDR %s of node %s overlaps the address range %s of Netmaker network %s",
+						p.prefix, p.node, networkPrefix, network.NetID))
+				}
+			}
+		}
+	}
+
+	var outside []string
+	if len(cfg.ClusterCIDRs) > 0 {
+		var clusterPrefixes []netip.Prefix
+		for _, cidr := range cfg.ClusterCIDRs {
+			if prefix, err := netip.ParsePrefix(cidr); err == nil {
+				clusterPrefixes = append(clusterPrefixes, prefix.Masked())
+			}
+		}
+		for _, p := range prefixes {
+			contained := false
+			for _, clusterPrefix := range clusterPrefixes {
+				if clusterPrefix.Bits() <= p.prefix.Bits() && clusterPrefix.Contains(p.prefix.Addr()) {
+					contained = true
+					break
+				}
+			}
+			if !contained {
+				outside = append(outside, fmt.Sprintf("%s (%s)", p.prefix, p.node))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		d.report(doctorFail, "Pod CIDRs", strings.Join(problems, "; "),
+			"overlapping routes make Netmaker send pod traffic to the wrong gateway - fix the pod CIDR allocation or the Netmaker network ranges")
+	}
+	if len(withoutCIDR) > 0 {
+		d.report(doctorWarn, "Pod CIDRs", fmt.Sprintf("%d node(s) without pod CIDRs: %s", len(withoutCIDR), listNames(withoutCIDR)),
+			"these nodes publish no egress rules - enable --allocate-node-cidrs or use CILIUM_IP_POOLS")
+	}
+	if len(outside) > 0 {
+		d.report(doctorWarn, "Pod CIDRs", fmt.Sprintf("%d pod CIDR(s) outside CLUSTER_CIDRS: %s", len(outside), listNames(outside)),
+			"add the missing ranges to CLUSTER_CIDRS")
+	}
+	if len(problems) == 0 && len(withoutCIDR) == 0 && len(outside) == 0 {
+		d.report(doctorOK, "Pod CIDRs", fmt.Sprintf("%d pod CIDR(s) of %d node(s), no overlaps", len(prefixes), len(nodes)), "")
+	}
+}
+
+// listNames joins names, abbreviated after doctorMaxListed
+func listNames(names []string) string {
+	if len(names) <= doctorMaxListed {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:doctorMaxListed], ", "), len(names)-doctorMaxListed)
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// handleDumpSignal logs a debug dump whenever the process receives one of dumpSignals (SIGUSR1), until ctx is done:
+// the stacks of all goroutines, the Netmaker cache statistics and the workqueue contents
+// For stuck reconciliations in production, where the distroless image has no kill: send the signal from an
+// ephemeral container sharing the process namespace (kubectl debug --target, see the README)
+func handleDumpSignal(ctx context.Context, ctrl *controller.Controller, cachedClient *netmaker.CachedClient) {
+	if len(dumpSignals) == 0 {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, dumpSignals...)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				logDebugDump(ctrl, cachedClient)
+			}
+		}
+	}()
+}
+
+// logDebugDump logs the goroutines, Netmaker cache statistics and workqueue contents
+func logDebugDump(ctrl *controller.Controller, cachedClient *netmaker.CachedClient) {
+	var goroutines bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&goroutines, 2); err != nil {
+		log.Printf("Failed to dump goroutines: %v", err)
+	}
+	log.Printf("Debug dump: %d goroutines\n%s", runtime.NumGoroutine(), goroutines.String())
+
+	stats, err := json.Marshal(cachedClient.Stats())
+	if err != nil {
+		log.Printf("Failed to dump Netmaker cache statistics: %v", err)
+	} else {
+		log.Printf("Debug dump: Netmaker cache %s", stats)
+	}
+
+	var queues bytes.Buffer
+	ctrl.Dump(&queues)
+	log.Printf("Debug dump: %s", queues.String())
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// dumpSignals trigger a debug dump (see handleDumpSignal); none without SIGUSR1
+var dumpSignals []os.Signal
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/leaderelection"
+)
+
+// leaderHandoff encodes the controller's handoff state for the lease annotation
+func leaderHandoff(ctrl *controller.Controller) string {
+	handoff := ctrl.Handoff()
+	encoded, err := json.Marshal(handoff)
+	if err != nil {
+		return ""
+	}
+	log.Printf("Leaving the leader handoff state on the lease: %d pending deletion(s)", len(handoff.PendingDeletes))
+	return string(encoded)
+}
+
+// resumeLeaderHandoff passes the state the previous leader left on the lease to the controller
+// Without a usable handoff, the controller starts with the usual full cleanup
+func resumeLeaderHandoff(ctx context.Context, cfg *Config, kubeClient kubernetes.Interface, ctrl *controller.Controller) {
+	state, err := leaderelection.ReadHandoff(ctx, kubeClient, cfg.LeaderElectionNamespace, cfg.LeaderElectionID, cfg.LeaderElectionIdentity)
+	if err != nil {
+		log.Printf("WARNING: failed to read the leader handoff state: %v", err)
+		return
+	}
+	if state == "" {
+		log.Println("No leader handoff state from the previous leader")
+		return
+	}
+
+	var handoff controller.Handoff
+	if err := json.Unmarshal([]byte(state), &handoff); err != nil {
+		log.Printf("WARNING: ignoring malformed leader handoff state: %v", err)
+		return
+	}
+	ctrl.ResumeFrom(handoff)
+}
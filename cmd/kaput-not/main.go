@@ -2,25 +2,68 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"maps"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/bsure-analytics/kaput-not/pkg/admin"
+	"github.com/bsure-analytics/kaput-not/pkg/apis/v1alpha1"
+	"github.com/bsure-analytics/kaput-not/pkg/clusterconfig"
 	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/dashboard"
+	"github.com/bsure-analytics/kaput-not/pkg/hooks"
+	"github.com/bsure-analytics/kaput-not/pkg/hostwatcher"
+	"github.com/bsure-analytics/kaput-not/pkg/ippools"
 	"github.com/bsure-analytics/kaput-not/pkg/leaderelection"
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
 	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/nodeclaims"
 	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+	"github.com/bsure-analytics/kaput-not/pkg/runtimeconfig"
+	"github.com/bsure-analytics/kaput-not/pkg/statestore"
+	"github.com/bsure-analytics/kaput-not/pkg/trash"
+	"github.com/bsure-analytics/kaput-not/pkg/version"
 )
 
 func main() {
 	// Setup logging
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	log.Println("Starting kaput-not Kubernetes controller...")
+
+	// Subcommands (the controller runs when none is given)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "plan":
+			os.Exit(runPlan(os.Args[2:]))
+		case "purge":
+			os.Exit(runPurge(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "rbac":
+			os.Exit(runRBAC(os.Args[2:]))
+		case "restore":
+			os.Exit(runRestore(os.Args[2:]))
+		case "simulate":
+			os.Exit(runSimulate(os.Args[2:]))
+		case "version":
+			os.Exit(runVersion(os.Args[2:]))
+		}
+	}
+
+	log.Printf("Starting kaput-not Kubernetes controller (%s)...", version.Get())
 
 	// Load configuration from environment
 	cfg, err := LoadConfig()
@@ -28,31 +71,121 @@ func main() {
 		log.Fatalf("Configuration error: %v", err)
 	}
 
-	log.Printf("Configuration loaded: api=%s, leader-election=%v (networks auto-discovered)",
-		cfg.NetmakerAPIURL, cfg.LeaderElectionEnabled)
+	dump, err := configDump(cfg)
+	if err != nil {
+		log.Fatalf("Failed to dump configuration: %v", err)
+	}
+	log.Printf("Effective configuration: %s", dump)
+	for _, key := range unknownEnvVars() {
+		log.Printf("WARNING: unknown environment variable %s is ignored (typo?)", key)
+	}
 
-	// Create Kubernetes client
-	kubeClient, err := createKubeClient(cfg.Kubeconfig)
+	// Create Kubernetes clients
+	restConfig, err := createRestConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load Kubernetes configuration: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		log.Fatalf("Failed to create Kubernetes client: %v", err)
 	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes dynamic client: %v", err)
+	}
 	log.Println("Kubernetes client created successfully")
 
 	// Create single Netmaker client for all networks
 	ctx := context.Background()
 
-	// Create HTTP client (works with all networks)
-	httpClient, err := netmaker.NewHTTPClient(
-		cfg.NetmakerAPIURL,
-		cfg.NetmakerUsername,
-		cfg.NetmakerPassword,
-	)
+	// Create HTTP client for the configured auth mode (works with all networks)
+	httpClient, err := createNetmakerClient(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create Netmaker HTTP client: %v", err)
+		log.Fatalf("Failed to create Netmaker client: %v", err)
+	}
+
+	// Limit concurrent Netmaker writes (optional), innermost so hooks don't hold a slot
+	// API calls are timed below the limit, so waiting for a slot doesn't count as Netmaker latency
+	client, err := limitMutations(cfg, metrics.InstrumentNetmaker(httpClient))
+	if err != nil {
+		log.Fatalf("Failed to create Netmaker client: %v", err)
+	}
+
+	// Keep copies of deleted egress rules for "kaput-not restore" (optional), as the last mutation hook
+	var trashBin *trash.Bin
+	if cfg.TrashConfigMap != "" {
+		trashBin, err = trash.New(&trash.Options{
+			KubeClient: kubeClient,
+			Name:       cfg.TrashConfigMap,
+			Namespace:  cfg.LeaderElectionNamespace,
+			MaxEntries: cfg.TrashMaxEntries,
+			TTL:        cfg.TrashTTL,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create trash bin: %v", err)
+		}
+		log.Printf("Trash bin enabled: configmap=%s/%s", cfg.LeaderElectionNamespace, cfg.TrashConfigMap)
+	}
+
+	// Run hooks before and after every egress rule mutation (optional)
+	mutationHooks := createHooks(cfg)
+	if trashBin != nil {
+		mutationHooks = append(mutationHooks, trashBin)
+	}
+	if len(mutationHooks) > 0 {
+		client = hooks.NewClient(client, mutationHooks...)
 	}
 
-	// Wrap with caching layer (30 second TTL, shared across all networks)
-	cachedClient := netmaker.NewCachedClient(httpClient, 0)
+	// Report changes to managed egress rules made outside kaput-not, and other clusters using our name (optional)
+	// Below the read-only layer, so skipped writes are never expected; the controller is created further down,
+	// listings before that only establish the baseline
+	var ctrl *controller.Controller
+	if cfg.DetectExternalChanges || cfg.DetectClusterNameConflicts {
+		client = netmaker.NewChangeDetector(client,
+			func(egress netmaker.Egress) bool {
+				return reconciler.ManagedBy(egress, cfg.ClusterName, cfg.InstanceID)
+			},
+			func(change netmaker.ExternalChange) {
+				if ctrl == nil {
+					return
+				}
+				go func() { // The CachedClient holds its lock while listing
+					if cfg.DetectExternalChanges {
+						ctrl.RecordExternalChange(change)
+					}
+					if cfg.DetectClusterNameConflicts {
+						ctrl.CheckIdentityConflict(change)
+					}
+				}()
+			})
+		if cfg.DetectExternalChanges {
+			log.Println("Detecting changes to managed egress rules made outside kaput-not")
+		}
+		if cfg.DetectClusterNameConflicts {
+			log.Println("Detecting other kaput-not instances writing egress rules as this cluster")
+		}
+	}
+
+	// Only report drift in read-only networks instead of mutating them (optional)
+	// Also needed for the KaputNotConfig and admin service dry-run switches; wraps the hooks, so skipped mutations never reach them
+	var readOnlyClient *netmaker.ReadOnlyClient
+	if len(cfg.NetmakerReadOnlyNetworks) > 0 || cfg.WatchKaputNotConfig || cfg.AdminGRPCBindAddress != "" {
+		readOnlyClient = netmaker.NewReadOnlyClient(client, cfg.NetmakerReadOnlyNetworks)
+		if err := metrics.RegisterReadOnlyNetworks(readOnlyClient.Skipped); err != nil {
+			log.Fatalf("Failed to register read-only network metrics: %v", err)
+		}
+		if len(cfg.NetmakerReadOnlyNetworks) > 0 {
+			log.Printf("Read-only Netmaker networks (drift is reported, never corrected): %v", cfg.NetmakerReadOnlyNetworks)
+		}
+		client = readOnlyClient
+	}
+
+	// Wrap with caching layer (30 second TTL by default, shared across all networks)
+	cachedClient := netmaker.NewCachedClient(client, cfg.NetmakerCacheTTL)
+	log.Printf("Netmaker cache TTL: %s", cachedClient.TTL())
+	if err := metrics.RegisterNetmakerCache(cachedClient.Stats); err != nil {
+		log.Fatalf("Failed to register Netmaker cache metrics: %v", err)
+	}
 
 	// Authenticate immediately to validate credentials
 	if err := cachedClient.Authenticate(ctx); err != nil {
@@ -60,49 +193,347 @@ func main() {
 	}
 	log.Println("Successfully authenticated with Netmaker")
 
+	// Fail fast when the Netmaker users lack roles, instead of with 403s during reconciliation (optional)
+	if cfg.NetmakerPermissionCheck {
+		audit, err := auditNetmakerPermissions(ctx, cfg, httpClient)
+		if err != nil {
+			log.Fatalf("Failed to audit Netmaker permissions: %v", err)
+		}
+		for _, reason := range audit.Unverified {
+			log.Printf("WARNING: Netmaker permissions not verified for %s", reason)
+		}
+		if len(audit.Missing) > 0 {
+			log.Fatalf("Netmaker permissions missing (set NETMAKER_PERMISSION_CHECK=false to skip this check):\n  %s",
+				strings.Join(audit.Missing, "\n  "))
+		}
+		log.Printf("Netmaker permissions verified for %d user(s)", audit.Users)
+	}
+
+	// Create the persistent state store (optional, lives next to the leader election lease)
+	var stateStore *statestore.ConfigMapStore
+	if cfg.StateConfigMap != "" {
+		stateStore, err = statestore.NewConfigMapStore(&statestore.ConfigMapOptions{
+			KubeClient: kubeClient,
+			Name:       cfg.StateConfigMap,
+			Namespace:  cfg.LeaderElectionNamespace,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create state store: %v", err)
+		}
+		log.Printf("State store enabled: configmap=%s/%s", cfg.LeaderElectionNamespace, cfg.StateConfigMap)
+	}
+
 	// Create reconciler with single client (networks auto-discovered)
-	rec := reconciler.New(cachedClient, cfg.ClusterName)
+	recOpts, err := reconcilerOptions(ctx, cfg, kubeClient, cachedClient)
+	if err != nil {
+		log.Fatalf("Failed to configure reconciler: %v", err)
+	}
+	if stateStore != nil {
+		recOpts.StateStore = stateStore
+	}
+	if cfg.GatewayHealthCheck {
+		// The controller is created further down; changes before that are only logged
+		recOpts.OnGatewayHealthChange = func(health reconciler.GatewayHealth) {
+			if ctrl != nil {
+				ctrl.RecordGatewayHealth(health)
+			}
+		}
+	}
+	rec, err := reconciler.New(recOpts)
+	if err != nil {
+		log.Fatalf("Failed to create reconciler: %v", err)
+	}
+	if cfg.GatewayHealthCheck {
+		if err := metrics.RegisterUnhealthyGateways(rec.UnhealthyGateways); err != nil {
+			log.Fatalf("Failed to register gateway health metrics: %v", err)
+		}
+		log.Printf("Gateway health checks enabled: rules are turned off while no gateway is connected and checked in within %s",
+			recOpts.GatewayStaleAfter)
+	}
 	if cfg.ClusterName != "" {
 		log.Printf("Reconciler created successfully (cluster=%s)", cfg.ClusterName)
 	} else {
 		log.Println("Reconciler created successfully (single-cluster mode)")
 	}
+	if cfg.InstanceID != "" {
+		log.Printf("Instance %s: only egress rules, ClusterEgressRules and the KaputNotConfig of this instance are managed", cfg.InstanceID)
+	}
+	for _, network := range recOpts.Networks {
+		log.Printf("Netmaker network %s is created if missing (ipv4=%s, ipv6=%s)",
+			network.NetID, valueOrDash(network.AddressRange), valueOrDash(network.AddressRange6))
+	}
+	if cfg.EgressLeaseDuration > 0 {
+		log.Printf("Egress leases enabled: duration=%s, grace-period=%s",
+			recOpts.LeaseDuration, recOpts.LeaseGracePeriod)
+	}
+
+	// Track the CIDRs allocated from the advertised Cilium IP pools (optional, runs on all replicas)
+	// The watcher only starts after the controller exists, so OnChange never sees a nil ctrl
+	var ipPoolWatcher *ippools.Watcher
+	if cfg.CiliumIPPools {
+		ipPoolWatcher, err = ippools.New(&ippools.Options{
+			DynamicClient: dynamicClient,
+			Selector:      cfg.CiliumIPPoolSelector,
+			OnChange:      func(nodeNames []string) { ctrl.EnqueueNodes(nodeNames) },
+		})
+		if err != nil {
+			log.Fatalf("Failed to create IP pool watcher: %v", err)
+		}
+	}
+
+	// Learn about Karpenter node churn from NodeClaims (optional, runs on all replicas)
+	// Registered nodes are looked up with fresh Netmaker hosts, so their rules don't wait for the cache TTL
+	var nodeClaimWatcher *nodeclaims.Watcher
+	if cfg.KarpenterNodeClaims {
+		nodeClaimWatcher, err = nodeclaims.New(&nodeclaims.Options{
+			DynamicClient: dynamicClient,
+			OnTerminating: func(nodeNames []string) { ctrl.LeavingNodes(nodeNames) },
+			OnRegistered: func(nodeNames []string) {
+				if err := cachedClient.Invalidate(netmaker.CacheKindHosts, ""); err != nil {
+					log.Printf("Failed to invalidate Netmaker hosts for registered nodes: %v", err)
+				}
+				ctrl.EnqueuePriority(nodeNames)
+			},
+		})
+		if err != nil {
+			log.Fatalf("Failed to create NodeClaim watcher: %v", err)
+		}
+	}
+
+	// Reconcile nodes as soon as netclient enrolled their Netmaker host (optional, runs on the leader)
+	// Lists below the cache, which is invalidated so the reconcile finds the new host
+	var hostWatcher *hostwatcher.Watcher
+	if cfg.NetmakerHostPollInterval > 0 {
+		hostWatcher, err = hostwatcher.New(&hostwatcher.Options{
+			Client:   client,
+			Interval: cfg.NetmakerHostPollInterval,
+			OnEnrolled: func(hostNames []string) {
+				if err := cachedClient.Invalidate(netmaker.CacheKindHosts, ""); err != nil {
+					log.Printf("Failed to invalidate Netmaker hosts for enrolled hosts: %v", err)
+				}
+				if err := cachedClient.Invalidate(netmaker.CacheKindNodes, ""); err != nil {
+					log.Printf("Failed to invalidate Netmaker nodes for enrolled hosts: %v", err)
+				}
+				ctrl.HostsEnrolled(hostNames)
+			},
+		})
+		if err != nil {
+			log.Fatalf("Failed to create Netmaker host watcher: %v", err)
+		}
+		log.Printf("Watching for newly enrolled Netmaker hosts every %s", cfg.NetmakerHostPollInterval)
+	}
 
 	// Create controller
-	ctrl, err := controller.New(&controller.Options{
-		KubeClient:     kubeClient,
-		NetmakerClient: cachedClient,
-		Reconciler:     rec,
-		ClusterName:    cfg.ClusterName,
-	})
+	ctrlOpts := controllerOptions(cfg, kubeClient, dynamicClient, cachedClient, rec)
+	if ipPoolWatcher != nil {
+		ctrlOpts.PodIPPools = ipPoolWatcher
+	}
+	if nodeClaimWatcher != nil {
+		ctrlOpts.NodeClaims = nodeClaimWatcher
+	}
+	ctrl, err = controller.New(ctrlOpts)
 	if err != nil {
 		log.Fatalf("Failed to create controller: %v", err)
 	}
 	log.Println("Controller created successfully")
+	if cfg.HAGatewaySelector != "" {
+		log.Printf("HA gateways enabled: selector=%s", cfg.HAGatewaySelector)
+	}
+	if cfg.PublisherSelector != "" {
+		log.Printf("Only nodes matching %s publish egress rules", cfg.PublisherSelector)
+	}
+	if cfg.SummarizePodCIDRs {
+		log.Println("Summarized mode: publishers advertise the minimal covering set of node pod CIDRs")
+	}
+	if cfg.PoolLabel != "" {
+		log.Printf("Node pools enabled: nodes sharing a value of label %s share egress rules", cfg.PoolLabel)
+	}
+	if cfg.ManageExtClients {
+		log.Printf("External client routes enabled: nodes annotated with %s are exposed", controller.ExtClientsAnnotation)
+	}
+	if cfg.ManageEgressRules {
+		log.Println("ClusterEgressRule management enabled")
+	}
+
+	// Apply runtime settings from the KaputNotConfig (optional, runs on all replicas)
+	var runtimeConfigWatcher *runtimeconfig.Watcher
+	if cfg.WatchKaputNotConfig {
+		runtimeConfigWatcher, err = runtimeconfig.New(&runtimeconfig.Options{
+			DynamicClient: dynamicClient,
+			Name:          instanceName(v1alpha1.KaputNotConfigName, cfg.InstanceID),
+			Defaults: runtimeconfig.Settings{
+				PublisherSelector:          cfg.PublisherSelector,
+				IncludeWindowsNodes:        cfg.IncludeWindowsNodes,
+				InformerCacheWarnThreshold: cfg.InformerCacheWarnThreshold,
+				EgressCacheWarnThreshold:   cfg.EgressCacheWarnThreshold,
+			},
+			OnChange: func(settings runtimeconfig.Settings) error {
+				if err := ctrl.ApplySettings(controller.Settings{
+					PublisherSelector:          settings.PublisherSelector,
+					IncludeWindowsNodes:        settings.IncludeWindowsNodes,
+					InformerCacheWarnThreshold: settings.InformerCacheWarnThreshold,
+					EgressCacheWarnThreshold:   settings.EgressCacheWarnThreshold,
+				}); err != nil {
+					return err
+				}
+				readOnlyClient.SetDryRun(settings.DryRun)
+				return nil
+			},
+		})
+		if err != nil {
+			log.Fatalf("Failed to create KaputNotConfig watcher: %v", err)
+		}
+	}
 
 	// Setup signal handling for graceful shutdown
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	// Watch the cluster pod and service subnets (optional, runs on all replicas for metrics)
+	var clusterNetworkWatcher *clusterconfig.Watcher
+	if cfg.WatchClusterNetworks {
+		clusterNetworkWatcher, err = clusterconfig.New(&clusterconfig.Options{
+			KubeClient: kubeClient,
+			OnChange: func(networking clusterconfig.Networking) {
+				if len(cfg.AdvertiseClusterNetworks) > 0 {
+					ctrl.SetClusterNetworkCIDRs(advertisedClusterNetworks(networking, cfg.AdvertiseClusterNetworks))
+				}
+			},
+		})
+		if err != nil {
+			log.Fatalf("Failed to create cluster network watcher: %v", err)
+		}
+		go clusterNetworkWatcher.Run(ctx)
+		log.Printf("Watching cluster networks (advertised by HA gateways: %v)", cfg.AdvertiseClusterNetworks)
+	}
+
+	if runtimeConfigWatcher != nil {
+		go runtimeConfigWatcher.Run(ctx)
+		log.Printf("Watching KaputNotConfig %s for runtime settings", instanceName(v1alpha1.KaputNotConfigName, cfg.InstanceID))
+	}
+
+	if ipPoolWatcher != nil {
+		go ipPoolWatcher.Run(ctx)
+		log.Printf("Publishing pod CIDRs of Cilium IP pools matching %q", cfg.CiliumIPPoolSelector)
+	}
+
+	if nodeClaimWatcher != nil {
+		go nodeClaimWatcher.Run(ctx)
+		log.Println("Watching Karpenter NodeClaims: rules of nodes being removed are turned off early")
+	}
+
+	// Serve metrics, probes and debug state on all replicas (not just the leader)
+	leaderTracker := leaderelection.NewTracker(cfg.LeaderElectionIdentity, cfg.LeaderElectionEnabled)
+	var dash *dashboard.Dashboard
+	if cfg.DashboardEnabled {
+		dash, err = dashboard.New(&dashboard.Options{State: ctrl, Egress: cachedClient, Owner: rec})
+		if err != nil {
+			log.Fatalf("Failed to create dashboard: %v", err)
+		}
+	}
+	startHTTPServer(ctx, cfg.MetricsBindAddress, ctrl, cachedClient, cfg.NetmakerCacheFlushToken, leaderTracker, dash)
+
+	// Serve the admin gRPC service on all replicas - Resync is refused by non-leaders (optional)
+	if cfg.AdminGRPCBindAddress != "" {
+		adminServer, err := admin.New(&admin.Options{
+			KubeClient:    kubeClient,
+			Controller:    ctrl,
+			DryRun:        readOnlyClient,
+			Cache:         cachedClient,
+			AllowedUsers:  cfg.AdminGRPCAllowedUsers,
+			AllowedGroups: cfg.AdminGRPCAllowedGroups,
+			Audiences:     cfg.AdminGRPCAudiences,
+			TLSCertFile:   cfg.AdminGRPCTLSCertFile,
+			TLSKeyFile:    cfg.AdminGRPCTLSKeyFile,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create admin service: %v", err)
+		}
+		go func() {
+			if err := adminServer.Serve(ctx, cfg.AdminGRPCBindAddress); err != nil {
+				log.Fatalf("Admin service failed: %v", err)
+			}
+		}()
+		log.Printf("Admin gRPC service listening on %s (TLS: %t)", cfg.AdminGRPCBindAddress, cfg.AdminGRPCTLSCertFile != "")
+	}
+
+	// Log goroutines, cache statistics and workqueue contents on SIGUSR1
+	handleDumpSignal(ctx, ctrl, cachedClient)
+
+	// Refresh the Netmaker token before its exp claim (all replicas - observers read Netmaker too)
+	if cfg.NetmakerTokenRefreshMargin > 0 {
+		go httpClient.RunTokenRefresher(ctx, cfg.NetmakerTokenRefreshMargin)
+		log.Printf("Proactive token refresh enabled: margin=%s", cfg.NetmakerTokenRefreshMargin)
+	}
+
+	// Leader work: load the state store (if any) before reconciling, then run the controller
+	runLeader := func(ctx context.Context) error {
+		// Don't reconcile gateways before their cluster network CIDRs are known (avoids route flaps)
+		if clusterNetworkWatcher != nil && !clusterNetworkWatcher.WaitForSync(ctx) {
+			return ctx.Err()
+		}
+		// Nor nodes before their IP pool CIDRs are known (they would look like they have none)
+		if ipPoolWatcher != nil && !ipPoolWatcher.WaitForSync(ctx) {
+			return ctx.Err()
+		}
+		// Nor nodes Karpenter is removing before their NodeClaims are known (they would not be gated)
+		if nodeClaimWatcher != nil && !nodeClaimWatcher.WaitForSync(ctx) {
+			return ctx.Err()
+		}
+		// Nor anything before the runtime settings (e.g. dry-run) are applied
+		if runtimeConfigWatcher != nil && !runtimeConfigWatcher.WaitForSync(ctx) {
+			return ctx.Err()
+		}
+		if stateStore != nil {
+			if err := stateStore.Load(ctx); err != nil {
+				return err
+			}
+			go stateStore.Run(ctx)
+		}
+		if trashBin != nil {
+			go trashBin.Run(ctx)
+		}
+		if hostWatcher != nil {
+			go hostWatcher.Run(ctx)
+		}
+		if cfg.LeaderElectionEnabled && cfg.LeaderElectionHandoff {
+			resumeLeaderHandoff(ctx, cfg, kubeClient, ctrl)
+		}
+		return ctrl.Run(ctx)
+	}
+
 	// Run with or without leader election
 	if cfg.LeaderElectionEnabled {
-		log.Printf("Leader election enabled: namespace=%s, id=%s",
-			cfg.LeaderElectionNamespace, cfg.LeaderElectionID)
-		runWithLeaderElection(ctx, kubeClient, ctrl, cfg)
+		log.Printf("Leader election enabled: namespace=%s, id=%s, identity=%s",
+			cfg.LeaderElectionNamespace, cfg.LeaderElectionID, cfg.LeaderElectionIdentity)
+
+		// Observe in read-only mode until (and while) leading - no Netmaker mutations
+		go func() {
+			if err := ctrl.RunObserver(ctx); err != nil {
+				log.Fatalf("Observer failed: %v", err)
+			}
+		}()
+
+		var handoff func() string
+		if cfg.LeaderElectionHandoff {
+			handoff = func() string { return leaderHandoff(ctrl) }
+		}
+		runWithLeaderElection(ctx, kubeClient, runLeader, handoff, cfg, leaderTracker)
 	} else {
 		log.Println("Leader election disabled - running as single replica")
-		runWithoutLeaderElection(ctx, ctrl)
+		runWithoutLeaderElection(ctx, runLeader)
 	}
 
 	log.Println("Shutting down gracefully...")
 }
 
-// createKubeClient creates a Kubernetes client
-// If kubeconfig is empty, uses in-cluster configuration
-func createKubeClient(kubeconfig string) (kubernetes.Interface, error) {
+// createRestConfig loads the Kubernetes client configuration
+// If cfg.Kubeconfig is empty, uses in-cluster configuration
+func createRestConfig(cfg *Config) (*rest.Config, error) {
 	var config *rest.Config
 	var err error
 
+	kubeconfig := cfg.Kubeconfig
 	if kubeconfig == "" {
 		// In-cluster: read service account token and CA cert
 		log.Println("Using in-cluster Kubernetes configuration")
@@ -119,25 +550,353 @@ func createKubeClient(kubeconfig string) (kubernetes.Interface, error) {
 		}
 	}
 
-	client, err := kubernetes.NewForConfig(config)
+	// Client-side rate limiting (zero values keep the client-go defaults)
+	if cfg.KubeClientQPS > 0 {
+		config.QPS = cfg.KubeClientQPS
+	}
+	if cfg.KubeClientBurst > 0 {
+		config.Burst = cfg.KubeClientBurst
+	}
+
+	return config, nil
+}
+
+// reconcilerOptions builds the reconciler options from the configuration (without a state store)
+// In aggregated mode the cluster CIDRs are detected from kube-controller-manager unless configured
+func reconcilerOptions(ctx context.Context, cfg *Config, kubeClient kubernetes.Interface, cachedClient *netmaker.CachedClient) (*reconciler.Options, error) {
+	var clusterCIDRs []string
+	if cfg.AggregateClusterCIDR {
+		clusterCIDRs = cfg.ClusterCIDRs
+		if len(clusterCIDRs) == 0 {
+			var err error
+			clusterCIDRs, err = detectClusterCIDRs(ctx, kubeClient)
+			if err != nil {
+				return nil, err
+			}
+		}
+		log.Printf("Aggregated mode: publishers advertise cluster CIDRs %v", clusterCIDRs)
+	}
+
+	networks, err := cfg.createNetworks()
+	if err != nil {
+		return nil, err
+	}
+	descriptionLabels, err := cfg.descriptionLabels()
+	if err != nil {
+		return nil, err
+	}
+	nodeMetrics, err := cfg.egressMetrics()
 	if err != nil {
 		return nil, err
 	}
 
+	return &reconciler.Options{
+		NetmakerClient:           cachedClient,
+		ClusterName:              cfg.ClusterName,
+		InstanceID:               cfg.InstanceID,
+		LeaseDuration:            cfg.EgressLeaseDuration,
+		LeaseGracePeriod:         cfg.EgressLeaseGracePeriod,
+		CleanupBatchSize:         cfg.CleanupBatchSize,
+		CleanupTimeBudget:        cfg.CleanupTimeBudget,
+		CleanupDeleteConcurrency: cfg.CleanupDeleteConcurrency,
+		ResyncListConcurrency:    cfg.ResyncListConcurrency,
+		GatewayHealthCheck:       cfg.GatewayHealthCheck,
+		GatewayStaleAfter:        cfg.GatewayStaleAfter,
+		ClusterCIDRs:             clusterCIDRs,
+		AdoptExisting:            cfg.AdoptExisting,
+		MatchHostsByAddress:      cfg.MatchHostsByAddress,
+		DefaultNetwork:           cfg.NetmakerDefaultNetwork,
+		Networks:                 networks,
+		DescriptionLabels:        descriptionLabels,
+		NameMarker:               cfg.EgressNameMarker,
+		NodeMetrics:              nodeMetrics,
+	}, nil
+}
+
+// controllerOptions builds the controller options from the configuration
+func controllerOptions(cfg *Config, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, cachedClient *netmaker.CachedClient, rec controller.Reconciler) *controller.Options {
+	// Changes of the labels embedded in descriptions or selecting egress metrics re-reconcile the node
+	// (DESCRIPTION_LABELS and EGRESS_METRICS are validated on load)
+	descriptionLabels, _ := cfg.descriptionLabels()
+	nodeMetrics, _ := cfg.egressMetrics()
+	watchedLabels := slices.Sorted(maps.Values(descriptionLabels))
+	for _, key := range reconciler.NodeMetricLabels(nodeMetrics) {
+		if !slices.Contains(watchedLabels, key) {
+			watchedLabels = append(watchedLabels, key)
+		}
+	}
+
+	return &controller.Options{
+		KubeClient:     kubeClient,
+		DynamicClient:  dynamicClient,
+		NetmakerClient: cachedClient,
+		Reconciler:     rec,
+		ClusterName:    cfg.ClusterName,
+		InstanceID:     cfg.InstanceID,
+
+		IncludeWindowsNodes:        cfg.IncludeWindowsNodes,
+		DeletionDelay:              cfg.NodeDeletionDelay,
+		HostNotFoundThreshold:      cfg.HostNotFoundThreshold,
+		CanaryNode:                 cfg.CanaryNode,
+		GatingTaints:               cfg.GatingTaints,
+		AutoscalerScaleDown:        cfg.AutoscalerScaleDown,
+		ChurnThreshold:             cfg.ChurnThreshold,
+		ChurnWindow:                cfg.ChurnWindow,
+		QuarantineThreshold:        cfg.QuarantineThreshold,
+		QuarantineRetryInterval:    cfg.QuarantineRetryInterval,
+		GatewaySelector:            cfg.HAGatewaySelector,
+		PublisherSelector:          cfg.PublisherSelector,
+		SummarizePodCIDRs:          cfg.SummarizePodCIDRs,
+		PoolLabel:                  cfg.PoolLabel,
+		WatchedLabels:              watchedLabels,
+		MatchHostsByAddress:        cfg.MatchHostsByAddress,
+		ManageExtClients:           cfg.ManageExtClients,
+		ManageEgressRules:          cfg.ManageEgressRules,
+		DisableWatchBookmarks:      !cfg.KubeWatchBookmark,
+		MaxInformerRestarts:        cfg.KubeInformerMaxRestarts,
+		InformerCacheWarnThreshold: cfg.InformerCacheWarnThreshold,
+		EgressCacheWarnThreshold:   cfg.EgressCacheWarnThreshold,
+	}
+}
+
+// connectNetmaker creates the cached Netmaker client and authenticates it (used by the subcommands)
+func connectNetmaker(ctx context.Context, cfg *Config) (*netmaker.CachedClient, error) {
+	httpClient, err := createNetmakerClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := limitMutations(cfg, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.NetmakerReadOnlyNetworks) > 0 {
+		client = netmaker.NewReadOnlyClient(client, cfg.NetmakerReadOnlyNetworks)
+	}
+	cachedClient := netmaker.NewCachedClient(client, cfg.NetmakerCacheTTL)
+	if err := cachedClient.Authenticate(ctx); err != nil {
+		return nil, fmt.Errorf("failed to authenticate with Netmaker: %w", err)
+	}
+	return cachedClient, nil
+}
+
+// createHooks creates the configured mutation hooks, in the order they run
+func createHooks(cfg *Config) []hooks.Hook {
+	var mutationHooks []hooks.Hook
+	if len(cfg.HookCommand) > 0 {
+		mutationHooks = append(mutationHooks, &hooks.Exec{Command: cfg.HookCommand, Timeout: cfg.HookTimeout})
+		log.Printf("Mutation hook command: %s", cfg.HookCommand[0])
+	}
+	if cfg.HookWebhookURL != "" {
+		mutationHooks = append(mutationHooks, &hooks.Webhook{URL: cfg.HookWebhookURL, Timeout: cfg.HookTimeout})
+		if u, err := url.Parse(cfg.HookWebhookURL); err == nil {
+			log.Printf("Mutation hook webhook: %s://%s", u.Scheme, u.Host) // Path and query may carry a token
+		}
+	}
+	return mutationHooks
+}
+
+// limitMutations wraps a Netmaker client so that at most NETMAKER_MAX_CONCURRENT_MUTATIONS writes run at once
+// Returns the client unchanged when no limit is configured
+func limitMutations(cfg *Config, client netmaker.Client) (netmaker.Client, error) {
+	if cfg.NetmakerMaxMutations == 0 {
+		return client, nil
+	}
+	limited, err := netmaker.NewMutationLimitClient(client, cfg.NetmakerMaxMutations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to limit Netmaker mutations: %w", err)
+	}
+	log.Printf("Limiting concurrent Netmaker mutations to %d", cfg.NetmakerMaxMutations)
+	return limited, nil
+}
+
+// netmakerClient is the Netmaker client created from the configuration
+// Implemented by *netmaker.HTTPClient and, with per-network credentials, *netmaker.NetworkCredentialsClient
+type netmakerClient interface {
+	netmaker.Client
+	RunTokenRefresher(ctx context.Context, margin time.Duration)
+}
+
+// createNetmakerClient creates the Netmaker HTTP client with the configured authenticator and proxy
+// With NETMAKER_NETWORK_CREDENTIALS_DIR, networks with credentials of their own get a client each
+func createNetmakerClient(cfg *Config) (netmakerClient, error) {
+	authenticator, err := createAuthenticator(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Netmaker authenticator: %w", err)
+	}
+	forgetNetmakerPassword(cfg)
+	httpClient, err := newNetmakerHTTPClient(cfg, authenticator)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint := cfg.loginEndpoint(); !endpoint.IsZero() {
+		log.Printf("Custom Netmaker login: %s", endpoint)
+	}
+	if cfg.NetmakerAuthHeader != netmaker.AuthHeaderBearer {
+		log.Printf("Sending the Netmaker token in the %s header", cfg.NetmakerAuthHeader)
+	}
+	if cfg.NetmakerProxyURL != "" {
+		log.Printf("Reaching Netmaker through proxy %s", netmaker.RedactedProxyURL(cfg.NetmakerProxyURL))
+	}
+	if tlsPolicy := cfg.tlsPolicy(); !tlsPolicy.IsZero() {
+		log.Printf("Netmaker TLS policy: %s", tlsPolicy)
+	}
+
+	if cfg.NetmakerNetworkCredentialsDir == "" {
+		return httpClient, nil
+	}
+
+	credentials, err := cfg.networkCredentials()
+	if err != nil {
+		return nil, err
+	}
+	networkClients := make(map[string]netmaker.Client, len(credentials))
+	for network, files := range credentials {
+		source, err := netmaker.NewFileCredentialSource(files.Username, files.Password, netmaker.Credentials{})
+		if err != nil {
+			return nil, fmt.Errorf("network %s: %w", network, err)
+		}
+		networkAuthenticator, err := netmaker.NewPasswordAuthenticatorWithSource(cfg.NetmakerAPIURL, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Netmaker authenticator for network %s: %w", network, err)
+		}
+		networkClients[network], err = newNetmakerHTTPClient(cfg, networkAuthenticator)
+		if err != nil {
+			return nil, fmt.Errorf("network %s: %w", network, err)
+		}
+	}
+	client, err := netmaker.NewNetworkCredentialsClient(httpClient, networkClients)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Per-network Netmaker credentials from %s: %v", cfg.NetmakerNetworkCredentialsDir, client.Networks())
 	return client, nil
 }
 
+// newNetmakerHTTPClient creates a Netmaker HTTP client with the configured login endpoint, auth header, response
+// size limit, per-network timeouts, proxy and TLS policy
+func newNetmakerHTTPClient(cfg *Config, authenticator netmaker.Authenticator) (*netmaker.HTTPClient, error) {
+	if endpoint := cfg.loginEndpoint(); !endpoint.IsZero() {
+		if passwordAuthenticator, ok := authenticator.(*netmaker.PasswordAuthenticator); ok {
+			if err := passwordAuthenticator.SetLoginEndpoint(endpoint); err != nil {
+				return nil, fmt.Errorf("failed to configure Netmaker login: %w", err)
+			}
+		}
+	}
+	httpClient, err := netmaker.NewHTTPClientWithAuthenticator(cfg.NetmakerAPIURL, authenticator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Netmaker HTTP client: %w", err)
+	}
+	if err := httpClient.SetAuthHeader(cfg.NetmakerAuthHeader); err != nil {
+		return nil, fmt.Errorf("failed to configure Netmaker auth header: %w", err)
+	}
+	if cfg.NetmakerMaxResponseBytes > 0 {
+		if err := httpClient.SetMaxResponseBytes(int64(cfg.NetmakerMaxResponseBytes)); err != nil {
+			return nil, fmt.Errorf("failed to configure Netmaker response size limit: %w", err)
+		}
+	}
+	timeouts, err := cfg.networkTimeouts()
+	if err != nil {
+		return nil, fmt.Errorf("invalid NETMAKER_NETWORK_TIMEOUTS: %w", err)
+	}
+	if len(timeouts) > 0 {
+		if err := httpClient.SetNetworkTimeouts(timeouts); err != nil {
+			return nil, fmt.Errorf("failed to configure Netmaker network timeouts: %w", err)
+		}
+	}
+	if cfg.NetmakerProxyURL != "" {
+		if err := httpClient.SetProxy(cfg.NetmakerProxyURL, cfg.NetmakerNoProxy); err != nil {
+			return nil, fmt.Errorf("failed to configure Netmaker proxy: %w", err)
+		}
+	}
+	if tlsPolicy := cfg.tlsPolicy(); !tlsPolicy.IsZero() {
+		if err := httpClient.SetTLSPolicy(tlsPolicy); err != nil {
+			return nil, fmt.Errorf("failed to configure Netmaker TLS policy: %w", err)
+		}
+	}
+	return httpClient, nil
+}
+
+// createAuthenticator creates the Netmaker authenticator for the configured auth mode
+func createAuthenticator(cfg *Config) (netmaker.Authenticator, error) {
+	if cfg.NetmakerAuthMode == authModeTokenExchange {
+		log.Printf("Using service account token exchange: url=%s, token=%s",
+			cfg.NetmakerTokenExchangeURL, cfg.NetmakerServiceAccountToken)
+		return netmaker.NewTokenExchangeAuthenticator(
+			cfg.NetmakerTokenExchangeURL,
+			cfg.NetmakerServiceAccountToken,
+			cfg.NetmakerTokenExchangeAudience,
+		)
+	}
+
+	if cfg.NetmakerAuthMode == authModeVault {
+		log.Printf("Reading Netmaker credentials from Vault: addr=%s, role=%s, path=%s",
+			cfg.VaultAddress, cfg.VaultRole, cfg.VaultSecretPath)
+		source, err := netmaker.NewVaultCredentialSource(&netmaker.VaultConfig{
+			Address:     cfg.VaultAddress,
+			Role:        cfg.VaultRole,
+			SecretPath:  cfg.VaultSecretPath,
+			AuthMount:   cfg.VaultAuthMount,
+			Namespace:   cfg.VaultNamespace,
+			TokenFile:   cfg.VaultTokenFile,
+			UsernameKey: cfg.VaultUsernameKey,
+			PasswordKey: cfg.VaultPasswordKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return netmaker.NewPasswordAuthenticatorWithSource(cfg.NetmakerAPIURL, source)
+	}
+
+	if cfg.NetmakerUsernameFile != "" || cfg.NetmakerPasswordFile != "" {
+		log.Printf("Reading Netmaker credentials from files: username=%s, password=%s",
+			valueOrDash(cfg.NetmakerUsernameFile), valueOrDash(cfg.NetmakerPasswordFile))
+		source, err := netmaker.NewFileCredentialSource(
+			cfg.NetmakerUsernameFile,
+			cfg.NetmakerPasswordFile,
+			netmaker.Credentials{Username: cfg.NetmakerUsername, Password: cfg.NetmakerPassword},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return netmaker.NewPasswordAuthenticatorWithSource(cfg.NetmakerAPIURL, source)
+	}
+
+	return netmaker.NewPasswordAuthenticator(cfg.NetmakerAPIURL, cfg.NetmakerUsername, cfg.NetmakerPassword)
+}
+
+// forgetNetmakerPassword drops the plain NETMAKER_PASSWORD once the authenticator holds it in a locked buffer
+// The variable is removed from the process environment too, so later lookups and child processes don't see it
+func forgetNetmakerPassword(cfg *Config) {
+	cfg.NetmakerPassword = ""
+	if err := os.Unsetenv("NETMAKER_PASSWORD"); err != nil {
+		log.Printf("Failed to remove NETMAKER_PASSWORD from the environment: %v", err)
+	}
+}
+
+// valueOrDash returns value, or "-" if it is empty (for log output)
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 // runWithLeaderElection runs the controller with leader election
 // Only the elected leader will run the controller
-func runWithLeaderElection(ctx context.Context, kubeClient kubernetes.Interface, ctrl *controller.Controller, cfg *Config) {
+// handoff returns the state left on the lease for the next leader (nil disables the handoff)
+func runWithLeaderElection(ctx context.Context, kubeClient kubernetes.Interface, runLeader func(context.Context) error,
+	handoff func() string, cfg *Config, tracker *leaderelection.Tracker) {
 	// Create leader election config
 	leConfig := &leaderelection.Config{
 		KubeClient:    kubeClient,
 		LockName:      cfg.LeaderElectionID,
 		LockNamespace: cfg.LeaderElectionNamespace,
+		Identity:      cfg.LeaderElectionIdentity,
+		Tracker:       tracker,
+		Handoff:       handoff,
 		OnStartedLeading: func(ctx context.Context) {
 			log.Println("*** Became leader - starting controller ***")
-			if err := ctrl.Run(ctx); err != nil {
+			if err := runLeader(ctx); err != nil {
 				log.Fatalf("Controller failed: %v", err)
 			}
 		},
@@ -147,11 +906,10 @@ func runWithLeaderElection(ctx context.Context, kubeClient kubernetes.Interface,
 			os.Exit(0)
 		},
 		OnNewLeader: func(identity string) {
-			hostname, _ := os.Hostname()
-			if identity == hostname {
+			if identity == cfg.LeaderElectionIdentity {
 				log.Printf("*** I am the new leader: %s ***", identity)
 			} else {
-				log.Printf("New leader elected: %s (I am: %s)", identity, hostname)
+				log.Printf("New leader elected: %s (I am: %s)", identity, cfg.LeaderElectionIdentity)
 			}
 		},
 	}
@@ -163,8 +921,22 @@ func runWithLeaderElection(ctx context.Context, kubeClient kubernetes.Interface,
 }
 
 // runWithoutLeaderElection runs the controller directly without leader election
-func runWithoutLeaderElection(ctx context.Context, ctrl *controller.Controller) {
-	if err := ctrl.Run(ctx); err != nil {
+func runWithoutLeaderElection(ctx context.Context, runLeader func(context.Context) error) {
+	if err := runLeader(ctx); err != nil {
 		log.Fatalf("Controller failed: %v", err)
 	}
 }
+
+// advertisedClusterNetworks selects the subnets of the given kinds ("pod", "service")
+func advertisedClusterNetworks(networking clusterconfig.Networking, kinds []string) []string {
+	var cidrs []string
+	for _, kind := range kinds {
+		switch kind {
+		case clusterNetworkPod:
+			cidrs = append(cidrs, networking.PodSubnets...)
+		case clusterNetworkService:
+			cidrs = append(cidrs, networking.ServiceSubnets...)
+		}
+	}
+	return cidrs
+}
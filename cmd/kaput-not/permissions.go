@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// currentUserClient looks up the Netmaker user it authenticates as
+// Implemented by *netmaker.HTTPClient
+type currentUserClient interface {
+	CurrentUser(ctx context.Context) (*netmaker.User, error)
+}
+
+// permissionAudit is the result of auditNetmakerPermissions
+type permissionAudit struct {
+	Missing    []string // Rights the Netmaker users provably lack, e.g. "user k8s: network-admin role in network x (...)"
+	Unverified []string // Accounts whose rights could not be checked, with the reason
+	Users      int      // Accounts checked
+}
+
+// netmakerAccount is a Netmaker user and the networks kaput-not manages as that user
+type netmakerAccount struct {
+	client   netmaker.Client
+	networks []string
+	create   bool // Creates missing networks (NETMAKER_CREATE_NETWORKS)
+}
+
+// auditNetmakerPermissions checks that the Netmaker users may manage egress rules in the networks kaput-not touches:
+// the networks of the visible nodes and of NETMAKER_CREATE_NETWORKS, without read-only networks
+// With per-network credentials, every network is checked against the user it is managed as
+// Catches accounts without the needed roles at startup instead of with 403s during reconciliation
+func auditNetmakerPermissions(ctx context.Context, cfg *Config, client netmakerClient) (*permissionAudit, error) {
+	nodes, err := client.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	managed := make(map[string]bool)
+	for _, node := range nodes {
+		managed[node.Network] = true
+	}
+
+	create := make(map[string]bool)
+	createNetworks, err := cfg.createNetworks()
+	if err != nil {
+		return nil, err
+	}
+	for _, network := range createNetworks {
+		managed[network.NetID] = true
+		if _, err := client.GetNetwork(ctx, network.NetID); errors.Is(err, netmaker.ErrNotFound) {
+			create[network.NetID] = true
+		}
+	}
+	if cfg.NetmakerDefaultNetwork != "" {
+		managed[cfg.NetmakerDefaultNetwork] = true // Hosts are joined to it
+	}
+	for _, network := range cfg.NetmakerReadOnlyNetworks {
+		delete(managed, network) // Only read
+	}
+
+	// Group the networks by the user managing them
+	defaultAccount := &netmakerAccount{client: client}
+	accounts := []*netmakerAccount{defaultAccount}
+	credentialsClient, _ := client.(*netmaker.NetworkCredentialsClient)
+	if credentialsClient != nil {
+		defaultAccount.client = credentialsClient.Client
+	}
+	for _, network := range slices.Sorted(maps.Keys(managed)) {
+		account := defaultAccount
+		if credentialsClient != nil {
+			if networkClient, ok := credentialsClient.NetworkClient(network); ok {
+				account = &netmakerAccount{client: networkClient}
+				accounts = append(accounts, account)
+			}
+		}
+		account.networks = append(account.networks, network)
+		account.create = account.create || create[network]
+	}
+
+	audit := &permissionAudit{}
+	for _, account := range accounts {
+		if len(account.networks) == 0 {
+			continue
+		}
+		lookup, ok := account.client.(currentUserClient)
+		if !ok {
+			audit.Unverified = append(audit.Unverified, fmt.Sprintf("networks %v: the client cannot look up its user", account.networks))
+			continue
+		}
+		user, err := lookup.CurrentUser(ctx)
+		if errors.Is(err, netmaker.ErrUserUnknown) {
+			audit.Unverified = append(audit.Unverified, fmt.Sprintf("networks %v: the %s auth mode has no username", account.networks, cfg.NetmakerAuthMode))
+			continue
+		}
+		if err != nil {
+			audit.Unverified = append(audit.Unverified, fmt.Sprintf("networks %v: failed to look up the user: %v", account.networks, err))
+			continue
+		}
+		audit.Users++
+
+		missing := user.MissingPermissions(account.networks, account.create)
+		if len(missing) > 0 && len(user.UserGroups) > 0 {
+			// Roles may be granted through the groups, which are not resolved
+			audit.Unverified = append(audit.Unverified, fmt.Sprintf("user %s: lacks %d role(s) unless granted through user groups",
+				user.UserName, len(missing)))
+			continue
+		}
+		for _, permission := range missing {
+			audit.Missing = append(audit.Missing, fmt.Sprintf("user %s: %s", user.UserName, permission))
+		}
+	}
+	return audit, nil
+}
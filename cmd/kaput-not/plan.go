@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bsure-analytics/kaput-not/pkg/clusterconfig"
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/ippools"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// runPlan implements "kaput-not plan": prints the egress rules every node should have (per network, per node)
+// and the changes needed to get there from the current Netmaker state, without modifying anything
+// Configuration comes from the usual environment variables; returns the process exit code
+func runPlan(args []string) int {
+	flags := flag.NewFlagSet("plan", flag.ContinueOnError)
+	output := flags.String("output", "yaml", "Output format: yaml (one document per network) or json")
+	detailedExitCode := flags.Bool("detailed-exitcode", false, "Exit with 2 instead of 0 when there are changes")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not plan [--output yaml|json] [--detailed-exitcode]\n\n")
+		fmt.Fprintf(flags.Output(), "Prints the desired egress rules and the changes against the current Netmaker state.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output != "yaml" && *output != "json" {
+		log.Printf("Invalid output format %q (must be yaml or json)", *output)
+		return 2
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Printf("Configuration error: %v", err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	plan, err := computePlan(ctx, cfg)
+	if err != nil {
+		log.Printf("Failed to compute plan: %v", err)
+		return 1
+	}
+
+	if err := writePlan(plan, *output); err != nil {
+		log.Printf("Failed to write plan: %v", err)
+		return 1
+	}
+
+	creates, updates, deletes := plan.Changes()
+	fmt.Fprintf(os.Stderr, "Plan: %d to create, %d to update, %d to delete\n", creates, updates, deletes)
+	if *detailedExitCode && creates+updates+deletes > 0 {
+		return 2
+	}
+	return 0
+}
+
+// computePlan wires the reconciler and controller as main does and plans all publisher nodes
+func computePlan(ctx context.Context, cfg *Config) (*reconciler.Plan, error) {
+	ctrl, _, err := planningController(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.Plan(ctx)
+}
+
+// planningController wires the reconciler and controller as main does, for planning without mutations
+// ClusterEgressRules are left out; the controller's informer starts with its first Plan
+func planningController(ctx context.Context, cfg *Config) (*controller.Controller, *reconciler.Reconciler, error) {
+	restConfig, err := createRestConfig(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load Kubernetes configuration: %w", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	cachedClient, err := connectNetmaker(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	recOpts, err := reconcilerOptions(ctx, cfg, kubeClient, cachedClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to configure reconciler: %w", err)
+	}
+	rec, err := reconciler.New(recOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create reconciler: %w", err)
+	}
+
+	// ClusterEgressRules are not planned
+	ctrlOpts := controllerOptions(cfg, kubeClient, dynamicClient, cachedClient, rec)
+	ctrlOpts.ManageEgressRules = false
+
+	// Nodes publish the CIDRs of the advertised IP pools instead of their spec.podCIDRs
+	if cfg.CiliumIPPools {
+		watcher, err := ippools.New(&ippools.Options{DynamicClient: dynamicClient, Selector: cfg.CiliumIPPoolSelector})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create IP pool watcher: %w", err)
+		}
+		go watcher.Run(ctx)
+		if !watcher.WaitForSync(ctx) {
+			return nil, nil, ctx.Err()
+		}
+		ctrlOpts.PodIPPools = watcher
+	}
+	ctrl, err := controller.New(ctrlOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create controller: %w", err)
+	}
+
+	// HA gateways publish the cluster networks in addition to their pod CIDRs
+	if cfg.WatchClusterNetworks && len(cfg.AdvertiseClusterNetworks) > 0 {
+		watcher, err := clusterconfig.New(&clusterconfig.Options{KubeClient: kubeClient})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create cluster network watcher: %w", err)
+		}
+		go watcher.Run(ctx)
+		if !watcher.WaitForSync(ctx) {
+			return nil, nil, ctx.Err()
+		}
+		ctrl.SetClusterNetworkCIDRs(advertisedClusterNetworks(watcher.Networking(), cfg.AdvertiseClusterNetworks))
+	}
+
+	return ctrl, rec, nil
+}
+
+// writePlan prints the plan to stdout, as one YAML document per network or as a single JSON object
+func writePlan(plan *reconciler.Plan, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	}
+
+	for _, network := range plan.Networks {
+		document, err := yaml.Marshal(network)
+		if err != nil {
+			return fmt.Errorf("failed to marshal network %s: %w", network.Network, err)
+		}
+		if _, err := fmt.Fprintf(os.Stdout, "---\n%s", document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// runPurge implements "kaput-not purge": deletes every egress rule managed by a cluster identity
+// Meant to run as a Job when decommissioning a cluster - stop the controller first, or it recreates the rules
+// Netmaker settings come from the usual environment variables; returns the process exit code
+func runPurge(args []string) int {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	clusterName := flags.String("cluster", os.Getenv("K8S_CLUSTER_NAME"),
+		"Cluster name whose egress rules are deleted (empty: rules without a cluster name, i.e. single-cluster mode)")
+	instanceID := flags.String("instance", os.Getenv("INSTANCE_ID"),
+		"Instance ID whose egress rules are deleted (empty: rules without an instance ID)")
+	dryRun := flags.Bool("dry-run", false, "List the egress rules that would be deleted without deleting them")
+	yes := flags.Bool("yes", false, "Skip the interactive confirmation (required when stdin is not a terminal)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not purge [--cluster NAME] [--instance ID] [--dry-run] [--yes]\n\n")
+		fmt.Fprintf(flags.Output(), "Deletes every Netmaker egress rule managed by kaput-not for a cluster.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Printf("Configuration error: %v", err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cachedClient, err := connectNetmaker(ctx, cfg)
+	if err != nil {
+		log.Printf("Netmaker error: %v", err)
+		return 1
+	}
+
+	rec, err := reconciler.New(&reconciler.Options{
+		NetmakerClient:           cachedClient,
+		ClusterName:              *clusterName,
+		InstanceID:               *instanceID,
+		CleanupDeleteConcurrency: cfg.CleanupDeleteConcurrency,
+	})
+	if err != nil {
+		log.Printf("Failed to create reconciler: %v", err)
+		return 1
+	}
+
+	egresses, err := rec.ManagedEgresses(ctx)
+	if err != nil {
+		log.Printf("Failed to list managed egress rules: %v", err)
+		return 1
+	}
+
+	identity := fmt.Sprintf("cluster %q", *clusterName)
+	if *clusterName == "" {
+		identity = "single-cluster mode (no cluster name)"
+	}
+	if *instanceID != "" {
+		identity += fmt.Sprintf(", instance %q", *instanceID)
+	}
+	fmt.Printf("Found %d egress rule(s) managed for %s:\n", len(egresses), identity)
+	for _, egress := range egresses {
+		fmt.Printf("  %s\t%s\t%s\t%s\n", egress.Network, egress.ID, egress.Range, egress.Name)
+	}
+
+	if len(egresses) == 0 || *dryRun {
+		return 0
+	}
+
+	if !*yes && !confirm(fmt.Sprintf("Delete %d egress rule(s)? Type \"yes\" to confirm: ", len(egresses))) {
+		fmt.Println("Aborted - nothing deleted")
+		return 1
+	}
+
+	deleted, err := rec.DeleteEgresses(ctx, egresses)
+	fmt.Printf("Deleted %d of %d egress rule(s)\n", deleted, len(egresses))
+	if err != nil {
+		log.Printf("Some egress rules could not be deleted: %v", err)
+		return 1
+	}
+
+	return 0
+}
+
+// confirm prompts on stdout and reports whether "yes" was typed (EOF counts as no)
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == "yes"
+}
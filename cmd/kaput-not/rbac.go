@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bsure-analytics/kaput-not/pkg/admin"
+	"github.com/bsure-analytics/kaput-not/pkg/clusterconfig"
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/ippools"
+	"github.com/bsure-analytics/kaput-not/pkg/leaderelection"
+	"github.com/bsure-analytics/kaput-not/pkg/nodeclaims"
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+	"github.com/bsure-analytics/kaput-not/pkg/runtimeconfig"
+	"github.com/bsure-analytics/kaput-not/pkg/statestore"
+	"github.com/bsure-analytics/kaput-not/pkg/trash"
+)
+
+// runRBAC implements "kaput-not rbac": prints the ClusterRole/Role manifests for the current configuration
+// Each package declares the permissions of the API calls it performs, so the output follows the code
+// Features are read from the usual environment variables; returns the process exit code
+func runRBAC(args []string) int {
+	cfg, _ := readConfig() // Invalid values fall back to their defaults
+
+	flags := flag.NewFlagSet("rbac", flag.ContinueOnError)
+	name := flags.String("name", "kaput-not", "Name of the generated roles and bindings")
+	serviceAccount := flags.String("service-account", "kaput-not", "Service account the roles are bound to")
+	namespace := flags.String("namespace", cfg.LeaderElectionNamespace, "Namespace of the service account, lease and state ConfigMap")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not rbac [--name NAME] [--service-account NAME] [--namespace NAMESPACE]\n\n")
+		fmt.Fprintf(flags.Output(), "Prints the RBAC manifests required by the features enabled in the environment.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	cfg.LeaderElectionNamespace = *namespace
+
+	manifests, err := rbac.Render(*name, rbac.Subject{Name: *serviceAccount, Namespace: *namespace}, permissions(cfg))
+	if err != nil {
+		log.Printf("Failed to render RBAC manifests: %v", err)
+		return 1
+	}
+
+	if _, err := os.Stdout.Write(manifests); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// permissions collects the RBAC rules of every component enabled in cfg
+// Mirrors the wiring in main - a component added there must contribute its permissions here
+func permissions(cfg *Config) []rbac.Permission {
+	ctrlOpts := &controller.Options{ManageEgressRules: cfg.ManageEgressRules}
+	permissions := ctrlOpts.Permissions()
+
+	if cfg.AggregateClusterCIDR && len(cfg.ClusterCIDRs) == 0 {
+		// detectClusterCIDRs lists the kube-controller-manager pods
+		permissions = append(permissions, rbac.Permission{
+			Namespace: controllerManagerNamespace,
+			Rule:      rbac.Rule("", "pods", "list"),
+		})
+	}
+
+	if cfg.WatchClusterNetworks {
+		watcherOpts := &clusterconfig.Options{}
+		permissions = append(permissions, watcherOpts.Permissions()...)
+	}
+
+	if cfg.CiliumIPPools {
+		watcherOpts := &ippools.Options{}
+		permissions = append(permissions, watcherOpts.Permissions()...)
+	}
+
+	if cfg.KarpenterNodeClaims {
+		watcherOpts := &nodeclaims.Options{}
+		permissions = append(permissions, watcherOpts.Permissions()...)
+	}
+
+	if cfg.WatchKaputNotConfig {
+		watcherOpts := &runtimeconfig.Options{}
+		permissions = append(permissions, watcherOpts.Permissions()...)
+	}
+
+	if cfg.StateConfigMap != "" {
+		storeOpts := &statestore.ConfigMapOptions{Name: cfg.StateConfigMap, Namespace: cfg.LeaderElectionNamespace}
+		permissions = append(permissions, storeOpts.Permissions()...)
+	}
+
+	if cfg.TrashConfigMap != "" {
+		trashOpts := &trash.Options{Name: cfg.TrashConfigMap, Namespace: cfg.LeaderElectionNamespace}
+		permissions = append(permissions, trashOpts.Permissions()...)
+	}
+
+	if cfg.AdminGRPCBindAddress != "" {
+		adminOpts := &admin.Options{}
+		permissions = append(permissions, adminOpts.Permissions()...)
+	}
+
+	if cfg.LeaderElectionEnabled {
+		permissions = append(permissions, leaderelection.Permissions(cfg.LeaderElectionNamespace, cfg.LeaderElectionID)...)
+	}
+
+	return permissions
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// runReport implements "kaput-not report": summarizes the egress rules managed for this cluster, grouped by
+// the description labels embedded from node labels (DESCRIPTION_LABELS), e.g. per team or cost center
+// With --format, lists every node's pod CIDRs, egress rules and discrepancies instead (compliance exports)
+// Only reads Netmaker; configuration comes from the usual environment variables; returns the process exit code
+func runReport(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	by := flags.String("by", "", "Comma-separated description keys to group by (default: the keys of DESCRIPTION_LABELS)")
+	output := flags.String("output", "table", "Output format: table or json")
+	format := flags.String("format", "", "Per-node compliance report format: json or csv (replaces the label summary)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not report [--by team,environment] [--output table|json]\n")
+		fmt.Fprintf(flags.Output(), "       kaput-not report --format json|csv\n\n")
+		fmt.Fprintf(flags.Output(), "Counts the managed egress rules per combination of description label values.\n")
+		fmt.Fprintf(flags.Output(), "With --format, lists each node's pod CIDRs, egress rules and discrepancies instead.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		log.Printf("Invalid output format %q (must be table or json)", *output)
+		return 2
+	}
+	if *format != "" && *format != "json" && *format != "csv" {
+		log.Printf("Invalid format %q (must be json or csv)", *format)
+		return 2
+	}
+	if *format != "" && (*by != "" || *output != "table") {
+		log.Printf("--format cannot be combined with --by or --output")
+		return 2
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Printf("Configuration error: %v", err)
+		return 1
+	}
+	if *format != "" {
+		return runComplianceReport(cfg, *format)
+	}
+
+	keys := splitList(*by)
+	if len(keys) == 0 {
+		keys = cfg.descriptionKeys()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cachedClient, err := connectNetmaker(ctx, cfg)
+	if err != nil {
+		log.Printf("Netmaker error: %v", err)
+		return 1
+	}
+
+	rec, err := reconciler.New(&reconciler.Options{
+		NetmakerClient: cachedClient,
+		ClusterName:    cfg.ClusterName,
+		InstanceID:     cfg.InstanceID,
+	})
+	if err != nil {
+		log.Printf("Failed to create reconciler: %v", err)
+		return 1
+	}
+
+	report, err := rec.Report(ctx, keys)
+	if err != nil {
+		log.Printf("Failed to build report: %v", err)
+		return 1
+	}
+
+	if err := writeReport(report, *output); err != nil {
+		log.Printf("Failed to write report: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// writeReport prints the report to stdout, as a table (one row per group, "-" for missing labels) or as JSON
+func writeReport(report *reconciler.Report, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := make([]string, 0, len(report.Keys)+5)
+	for _, key := range report.Keys {
+		header = append(header, strings.ToUpper(key))
+	}
+	header = append(header, "RULES", "DISABLED", "HELD", "HOSTS", "NETWORKS")
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+
+	for _, group := range report.Groups {
+		row := make([]string, 0, len(header))
+		for _, key := range report.Keys {
+			row = append(row, valueOrDash(group.Labels[key]))
+		}
+		row = append(row, fmt.Sprint(group.Rules), fmt.Sprint(group.Disabled), fmt.Sprint(group.Held), fmt.Sprint(group.Hosts),
+			valueOrDash(strings.Join(group.Networks, ",")))
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	fmt.Fprintf(writer, "\nTotal: %d managed egress rule(s)\n", report.Rules)
+	if report.Held > 0 {
+		fmt.Fprintf(writer, "Held: %d rule(s) kept when their node is deleted (%s)\n", report.Held, controller.HoldAnnotation)
+	}
+	return writer.Flush()
+}
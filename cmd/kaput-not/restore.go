@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/trash"
+)
+
+// runRestore implements "kaput-not restore": lists the deleted egress rules kept in the trash bin (TRASH_CONFIGMAP),
+// or recreates the given ones in Netmaker and removes them from the bin; returns the process exit code
+// Restored rules get new IDs. A rule whose node is gone for good is deleted again by the next orphan cleanup
+func runRestore(args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not restore [EGRESS_ID...]\n\n")
+		fmt.Fprintf(flags.Output(), "Lists the deleted egress rules kept in the trash bin, or recreates the given ones.\n")
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Printf("Configuration error: %v", err)
+		return 1
+	}
+	if cfg.TrashConfigMap == "" {
+		log.Printf("The trash bin is disabled (TRASH_CONFIGMAP is not set)")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	restConfig, err := createRestConfig(cfg)
+	if err != nil {
+		log.Printf("Failed to load Kubernetes configuration: %v", err)
+		return 1
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		log.Printf("Failed to create Kubernetes client: %v", err)
+		return 1
+	}
+	bin, err := trash.New(&trash.Options{
+		KubeClient: kubeClient,
+		Name:       cfg.TrashConfigMap,
+		Namespace:  cfg.LeaderElectionNamespace,
+		MaxEntries: cfg.TrashMaxEntries,
+		TTL:        cfg.TrashTTL,
+	})
+	if err != nil {
+		log.Printf("Failed to create trash bin: %v", err)
+		return 1
+	}
+
+	entries, err := bin.List(ctx)
+	if err != nil {
+		log.Printf("Failed to list the trash bin: %v", err)
+		return 1
+	}
+	if flags.NArg() == 0 {
+		writeTrash(entries)
+		return 0
+	}
+
+	cachedClient, err := connectNetmaker(ctx, cfg)
+	if err != nil {
+		log.Printf("Netmaker error: %v", err)
+		return 1
+	}
+
+	deleted := make(map[string]trash.Entry, len(entries))
+	for _, entry := range entries {
+		deleted[entry.Egress.ID] = entry
+	}
+	exitCode := 0
+	for _, id := range flags.Args() {
+		entry, ok := deleted[id]
+		if !ok {
+			log.Printf("Egress rule %s is not in the trash bin", id)
+			exitCode = 1
+			continue
+		}
+
+		egress := entry.Egress
+		created, err := cachedClient.CreateEgress(ctx, netmaker.EgressReq{
+			Name:        egress.Name,
+			Network:     egress.Network,
+			Description: egress.Description,
+			Range:       egress.Range,
+			NAT:         egress.NAT,
+			Nodes:       egress.Nodes,
+			Status:      egress.Status,
+		})
+		if err != nil {
+			log.Printf("Failed to restore egress rule %s (%q) in network %s: %v", id, egress.Name, egress.Network, err)
+			exitCode = 1
+			continue
+		}
+		fmt.Printf("Restored egress rule %s (%q, %s) in network %s as %s\n", id, egress.Name, egress.Range, egress.Network, created.ID)
+
+		if err := bin.Remove(ctx, id); err != nil {
+			log.Printf("Failed to remove restored egress rule %s from the trash bin: %v", id, err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// writeTrash prints the deleted egress rules as a table, most recently deleted first
+func writeTrash(entries []trash.Entry) {
+	if len(entries) == 0 {
+		fmt.Println("The trash bin is empty")
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNETWORK\tNAME\tRANGE\tDELETED")
+	for _, entry := range entries {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", entry.Egress.ID, entry.Egress.Network, entry.Egress.Name,
+			entry.Egress.Range, entry.DeletedAt.Local().Format(time.RFC3339))
+	}
+	_ = writer.Flush()
+}
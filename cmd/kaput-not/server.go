@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/dashboard"
+	"github.com/bsure-analytics/kaput-not/pkg/leaderelection"
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// startHTTPServer serves metrics, probes, debug state, the cache flush endpoint (empty flushToken: disabled)
+// and the dashboard (nil: disabled) in the background
+// Runs on every replica (leader and observers); an empty address disables the server
+// The server shuts down when ctx is canceled
+func startHTTPServer(ctx context.Context, addr string, ctrl *controller.Controller, cachedClient *netmaker.CachedClient,
+	flushToken string, leaderTracker *leaderelection.Tracker, dash *dashboard.Dashboard) {
+	if addr == "" {
+		log.Println("HTTP server disabled")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	// Liveness: the process is up and serving
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	// Readiness: the informer cache is synced and its watch not failing (true for leader and observers alike)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !ctrl.HasSynced() {
+			http.Error(w, "informer cache not synced", http.StatusServiceUnavailable)
+			return
+		}
+		if ctrl.WatchFailing() {
+			http.Error(w, "node watch failing, informer cache may be stale", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	// Debug state: read-only snapshot of informer and cache contents
+	mux.HandleFunc("/debug/state", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, ctrl.State())
+	})
+
+	// Leader election: this replica's identity and the current lease holder
+	mux.HandleFunc("/debug/leader", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, leaderTracker.Status())
+	})
+
+	// Dashboard: read-only status page for people without kubectl or Netmaker access
+	if dash != nil {
+		mux.Handle(dashboard.Path, dash)
+		log.Printf("Serving the dashboard on %s", dashboard.Path)
+	}
+
+	// Cache flush: POST /admin/cache/flush?kind=egress&network=mynet forces fresh Netmaker reads
+	// kind defaults to "all"; each replica has its own cache, so target the leader
+	// The listener is reachable by probes and scrapers, so callers must present the flush token
+	mux.HandleFunc("/admin/cache/flush", func(w http.ResponseWriter, r *http.Request) {
+		if flushToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if !hasBearerToken(r, flushToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		kind := netmaker.CacheKind(r.URL.Query().Get("kind"))
+		if kind == "" {
+			kind = netmaker.CacheKindAll
+		}
+		network := r.URL.Query().Get("network")
+
+		if err := cachedClient.Invalidate(kind, network); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Netmaker cache flushed: kind=%s, network=%s", kind, valueOrDash(network))
+		writeJSON(w, map[string]string{"kind": string(kind), "network": network})
+	})
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Serving metrics and debug endpoints on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("HTTP server failed: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+}
+
+// hasBearerToken reports whether r carries "Authorization: Bearer <token>", compared in constant time
+func hasBearerToken(r *http.Request, token string) bool {
+	scheme, presented, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "bearer") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(presented)), []byte(token)) == 1
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
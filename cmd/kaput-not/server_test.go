@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHasBearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		want          bool
+	}{
+		{name: "matching token", authorization: "Bearer s3cret", want: true},
+		{name: "no header", authorization: "", want: false},
+		{name: "wrong token", authorization: "Bearer other", want: false},
+		{name: "token prefix", authorization: "Bearer s3c", want: false},
+		{name: "basic auth", authorization: "Basic s3cret", want: false},
+		{name: "lowercase scheme", authorization: "bearer s3cret", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/admin/cache/flush", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if got := hasBearerToken(r, "s3cret"); got != tt.want {
+				t.Errorf("hasBearerToken(%q) = %v, want %v", tt.authorization, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/bsure-analytics/kaput-not/pkg/clusterconfig"
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// runSimulate implements "kaput-not simulate": plans like "kaput-not plan", but against recorded Kubernetes
+// objects and Netmaker state instead of live clusters, for offline debugging of production incidents
+// With --record, the live Netmaker state is written to the Netmaker fixture instead
+// Configuration comes from the usual environment variables; returns the process exit code
+func runSimulate(args []string) int {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	k8sFixture := flags.String("k8s-fixture", "", "Kubernetes objects (e.g. kubectl get nodes -o yaml), YAML or JSON, lists and multiple documents allowed")
+	netmakerFixture := flags.String("netmaker-fixture", "", "Recorded Netmaker state (JSON, see --record)")
+	record := flags.Bool("record", false, "Write the live Netmaker state to --netmaker-fixture instead of simulating")
+	output := flags.String("output", "yaml", "Output format: yaml (one document per network) or json")
+	detailedExitCode := flags.Bool("detailed-exitcode", false, "Exit with 2 instead of 0 when there are changes")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not simulate --k8s-fixture nodes.yaml --netmaker-fixture state.json [--output yaml|json] [--detailed-exitcode]\n")
+		fmt.Fprintf(flags.Output(), "       kaput-not simulate --record --netmaker-fixture state.json\n\n")
+		fmt.Fprintf(flags.Output(), "Prints the desired egress rules and the changes the reconciler would make to the recorded state.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *netmakerFixture == "" || (!*record && *k8sFixture == "") {
+		flags.Usage()
+		return 2
+	}
+	if *output != "yaml" && *output != "json" {
+		log.Printf("Invalid output format %q (must be yaml or json)", *output)
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if *record {
+		cfg, err := LoadConfig()
+		if err != nil {
+			log.Printf("Configuration error: %v", err)
+			return 1
+		}
+		if err := recordNetmakerFixture(ctx, cfg, *netmakerFixture); err != nil {
+			log.Printf("Failed to record Netmaker state: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	// The fixtures replace both APIs, so only the feature toggles of the configuration matter
+	cfg, _ := readConfig() // Invalid values fall back to their defaults
+	plan, err := simulatePlan(ctx, cfg, *k8sFixture, *netmakerFixture)
+	if err != nil {
+		log.Printf("Failed to simulate: %v", err)
+		return 1
+	}
+
+	if err := writePlan(plan, *output); err != nil {
+		log.Printf("Failed to write plan: %v", err)
+		return 1
+	}
+
+	creates, updates, deletes := plan.Changes()
+	fmt.Fprintf(os.Stderr, "Plan: %d to create, %d to update, %d to delete\n", creates, updates, deletes)
+	if *detailedExitCode && creates+updates+deletes > 0 {
+		return 2
+	}
+	return 0
+}
+
+// recordNetmakerFixture writes the live Netmaker state to path
+func recordNetmakerFixture(ctx context.Context, cfg *Config, path string) error {
+	cachedClient, err := connectNetmaker(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	fixture, err := netmaker.RecordFixture(ctx, cachedClient)
+	if err != nil {
+		return err
+	}
+	if err := fixture.Save(path); err != nil {
+		return err
+	}
+	egresses := 0
+	for _, rules := range fixture.Egress {
+		egresses += len(rules)
+	}
+	fmt.Fprintf(os.Stderr, "Recorded %d hosts, %d nodes and %d egress rules to %s\n",
+		len(fixture.Hosts), len(fixture.Nodes), egresses, path)
+	return nil
+}
+
+// simulatePlan wires the reconciler and controller as computePlan does, on top of the fixtures
+func simulatePlan(ctx context.Context, cfg *Config, k8sFixture, netmakerFixture string) (*reconciler.Plan, error) {
+	if cfg.CiliumIPPools {
+		return nil, fmt.Errorf("CILIUM_IP_POOLS cannot be simulated, the fixtures hold no CiliumNodes")
+	}
+
+	objects, err := loadKubernetesFixture(k8sFixture)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient := fake.NewClientset(objects...)
+
+	fixture, err := netmaker.LoadFixture(netmakerFixture)
+	if err != nil {
+		return nil, err
+	}
+	cachedClient := netmaker.NewCachedClient(netmaker.NewFixtureClient(fixture), cfg.NetmakerCacheTTL)
+
+	recOpts, err := reconcilerOptions(ctx, cfg, kubeClient, cachedClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure reconciler: %w", err)
+	}
+	rec, err := reconciler.New(recOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reconciler: %w", err)
+	}
+
+	// ClusterEgressRules are not planned
+	ctrlOpts := controllerOptions(cfg, kubeClient, nil, cachedClient, rec)
+	ctrlOpts.ManageEgressRules = false
+	ctrl, err := controller.New(ctrlOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create controller: %w", err)
+	}
+
+	// HA gateways publish the cluster networks found in the fixture's kubeadm-config / kube-proxy ConfigMaps
+	if cfg.WatchClusterNetworks && len(cfg.AdvertiseClusterNetworks) > 0 {
+		watcher, err := clusterconfig.New(&clusterconfig.Options{KubeClient: kubeClient})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cluster network watcher: %w", err)
+		}
+		go watcher.Run(ctx)
+		if !watcher.WaitForSync(ctx) {
+			return nil, ctx.Err()
+		}
+		ctrl.SetClusterNetworkCIDRs(advertisedClusterNetworks(watcher.Networking(), cfg.AdvertiseClusterNetworks))
+	}
+
+	return ctrl.Plan(ctx)
+}
+
+// loadKubernetesFixture decodes the Kubernetes objects of a YAML or JSON file
+// Accepts multiple documents and lists (kind: List or e.g. NodeList), as printed by kubectl get -o yaml
+func loadKubernetesFixture(path string) ([]runtime.Object, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Kubernetes fixture: %w", err)
+	}
+
+	var objects []runtime.Object
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse Kubernetes fixture %s: %w", path, err)
+		}
+		if len(bytes.TrimSpace(raw.Raw)) == 0 || string(bytes.TrimSpace(raw.Raw)) == "null" {
+			continue // Empty document
+		}
+
+		var list struct {
+			Items []runtime.RawExtension `json:"items"`
+		}
+		if err := json.Unmarshal(raw.Raw, &list); err == nil && list.Items != nil {
+			for _, item := range list.Items {
+				object, err := decodeKubernetesObject(item.Raw)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decode item of Kubernetes fixture %s: %w", path, err)
+				}
+				objects = append(objects, object)
+			}
+			continue
+		}
+
+		object, err := decodeKubernetesObject(raw.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode Kubernetes fixture %s: %w", path, err)
+		}
+		objects = append(objects, object)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("Kubernetes fixture %s holds no objects", path)
+	}
+	return objects, nil
+}
+
+// decodeKubernetesObject decodes a single built-in Kubernetes object from JSON
+func decodeKubernetesObject(data []byte) (runtime.Object, error) {
+	object, _, err := scheme.Codecs.UniversalDeserializer().Decode(data, nil, nil)
+	return object, err
+}
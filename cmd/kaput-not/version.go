@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bsure-analytics/kaput-not/pkg/version"
+)
+
+// runVersion implements "kaput-not version": prints the build information of the binary
+// Returns the process exit code
+func runVersion(args []string) int {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	output := flags.String("output", "", "Output format: empty for a single line, or json")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: kaput-not version [--output json]\n\n")
+		fmt.Fprintf(flags.Output(), "Prints the version, commit, Go version and supported Netmaker versions.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	info := version.Get()
+	switch *output {
+	case "":
+		fmt.Println(info)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(info); err != nil {
+			return 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported output format %q (must be json or empty)\n", *output)
+		return 2
+	}
+	return 0
+}
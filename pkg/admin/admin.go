@@ -0,0 +1,254 @@
+// Package admin serves the gRPC admin service (see adminv1/admin.proto): Resync, GetState, SetDryRun and FlushCache,
+// for other operators and automation driving kaput-not programmatically
+// Callers authenticate with a Kubernetes token, checked with a TokenReview and an allow-list (see auth.go)
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bsure-analytics/kaput-not/pkg/admin/adminv1"
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+// Controller is the controller driven by the admin service
+// Implemented by *controller.Controller
+type Controller interface {
+	IsLeading() bool
+	State() controller.State
+	TriggerResync()
+	EnqueueNodes(names []string)
+}
+
+// DryRunSwitch turns dry-run mode on and off
+// Implemented by *netmaker.ReadOnlyClient
+type DryRunSwitch interface {
+	DryRun() bool
+	SetDryRun(enabled bool)
+}
+
+// CacheFlusher drops cached Netmaker responses
+// Implemented by *netmaker.CachedClient
+type CacheFlusher interface {
+	Invalidate(kind netmaker.CacheKind, network string) error
+}
+
+// Options contains configuration for the admin service
+type Options struct {
+	// KubeClient creates the TokenReviews authenticating callers
+	KubeClient kubernetes.Interface
+
+	// Controller is resynced and reports its state
+	Controller Controller
+
+	// DryRun is switched by SetDryRun
+	DryRun DryRunSwitch
+
+	// Cache is flushed by FlushCache
+	Cache CacheFlusher
+
+	// AllowedUsers are the usernames (e.g. "system:serviceaccount:ops:automation") allowed to call the service
+	AllowedUsers []string
+
+	// AllowedGroups are the groups (e.g. "system:serviceaccounts:ops") whose members may call the service
+	// At least one allowed user or group is required: any valid token of the cluster is not enough
+	AllowedGroups []string
+
+	// Audiences the caller's token must be issued for (e.g. "kaput-not" for projected service account tokens)
+	// Default: empty (the API server's audiences)
+	Audiences []string
+
+	// TLSCertFile and TLSKeyFile serve the service over TLS
+	// Default: empty (plaintext - restrict access, e.g. with a NetworkPolicy, as tokens are sent in the clear)
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ReviewTimeout bounds each TokenReview
+	// Default: 10 seconds
+	ReviewTimeout time.Duration
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.KubeClient == nil {
+		return fmt.Errorf("KubeClient is required")
+	}
+	if o.Controller == nil {
+		return fmt.Errorf("Controller is required")
+	}
+	if o.DryRun == nil {
+		return fmt.Errorf("DryRun is required")
+	}
+	if o.Cache == nil {
+		return fmt.Errorf("Cache is required")
+	}
+	if len(o.AllowedUsers) == 0 && len(o.AllowedGroups) == 0 {
+		return fmt.Errorf("AllowedUsers or AllowedGroups is required")
+	}
+	if (o.TLSCertFile == "") != (o.TLSKeyFile == "") {
+		return fmt.Errorf("TLSCertFile and TLSKeyFile must be set together")
+	}
+	if o.ReviewTimeout < 0 {
+		return fmt.Errorf("ReviewTimeout must not be negative")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.ReviewTimeout == 0 {
+		o.ReviewTimeout = 10 * time.Second
+	}
+}
+
+// Permissions returns the RBAC rules the service needs (callers' tokens are checked with TokenReviews)
+func (o *Options) Permissions() []rbac.Permission {
+	return []rbac.Permission{{Rule: rbac.Rule("authentication.k8s.io", "tokenreviews", "create")}}
+}
+
+// Server implements the admin service
+type Server struct {
+	adminv1.UnimplementedAdminServer
+
+	options *Options
+	auth    *authenticator
+	creds   credentials.TransportCredentials // nil: plaintext
+}
+
+// New creates a new admin server
+// Returns error for validation failures or unreadable TLS files, never panics
+func New(opts *Options) (*Server, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	s := &Server{options: opts, auth: newAuthenticator(opts)}
+	if opts.TLSCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(opts.TLSCertFile, opts.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		s.creds = credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12})
+	}
+	return s, nil
+}
+
+// Serve serves the admin service on addr until ctx is canceled
+// In-flight calls get 5 seconds to finish on shutdown
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	serverOptions := []grpc.ServerOption{grpc.UnaryInterceptor(s.auth.intercept)}
+	if s.creds != nil {
+		serverOptions = append(serverOptions, grpc.Creds(s.creds))
+	}
+	server := grpc.NewServer(serverOptions...)
+	adminv1.RegisterAdminServer(server, s)
+
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			server.Stop()
+		}
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Resync implements adminv1.AdminServer
+func (s *Server) Resync(ctx context.Context, req *adminv1.ResyncRequest) (*adminv1.ResyncResponse, error) {
+	if !s.options.Controller.IsLeading() {
+		return nil, status.Error(codes.FailedPrecondition, "this replica is not the leader")
+	}
+
+	if len(req.GetNodes()) == 0 {
+		s.options.Controller.TriggerResync()
+		log.Printf("Admin: full resync requested by %s", caller(ctx))
+		return &adminv1.ResyncResponse{}, nil
+	}
+
+	s.options.Controller.EnqueueNodes(req.GetNodes())
+	log.Printf("Admin: resync of nodes %v requested by %s", req.GetNodes(), caller(ctx))
+	return &adminv1.ResyncResponse{Queued: int32(len(req.GetNodes()))}, nil
+}
+
+// GetState implements adminv1.AdminServer
+func (s *Server) GetState(_ context.Context, _ *adminv1.GetStateRequest) (*adminv1.GetStateResponse, error) {
+	state := s.options.Controller.State()
+
+	resp := &adminv1.GetStateResponse{
+		Leading:             state.Leading,
+		InformerSynced:      state.InformerSynced,
+		WatchFailing:        state.WatchFailing,
+		DryRun:              s.options.DryRun.DryRun(),
+		QueueLength:         int32(state.QueueLength),
+		PriorityQueueLength: int32(state.PriorityQueue),
+		PendingDeletes:      int32(state.PendingDeletes),
+		Quarantined:         state.Quarantined,
+		MissingHosts:        state.MissingHosts,
+	}
+	for _, node := range state.Nodes {
+		n := &adminv1.Node{
+			Name:      node.Name,
+			PodCidrs:  node.PodCIDRs,
+			Supported: node.Supported,
+			Publisher: node.Publisher,
+			Gateway:   node.Gateway,
+			Gated:     node.Gated,
+			Pool:      node.Pool,
+		}
+		if node.Netmaker != nil {
+			n.NetmakerHost = node.Netmaker.ID
+		}
+		resp.Nodes = append(resp.Nodes, n)
+	}
+	return resp, nil
+}
+
+// SetDryRun implements adminv1.AdminServer
+func (s *Server) SetDryRun(ctx context.Context, req *adminv1.SetDryRunRequest) (*adminv1.SetDryRunResponse, error) {
+	previous := s.options.DryRun.DryRun()
+	s.options.DryRun.SetDryRun(req.GetEnabled())
+	log.Printf("Admin: dry-run set to %t (was %t) by %s", req.GetEnabled(), previous, caller(ctx))
+	return &adminv1.SetDryRunResponse{Previous: previous}, nil
+}
+
+// FlushCache implements adminv1.AdminServer
+func (s *Server) FlushCache(ctx context.Context, req *adminv1.FlushCacheRequest) (*adminv1.FlushCacheResponse, error) {
+	kind := netmaker.CacheKind(req.GetKind())
+	if kind == "" {
+		kind = netmaker.CacheKindAll
+	}
+	if err := s.options.Cache.Invalidate(kind, req.GetNetwork()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	log.Printf("Admin: Netmaker cache flushed (kind=%s, network=%q) by %s", kind, req.GetNetwork(), caller(ctx))
+	return &adminv1.FlushCacheResponse{}, nil
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// stubController is a Controller that does nothing
+type stubController struct{}
+
+func (stubController) IsLeading() bool             { return true }
+func (stubController) State() controller.State     { return controller.State{} }
+func (stubController) TriggerResync()              {}
+func (stubController) EnqueueNodes(names []string) {}
+
+// stubDryRun is a DryRunSwitch that remembers its setting
+type stubDryRun struct{ enabled bool }
+
+func (s *stubDryRun) DryRun() bool           { return s.enabled }
+func (s *stubDryRun) SetDryRun(enabled bool) { s.enabled = enabled }
+
+// stubCache is a CacheFlusher that does nothing
+type stubCache struct{}
+
+func (stubCache) Invalidate(netmaker.CacheKind, string) error { return nil }
+
+// validOptions returns options that pass Validate
+func validOptions() *Options {
+	return &Options{
+		KubeClient:   fake.NewClientset(),
+		Controller:   stubController{},
+		DryRun:       &stubDryRun{},
+		Cache:        stubCache{},
+		AllowedUsers: []string{"system:serviceaccount:ops:automation"},
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *Options)
+		wantErr string
+	}{
+		{name: "valid", modify: func(o *Options) {}},
+		{name: "allowed groups only", modify: func(o *Options) { o.AllowedUsers, o.AllowedGroups = nil, []string{"ops"} }},
+		{name: "empty allow-list", modify: func(o *Options) { o.AllowedUsers = nil }, wantErr: "AllowedUsers or AllowedGroups is required"},
+		{name: "empty allow-list slices", modify: func(o *Options) { o.AllowedUsers, o.AllowedGroups = []string{}, []string{} }, wantErr: "AllowedUsers or AllowedGroups is required"},
+		{name: "no kube client", modify: func(o *Options) { o.KubeClient = nil }, wantErr: "KubeClient is required"},
+		{name: "TLS cert without key", modify: func(o *Options) { o.TLSCertFile = "tls.crt" }, wantErr: "must be set together"},
+		{name: "negative review timeout", modify: func(o *Options) { o.ReviewTimeout = -1 }, wantErr: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := validOptions()
+			tt.modify(opts)
+			err := opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
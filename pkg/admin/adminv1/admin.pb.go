@@ -0,0 +1,661 @@
+// Admin service of kaput-not, for operators and automation driving it programmatically
+// Every call needs a Kubernetes service account (or other) token as "authorization: Bearer <token>" metadata,
+// authenticated with a TokenReview; each replica serves its own state, so target the leader for Resync and SetDryRun
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: admin.proto
+
+package adminv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ResyncRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Kubernetes node names; empty resyncs all nodes
+	Nodes         []string `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResyncRequest) Reset() {
+	*x = ResyncRequest{}
+	mi := &file_admin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResyncRequest) ProtoMessage() {}
+
+func (x *ResyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResyncRequest.ProtoReflect.Descriptor instead.
+func (*ResyncRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ResyncRequest) GetNodes() []string {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type ResyncResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Number of nodes queued (0 for a full resync, which reconciles every publisher node)
+	Queued        int32 `protobuf:"varint,1,opt,name=queued,proto3" json:"queued,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResyncResponse) Reset() {
+	*x = ResyncResponse{}
+	mi := &file_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResyncResponse) ProtoMessage() {}
+
+func (x *ResyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResyncResponse.ProtoReflect.Descriptor instead.
+func (*ResyncResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ResyncResponse) GetQueued() int32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+type GetStateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStateRequest) Reset() {
+	*x = GetStateRequest{}
+	mi := &file_admin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStateRequest) ProtoMessage() {}
+
+func (x *GetStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStateRequest.ProtoReflect.Descriptor instead.
+func (*GetStateRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{2}
+}
+
+type GetStateResponse struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Leading             bool                   `protobuf:"varint,1,opt,name=leading,proto3" json:"leading,omitempty"`
+	InformerSynced      bool                   `protobuf:"varint,2,opt,name=informer_synced,json=informerSynced,proto3" json:"informer_synced,omitempty"`
+	WatchFailing        bool                   `protobuf:"varint,3,opt,name=watch_failing,json=watchFailing,proto3" json:"watch_failing,omitempty"`
+	DryRun              bool                   `protobuf:"varint,4,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	QueueLength         int32                  `protobuf:"varint,5,opt,name=queue_length,json=queueLength,proto3" json:"queue_length,omitempty"`
+	PriorityQueueLength int32                  `protobuf:"varint,6,opt,name=priority_queue_length,json=priorityQueueLength,proto3" json:"priority_queue_length,omitempty"`
+	PendingDeletes      int32                  `protobuf:"varint,7,opt,name=pending_deletes,json=pendingDeletes,proto3" json:"pending_deletes,omitempty"`
+	Quarantined         []string               `protobuf:"bytes,8,rep,name=quarantined,proto3" json:"quarantined,omitempty"`
+	// Nodes without a Netmaker host beyond the configured threshold
+	MissingHosts  []string `protobuf:"bytes,9,rep,name=missing_hosts,json=missingHosts,proto3" json:"missing_hosts,omitempty"`
+	Nodes         []*Node  `protobuf:"bytes,10,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStateResponse) Reset() {
+	*x = GetStateResponse{}
+	mi := &file_admin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStateResponse) ProtoMessage() {}
+
+func (x *GetStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStateResponse.ProtoReflect.Descriptor instead.
+func (*GetStateResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetStateResponse) GetLeading() bool {
+	if x != nil {
+		return x.Leading
+	}
+	return false
+}
+
+func (x *GetStateResponse) GetInformerSynced() bool {
+	if x != nil {
+		return x.InformerSynced
+	}
+	return false
+}
+
+func (x *GetStateResponse) GetWatchFailing() bool {
+	if x != nil {
+		return x.WatchFailing
+	}
+	return false
+}
+
+func (x *GetStateResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *GetStateResponse) GetQueueLength() int32 {
+	if x != nil {
+		return x.QueueLength
+	}
+	return 0
+}
+
+func (x *GetStateResponse) GetPriorityQueueLength() int32 {
+	if x != nil {
+		return x.PriorityQueueLength
+	}
+	return 0
+}
+
+func (x *GetStateResponse) GetPendingDeletes() int32 {
+	if x != nil {
+		return x.PendingDeletes
+	}
+	return 0
+}
+
+func (x *GetStateResponse) GetQuarantined() []string {
+	if x != nil {
+		return x.Quarantined
+	}
+	return nil
+}
+
+func (x *GetStateResponse) GetMissingHosts() []string {
+	if x != nil {
+		return x.MissingHosts
+	}
+	return nil
+}
+
+func (x *GetStateResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+// Node is a Kubernetes node as seen by the informer cache
+type Node struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Name      string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	PodCidrs  []string               `protobuf:"bytes,2,rep,name=pod_cidrs,json=podCidrs,proto3" json:"pod_cidrs,omitempty"`
+	Supported bool                   `protobuf:"varint,3,opt,name=supported,proto3" json:"supported,omitempty"`
+	Publisher bool                   `protobuf:"varint,4,opt,name=publisher,proto3" json:"publisher,omitempty"`
+	Gateway   bool                   `protobuf:"varint,5,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	Gated     bool                   `protobuf:"varint,6,opt,name=gated,proto3" json:"gated,omitempty"`
+	Pool      string                 `protobuf:"bytes,7,opt,name=pool,proto3" json:"pool,omitempty"`
+	// Netmaker host ID (empty if unknown or not cached yet)
+	NetmakerHost  string `protobuf:"bytes,8,opt,name=netmaker_host,json=netmakerHost,proto3" json:"netmaker_host,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_admin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Node) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Node) GetPodCidrs() []string {
+	if x != nil {
+		return x.PodCidrs
+	}
+	return nil
+}
+
+func (x *Node) GetSupported() bool {
+	if x != nil {
+		return x.Supported
+	}
+	return false
+}
+
+func (x *Node) GetPublisher() bool {
+	if x != nil {
+		return x.Publisher
+	}
+	return false
+}
+
+func (x *Node) GetGateway() bool {
+	if x != nil {
+		return x.Gateway
+	}
+	return false
+}
+
+func (x *Node) GetGated() bool {
+	if x != nil {
+		return x.Gated
+	}
+	return false
+}
+
+func (x *Node) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *Node) GetNetmakerHost() string {
+	if x != nil {
+		return x.NetmakerHost
+	}
+	return ""
+}
+
+type SetDryRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetDryRunRequest) Reset() {
+	*x = SetDryRunRequest{}
+	mi := &file_admin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetDryRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetDryRunRequest) ProtoMessage() {}
+
+func (x *SetDryRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetDryRunRequest.ProtoReflect.Descriptor instead.
+func (*SetDryRunRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SetDryRunRequest) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+type SetDryRunResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Whether dry-run mode was on before the call
+	Previous      bool `protobuf:"varint,1,opt,name=previous,proto3" json:"previous,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetDryRunResponse) Reset() {
+	*x = SetDryRunResponse{}
+	mi := &file_admin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetDryRunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetDryRunResponse) ProtoMessage() {}
+
+func (x *SetDryRunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetDryRunResponse.ProtoReflect.Descriptor instead.
+func (*SetDryRunResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SetDryRunResponse) GetPrevious() bool {
+	if x != nil {
+		return x.Previous
+	}
+	return false
+}
+
+type FlushCacheRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Cache kind: hosts, nodes, networks, egress or all (default)
+	Kind string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	// Network whose egress rules to flush (kind egress only; empty flushes every network)
+	Network       string `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlushCacheRequest) Reset() {
+	*x = FlushCacheRequest{}
+	mi := &file_admin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushCacheRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushCacheRequest) ProtoMessage() {}
+
+func (x *FlushCacheRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushCacheRequest.ProtoReflect.Descriptor instead.
+func (*FlushCacheRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FlushCacheRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *FlushCacheRequest) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+type FlushCacheResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlushCacheResponse) Reset() {
+	*x = FlushCacheResponse{}
+	mi := &file_admin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushCacheResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushCacheResponse) ProtoMessage() {}
+
+func (x *FlushCacheResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushCacheResponse.ProtoReflect.Descriptor instead.
+func (*FlushCacheResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{8}
+}
+
+var File_admin_proto protoreflect.FileDescriptor
+
+const file_admin_proto_rawDesc = "" +
+	"\n" +
+	"\vadmin.proto\x12\x11kaputnot.admin.v1\"%\n" +
+	"\rResyncRequest\x12\x14\n" +
+	"\x05nodes\x18\x01 \x03(\tR\x05nodes\"(\n" +
+	"\x0eResyncResponse\x12\x16\n" +
+	"\x06queued\x18\x01 \x01(\x05R\x06queued\"\x11\n" +
+	"\x0fGetStateRequest\"\x89\x03\n" +
+	"\x10GetStateResponse\x12\x18\n" +
+	"\aleading\x18\x01 \x01(\bR\aleading\x12'\n" +
+	"\x0finformer_synced\x18\x02 \x01(\bR\x0einformerSynced\x12#\n" +
+	"\rwatch_failing\x18\x03 \x01(\bR\fwatchFailing\x12\x17\n" +
+	"\adry_run\x18\x04 \x01(\bR\x06dryRun\x12!\n" +
+	"\fqueue_length\x18\x05 \x01(\x05R\vqueueLength\x122\n" +
+	"\x15priority_queue_length\x18\x06 \x01(\x05R\x13priorityQueueLength\x12'\n" +
+	"\x0fpending_deletes\x18\a \x01(\x05R\x0ependingDeletes\x12 \n" +
+	"\vquarantined\x18\b \x03(\tR\vquarantined\x12#\n" +
+	"\rmissing_hosts\x18\t \x03(\tR\fmissingHosts\x12-\n" +
+	"\x05nodes\x18\n" +
+	" \x03(\v2\x17.kaputnot.admin.v1.NodeR\x05nodes\"\xdc\x01\n" +
+	"\x04Node\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1b\n" +
+	"\tpod_cidrs\x18\x02 \x03(\tR\bpodCidrs\x12\x1c\n" +
+	"\tsupported\x18\x03 \x01(\bR\tsupported\x12\x1c\n" +
+	"\tpublisher\x18\x04 \x01(\bR\tpublisher\x12\x18\n" +
+	"\agateway\x18\x05 \x01(\bR\agateway\x12\x14\n" +
+	"\x05gated\x18\x06 \x01(\bR\x05gated\x12\x12\n" +
+	"\x04pool\x18\a \x01(\tR\x04pool\x12#\n" +
+	"\rnetmaker_host\x18\b \x01(\tR\fnetmakerHost\",\n" +
+	"\x10SetDryRunRequest\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\"/\n" +
+	"\x11SetDryRunResponse\x12\x1a\n" +
+	"\bprevious\x18\x01 \x01(\bR\bprevious\"A\n" +
+	"\x11FlushCacheRequest\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x18\n" +
+	"\anetwork\x18\x02 \x01(\tR\anetwork\"\x14\n" +
+	"\x12FlushCacheResponse2\xde\x02\n" +
+	"\x05Admin\x12M\n" +
+	"\x06Resync\x12 .kaputnot.admin.v1.ResyncRequest\x1a!.kaputnot.admin.v1.ResyncResponse\x12S\n" +
+	"\bGetState\x12\".kaputnot.admin.v1.GetStateRequest\x1a#.kaputnot.admin.v1.GetStateResponse\x12V\n" +
+	"\tSetDryRun\x12#.kaputnot.admin.v1.SetDryRunRequest\x1a$.kaputnot.admin.v1.SetDryRunResponse\x12Y\n" +
+	"\n" +
+	"FlushCache\x12$.kaputnot.admin.v1.FlushCacheRequest\x1a%.kaputnot.admin.v1.FlushCacheResponseB8Z6github.com/bsure-analytics/kaput-not/pkg/admin/adminv1b\x06proto3"
+
+var (
+	file_admin_proto_rawDescOnce sync.Once
+	file_admin_proto_rawDescData []byte
+)
+
+func file_admin_proto_rawDescGZIP() []byte {
+	file_admin_proto_rawDescOnce.Do(func() {
+		file_admin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_admin_proto_rawDesc), len(file_admin_proto_rawDesc)))
+	})
+	return file_admin_proto_rawDescData
+}
+
+var file_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_admin_proto_goTypes = []any{
+	(*ResyncRequest)(nil),      // 0: kaputnot.admin.v1.ResyncRequest
+	(*ResyncResponse)(nil),     // 1: kaputnot.admin.v1.ResyncResponse
+	(*GetStateRequest)(nil),    // 2: kaputnot.admin.v1.GetStateRequest
+	(*GetStateResponse)(nil),   // 3: kaputnot.admin.v1.GetStateResponse
+	(*Node)(nil),               // 4: kaputnot.admin.v1.Node
+	(*SetDryRunRequest)(nil),   // 5: kaputnot.admin.v1.SetDryRunRequest
+	(*SetDryRunResponse)(nil),  // 6: kaputnot.admin.v1.SetDryRunResponse
+	(*FlushCacheRequest)(nil),  // 7: kaputnot.admin.v1.FlushCacheRequest
+	(*FlushCacheResponse)(nil), // 8: kaputnot.admin.v1.FlushCacheResponse
+}
+var file_admin_proto_depIdxs = []int32{
+	4, // 0: kaputnot.admin.v1.GetStateResponse.nodes:type_name -> kaputnot.admin.v1.Node
+	0, // 1: kaputnot.admin.v1.Admin.Resync:input_type -> kaputnot.admin.v1.ResyncRequest
+	2, // 2: kaputnot.admin.v1.Admin.GetState:input_type -> kaputnot.admin.v1.GetStateRequest
+	5, // 3: kaputnot.admin.v1.Admin.SetDryRun:input_type -> kaputnot.admin.v1.SetDryRunRequest
+	7, // 4: kaputnot.admin.v1.Admin.FlushCache:input_type -> kaputnot.admin.v1.FlushCacheRequest
+	1, // 5: kaputnot.admin.v1.Admin.Resync:output_type -> kaputnot.admin.v1.ResyncResponse
+	3, // 6: kaputnot.admin.v1.Admin.GetState:output_type -> kaputnot.admin.v1.GetStateResponse
+	6, // 7: kaputnot.admin.v1.Admin.SetDryRun:output_type -> kaputnot.admin.v1.SetDryRunResponse
+	8, // 8: kaputnot.admin.v1.Admin.FlushCache:output_type -> kaputnot.admin.v1.FlushCacheResponse
+	5, // [5:9] is the sub-list for method output_type
+	1, // [1:5] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_admin_proto_init() }
+func file_admin_proto_init() {
+	if File_admin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_admin_proto_rawDesc), len(file_admin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_admin_proto_goTypes,
+		DependencyIndexes: file_admin_proto_depIdxs,
+		MessageInfos:      file_admin_proto_msgTypes,
+	}.Build()
+	File_admin_proto = out.File
+	file_admin_proto_goTypes = nil
+	file_admin_proto_depIdxs = nil
+}
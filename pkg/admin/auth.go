@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// callerKey is the context key of the authenticated caller's username
+type callerKey struct{}
+
+// caller returns the authenticated caller's username of a call's context
+func caller(ctx context.Context) string {
+	username, _ := ctx.Value(callerKey{}).(string)
+	return username
+}
+
+// authenticator authenticates calls with a TokenReview of their bearer token and authorizes them by allow-list
+type authenticator struct {
+	options *Options
+	users   map[string]bool
+	groups  map[string]bool
+}
+
+// newAuthenticator creates the authenticator for the allowed users and groups of opts
+func newAuthenticator(opts *Options) *authenticator {
+	a := &authenticator{options: opts, users: make(map[string]bool), groups: make(map[string]bool)}
+	for _, user := range opts.AllowedUsers {
+		a.users[user] = true
+	}
+	for _, group := range opts.AllowedGroups {
+		a.groups[group] = true
+	}
+	return a
+}
+
+// intercept is the unary server interceptor rejecting calls without an allowed, valid token
+func (a *authenticator) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	user, err := a.review(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !a.users[user.Username] && !slices.ContainsFunc(user.Groups, func(group string) bool { return a.groups[group] }) {
+		log.Printf("Admin: denied %s to %s", info.FullMethod, user.Username)
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to use the admin service", user.Username)
+	}
+
+	return handler(context.WithValue(ctx, callerKey{}, user.Username), req)
+}
+
+// review authenticates a token with a TokenReview
+func (a *authenticator) review(ctx context.Context, token string) (authenticationv1.UserInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.options.ReviewTimeout)
+	defer cancel()
+
+	review, err := a.options.KubeClient.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: a.options.Audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		log.Printf("Admin: TokenReview failed: %v", err)
+		return authenticationv1.UserInfo{}, status.Error(codes.Unavailable, "failed to review token")
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return review.Status.User, nil
+}
+
+// bearerToken returns the token of a call's "authorization: Bearer <token>" metadata (empty if none)
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
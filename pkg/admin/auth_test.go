@@ -0,0 +1,167 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// withAuthorization returns a context carrying incoming "authorization" metadata (none if values is empty)
+func withAuthorization(values ...string) context.Context {
+	ctx := context.Background()
+	if len(values) == 0 {
+		return ctx
+	}
+	md := metadata.MD{}
+	for _, value := range values {
+		md.Append("authorization", value)
+	}
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{name: "no metadata", want: ""},
+		{name: "bearer token", values: []string{"Bearer abc"}, want: "abc"},
+		{name: "case-insensitive scheme", values: []string{"bearer abc"}, want: "abc"},
+		{name: "surrounding spaces", values: []string{"Bearer  abc "}, want: "abc"},
+		{name: "other scheme", values: []string{"Basic dXNlcjpwYXNz"}, want: ""},
+		{name: "no scheme", values: []string{"abc"}, want: ""},
+		{name: "bearer after other scheme", values: []string{"Basic dXNlcjpwYXNz", "Bearer abc"}, want: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bearerToken(withAuthorization(tt.values...)); got != tt.want {
+				t.Errorf("bearerToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorIntercept(t *testing.T) {
+	const token = "valid-token"
+	tests := []struct {
+		name        string
+		values      []string
+		review      authenticationv1.TokenReviewStatus
+		reviewErr   error
+		wantCode    codes.Code
+		wantCaller  string
+		wantReviews int
+	}{
+		{
+			name:     "missing token",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:        "unauthenticated review",
+			values:      []string{"Bearer " + token},
+			review:      authenticationv1.TokenReviewStatus{Authenticated: false},
+			wantCode:    codes.Unauthenticated,
+			wantReviews: 1,
+		},
+		{
+			name:        "TokenReview API failure",
+			values:      []string{"Bearer " + token},
+			reviewErr:   errors.New("connection refused"),
+			wantCode:    codes.Unavailable,
+			wantReviews: 1,
+		},
+		{
+			name:   "allowed user",
+			values: []string{"Bearer " + token},
+			review: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "system:serviceaccount:ops:automation"},
+			},
+			wantCode:    codes.OK,
+			wantCaller:  "system:serviceaccount:ops:automation",
+			wantReviews: 1,
+		},
+		{
+			name:   "allowed group",
+			values: []string{"Bearer " + token},
+			review: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "jane", Groups: []string{"system:authenticated", "platform-admins"}},
+			},
+			wantCode:    codes.OK,
+			wantCaller:  "jane",
+			wantReviews: 1,
+		},
+		{
+			name:   "denied caller",
+			values: []string{"Bearer " + token},
+			review: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "mallory", Groups: []string{"system:authenticated"}},
+			},
+			wantCode:    codes.PermissionDenied,
+			wantReviews: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := fake.NewClientset()
+			reviews := 0
+			kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				reviews++
+				review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				if review.Spec.Token != token {
+					t.Errorf("reviewed token = %q, want %q", review.Spec.Token, token)
+				}
+				if tt.reviewErr != nil {
+					return true, nil, tt.reviewErr
+				}
+				review.Status = tt.review
+				return true, review, nil
+			})
+
+			a := newAuthenticator(&Options{
+				KubeClient:    kubeClient,
+				AllowedUsers:  []string{"system:serviceaccount:ops:automation"},
+				AllowedGroups: []string{"platform-admins"},
+				ReviewTimeout: time.Second,
+			})
+			handlerCaller := ""
+			handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+				handlerCaller = caller(ctx)
+				return "ok", nil
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/kaputnot.admin.v1.Admin/GetState"}
+
+			resp, err := a.intercept(withAuthorization(tt.values...), nil, info, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("intercept() error = %v, want code %v", err, tt.wantCode)
+			}
+			if reviews != tt.wantReviews {
+				t.Errorf("TokenReviews = %d, want %d", reviews, tt.wantReviews)
+			}
+			if tt.wantCode != codes.OK {
+				if resp != nil || handlerCaller != "" {
+					t.Errorf("handler was called for a rejected call")
+				}
+				return
+			}
+			if handlerCaller != tt.wantCaller {
+				t.Errorf("caller = %q, want %q", handlerCaller, tt.wantCaller)
+			}
+		})
+	}
+}
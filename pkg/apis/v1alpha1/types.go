@@ -0,0 +1,146 @@
+// Package v1alpha1 contains the kaput-not.io/v1alpha1 custom resource types
+// The CRDs are shipped with the Helm chart (charts/kaput-not/crds); objects are read through the
+// dynamic client and converted with FromUnstructured, so no generated clientsets are needed
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersion is the API group and version of the kaput-not custom resources
+var GroupVersion = schema.GroupVersion{Group: "kaput-not.io", Version: "v1alpha1"}
+
+// ClusterEgressRuleResource is the resource of ClusterEgressRule objects
+var ClusterEgressRuleResource = GroupVersion.WithResource("clusteregressrules")
+
+// KaputNotConfigResource is the resource of KaputNotConfig objects
+var KaputNotConfigResource = GroupVersion.WithResource("kaputnotconfigs")
+
+// KaputNotConfigName is the name of the KaputNotConfig read by the default instance
+// Instances with an instance ID read the KaputNotConfig named like their ID instead
+const KaputNotConfigName = "default"
+
+// InstanceLabel selects the kaput-not instance managing a ClusterEgressRule (see controller.Options.InstanceID)
+// ClusterEgressRules without it are managed by the default instance
+const InstanceLabel = "kaput-not.io/instance"
+
+// Condition types reported in the status of kaput-not custom resources
+const (
+	// ConditionSynced is True when the Netmaker egress rules match the current spec
+	ConditionSynced = "Synced"
+	// ConditionDegraded is True when the resource is invalid, failed to sync or has no gateway nodes
+	ConditionDegraded = "Degraded"
+	// ConditionProgressing is True while a new generation or a failed sync is being reconciled
+	ConditionProgressing = "Progressing"
+	// ConditionApplied is True when the current KaputNotConfig spec is in effect
+	ConditionApplied = "Applied"
+)
+
+// ClusterEgressRule routes arbitrary CIDRs through selected nodes (cluster-scoped)
+// The controller manages one Netmaker egress rule per CIDR in every network of the selected nodes,
+// and deletes them when the ClusterEgressRule is deleted
+type ClusterEgressRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterEgressRuleSpec   `json:"spec"`
+	Status ClusterEgressRuleStatus `json:"status,omitempty"`
+}
+
+// ClusterEgressRuleSpec is the desired state of a ClusterEgressRule
+type ClusterEgressRuleSpec struct {
+	// CIDRs are the destination ranges routed through the selected nodes
+	CIDRs []string `json:"cidrs"`
+
+	// NodeSelector selects the gateway nodes; the first matching node (by name) is the primary gateway,
+	// the others are backups with increasing metrics
+	NodeSelector metav1.LabelSelector `json:"nodeSelector"`
+
+	// NAT masquerades routed traffic behind the gateway
+	NAT bool `json:"nat,omitempty"`
+}
+
+// ClusterEgressRuleStatus is the observed state of a ClusterEgressRule (status subresource)
+type ClusterEgressRuleStatus struct {
+	// ObservedGeneration is the spec generation the conditions refer to
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// GatewayNodes are the nodes currently selected as gateways, in order of preference
+	GatewayNodes []string `json:"gatewayNodes,omitempty"`
+
+	// Conditions are the Synced, Degraded and Progressing conditions
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ClusterEgressRuleFromUnstructured converts a dynamic client object to a ClusterEgressRule
+func ClusterEgressRuleFromUnstructured(obj map[string]interface{}) (*ClusterEgressRule, error) {
+	rule := &ClusterEgressRule{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ClusterEgressRuleStatusToUnstructured converts a status to its dynamic client representation
+func ClusterEgressRuleStatusToUnstructured(status *ClusterEgressRuleStatus) (map[string]interface{}, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+}
+
+// KaputNotConfig holds runtime settings of the controller (cluster-scoped singleton named "default")
+// Changes take effect without a restart; unset fields keep the value configured by environment variables
+type KaputNotConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KaputNotConfigSpec   `json:"spec,omitempty"`
+	Status KaputNotConfigStatus `json:"status,omitempty"`
+}
+
+// KaputNotConfigSpec is the desired runtime configuration
+type KaputNotConfigSpec struct {
+	// DryRun logs and counts Netmaker egress mutations in every network instead of applying them
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// PublisherSelector selects the nodes that publish egress rules (empty string: all nodes)
+	PublisherSelector *string `json:"publisherSelector,omitempty"`
+
+	// IncludeWindowsNodes reconciles Windows nodes
+	IncludeWindowsNodes *bool `json:"includeWindowsNodes,omitempty"`
+
+	// Metrics configures the self-metrics
+	Metrics KaputNotConfigMetrics `json:"metrics,omitempty"`
+}
+
+// KaputNotConfigMetrics configures the self-metrics
+type KaputNotConfigMetrics struct {
+	// InformerCacheWarnThreshold logs a warning when the node informer cache holds more objects (0 disables)
+	InformerCacheWarnThreshold *int `json:"informerCacheWarnThreshold,omitempty"`
+
+	// EgressCacheWarnThreshold logs a warning when the Netmaker cache holds more egress rules (0 disables)
+	EgressCacheWarnThreshold *int `json:"egressCacheWarnThreshold,omitempty"`
+}
+
+// KaputNotConfigStatus is the observed state of the KaputNotConfig (status subresource)
+type KaputNotConfigStatus struct {
+	// ObservedGeneration is the spec generation the conditions refer to
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are the Applied and Degraded conditions
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// KaputNotConfigFromUnstructured converts a dynamic client object to a KaputNotConfig
+func KaputNotConfigFromUnstructured(obj map[string]interface{}) (*KaputNotConfig, error) {
+	config := &KaputNotConfig{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// KaputNotConfigStatusToUnstructured converts a status to its dynamic client representation
+func KaputNotConfigStatusToUnstructured(status *KaputNotConfigStatus) (map[string]interface{}, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+}
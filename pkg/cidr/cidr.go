@@ -0,0 +1,73 @@
+// Package cidr validates, normalizes and compares CIDR strings (IPv4 and IPv6)
+// Netmaker and Kubernetes may spell the same range differently (host bits set, uppercase or uncompressed IPv6),
+// so ranges must be compared with Equal rather than string comparison
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Parse parses a CIDR and returns its canonical prefix (host bits cleared)
+func Parse(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", s, err)
+	}
+	return prefix.Masked(), nil
+}
+
+// Validate checks that s is a valid CIDR
+func Validate(s string) error {
+	_, err := Parse(s)
+	return err
+}
+
+// Normalize returns the canonical spelling of a CIDR: host bits cleared, lowercase, compressed IPv6
+// e.g. 10.0.1.1/24 becomes 10.0.1.0/24 and FD00:0:0::1/64 becomes fd00::/64
+func Normalize(s string) (string, error) {
+	prefix, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return prefix.String(), nil
+}
+
+// NormalizeOrKeep returns the canonical spelling of a CIDR, or s unchanged if it is not a valid CIDR
+func NormalizeOrKeep(s string) string {
+	if normalized, err := Normalize(s); err == nil {
+		return normalized
+	}
+	return s
+}
+
+// Equal reports whether two CIDRs denote the same range
+// Invalid CIDRs are only equal to the identical string
+func Equal(a, b string) bool {
+	if a == b {
+		return true
+	}
+	prefixA, errA := Parse(a)
+	prefixB, errB := Parse(b)
+	return errA == nil && errB == nil && prefixA == prefixB
+}
+
+// IsIPv4 reports whether s is a valid IPv4 CIDR
+func IsIPv4(s string) bool {
+	prefix, err := Parse(s)
+	return err == nil && prefix.Addr().Is4()
+}
+
+// IsIPv6 reports whether s is a valid IPv6 CIDR
+func IsIPv6(s string) bool {
+	prefix, err := Parse(s)
+	return err == nil && prefix.Addr().Is6()
+}
+
+// Overlaps reports whether two CIDRs share any address
+// Invalid CIDRs never overlap
+func Overlaps(a, b string) bool {
+	prefixA, errA := Parse(a)
+	prefixB, errB := Parse(b)
+	return errA == nil && errB == nil && prefixA.Overlaps(prefixB)
+}
@@ -0,0 +1,272 @@
+package clusterconfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+const (
+	// KubeadmConfigName is the ConfigMap holding kubeadm's ClusterConfiguration
+	KubeadmConfigName = "kubeadm-config"
+	// KubeProxyConfigName is the ConfigMap holding kube-proxy's KubeProxyConfiguration
+	KubeProxyConfigName = "kube-proxy"
+
+	// kubeadmConfigKey is the data key of the ClusterConfiguration in kubeadm-config
+	kubeadmConfigKey = "ClusterConfiguration"
+	// kubeProxyConfigKey is the data key of the KubeProxyConfiguration in kube-proxy
+	kubeProxyConfigKey = "config.conf"
+)
+
+// Networking holds the cluster-wide network settings
+type Networking struct {
+	PodSubnets     []string `json:"podSubnets,omitempty"`
+	ServiceSubnets []string `json:"serviceSubnets,omitempty"`
+	Source         string   `json:"source,omitempty"` // ConfigMap the settings were read from
+}
+
+// Options contains configuration for the watcher
+type Options struct {
+	// KubeClient is the Kubernetes client
+	KubeClient kubernetes.Interface
+
+	// Namespace holds the kubeadm-config and kube-proxy ConfigMaps
+	// Default: kube-system
+	Namespace string
+
+	// ResyncPeriod is how often the informer resyncs
+	// Default: 10 minutes
+	ResyncPeriod time.Duration
+
+	// OnChange is called with the new settings whenever they change (optional)
+	OnChange func(Networking)
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.KubeClient == nil {
+		return fmt.Errorf("KubeClient is required")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.Namespace == "" {
+		o.Namespace = "kube-system"
+	}
+	if o.ResyncPeriod == 0 {
+		o.ResyncPeriod = 10 * time.Minute
+	}
+}
+
+// Watcher tracks the cluster pod and service subnets from kubeadm-config (preferred) or kube-proxy
+// Managed clusters usually have neither ConfigMap - the settings then stay empty
+type Watcher struct {
+	options *Options
+
+	informer     cache.SharedIndexInformer
+	registration cache.ResourceEventHandlerRegistration
+
+	mu      sync.RWMutex
+	current Networking
+}
+
+// New creates a new watcher
+// Returns error for validation failures, never panics
+func New(opts *Options) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	w := &Watcher{
+		options:  opts,
+		informer: coreinformers.NewConfigMapInformer(opts.KubeClient, opts.Namespace, opts.ResyncPeriod, cache.Indexers{}),
+	}
+
+	handler := func(interface{}) { w.refresh() }
+	registration, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    handler,
+		UpdateFunc: func(_, newObj interface{}) { handler(newObj) },
+		DeleteFunc: handler,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add event handler: %w", err)
+	}
+	w.registration = registration
+
+	return w, nil
+}
+
+// Run starts the informer and blocks until the context is canceled
+func (w *Watcher) Run(ctx context.Context) {
+	defer runtime.HandleCrash()
+	w.informer.Run(ctx.Done())
+}
+
+// WaitForSync blocks until the initial ConfigMaps have been processed (OnChange called) or ctx is canceled
+// Returns false if ctx was canceled first
+func (w *Watcher) WaitForSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), w.registration.HasSynced)
+}
+
+// Networking returns the current cluster network settings
+func (w *Watcher) Networking() Networking {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// refresh re-reads the settings from the informer cache and notifies OnChange if they changed
+func (w *Watcher) refresh() {
+	networking := w.read()
+
+	w.mu.Lock()
+	changed := !networkingEqual(w.current, networking)
+	w.current = networking
+	w.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	log.Printf("Cluster networking changed: pod subnets %v, service subnets %v (source: %s)",
+		networking.PodSubnets, networking.ServiceSubnets, valueOrNone(networking.Source))
+	updateMetrics(networking)
+
+	if w.options.OnChange != nil {
+		w.options.OnChange(networking)
+	}
+}
+
+// read extracts the settings from kubeadm-config, falling back to kube-proxy for the pod subnets
+func (w *Watcher) read() Networking {
+	if cm := w.configMap(KubeadmConfigName); cm != nil {
+		networking, err := parseKubeadmConfig(cm.Data[kubeadmConfigKey])
+		if err != nil {
+			runtime.HandleError(fmt.Errorf("failed to parse %s/%s: %w", w.options.Namespace, KubeadmConfigName, err))
+		} else if len(networking.PodSubnets) > 0 || len(networking.ServiceSubnets) > 0 {
+			networking.Source = KubeadmConfigName
+			return networking
+		}
+	}
+
+	if cm := w.configMap(KubeProxyConfigName); cm != nil {
+		networking, err := parseKubeProxyConfig(cm.Data[kubeProxyConfigKey])
+		if err != nil {
+			runtime.HandleError(fmt.Errorf("failed to parse %s/%s: %w", w.options.Namespace, KubeProxyConfigName, err))
+		} else if len(networking.PodSubnets) > 0 {
+			networking.Source = KubeProxyConfigName
+			return networking
+		}
+	}
+
+	return Networking{}
+}
+
+// configMap returns a ConfigMap from the informer cache, or nil if it doesn't exist
+func (w *Watcher) configMap(name string) *corev1.ConfigMap {
+	obj, exists, err := w.informer.GetIndexer().GetByKey(w.options.Namespace + "/" + name)
+	if err != nil || !exists {
+		return nil
+	}
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return nil
+	}
+	return cm
+}
+
+// parseKubeadmConfig extracts networking.podSubnet and networking.serviceSubnet from a ClusterConfiguration
+func parseKubeadmConfig(data string) (Networking, error) {
+	var config struct {
+		Networking struct {
+			PodSubnet     string `json:"podSubnet"`
+			ServiceSubnet string `json:"serviceSubnet"`
+		} `json:"networking"`
+	}
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		return Networking{}, err
+	}
+
+	return Networking{
+		PodSubnets:     splitList(config.Networking.PodSubnet),
+		ServiceSubnets: splitList(config.Networking.ServiceSubnet),
+	}, nil
+}
+
+// parseKubeProxyConfig extracts clusterCIDR from a KubeProxyConfiguration
+func parseKubeProxyConfig(data string) (Networking, error) {
+	var config struct {
+		ClusterCIDR string `json:"clusterCIDR"`
+	}
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		return Networking{}, err
+	}
+
+	return Networking{
+		PodSubnets: splitList(config.ClusterCIDR),
+	}, nil
+}
+
+// updateMetrics replaces the exported cluster network series
+func updateMetrics(networking Networking) {
+	metrics.ClusterNetworkInfo.Reset()
+	for _, cidr := range networking.PodSubnets {
+		metrics.ClusterNetworkInfo.WithLabelValues("pod", cidr, networking.Source).Set(1)
+	}
+	for _, cidr := range networking.ServiceSubnets {
+		metrics.ClusterNetworkInfo.WithLabelValues("service", cidr, networking.Source).Set(1)
+	}
+}
+
+// networkingEqual checks if two settings are equal
+func networkingEqual(a, b Networking) bool {
+	return a.Source == b.Source &&
+		strings.Join(a.PodSubnets, ",") == strings.Join(b.PodSubnets, ",") &&
+		strings.Join(a.ServiceSubnets, ",") == strings.Join(b.ServiceSubnets, ",")
+}
+
+// splitList splits a comma-separated list, trimming whitespace and dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// valueOrNone returns "none" for empty strings (for log output)
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// Permissions returns the RBAC rules the watcher's ConfigMap informer needs
+func (o *Options) Permissions() []rbac.Permission {
+	namespace := o.Namespace
+	if namespace == "" {
+		namespace = "kube-system"
+	}
+	return []rbac.Permission{
+		{Namespace: namespace, Rule: rbac.Rule("", "configmaps", "list", "watch")},
+	}
+}
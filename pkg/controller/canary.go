@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// canaryFailedReason is the reason of the Node event emitted when the canary node fails
+const canaryFailedReason = "CanaryFailed"
+
+// runCanary reconciles CanaryNode and verifies its egress rules by reading them back from Netmaker
+// Returns nil if no canary is configured; any error means the rollout must not proceed
+func (c *Controller) runCanary(ctx context.Context) error {
+	name := c.options.CanaryNode
+	if name == "" {
+		return nil
+	}
+
+	obj, exists, err := c.nodeInformer.GetIndexer().GetByKey(name)
+	if err != nil {
+		return fmt.Errorf("canary node %s: failed to get node from cache: %w", name, err)
+	}
+	if !exists {
+		return fmt.Errorf("canary node %s not found", name)
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return fmt.Errorf("canary node %s: expected Node but got %T", name, obj)
+	}
+	if !c.isSupportedNode(node) || !c.isPublisherNode(node) {
+		return fmt.Errorf("canary node %s is not reconciled (unsupported platform or not a publisher)", name)
+	}
+
+	topology, err := c.topology(node)
+	if err != nil {
+		return fmt.Errorf("canary node %s: failed to compute topology: %w", name, err)
+	}
+
+	ctx, requestID := netmaker.EnsureRequestID(ctx)
+	log.Printf("Reconciling canary node %s before all other nodes (request %s)", name, requestID)
+	result, err := c.options.Reconciler.ReconcileNode(ctx, node, topology)
+	c.reportResult(node, result, false, err)
+	if err != nil {
+		return c.canaryFailed(node, fmt.Errorf("reconcile failed (request %s): %w", requestID, err))
+	}
+	if err := c.options.Reconciler.VerifyNode(ctx, node, topology); err != nil {
+		return c.canaryFailed(node, fmt.Errorf("verification failed (request %s): %w", requestID, err))
+	}
+
+	log.Printf("Canary node %s verified - reconciling all nodes", name)
+	return nil
+}
+
+// canaryFailed reports a canary failure (error log and Node event) and returns it wrapped for Run
+func (c *Controller) canaryFailed(node *corev1.Node, err error) error {
+	log.Printf("ERROR: canary node %s failed, not reconciling any other node: %v", node.Name, err)
+	c.recorder.Eventf(node, corev1.EventTypeWarning, canaryFailedReason, "Canary reconcile failed: %v", err)
+	return fmt.Errorf("canary node %s: %w", node.Name, err)
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+)
+
+// Mass node churn (e.g. a cluster upgrade replacing every node) turns each add and delete event into its own
+// reconcile, plus a fan-out over all nodes whenever a gateway or the summarized pod CIDRs change - a thundering
+// herd of Netmaker listings and create/delete pairs. Once ChurnThreshold add/delete events arrive within
+// ChurnWindow, the controller switches to storm mode:
+//   - node adds and fan-outs are not enqueued; all nodes are resynced in bulk every ChurnWindow instead
+//     (one Netmaker snapshot per cycle, see resyncAllNodes)
+//   - per-event deletes are suppressed; the deleted nodes are handed to the delete lane once the storm is over,
+//     where a node that came back is reconciled instead
+//
+// The storm is over once a whole ChurnWindow passes with fewer than ChurnThreshold events
+
+// churnDetector counts node add/delete events in a sliding window and tracks storm mode
+type churnDetector struct {
+	mu sync.Mutex
+
+	threshold int
+	window    time.Duration
+
+	events          []time.Time     // Add/delete events within the window, oldest first
+	active          bool            // Storm mode
+	resyncPending   bool            // Suppressed adds or fan-outs wait for the next bulk resync
+	deferredDeletes map[string]bool // Deleted nodes whose egress rules are deleted after the storm
+}
+
+// newChurnDetector creates a detector; a threshold of 0 disables storm mode
+func newChurnDetector(threshold int, window time.Duration) *churnDetector {
+	return &churnDetector{threshold: threshold, window: window, deferredDeletes: make(map[string]bool)}
+}
+
+// record notes a node add or delete event and reports whether storm mode is active
+func (d *churnDetector) record(now time.Time) bool {
+	if d.threshold <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prune(now)
+	d.events = append(d.events, now)
+	if !d.active && len(d.events) >= d.threshold {
+		d.active = true
+		metrics.ChurnStormActive.Set(1)
+		log.Printf("WARNING: %d node add/delete events within %s, switching to bulk reconciliation until the churn settles",
+			len(d.events), d.window)
+	}
+	return d.active
+}
+
+// prune drops events older than the window; the caller holds mu
+func (d *churnDetector) prune(now time.Time) {
+	i := 0
+	for i < len(d.events) && now.Sub(d.events[i]) > d.window {
+		i++
+	}
+	d.events = d.events[i:]
+}
+
+// suppress reports whether work for an event should be left to the next bulk resync (storm mode active)
+func (d *churnDetector) suppress(event string) bool {
+	if d.threshold <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.active {
+		return false
+	}
+	d.resyncPending = true
+	metrics.ChurnSuppressedEvents.WithLabelValues(event).Inc()
+	return true
+}
+
+// deferDelete remembers a deleted node while storm mode is active; returns false (not deferred) otherwise
+func (d *churnDetector) deferDelete(name string) bool {
+	if d.threshold <= 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.active {
+		return false
+	}
+	d.deferredDeletes[name] = true
+	metrics.ChurnSuppressedEvents.WithLabelValues("delete").Inc()
+	return true
+}
+
+// tick is called every window while leading
+// Returns whether a bulk resync is due, and whether the storm just ended with its deferred deletes (sorted)
+func (d *churnDetector) tick(now time.Time) (resync, ended bool, deletes []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.active {
+		return false, false, nil
+	}
+
+	d.prune(now)
+	resync = d.resyncPending
+	d.resyncPending = false
+	if len(d.events) >= d.threshold {
+		return resync, false, nil
+	}
+
+	// Storm is over: resync once more to settle, then release the deferred deletes
+	d.active = false
+	metrics.ChurnStormActive.Set(0)
+	for name := range d.deferredDeletes {
+		deletes = append(deletes, name)
+	}
+	sort.Strings(deletes)
+	d.deferredDeletes = make(map[string]bool)
+	return true, true, deletes
+}
+
+// runChurnControl drives storm mode while leading: bulk resyncs during a storm, deferred deletes after it
+func (c *Controller) runChurnControl(ctx context.Context) {
+	ticker := time.NewTicker(c.options.ChurnWindow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			resync, ended, deletes := c.churn.tick(now)
+			if resync {
+				c.resyncAllNodes(ctx)
+			}
+			if ended {
+				log.Printf("Node churn settled, deleting the egress rules of %d node(s) deleted during the storm", len(deletes))
+			}
+			for _, name := range deletes {
+				c.deleteQueue.Add(name) // Verified with a live GET like any delete
+			}
+		}
+	}
+}
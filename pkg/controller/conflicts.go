@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+const (
+	// identityConflictThreshold conflicting writes within identityConflictWindow flag a duplicate cluster identity
+	// A few are expected after a failover, when the new leader's baseline predates the old leader's last writes
+	identityConflictThreshold = 5
+	identityConflictWindow    = 10 * time.Minute
+)
+
+// identityConflict tracks egress rule writes of another kaput-not using our cluster identity
+type identityConflict struct {
+	mu     sync.Mutex
+	writes []time.Time // Conflicting writes within identityConflictWindow
+
+	// detected stays set until restart: the misconfiguration needs fixing, which restarts the pods anyway
+	detected atomic.Bool
+}
+
+// CheckIdentityConflict inspects an out-of-band change (see netmaker.ChangeDetector) for another kaput-not writing
+// egress rules as our cluster identity, i.e. two clusters sharing a cluster name and instance ID
+// Such clusters delete each other's rules as orphans and recreate their own in an endless loop; once
+// identityConflictThreshold conflicting writes are seen within identityConflictWindow, an error is logged, the
+// kaput_not_cluster_identity_conflict gauge is set and the orphan cleanup pauses until restart
+// Only the leader checks - observers see the leader's own writes as out-of-band
+func (c *Controller) CheckIdentityConflict(change netmaker.ExternalChange) {
+	if !c.IsLeading() || !reconciler.ConflictingWrite(change, c.options.ClusterName, c.options.InstanceID) {
+		return
+	}
+	metrics.IdentityConflictWrites.WithLabelValues(change.Network).Inc()
+
+	conflict := &c.identityConflict
+	conflict.mu.Lock()
+	now := time.Now()
+	writes := conflict.writes[:0]
+	for _, at := range conflict.writes {
+		if now.Sub(at) < identityConflictWindow {
+			writes = append(writes, at)
+		}
+	}
+	conflict.writes = append(writes, now)
+	count := len(conflict.writes)
+	conflict.mu.Unlock()
+
+	if count < identityConflictThreshold || conflict.detected.Swap(true) {
+		return
+	}
+	metrics.IdentityConflict.Set(1)
+	log.Printf("ERROR: another kaput-not writes egress rules as cluster %q (instance %q): %d rules created or rewritten "+
+		"outside this controller within %s, last in network %s. Two clusters probably share K8S_CLUSTER_NAME "+
+		"(and INSTANCE_ID) - give each a unique name. Orphan cleanup is paused until restart so the clusters stop "+
+		"deleting each other's rules", c.options.ClusterName, c.options.InstanceID, count, identityConflictWindow, change.Network)
+}
+
+// identityConflictDetected reports whether another kaput-not was found writing as our cluster identity
+func (c *Controller) identityConflictDetected() bool {
+	return c.identityConflict.detected.Load()
+}
@@ -2,23 +2,112 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
-	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
 )
 
 // Controller watches Kubernetes Node resources and synchronizes pod CIDRs to Netmaker
 type Controller struct {
 	options *Options
 
-	nodeInformer cache.SharedIndexInformer
+	// nodeInformer is replaced by a fresh informer after watch failure storms (see watchhealth.go)
+	nodeInformer *restartableInformer
 	workqueue    workqueue.TypedRateLimitingInterface[string]
+
+	// deleteQueue holds names of deleted nodes, processed after DeletionDelay
+	deleteQueue workqueue.TypedRateLimitingInterface[string]
+
+	// priorityQueue is the fast lane for node keys that must not wait behind bulk work (see priority.go)
+	priorityQueue workqueue.TypedRateLimitingInterface[string]
+	nodeLocks     *keyLocks
+
+	// gatewaySelector matches HA gateway nodes (nil when disabled)
+	gatewaySelector labels.Selector
+
+	// settings are the options changeable at runtime (see ApplySettings)
+	settings atomic.Pointer[settings]
+
+	// ruleInformer and ruleQueue track ClusterEgressRules (nil when ManageEgressRules is off)
+	ruleInformer cache.SharedIndexInformer
+	ruleQueue    workqueue.TypedRateLimitingInterface[string]
+
+	// poolQueue holds node pool names (nil when PoolLabel is unset, see pools.go)
+	poolQueue workqueue.TypedRateLimitingInterface[string]
+
+	// extClientSync signals a pending external client sync (buffered, see triggerExtClientSync)
+	extClientSync chan struct{}
+
+	// resyncRequests signals a pending full resync (buffered, see TriggerResync)
+	resyncRequests chan struct{}
+
+	// clusterNetworkCIDRs are cluster-level CIDRs published by HA gateways (see SetClusterNetworkCIDRs)
+	clusterNetworkCIDRs   []string
+	clusterNetworkCIDRsMu sync.RWMutex
+
+	// retiredExtClientGrants holds deleted nodes whose pod CIDRs must still be withdrawn from external clients
+	retiredExtClientGrants   []reconciler.ExtClientGrant
+	retiredExtClientGrantsMu sync.Mutex
+
+	// missingHosts holds managed nodes without a Netmaker host, as of the last cleanup cycle
+	missingHosts   map[string]*missingHost
+	missingHostsMu sync.Mutex
+
+	// eventBroadcaster and recorder emit Node events (recording to the API starts in Run)
+	eventBroadcaster record.EventBroadcaster
+	recorder         record.EventRecorder
+
+	// synced holds what each node's last successful workqueue reconcile was based on (see alreadySynced)
+	synced   map[string]syncedNode
+	syncedMu sync.Mutex
+
+	// churn detects mass node churn and holds the work deferred meanwhile (see churn.go)
+	churn *churnDetector
+
+	// quarantined holds nodes that failed QuarantineThreshold times in a row, with the time they were quarantined
+	quarantined   map[string]time.Time
+	quarantinedMu sync.Mutex
+
+	// reconcileMu serializes resync cycles (write lock) against workqueue reconciles (read lock),
+	// so a node is never reconciled twice at the same time
+	reconcileMu sync.RWMutex
+
+	// watchHealth tracks node watch failures to detect failure storms (see watchhealth.go)
+	watchHealth watchHealth
+
+	// observeOnce starts the informer and self-metrics exactly once (shared by observer and leader)
+	observeOnce sync.Once
+
+	// leading is true while Run is processing the workqueue (Netmaker mutations allowed)
+	leading atomic.Bool
+
+	// handoff holds the state exchanged with the previous and next leader (see handoff.go)
+	handoff   handoffState
+	handoffMu sync.Mutex
+
+	// identityConflict tracks another kaput-not writing rules as our cluster identity (see conflicts.go)
+	identityConflict identityConflict
 }
 
 // New creates a new controller
@@ -29,24 +118,54 @@ func New(opts *Options) (*Controller, error) {
 	}
 	opts.ApplyDefaults()
 
-	// Create node informer
-	nodeInformerFactory := coreinformers.NewNodeInformer(
-		opts.KubeClient,
-		opts.ResyncPeriod,
-		cache.Indexers{},
-	)
+	var gatewaySelector labels.Selector
+	if opts.GatewaySelector != "" {
+		selector, err := labels.Parse(opts.GatewaySelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gateway selector %q: %w", opts.GatewaySelector, err)
+		}
+		gatewaySelector = selector
+	}
+
+	// Node events are only emitted by the leader (see Run)
+	eventBroadcaster := record.NewBroadcaster()
 
-	// Create workqueue with rate limiting
-	workqueue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]())
+	// Create workqueues with rate limiting, tracked for Dump
+	deleteQueue := newTrackedQueue()
+	priorityQueue := newTrackedQueue()
+	workqueue := newTrackedQueue()
 
 	c := &Controller{
-		options:      opts,
-		nodeInformer: nodeInformerFactory,
-		workqueue:    workqueue,
+		options:          opts,
+		workqueue:        workqueue,
+		deleteQueue:      deleteQueue,
+		priorityQueue:    priorityQueue,
+		nodeLocks:        newKeyLocks(),
+		gatewaySelector:  gatewaySelector,
+		extClientSync:    make(chan struct{}, 1),
+		resyncRequests:   make(chan struct{}, 1),
+		churn:            newChurnDetector(opts.ChurnThreshold, opts.ChurnWindow),
+		quarantined:      make(map[string]time.Time),
+		synced:           make(map[string]syncedNode),
+		missingHosts:     make(map[string]*missingHost),
+		eventBroadcaster: eventBroadcaster,
+		recorder:         eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent}),
+	}
+
+	nodeInformer, err := newRestartableInformer(c.newNodeInformer)
+	if err != nil {
+		return nil, err
+	}
+	c.nodeInformer = nodeInformer
+
+	initial, err := parseSettings(opts.settings())
+	if err != nil {
+		return nil, err
 	}
+	c.settings.Store(initial)
 
 	// Register event handlers
-	if _, err := c.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	if _, err := c.nodeInformer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
 		AddFunc:    c.handleNodeAdd,
 		UpdateFunc: c.handleNodeUpdate,
 		DeleteFunc: c.handleNodeDelete,
@@ -54,39 +173,159 @@ func New(opts *Options) (*Controller, error) {
 		return nil, fmt.Errorf("failed to add event handler: %w", err)
 	}
 
+	if opts.ManageEgressRules {
+		if err := c.setupEgressRules(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.PoolLabel != "" {
+		if err := c.setupPools(); err != nil {
+			return nil, err
+		}
+	}
+
 	return c, nil
 }
 
 // Run starts the controller and blocks until the context is canceled
+// Only the leader (or the single replica without leader election) calls Run
 func (c *Controller) Run(ctx context.Context) error {
 	defer runtime.HandleCrash()
 	defer c.workqueue.ShutDown()
+	defer c.deleteQueue.ShutDown()
+	defer c.priorityQueue.ShutDown()
 
-	// Start the informer
-	go c.nodeInformer.Run(ctx.Done())
+	// Start the informer (no-op if already observing) and wait for cache to sync
+	if err := c.startObserving(ctx); err != nil {
+		return err
+	}
 
-	// Wait for cache to sync
-	if !cache.WaitForCacheSync(ctx.Done(), c.nodeInformer.HasSynced) {
-		return fmt.Errorf("failed to wait for cache sync")
+	c.leading.Store(true)
+	defer c.leading.Store(false)
+
+	// Record Node events to the API while leading
+	c.eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.options.KubeClient.CoreV1().Events("")})
+	defer c.eventBroadcaster.Shutdown()
+
+	// Create missing Netmaker networks before reconciling (no-op unless configured)
+	if err := c.ensureNetworks(ctx); err != nil {
+		return nil // Context canceled while retrying
 	}
 
-	// Perform initial cleanup of orphaned egress rules
-	if err := c.cleanupOrphanedEgresses(ctx); err != nil {
-		runtime.HandleError(fmt.Errorf("initial cleanup failed: %w", err))
+	// Reconcile and verify the canary node before touching anything else (no-op unless configured)
+	if err := c.runCanary(ctx); err != nil {
+		return err
+	}
+
+	// Perform initial cleanup of orphaned egress rules, unless the previous leader ran one recently (see ResumeFrom)
+	cleanupDelay := c.resumeHandoff()
+	if cleanupDelay == 0 {
+		if err := c.cleanupOrphanedEgresses(ctx); err != nil {
+			runtime.HandleError(fmt.Errorf("initial cleanup failed: %w", err))
+		}
 	}
 
 	// Start workers
 	for i := 0; i < c.options.WorkerCount; i++ {
 		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
 	}
+	go wait.UntilWithContext(ctx, c.runDeleteWorker, time.Second)
+	go wait.UntilWithContext(ctx, c.runPriorityWorker, time.Second)
+
+	// Keep external client routes in sync with node annotations
+	if c.options.ManageExtClients {
+		go c.runExtClientSync(ctx)
+	}
+
+	// Manage egress rules declared by ClusterEgressRules
+	if c.options.ManageEgressRules {
+		go c.runEgressRules(ctx)
+	}
+
+	// Share egress rules among the nodes of each node pool
+	if c.options.PoolLabel != "" {
+		go c.runPools(ctx)
+	}
+
+	// Start periodic cleanup goroutine (runs every ResyncPeriod, from when the handed over cleanup is due)
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cleanupDelay):
+		}
+		wait.UntilWithContext(ctx, c.periodicCleanup, c.options.ResyncPeriod)
+	}()
+
+	// Switch to bulk reconciliation during mass node churn
+	if c.options.ChurnThreshold > 0 {
+		go c.runChurnControl(ctx)
+	}
+
+	// Start periodic resync (first run after one ResyncPeriod - the initial sync comes from informer add events)
+	go c.runPeriodicResync(ctx)
+
+	<-ctx.Done()
+	return nil
+}
+
+// RunObserver runs the controller in read-only mode and blocks until the context is canceled
+// Non-leader replicas keep an up-to-date informer cache and serve metrics and debug state,
+// but never process the workqueue or mutate Netmaker
+func (c *Controller) RunObserver(ctx context.Context) error {
+	defer runtime.HandleCrash()
 
-	// Start periodic cleanup goroutine (runs every ResyncPeriod)
-	go wait.UntilWithContext(ctx, c.periodicCleanup, c.options.ResyncPeriod)
+	if err := c.startObserving(ctx); err != nil {
+		return err
+	}
 
 	<-ctx.Done()
 	return nil
 }
 
+// startObserving starts the informer, its watch health checks and self-metrics sampling once, then waits for cache sync
+// Safe to call from both RunObserver and Run - the first caller's context owns the goroutines
+func (c *Controller) startObserving(ctx context.Context) error {
+	c.observeOnce.Do(func() {
+		go c.nodeInformer.Run(ctx)
+		go c.runWatchHealth(ctx)
+		go wait.UntilWithContext(ctx, c.collectSelfMetrics, c.options.SelfMetricsInterval)
+	})
+
+	// Wait for cache to sync
+	if !cache.WaitForCacheSync(ctx.Done(), c.nodeInformer.HasSynced) {
+		return fmt.Errorf("failed to wait for cache sync")
+	}
+
+	return nil
+}
+
+// networkRetryInterval is how often creating missing Netmaker networks is retried before reconciling
+const networkRetryInterval = 30 * time.Second
+
+// ensureNetworks retries EnsureNetworks every networkRetryInterval until it succeeds
+// Returns an error only if ctx is canceled first
+func (c *Controller) ensureNetworks(ctx context.Context) error {
+	return wait.PollUntilContextCancel(ctx, networkRetryInterval, true, func(ctx context.Context) (bool, error) {
+		if err := c.options.Reconciler.EnsureNetworks(ctx); err != nil {
+			runtime.HandleError(fmt.Errorf("failed to ensure Netmaker networks, retrying in %s: %w", networkRetryInterval, err))
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// IsLeading reports whether this replica is actively reconciling (not in read-only observer mode)
+func (c *Controller) IsLeading() bool {
+	return c.leading.Load()
+}
+
+// HasSynced reports whether the node informer cache has completed its initial sync
+func (c *Controller) HasSynced() bool {
+	return c.nodeInformer.HasSynced()
+}
+
 // runWorker processes items from the workqueue
 func (c *Controller) runWorker(ctx context.Context) {
 	for c.processNextWorkItem(ctx) {
@@ -103,17 +342,21 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	defer c.workqueue.Done(key)
 
 	if err := c.syncHandler(ctx, key); err != nil {
-		c.workqueue.AddRateLimited(key)
-		runtime.HandleError(fmt.Errorf("error syncing '%s': %w, requeuing", key, err))
+		c.requeueNode(key, err)
 		return true
 	}
 
 	c.workqueue.Forget(key)
+	c.releaseNode(key)
 	return true
 }
 
 // syncHandler processes a single node
+// Called by the workers of both node lanes; nodeLocks keeps them off the same node
 func (c *Controller) syncHandler(ctx context.Context, key string) error {
+	c.nodeLocks.lock(key)
+	defer c.nodeLocks.unlock(key)
+
 	// Parse the key
 	_, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
@@ -144,23 +387,91 @@ func (c *Controller) syncHandler(ctx context.Context, key string) error {
 		return fmt.Errorf("expected Node but got %T", obj)
 	}
 
+	nodeOS, nodeArch := nodePlatform(node)
+	zone := reconciler.NodeZone(node)
+
+	// Skip nodes on unsupported platforms and nodes not selected as publishers
+	if !c.isSupportedNode(node) || !c.isPublisherNode(node) {
+		metrics.ReconcileTotal.WithLabelValues(nodeOS, nodeArch, zone, "skipped").Inc()
+		return nil
+	}
+
+	topology, err := c.topology(node)
+	if err != nil {
+		metrics.ReconcileTotal.WithLabelValues(nodeOS, nodeArch, zone, "error").Inc()
+		return fmt.Errorf("failed to compute topology for node %s: %w", node.Name, err)
+	}
+
+	c.reconcileMu.RLock()
+	defer c.reconcileMu.RUnlock()
+
+	// Skip nodes whose last reconcile was based on the same inputs
+	syncKey, cacheable := c.syncKey(node, topology)
+	if cacheable && c.alreadySynced(node.Name, syncKey) {
+		metrics.ReconcileTotal.WithLabelValues(nodeOS, nodeArch, zone, "unchanged").Inc()
+		return nil
+	}
+
 	// Reconcile the node
-	if err := c.options.Reconciler.ReconcileNode(ctx, node); err != nil {
-		return fmt.Errorf("failed to reconcile node %s: %w", node.Name, err)
+	ctx, requestID := netmaker.EnsureRequestID(ctx)
+	result, err := c.options.Reconciler.ReconcileNode(ctx, node, topology)
+	c.reportResult(node, result, false, err)
+	recordNetworkResults(zone, result, err)
+	if err != nil {
+		c.forgetSynced(node.Name)
+		metrics.ReconcileTotal.WithLabelValues(nodeOS, nodeArch, zone, "error").Inc()
+		return fmt.Errorf("failed to reconcile node %s (request %s): %w", node.Name, requestID, err)
 	}
 
+	if cacheable {
+		c.recordSynced(node.Name, syncKey)
+	}
+	metrics.ReconcileTotal.WithLabelValues(nodeOS, nodeArch, zone, "success").Inc()
 	return nil
 }
 
 // handleNodeAdd handles node creation events
-func (c *Controller) handleNodeAdd(obj interface{}) {
+// Nodes of the informer's initial list don't count as churn
+func (c *Controller) handleNodeAdd(obj interface{}, isInInitialList bool) {
 	key, err := cache.MetaNamespaceKeyFunc(obj)
 	if err != nil {
 		runtime.HandleError(err)
 		return
 	}
 
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		c.workqueue.Add(key)
+		return
+	}
+
+	if !isInInitialList {
+		c.churn.record(time.Now())
+	}
+
+	// During mass node churn, new nodes and the fan-outs they cause are left to the bulk resync
+	if c.churn.suppress("add") {
+		if node.Annotations[ExtClientsAnnotation] != "" {
+			c.triggerExtClientSync()
+		}
+		return
+	}
+
 	c.workqueue.Add(key)
+
+	// A new gateway must be attached to every node's egress rules
+	if c.isGatewayNode(node) {
+		c.enqueueAllNodes()
+	}
+
+	// New pod CIDRs may change the summary published by every publisher
+	if c.options.SummarizePodCIDRs && len(c.podCIDRs(node)) > 0 {
+		c.enqueuePublisherNodes()
+	}
+
+	if node.Annotations[ExtClientsAnnotation] != "" {
+		c.triggerExtClientSync()
+	}
 }
 
 // handleNodeUpdate handles node update events
@@ -177,8 +488,43 @@ func (c *Controller) handleNodeUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
-	// Only reconcile if pod CIDRs changed
-	if !podCIDRsChanged(oldNode, newNode) {
+	// Only react to the changes the rules care about (see predicates.go)
+	// Leases are refreshed and drift is corrected by the periodic resync (see resyncAllNodes)
+	if statusOnlyUpdate(oldNode, newNode) {
+		metrics.NodeUpdates.WithLabelValues("status-only").Inc()
+		return
+	}
+	reactions, matched := c.nodeUpdateReactions(oldNode, newNode)
+	if matched == nil {
+		metrics.NodeUpdates.WithLabelValues("ignored").Inc()
+	}
+	for _, rule := range matched {
+		metrics.NodeUpdates.WithLabelValues(rule).Inc()
+	}
+
+	if reactions&reactSyncExtClients != 0 {
+		c.triggerExtClientSync()
+	}
+
+	if c.isScalingDown(newNode) && !c.isScalingDown(oldNode) {
+		log.Printf("Node %s is being scaled down by cluster-autoscaler, turning its egress rules off", newNode.Name)
+	}
+
+	if reactions&reactReconcilePools != 0 {
+		c.enqueueNodePool(oldObj)
+		c.enqueueNodePool(newObj)
+	}
+
+	if reactions&reactReconcileAll != 0 {
+		c.enqueueAllNodes()
+		return
+	}
+
+	if reactions&reactReconcilePublishers != 0 {
+		c.enqueuePublisherNodes()
+	}
+
+	if reactions&reactReconcileNode == 0 {
 		return
 	}
 
@@ -208,21 +554,95 @@ func (c *Controller) handleNodeDelete(obj interface{}) {
 		}
 	}
 
+	c.churn.record(time.Now())
+
+	// A removed gateway must be detached from every node's egress rules
+	if c.isGatewayNode(node) {
+		c.enqueueAllNodes()
+	}
+
+	// Removed pod CIDRs may shrink the summary published by every publisher
+	if c.options.SummarizePodCIDRs && len(c.podCIDRs(node)) > 0 {
+		c.enqueuePublisherNodes()
+	}
+
+	c.retireExtClientGrant(node)
+	c.releaseNode(node.Name)
+	c.forgetSynced(node.Name)
+
+	// Observers never mutate Netmaker - the leader handles this deletion
+	if !c.IsLeading() {
+		return
+	}
+
+	// During mass node churn, deletes wait until the churn settles
+	if c.churn.deferDelete(node.Name) {
+		return
+	}
+
+	// Delete egress rules after DeletionDelay, once a live GET confirms the node is gone
+	c.deleteQueue.AddAfter(node.Name, c.options.DeletionDelay)
+}
+
+// runDeleteWorker processes delayed node deletions
+func (c *Controller) runDeleteWorker(ctx context.Context) {
+	for c.processNextDeletion(ctx) {
+	}
+}
+
+// processNextDeletion processes a single delayed node deletion
+func (c *Controller) processNextDeletion(ctx context.Context) bool {
+	name, shutdown := c.deleteQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.deleteQueue.Done(name)
+
+	if err := c.deleteHandler(ctx, name); err != nil {
+		requeue(c.deleteQueue, name, err)
+		runtime.HandleError(fmt.Errorf("error deleting node '%s': %w, requeuing", name, err))
+		return true
+	}
+
+	c.deleteQueue.Forget(name)
+	return true
+}
+
+// deleteHandler deletes the egress rules of a node after confirming it is gone via a live GET
+// The informer cache is not trusted here: a tombstone or relist artifact must not flap routes
+func (c *Controller) deleteHandler(ctx context.Context, name string) error {
+	_, err := c.options.KubeClient.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		// Node still exists (or was recreated) - reconcile it instead
+		log.Printf("Node %s still exists after delete event, reconciling instead of deleting", name)
+		c.enqueuePriority(name)
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		// Can't tell - never delete on uncertainty, retry later
+		return fmt.Errorf("failed to verify deletion of node %s: %w", name, err)
+	}
+
 	// Delete egress rules for this node
-	ctx := context.Background()
-	if err := c.options.Reconciler.DeleteNode(ctx, node.Name); err != nil {
-		runtime.HandleError(fmt.Errorf("failed to delete egress rules for node %s: %w", node.Name, err))
+	ctx, requestID := netmaker.EnsureRequestID(ctx)
+	if err := c.options.Reconciler.DeleteNode(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete egress rules for node %s (request %s): %w", name, requestID, err)
 	}
+
+	return nil
 }
 
 // podCIDRsChanged checks if pod CIDRs changed between old and new node
-func podCIDRsChanged(oldNode, newNode *corev1.Node) bool {
-	if len(oldNode.Spec.PodCIDRs) != len(newNode.Spec.PodCIDRs) {
+// Always false with PodIPPools: pool CIDRs are not part of the node object (see EnqueueNodes)
+func (c *Controller) podCIDRsChanged(oldNode, newNode *corev1.Node) bool {
+	oldCIDRs, newCIDRs := c.podCIDRs(oldNode), c.podCIDRs(newNode)
+	if len(oldCIDRs) != len(newCIDRs) {
 		return true
 	}
 
-	for i := range oldNode.Spec.PodCIDRs {
-		if oldNode.Spec.PodCIDRs[i] != newNode.Spec.PodCIDRs[i] {
+	for i := range oldCIDRs {
+		if oldCIDRs[i] != newCIDRs[i] {
 			return true
 		}
 	}
@@ -230,6 +650,178 @@ func podCIDRsChanged(oldNode, newNode *corev1.Node) bool {
 	return false
 }
 
+// podCIDRs returns the pod CIDRs a node publishes: its spec.podCIDRs, or the CIDRs allocated to it
+// from the advertised IP pools (never nil then, so the reconciler doesn't fall back to spec.podCIDRs)
+func (c *Controller) podCIDRs(node *corev1.Node) []string {
+	if c.options.PodIPPools == nil {
+		return node.Spec.PodCIDRs
+	}
+	return append([]string{}, c.options.PodIPPools.NodeCIDRs(node.Name)...)
+}
+
+// EnqueueNodes reconciles the named nodes, e.g. after their IP pool CIDRs changed
+// Publishers are re-reconciled as well when summarizing pod CIDRs, and external clients are resynced
+func (c *Controller) EnqueueNodes(names []string) {
+	for _, name := range names {
+		c.workqueue.Add(name)
+	}
+	if c.options.SummarizePodCIDRs {
+		c.enqueuePublisherNodes()
+	}
+	c.triggerExtClientSync()
+}
+
+// nodePlatform returns the operating system and architecture of a node
+// Prefers the well-known kubernetes.io/os and kubernetes.io/arch labels, falls back to node status
+func nodePlatform(node *corev1.Node) (string, string) {
+	nodeOS := node.Labels[corev1.LabelOSStable]
+	if nodeOS == "" {
+		nodeOS = node.Status.NodeInfo.OperatingSystem
+	}
+
+	nodeArch := node.Labels[corev1.LabelArchStable]
+	if nodeArch == "" {
+		nodeArch = node.Status.NodeInfo.Architecture
+	}
+
+	return nodeOS, nodeArch
+}
+
+// isSupportedNode checks if a node runs on a platform we manage egress rules for
+// Windows nodes are skipped unless IncludeWindowsNodes is set
+func (c *Controller) isSupportedNode(node *corev1.Node) bool {
+	nodeOS, _ := nodePlatform(node)
+	if nodeOS == "windows" {
+		return c.settings.Load().IncludeWindowsNodes
+	}
+	return true
+}
+
+// isPublisherNode checks if a node matches PublisherSelector (all nodes match when it is unset)
+// Node pool members never publish rules of their own, their pool's shared rules cover them (see pools.go)
+func (c *Controller) isPublisherNode(node *corev1.Node) bool {
+	if c.poolName(node) != "" {
+		return false
+	}
+	selector := c.settings.Load().publisherSelector
+	if selector == nil {
+		return true
+	}
+	return selector.Matches(labels.Set(node.Labels))
+}
+
+// isGatewayNode checks if a node is an HA gateway (matches GatewaySelector and is supported)
+func (c *Controller) isGatewayNode(node *corev1.Node) bool {
+	if c.gatewaySelector == nil {
+		return false
+	}
+	return c.gatewaySelector.Matches(labels.Set(node.Labels)) && c.isSupportedNode(node)
+}
+
+// gatewayNodes returns the names of all HA gateway nodes from the informer cache, sorted by name
+// Nodes being deleted or scaled down are excluded so their rules fail over to the remaining gateways
+func (c *Controller) gatewayNodes() []string {
+	if c.gatewaySelector == nil {
+		return nil
+	}
+
+	var names []string
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || node.DeletionTimestamp != nil || c.isScalingDown(node) || !c.isGatewayNode(node) {
+			continue
+		}
+		names = append(names, node.Name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// enqueueAllNodes adds every node in the informer cache to the workqueue
+// Used when the HA gateway set changes, since it affects every egress rule
+// Left to the bulk resync during mass node churn
+func (c *Controller) enqueueAllNodes() {
+	if c.churn.suppress("fanout") {
+		return
+	}
+	for _, key := range c.nodeInformer.GetIndexer().ListKeys() {
+		c.workqueue.Add(key)
+	}
+}
+
+// enqueueGatewayNodes adds every HA gateway node in the informer cache to the workqueue
+// Used when the cluster network CIDRs change
+func (c *Controller) enqueueGatewayNodes() {
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !c.isGatewayNode(node) {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(node); err == nil {
+			c.workqueue.Add(key)
+		}
+	}
+}
+
+// enqueuePublisherNodes adds every publisher node in the informer cache to the workqueue
+// Used when the summarized pod CIDRs may have changed
+// Left to the bulk resync during mass node churn
+func (c *Controller) enqueuePublisherNodes() {
+	if c.churn.suppress("fanout") {
+		return
+	}
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !c.isPublisherNode(node) {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(node); err == nil {
+			c.workqueue.Add(key)
+		}
+	}
+}
+
+// topology computes the reconciler inputs for a node from the informer cache
+func (c *Controller) topology(node *corev1.Node) (reconciler.Topology, error) {
+	topology := reconciler.Topology{
+		GatewayNodes: c.gatewayNodes(),
+		Gated:        c.isGated(node),
+		Held:         isHeld(node),
+	}
+
+	if c.options.PodIPPools != nil {
+		topology.PodCIDRs = c.podCIDRs(node)
+	}
+
+	if c.isGatewayNode(node) {
+		topology.ClusterNetworkCIDRs = c.ClusterNetworkCIDRs()
+	}
+
+	if c.options.SummarizePodCIDRs {
+		summary, err := reconciler.SummarizeCIDRs(c.clusterPodCIDRs())
+		if err != nil {
+			return reconciler.Topology{}, err
+		}
+		topology.SummarizedCIDRs = summary
+	}
+
+	return topology, nil
+}
+
+// clusterPodCIDRs returns the pod CIDRs of all supported nodes in the informer cache
+func (c *Controller) clusterPodCIDRs() []string {
+	var cidrs []string
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !c.isSupportedNode(node) {
+			continue
+		}
+		cidrs = append(cidrs, c.podCIDRs(node)...)
+	}
+	return cidrs
+}
+
 // cleanupOrphanedEgresses builds a map of valid Netmaker node IDs from K8s nodes
 // and calls the reconciler to clean up orphaned egress rules
 //
@@ -245,14 +837,45 @@ func podCIDRsChanged(oldNode, newNode *corev1.Node) bool {
 // Time complexity: O(n + m) where n = K8s nodes, m = Netmaker hosts
 // Memory complexity: O(m) for hostname map + O(total node IDs) for validNodeIDs
 func (c *Controller) cleanupOrphanedEgresses(ctx context.Context) error {
+	if c.identityConflictDetected() {
+		log.Printf("Skipping orphan cleanup: another kaput-not writes egress rules as this cluster (duplicate cluster name?)")
+		return nil
+	}
+
+	validNodeIDs, managedNodes, missingHosts, err := c.managedNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Warn about nodes that stay without a Netmaker host (e.g. netclient never enrolled)
+	c.trackMissingHosts(missingHosts)
+
+	// Delete recorded rules of nodes that are gone (no-op without a state store)
+	if err := c.options.Reconciler.CleanupRecordedEgresses(ctx, managedNodes); err != nil {
+		runtime.HandleError(err)
+	}
+
+	// Call reconciler to clean up orphaned egress rules
+	if err := c.options.Reconciler.CleanupOrphanedEgresses(ctx, validNodeIDs); err != nil {
+		return err
+	}
+	c.recordCleanup()
+	return nil
+}
+
+// managedNodes returns the Netmaker node IDs that should have egress rules, the names of the
+// K8s nodes whose egress rules we manage, and the managed nodes without a Netmaker host
+func (c *Controller) managedNodes(ctx context.Context) (map[string]bool, map[string]bool, []*corev1.Node, error) {
 	// Build set of valid Netmaker node IDs from all K8s nodes
 	validNodeIDs := make(map[string]bool)
+	// Names of K8s nodes whose egress rules we manage (for the state store cleanup)
+	managedNodes := make(map[string]bool)
 
 	// List all Netmaker hosts once and build hostname->nodeIDs map for O(1) lookups
 	// This is O(n + m) instead of O(n × m) if we called GetNodeIDsByHostname per node
 	hosts, err := c.options.NetmakerClient.ListHosts(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list Netmaker hosts: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to list Netmaker hosts: %w", err)
 	}
 
 	hostnameToNodeIDs := make(map[string][]string, len(hosts))
@@ -260,40 +883,218 @@ func (c *Controller) cleanupOrphanedEgresses(ctx context.Context) error {
 		hostnameToNodeIDs[host.Name] = host.Nodes
 	}
 
-	// List all K8s nodes from informer cache (thread-safe read)
-	nodeList := c.nodeInformer.GetIndexer().List()
-	for _, obj := range nodeList {
-		node, ok := obj.(*corev1.Node)
-		if !ok {
-			runtime.HandleError(fmt.Errorf("expected Node but got %T", obj))
-			continue
+	var missingHosts []*corev1.Node
+
+	// Walk the candidate K8s nodes from the informer cache (thread-safe read)
+	err = c.forEachCandidateNode(ctx, func(node *corev1.Node) error {
+		// Skip nodes without pod CIDRs (not ready yet), unless they publish cluster-wide CIDRs
+		if len(c.podCIDRs(node)) == 0 && !c.publishesClusterCIDRs(node) {
+			return nil
 		}
 
-		// Skip nodes without pod CIDRs (not ready yet)
-		if len(node.Spec.PodCIDRs) == 0 {
-			continue
+		// Skip nodes on unsupported platforms and non-publishers (their egress rules are not managed)
+		if !c.isSupportedNode(node) || !c.isPublisherNode(node) {
+			return nil
 		}
 
+		managedNodes[node.Name] = true
+
 		// O(1) map lookup instead of O(m) linear search
 		nodeIDs, exists := hostnameToNodeIDs[node.Name]
 		if !exists {
-			// Host doesn't exist in Netmaker - skip silently
-			continue
+			// A host renamed in Netmaker keeps its ID - its rules are still the node's
+			renamed, err := c.options.Reconciler.RenamedHostNodeIDs(ctx, node.Name)
+			if err != nil {
+				return fmt.Errorf("failed to look up renamed host of node %s: %w", node.Name, err)
+			}
+			if len(renamed) == 0 && c.options.MatchHostsByAddress {
+				// Not reconciled since the last restart - the reconciler matches it by address once it is
+				if host, ok := reconciler.MatchHostByAddress(hosts, reconciler.NodeAddresses(node)); ok {
+					renamed = host.Nodes
+				}
+			}
+			if len(renamed) == 0 {
+				// Host doesn't exist in Netmaker (yet) - skip, but report it if it stays that way
+				missingHosts = append(missingHosts, node)
+				return nil
+			}
+			nodeIDs = renamed
 		}
 
 		// Add all node IDs to the valid set
 		for _, nodeID := range nodeIDs {
 			validNodeIDs[nodeID] = true
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	// Call reconciler to clean up orphaned egress rules
-	return c.options.Reconciler.CleanupOrphanedEgresses(ctx, validNodeIDs)
+	return validNodeIDs, managedNodes, missingHosts, nil
 }
 
 // periodicCleanup is a wrapper for periodic cleanup execution
+// Also runs the janitor for egress rules whose lease expired (no-op when leases are disabled)
 func (c *Controller) periodicCleanup(ctx context.Context) {
 	if err := c.cleanupOrphanedEgresses(ctx); err != nil {
 		runtime.HandleError(fmt.Errorf("periodic cleanup failed: %w", err))
 	}
+
+	if err := c.options.Reconciler.CleanupExpiredEgresses(ctx); err != nil {
+		runtime.HandleError(fmt.Errorf("expired egress cleanup failed: %w", err))
+	}
+}
+
+// SetClusterNetworkCIDRs sets the cluster-level CIDRs (e.g. the service subnet) published by HA gateways
+// Gateways are re-reconciled when the CIDRs change; empty stops publishing them
+func (c *Controller) SetClusterNetworkCIDRs(cidrs []string) {
+	c.clusterNetworkCIDRsMu.Lock()
+	changed := strings.Join(c.clusterNetworkCIDRs, ",") != strings.Join(cidrs, ",")
+	c.clusterNetworkCIDRs = append([]string(nil), cidrs...)
+	c.clusterNetworkCIDRsMu.Unlock()
+
+	if changed {
+		c.enqueueGatewayNodes()
+	}
+}
+
+// ClusterNetworkCIDRs returns the cluster-level CIDRs published by HA gateways
+func (c *Controller) ClusterNetworkCIDRs() []string {
+	c.clusterNetworkCIDRsMu.RLock()
+	defer c.clusterNetworkCIDRsMu.RUnlock()
+	return c.clusterNetworkCIDRs
+}
+
+// publishesClusterCIDRs checks if a node publishes cluster-wide CIDRs, so it needs egress rules
+// even without pod CIDRs of its own
+func (c *Controller) publishesClusterCIDRs(node *corev1.Node) bool {
+	return c.options.Reconciler.AggregatesClusterCIDRs() || c.options.SummarizePodCIDRs ||
+		(c.isGatewayNode(node) && len(c.ClusterNetworkCIDRs()) > 0)
+}
+
+// runPeriodicResync runs resyncAllNodes every ResyncPeriod, and on TriggerResync, until ctx is canceled
+func (c *Controller) runPeriodicResync(ctx context.Context) {
+	ticker := time.NewTicker(c.options.ResyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.resyncAllNodes(ctx)
+		case <-c.resyncRequests:
+			log.Println("Resyncing all nodes on request")
+			c.resyncAllNodes(ctx)
+		}
+	}
+}
+
+// TriggerResync requests a resync of all nodes against one Netmaker snapshot, without waiting for it
+// Only the leader resyncs; a request made while one is pending is merged into it
+func (c *Controller) TriggerResync() {
+	select {
+	case c.resyncRequests <- struct{}{}:
+	default:
+		// A resync is already pending
+	}
+}
+
+// resyncAllNodes reconciles every publisher node against one Netmaker snapshot
+// Refreshes egress leases and corrects drift; nodes that fail are requeued individually (rate limited)
+func (c *Controller) resyncAllNodes(ctx context.Context) {
+	c.reconcileMu.Lock()
+	defer c.reconcileMu.Unlock()
+
+	requests, topologyErrors := c.nodeRequests()
+	for name, err := range topologyErrors {
+		runtime.HandleError(err)
+		c.workqueue.AddRateLimited(name)
+	}
+
+	results, nodeErrors, err := c.options.Reconciler.ResyncNodes(ctx, requests)
+	if err != nil {
+		// Fall back to reconciling each node individually
+		runtime.HandleError(fmt.Errorf("resync failed, requeuing all nodes: %w", err))
+		for _, req := range requests {
+			requeue(c.workqueue, req.Node.Name, err)
+		}
+		return
+	}
+
+	for _, req := range requests {
+		nodeOS, nodeArch := nodePlatform(req.Node)
+		zone := reconciler.NodeZone(req.Node)
+		c.reportResult(req.Node, results[req.Node.Name], true, nodeErrors[req.Node.Name])
+		recordNetworkResults(zone, results[req.Node.Name], nodeErrors[req.Node.Name])
+		if nodeErr, failed := nodeErrors[req.Node.Name]; failed {
+			metrics.ReconcileTotal.WithLabelValues(nodeOS, nodeArch, zone, "error").Inc()
+			c.requeueNode(req.Node.Name, fmt.Errorf("resync (request %s): %w", results[req.Node.Name].RequestID, nodeErr))
+			continue
+		}
+		metrics.ReconcileTotal.WithLabelValues(nodeOS, nodeArch, zone, "success").Inc()
+		c.releaseNode(req.Node.Name)
+	}
+	c.recordResync()
+	log.Printf("Resynced %d nodes (%d failed)", len(requests), len(nodeErrors))
+}
+
+// nodeRequests returns every publisher node in the informer cache with its topology
+// Nodes whose topology cannot be computed are returned as errors, keyed by node name
+func (c *Controller) nodeRequests() ([]reconciler.NodeRequest, map[string]error) {
+	var requests []reconciler.NodeRequest
+	topologyErrors := make(map[string]error)
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !c.isSupportedNode(node) || !c.isPublisherNode(node) {
+			continue
+		}
+
+		topology, err := c.topology(node)
+		if err != nil {
+			topologyErrors[node.Name] = fmt.Errorf("failed to compute topology for node %s: %w", node.Name, err)
+			continue
+		}
+		requests = append(requests, reconciler.NodeRequest{Node: node, Topology: topology})
+	}
+	return requests, topologyErrors
+}
+
+// Plan computes the egress rules of every publisher node and the Netmaker changes needed to reach them,
+// without mutating anything (see reconciler.Reconciler.PlanNodes)
+// Starts the node informer if it is not running yet; ClusterEgressRules and external clients are not planned
+func (c *Controller) Plan(ctx context.Context) (*reconciler.Plan, error) {
+	if err := c.startObserving(ctx); err != nil {
+		return nil, err
+	}
+
+	requests, topologyErrors := c.nodeRequests()
+	if len(topologyErrors) > 0 {
+		errs := make([]error, 0, len(topologyErrors))
+		for _, err := range topologyErrors {
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	validNodeIDs, _, _, err := c.managedNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.options.Reconciler.PlanNodes(ctx, requests, validNodeIDs)
+}
+
+// requeue adds a failed key back to a queue
+// Rate-limited requests wait for Netmaker's Retry-After instead of the per-item exponential backoff,
+// which would otherwise retry hot while Netmaker is overloaded
+func requeue(queue workqueue.TypedRateLimitingInterface[string], key string, err error) {
+	var rateLimited *netmaker.RateLimitError
+	if errors.As(err, &rateLimited) && rateLimited.RetryAfter > 0 {
+		metrics.RateLimitedRequeues.Inc()
+		queue.AddAfter(key, rateLimited.RetryAfter)
+		return
+	}
+	queue.AddRateLimited(key)
 }
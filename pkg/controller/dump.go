@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// trackedQueue is a rate-limited workqueue that remembers its keys, which client-go's queues cannot list
+// Keys waiting for their rate limit or AddAfter delay count as pending
+type trackedQueue struct {
+	workqueue.TypedRateLimitingInterface[string]
+
+	mu      sync.Mutex
+	pending map[string]time.Time // Added but not handed out yet, by time of the first add
+	active  map[string]time.Time // Handed out by Get and not Done yet, by time of the Get
+}
+
+// newTrackedQueue creates a tracked queue with the default controller rate limiter
+func newTrackedQueue() *trackedQueue {
+	return &trackedQueue{
+		TypedRateLimitingInterface: workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]()),
+		pending:                    make(map[string]time.Time),
+		active:                     make(map[string]time.Time),
+	}
+}
+
+// track records a pending key
+func (q *trackedQueue) track(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[key]; !ok {
+		q.pending[key] = time.Now()
+	}
+}
+
+// Add implements workqueue.TypedInterface
+func (q *trackedQueue) Add(key string) {
+	q.track(key)
+	q.TypedRateLimitingInterface.Add(key)
+}
+
+// AddAfter implements workqueue.TypedDelayingInterface
+func (q *trackedQueue) AddAfter(key string, duration time.Duration) {
+	q.track(key)
+	q.TypedRateLimitingInterface.AddAfter(key, duration)
+}
+
+// AddRateLimited implements workqueue.TypedRateLimitingInterface
+func (q *trackedQueue) AddRateLimited(key string) {
+	q.track(key)
+	q.TypedRateLimitingInterface.AddRateLimited(key)
+}
+
+// Get implements workqueue.TypedInterface
+func (q *trackedQueue) Get() (string, bool) {
+	key, shutdown := q.TypedRateLimitingInterface.Get()
+	if shutdown {
+		return key, shutdown
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, key)
+	q.active[key] = time.Now()
+	return key, shutdown
+}
+
+// Done implements workqueue.TypedInterface
+func (q *trackedQueue) Done(key string) {
+	q.mu.Lock()
+	delete(q.active, key)
+	q.mu.Unlock()
+	q.TypedRateLimitingInterface.Done(key)
+}
+
+// queuedKey is a key in a queue dump
+type queuedKey struct {
+	key      string
+	since    time.Time
+	requeues int
+}
+
+// keys returns the pending and active keys, oldest first
+func (q *trackedQueue) keys() (pending, active []queuedKey) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := func(keys map[string]time.Time) []queuedKey {
+		listed := make([]queuedKey, 0, len(keys))
+		for key, since := range keys {
+			listed = append(listed, queuedKey{key: key, since: since, requeues: q.NumRequeues(key)})
+		}
+		sort.Slice(listed, func(i, j int) bool {
+			return listed[i].since.Before(listed[j].since)
+		})
+		return listed
+	}
+	return list(q.pending), list(q.active)
+}
+
+// Dump writes the keys of every workqueue for debugging stuck reconciliations (see the SIGUSR1 handler)
+// Active keys are being reconciled; their age shows how long a worker has been busy with them
+func (c *Controller) Dump(w io.Writer) {
+	queues := []struct {
+		name  string
+		queue workqueue.TypedRateLimitingInterface[string]
+	}{
+		{"nodes", c.workqueue},
+		{"priority", c.priorityQueue},
+		{"deletes", c.deleteQueue},
+		{"rules", c.ruleQueue},
+		{"pools", c.poolQueue},
+	}
+
+	now := time.Now()
+	fmt.Fprintf(w, "Workqueues (leading=%t, quarantined=%v):\n", c.IsLeading(), c.quarantinedNodes())
+	for _, q := range queues {
+		tracked, ok := q.queue.(*trackedQueue)
+		if !ok {
+			continue // Disabled
+		}
+		pending, active := tracked.keys()
+		fmt.Fprintf(w, "  %s: %d pending, %d active\n", q.name, len(pending), len(active))
+		for _, key := range active {
+			fmt.Fprintf(w, "    active  %s for %s (requeues=%d)\n", key.key, now.Sub(key.since).Round(time.Millisecond), key.requeues)
+		}
+		for _, key := range pending {
+			fmt.Fprintf(w, "    pending %s for %s (requeues=%d)\n", key.key, now.Sub(key.since).Round(time.Millisecond), key.requeues)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bsure-analytics/kaput-not/pkg/apis/v1alpha1"
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// setupEgressRules creates the ClusterEgressRule informer and queue, and re-enqueues all rules
+// whenever nodes come, go or change labels (their node selectors may match differently)
+func (c *Controller) setupEgressRules() error {
+	c.ruleInformer = dynamicinformer.NewFilteredDynamicInformer(
+		c.options.DynamicClient,
+		v1alpha1.ClusterEgressRuleResource,
+		metav1.NamespaceAll,
+		0,
+		cache.Indexers{},
+		func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = instanceSelector(c.options.InstanceID)
+		},
+	).Informer()
+	c.ruleQueue = newTrackedQueue()
+
+	if _, err := c.ruleInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueRule,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Status writes don't bump the generation - only spec changes need a reconcile
+			oldRule, okOld := oldObj.(*unstructured.Unstructured)
+			newRule, okNew := newObj.(*unstructured.Unstructured)
+			if !okOld || !okNew || oldRule.GetGeneration() != newRule.GetGeneration() {
+				c.enqueueRule(newObj)
+			}
+		},
+		DeleteFunc: c.enqueueRule,
+	}); err != nil {
+		return fmt.Errorf("failed to add ClusterEgressRule event handler: %w", err)
+	}
+
+	if _, err := c.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) { c.enqueueAllRules() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, okOld := oldObj.(*corev1.Node)
+			newNode, okNew := newObj.(*corev1.Node)
+			if okOld && okNew && !labels.Equals(oldNode.Labels, newNode.Labels) {
+				c.enqueueAllRules()
+			}
+		},
+		DeleteFunc: func(interface{}) { c.enqueueAllRules() },
+	}); err != nil {
+		return fmt.Errorf("failed to add node event handler for ClusterEgressRules: %w", err)
+	}
+
+	return nil
+}
+
+// instanceSelector returns the label selector of the ClusterEgressRules managed by an instance
+func instanceSelector(instanceID string) string {
+	if instanceID == "" {
+		return "!" + v1alpha1.InstanceLabel
+	}
+	return v1alpha1.InstanceLabel + "=" + instanceID
+}
+
+// enqueueRule adds a ClusterEgressRule to the rule queue (cluster-scoped, so the key is its name)
+func (c *Controller) enqueueRule(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.ruleQueue.Add(key)
+}
+
+// enqueueAllRules adds every known ClusterEgressRule to the rule queue
+func (c *Controller) enqueueAllRules() {
+	for _, key := range c.ruleInformer.GetStore().ListKeys() {
+		c.ruleQueue.Add(key)
+	}
+}
+
+// runEgressRules syncs ClusterEgressRules until ctx is canceled (leader only)
+// Rules of deleted ClusterEgressRules are cleaned up at startup and once per ResyncPeriod, which also
+// covers deletions missed while no leader was running; every rule is re-reconciled on the same period
+func (c *Controller) runEgressRules(ctx context.Context) {
+	defer runtime.HandleCrash()
+	defer c.ruleQueue.ShutDown()
+
+	go c.ruleInformer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.ruleInformer.HasSynced) {
+		return
+	}
+
+	go wait.UntilWithContext(ctx, c.runRuleWorker, time.Second)
+
+	ticker := time.NewTicker(c.options.ResyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := c.cleanupRules(ctx); err != nil {
+			runtime.HandleError(fmt.Errorf("ClusterEgressRule cleanup failed: %w", err))
+		}
+		c.enqueueAllRules()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runRuleWorker processes items from the rule queue
+func (c *Controller) runRuleWorker(ctx context.Context) {
+	for c.processNextRule(ctx) {
+	}
+}
+
+// processNextRule processes a single item from the rule queue
+func (c *Controller) processNextRule(ctx context.Context) bool {
+	key, shutdown := c.ruleQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.ruleQueue.Done(key)
+
+	if err := c.ruleSyncHandler(ctx, key); err != nil {
+		requeue(c.ruleQueue, key, err)
+		runtime.HandleError(fmt.Errorf("error syncing ClusterEgressRule '%s': %w, requeuing", key, err))
+		return true
+	}
+
+	c.ruleQueue.Forget(key)
+	return true
+}
+
+// ruleSyncHandler reconciles a single ClusterEgressRule, or deletes its egress rules if it's gone
+func (c *Controller) ruleSyncHandler(ctx context.Context, name string) error {
+	obj, exists, err := c.ruleInformer.GetIndexer().GetByKey(name)
+	if err != nil {
+		return fmt.Errorf("failed to get ClusterEgressRule from cache: %w", err)
+	}
+
+	if !exists {
+		if err := c.options.Reconciler.DeleteRule(ctx, name); err != nil {
+			metrics.EgressRuleReconcileTotal.WithLabelValues("error").Inc()
+			return fmt.Errorf("failed to delete egress rules of ClusterEgressRule %s: %w", name, err)
+		}
+		log.Printf("Deleted egress rules of ClusterEgressRule %s", name)
+		metrics.EgressRuleReconcileTotal.WithLabelValues("deleted").Inc()
+		return nil
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("expected Unstructured but got %T", obj)
+	}
+	cr, err := v1alpha1.ClusterEgressRuleFromUnstructured(u.Object)
+	if err != nil {
+		return fmt.Errorf("failed to decode ClusterEgressRule %s: %w", name, err)
+	}
+
+	status := v1alpha1.ClusterEgressRuleStatus{
+		ObservedGeneration: cr.Generation,
+		Conditions:         append([]metav1.Condition(nil), cr.Status.Conditions...),
+	}
+
+	rule, err := c.egressRule(cr)
+	if err != nil {
+		// Not retried - the spec has to change first
+		metrics.EgressRuleReconcileTotal.WithLabelValues("error").Inc()
+		setRuleCondition(&status, v1alpha1.ConditionSynced, false, "InvalidSpec", err.Error(), cr.Generation)
+		setRuleCondition(&status, v1alpha1.ConditionDegraded, true, "InvalidSpec", err.Error(), cr.Generation)
+		setRuleCondition(&status, v1alpha1.ConditionProgressing, false, "InvalidSpec", "Waiting for the spec to be fixed", cr.Generation)
+		c.updateRuleStatus(ctx, u, cr, status)
+		runtime.HandleError(fmt.Errorf("invalid ClusterEgressRule %s: %w", name, err))
+		return nil
+	}
+	status.GatewayNodes = rule.GatewayNodes
+
+	// Announce a new generation before the (possibly slow) Netmaker sync
+	if cr.Status.ObservedGeneration != cr.Generation {
+		setRuleCondition(&status, v1alpha1.ConditionProgressing, true, "Reconciling", "Syncing egress rules to Netmaker", cr.Generation)
+		c.updateRuleStatus(ctx, u, cr, status)
+	}
+
+	if err := c.options.Reconciler.ReconcileRule(ctx, rule); err != nil {
+		metrics.EgressRuleReconcileTotal.WithLabelValues("error").Inc()
+		setRuleCondition(&status, v1alpha1.ConditionSynced, false, "SyncFailed", err.Error(), cr.Generation)
+		setRuleCondition(&status, v1alpha1.ConditionDegraded, true, "SyncFailed", err.Error(), cr.Generation)
+		setRuleCondition(&status, v1alpha1.ConditionProgressing, true, "Retrying", "Retrying after a failed sync", cr.Generation)
+		c.updateRuleStatus(ctx, u, cr, status)
+		return fmt.Errorf("failed to reconcile ClusterEgressRule %s: %w", name, err)
+	}
+
+	metrics.EgressRuleReconcileTotal.WithLabelValues("success").Inc()
+	setRuleCondition(&status, v1alpha1.ConditionSynced, true, "Synced", "Egress rules match the spec", cr.Generation)
+	if len(rule.GatewayNodes) == 0 {
+		setRuleCondition(&status, v1alpha1.ConditionDegraded, true, "NoGatewayNodes", "The node selector matches no nodes - the CIDRs are not routed", cr.Generation)
+	} else {
+		setRuleCondition(&status, v1alpha1.ConditionDegraded, false, "GatewaysSelected",
+			fmt.Sprintf("%d gateway node(s) selected", len(rule.GatewayNodes)), cr.Generation)
+	}
+	setRuleCondition(&status, v1alpha1.ConditionProgressing, false, "Synced", "Egress rules match the spec", cr.Generation)
+	c.updateRuleStatus(ctx, u, cr, status)
+	return nil
+}
+
+// setRuleCondition sets a condition in a ClusterEgressRule status
+// The transition time only changes when the condition's status flips
+func setRuleCondition(status *v1alpha1.ClusterEgressRuleStatus, conditionType string, value bool, reason, message string, generation int64) {
+	conditionStatus := metav1.ConditionFalse
+	if value {
+		conditionStatus = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// updateRuleStatus writes a ClusterEgressRule status if it changed
+// Failures are only logged: the next spec change or resync writes the status again,
+// while requeuing would repeat the Netmaker sync just for the status
+func (c *Controller) updateRuleStatus(ctx context.Context, u *unstructured.Unstructured, cr *v1alpha1.ClusterEgressRule, status v1alpha1.ClusterEgressRuleStatus) {
+	if equality.Semantic.DeepEqual(cr.Status, status) {
+		return
+	}
+
+	statusObj, err := v1alpha1.ClusterEgressRuleStatusToUnstructured(&status)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("failed to encode status of ClusterEgressRule %s: %w", cr.Name, err))
+		return
+	}
+
+	updated := u.DeepCopy()
+	updated.Object["status"] = statusObj
+	result, err := c.options.DynamicClient.Resource(v1alpha1.ClusterEgressRuleResource).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		if !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+			runtime.HandleError(fmt.Errorf("failed to update status of ClusterEgressRule %s: %w", cr.Name, err))
+		}
+		return
+	}
+
+	// Later writes in the same sync build on the new resourceVersion
+	u.SetResourceVersion(result.GetResourceVersion())
+	cr.Status = status
+	cr.Status.Conditions = append([]metav1.Condition(nil), status.Conditions...)
+}
+
+// egressRule resolves a ClusterEgressRule to the reconciler input
+// Gateways are the supported nodes matching the node selector, sorted by name (stable metrics)
+func (c *Controller) egressRule(cr *v1alpha1.ClusterEgressRule) (reconciler.EgressRule, error) {
+	for _, ruleCIDR := range cr.Spec.CIDRs {
+		if err := cidr.Validate(ruleCIDR); err != nil {
+			return reconciler.EgressRule{}, err
+		}
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&cr.Spec.NodeSelector)
+	if err != nil {
+		return reconciler.EgressRule{}, fmt.Errorf("invalid node selector: %w", err)
+	}
+
+	var gateways []string
+	for _, nodeObj := range c.nodeInformer.GetStore().List() {
+		node, ok := nodeObj.(*corev1.Node)
+		if !ok || node.DeletionTimestamp != nil || !c.isSupportedNode(node) {
+			continue
+		}
+		if selector.Matches(labels.Set(node.Labels)) {
+			gateways = append(gateways, node.Name)
+		}
+	}
+	sort.Strings(gateways)
+
+	return reconciler.EgressRule{
+		Name:         cr.Name,
+		CIDRs:        cr.Spec.CIDRs,
+		NAT:          cr.Spec.NAT,
+		GatewayNodes: gateways,
+	}, nil
+}
+
+// cleanupRules deletes egress rules of ClusterEgressRules that no longer exist
+func (c *Controller) cleanupRules(ctx context.Context) error {
+	validRules := make(map[string]bool)
+	for _, key := range c.ruleInformer.GetStore().ListKeys() {
+		validRules[key] = true
+	}
+	return c.options.Reconciler.CleanupRules(ctx, validRules)
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// ExtClientsAnnotation lists the Netmaker external client IDs a node's pod CIDRs are exposed to
+// Comma-separated; "*" exposes the node to every external client in its networks
+const ExtClientsAnnotation = "kaput-not.io/extclients"
+
+// extClientSyncDebounce collapses bursts of node events into a single external client sync
+const extClientSyncDebounce = 5 * time.Second
+
+// triggerExtClientSync requests an external client sync (non-blocking, no-op when disabled)
+func (c *Controller) triggerExtClientSync() {
+	if !c.options.ManageExtClients {
+		return
+	}
+	select {
+	case c.extClientSync <- struct{}{}:
+	default:
+		// A sync is already pending
+	}
+}
+
+// extClientsChanged checks if a node update affects external client routes
+func (c *Controller) extClientsChanged(oldNode, newNode *corev1.Node) bool {
+	return oldNode.Annotations[ExtClientsAnnotation] != newNode.Annotations[ExtClientsAnnotation] ||
+		(newNode.Annotations[ExtClientsAnnotation] != "" && c.podCIDRsChanged(oldNode, newNode))
+}
+
+// runExtClientSync syncs external clients on every trigger and once per ResyncPeriod until ctx is canceled
+func (c *Controller) runExtClientSync(ctx context.Context) {
+	ticker := time.NewTicker(c.options.ResyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := c.syncExtClients(ctx); err != nil {
+			runtime.HandleError(fmt.Errorf("external client sync failed: %w", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.extClientSync:
+			// Debounce: let further events of the same burst queue up behind this one
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(extClientSyncDebounce):
+			}
+		}
+	}
+}
+
+// extClientNetworker is implemented by reconcilers remembering the networks of external client grants
+// (*reconciler.Reconciler), so retired grants are withdrawn even after the node's Netmaker host is gone
+type extClientNetworker interface {
+	ExtClientNetworks(nodeName string) []string
+}
+
+// retireExtClientGrant remembers a deleted annotated node, so its pod CIDRs are withdrawn on the next sync
+// Only the leader syncs external clients, so observers don't record anything
+func (c *Controller) retireExtClientGrant(node *corev1.Node) {
+	if !c.options.ManageExtClients || !c.IsLeading() || node.Annotations[ExtClientsAnnotation] == "" {
+		return
+	}
+
+	grant := reconciler.ExtClientGrant{
+		NodeName: node.Name,
+		PodCIDRs: c.podCIDRs(node),
+	}
+	if networker, ok := c.options.Reconciler.(extClientNetworker); ok {
+		grant.Networks = networker.ExtClientNetworks(node.Name)
+	}
+
+	c.retiredExtClientGrantsMu.Lock()
+	c.retiredExtClientGrants = append(c.retiredExtClientGrants, grant)
+	c.retiredExtClientGrantsMu.Unlock()
+
+	c.triggerExtClientSync()
+}
+
+// syncExtClients publishes annotated nodes' pod CIDRs to Netmaker external clients
+// Every supported node yields a grant, so CIDRs of nodes whose annotation was removed are withdrawn;
+// retired grants of deleted nodes (without client IDs) withdraw their CIDRs as well
+func (c *Controller) syncExtClients(ctx context.Context) error {
+	c.retiredExtClientGrantsMu.Lock()
+	retired := c.retiredExtClientGrants
+	c.retiredExtClientGrants = nil
+	c.retiredExtClientGrantsMu.Unlock()
+
+	grants := append([]reconciler.ExtClientGrant{}, retired...)
+	for _, obj := range c.nodeInformer.GetStore().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !c.isSupportedNode(node) {
+			continue
+		}
+		podCIDRs := c.podCIDRs(node)
+		if len(podCIDRs) == 0 {
+			continue
+		}
+		grants = append(grants, reconciler.ExtClientGrant{
+			NodeName:  node.Name,
+			PodCIDRs:  podCIDRs,
+			ClientIDs: parseExtClients(node.Annotations[ExtClientsAnnotation]),
+		})
+	}
+
+	if err := c.options.Reconciler.SyncExtClients(ctx, grants); err != nil {
+		// Keep retired grants for the next attempt
+		c.retiredExtClientGrantsMu.Lock()
+		c.retiredExtClientGrants = append(c.retiredExtClientGrants, retired...)
+		c.retiredExtClientGrantsMu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// parseExtClients splits the ExtClientsAnnotation value into client IDs
+func parseExtClients(value string) []string {
+	var clientIDs []string
+	for _, clientID := range strings.Split(value, ",") {
+		if clientID = strings.TrimSpace(clientID); clientID != "" {
+			clientIDs = append(clientIDs, clientID)
+		}
+	}
+	return clientIDs
+}
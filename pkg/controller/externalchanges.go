@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"log"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// externalChangeReason is the reason of the Node event emitted for out-of-band egress rule changes
+const externalChangeReason = "NetmakerEgressChangedExternally"
+
+// RecordExternalChange reports a change to a managed egress rule made outside kaput-not (see netmaker.ChangeDetector)
+// Logged, counted and emitted as a warning event on the rule's node, if the node is known;
+// the change itself is corrected by the next reconcile of the node
+// Reads the Netmaker cache, so it must not be called from within a Netmaker client call
+func (c *Controller) RecordExternalChange(change netmaker.ExternalChange) {
+	metrics.ExternalChanges.WithLabelValues(change.Network, change.Kind).Inc()
+
+	egress := change.After
+	if egress == nil {
+		egress = change.Before
+	}
+	detail := change.Kind
+	if len(change.Fields) > 0 {
+		detail += " (" + strings.Join(change.Fields, ", ") + ")"
+	}
+	if egress.UpdatedAt != "" {
+		detail += " at " + egress.UpdatedAt
+	}
+
+	log.Printf("WARNING: egress rule %s (%q, CIDR=%s) in network %s was %s outside kaput-not",
+		egress.ID, egress.Name, egress.Range, change.Network, detail)
+
+	node := c.egressOwnerNode(egress)
+	if node == nil {
+		return
+	}
+	c.recorder.Eventf(node, corev1.EventTypeWarning, externalChangeReason,
+		"Egress rule %q (%s) in Netmaker network %s was %s outside kaput-not",
+		egress.Name, egress.Range, change.Network, detail)
+}
+
+// egressOwnerNode returns the Kubernetes node owning an egress rule (its primary gateway), nil if unknown
+// Resolved from the cached Netmaker hosts and the informer cache only - never calls any API
+func (c *Controller) egressOwnerNode(egress *netmaker.Egress) *corev1.Node {
+	provider, ok := c.options.NetmakerClient.(cachedInventoryProvider)
+	if !ok {
+		return nil
+	}
+	nodeID := reconciler.OwnerNodeID(egress)
+	if nodeID == "" {
+		return nil
+	}
+	hosts, _ := provider.Cached()
+
+	for _, host := range hosts {
+		for _, id := range host.Nodes {
+			if id != nodeID {
+				continue
+			}
+			obj, exists, err := c.nodeInformer.GetIndexer().GetByKey(host.Name)
+			if err != nil || !exists {
+				return nil
+			}
+			node, _ := obj.(*corev1.Node)
+			return node
+		}
+	}
+	return nil
+}
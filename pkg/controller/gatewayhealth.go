@@ -0,0 +1,38 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+const (
+	// gatewayUnhealthyReason is the reason of the Node event emitted when a node's rules are turned off for
+	// unhealthy gateways
+	gatewayUnhealthyReason = "NetmakerGatewayUnhealthy"
+
+	// gatewayRecoveredReason is the reason of the Node event emitted when they are turned back on
+	gatewayRecoveredReason = "NetmakerGatewayRecovered"
+)
+
+// RecordGatewayHealth emits a Node event for a change in the gateway health of a node's egress rules
+// (see reconciler.Options.GatewayHealthCheck); nodes no longer in the informer cache are ignored
+func (c *Controller) RecordGatewayHealth(health reconciler.GatewayHealth) {
+	obj, exists, err := c.nodeInformer.GetIndexer().GetByKey(health.Node)
+	if err != nil || !exists {
+		return
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+
+	if health.Healthy {
+		c.recorder.Eventf(node, corev1.EventTypeNormal, gatewayRecoveredReason,
+			"Gateways in Netmaker network %s are healthy again, egress rules turned back on", health.Network)
+		return
+	}
+	c.recorder.Eventf(node, corev1.EventTypeWarning, gatewayUnhealthyReason,
+		"No healthy gateway in Netmaker network %s (disconnected or no recent check-in), egress rules turned off",
+		health.Network)
+}
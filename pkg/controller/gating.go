@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ScaleDownDeleting gates nodes cluster-autoscaler has started to remove
+	ScaleDownDeleting = "deleting"
+
+	// ScaleDownCandidates also gates nodes cluster-autoscaler considers removing, ahead of the decision
+	ScaleDownCandidates = "candidates"
+
+	// autoscalerToBeDeletedTaint is set by cluster-autoscaler right before it drains and deletes a node
+	autoscalerToBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+
+	// autoscalerDeletionCandidateTaint is set by cluster-autoscaler on nodes found unneeded (removed again if they
+	// are needed after all)
+	autoscalerDeletionCandidateTaint = "DeletionCandidateOfClusterAutoscaler"
+)
+
+// HoldAnnotation keeps a node's egress rules when the node is deleted, e.g. while it is re-imaged ("true")
+// Like a PodDisruptionBudget for the node's routes: remove the annotation (or purge the rules) once the node is not
+// coming back
+const HoldAnnotation = "kaput-not.io/hold-egress-rules"
+
+// isHeld checks if a node carries HoldAnnotation
+func isHeld(node *corev1.Node) bool {
+	return node.Annotations[HoldAnnotation] == "true"
+}
+
+// isGated checks if a node carries one of the GatingTaints (any effect) or is being scaled down
+func (c *Controller) isGated(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		for _, key := range c.options.GatingTaints {
+			if taint.Key == key {
+				return true
+			}
+		}
+	}
+	return c.isScalingDown(node)
+}
+
+// isScalingDown checks if cluster-autoscaler or Karpenter is about to remove a node (see AutoscalerScaleDown
+// and NodeClaims)
+func (c *Controller) isScalingDown(node *corev1.Node) bool {
+	if c.options.NodeClaims != nil && c.options.NodeClaims.Terminating(node.Name) {
+		return true
+	}
+	if c.options.AutoscalerScaleDown == "" {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		switch taint.Key {
+		case autoscalerToBeDeletedTaint:
+			return true
+		case autoscalerDeletionCandidateTaint:
+			if c.options.AutoscalerScaleDown == ScaleDownCandidates {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LeavingNodes re-reconciles nodes that started or stopped being removed without their Node changing
+// (e.g. their Karpenter NodeClaim is being deleted), so their rules are gated before the node goes away
+// Every node is re-reconciled if one of them is an HA gateway, and node pools lose or regain a gateway
+func (c *Controller) LeavingNodes(names []string) {
+	for _, name := range names {
+		obj, exists, err := c.nodeInformer.GetIndexer().GetByKey(name)
+		if err != nil || !exists {
+			continue
+		}
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			continue
+		}
+		if c.isScalingDown(node) {
+			log.Printf("Node %s is being removed by Karpenter, turning its egress rules off", name)
+		}
+		c.enqueuePriority(name)
+		if c.isGatewayNode(node) {
+			c.enqueueAllNodes()
+		}
+		if c.options.PoolLabel != "" {
+			c.enqueueNodePool(node)
+		}
+	}
+}
+
+// EnqueuePriority reconciles the named nodes in the priority lane, e.g. right after they registered for
+// a Karpenter NodeClaim, so their egress rules are created as soon as their Netmaker host shows up
+func (c *Controller) EnqueuePriority(names []string) {
+	for _, name := range names {
+		c.enqueuePriority(name)
+	}
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// Handoff is the state a leader leaves for its successor on a graceful leadership transfer
+// It lets the successor skip the full orphan cleanup the previous leader just ran
+type Handoff struct {
+	LastCleanup    time.Time `json:"lastCleanup,omitzero"`     // Last successful orphan cleanup
+	LastResync     time.Time `json:"lastResync,omitzero"`      // Last successful bulk resync
+	PendingDeletes []string  `json:"pendingDeletes,omitempty"` // Deleted nodes whose egress rules were not removed yet
+}
+
+// handoffState tracks what Handoff reports, and the handoff received from the previous leader
+type handoffState struct {
+	lastCleanup time.Time
+	lastResync  time.Time
+	resumed     *Handoff // Set by ResumeFrom, consumed by Run
+}
+
+// Handoff returns the state for the next leader
+func (c *Controller) Handoff() Handoff {
+	c.handoffMu.Lock()
+	handoff := Handoff{LastCleanup: c.handoff.lastCleanup, LastResync: c.handoff.lastResync}
+	c.handoffMu.Unlock()
+
+	if tracked, ok := c.deleteQueue.(*trackedQueue); ok {
+		pending, active := tracked.keys()
+		for _, key := range append(pending, active...) {
+			handoff.PendingDeletes = append(handoff.PendingDeletes, key.key)
+		}
+		sort.Strings(handoff.PendingDeletes)
+	}
+	return handoff
+}
+
+// ResumeFrom hands the previous leader's state to Run, which queues its pending deletions and skips the initial
+// orphan cleanup if the last one is less than ResyncPeriod ago
+// Call before Run
+func (c *Controller) ResumeFrom(handoff Handoff) {
+	c.handoffMu.Lock()
+	defer c.handoffMu.Unlock()
+	c.handoff.resumed = &handoff
+}
+
+// recordCleanup records a successful orphan cleanup for Handoff
+func (c *Controller) recordCleanup() {
+	c.handoffMu.Lock()
+	defer c.handoffMu.Unlock()
+	c.handoff.lastCleanup = time.Now()
+}
+
+// recordResync records a successful bulk resync for Handoff
+func (c *Controller) recordResync() {
+	c.handoffMu.Lock()
+	defer c.handoffMu.Unlock()
+	c.handoff.lastResync = time.Now()
+}
+
+// resumeHandoff queues the deletions handed over by the previous leader and returns how long the first orphan
+// cleanup can wait: until ResyncPeriod after the previous leader's last cleanup (0 without a handoff)
+func (c *Controller) resumeHandoff() time.Duration {
+	c.handoffMu.Lock()
+	handoff := c.handoff.resumed
+	c.handoff.resumed = nil
+	if handoff != nil {
+		c.handoff.lastCleanup = handoff.LastCleanup
+		c.handoff.lastResync = handoff.LastResync
+	}
+	c.handoffMu.Unlock()
+	if handoff == nil {
+		return 0
+	}
+
+	// Verified with a live GET like any delete, so nodes that came back are left alone
+	for _, name := range handoff.PendingDeletes {
+		c.deleteQueue.Add(name)
+	}
+
+	delay := time.Until(handoff.LastCleanup.Add(c.options.ResyncPeriod))
+	if handoff.LastCleanup.IsZero() || delay <= 0 {
+		log.Printf("Leader handoff: %d pending deletion(s) taken over, last cleanup is due", len(handoff.PendingDeletes))
+		return 0
+	}
+	log.Printf("Leader handoff: %d pending deletion(s) taken over, skipping the initial cleanup (last one %s ago, next in %s)",
+		len(handoff.PendingDeletes), time.Since(handoff.LastCleanup).Round(time.Second), delay.Round(time.Second))
+	return delay
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+)
+
+const (
+	// eventComponent is the source component of events emitted by the controller
+	eventComponent = "kaput-not"
+
+	// hostNotFoundReason is the reason of the Node event emitted for nodes without a Netmaker host
+	hostNotFoundReason = "NetmakerHostNotFound"
+
+	// hostNotFoundWarnInterval is how often a node without a Netmaker host is warned about again
+	hostNotFoundWarnInterval = time.Hour
+)
+
+// missingHost tracks a managed node without a matching Netmaker host
+type missingHost struct {
+	since    time.Time // First cleanup cycle the host was missing in
+	warnedAt time.Time // Last warning (zero until the node exceeded HostNotFoundThreshold)
+}
+
+// trackMissingHosts records the managed nodes found without a Netmaker host by a cleanup cycle
+// Nodes missing for longer than HostNotFoundThreshold are warned about (log and Node event) at most once
+// per hostNotFoundWarnInterval; nodes no longer in missing are forgotten (enrolled, unmanaged or deleted)
+// Nodes still in the informer cache got their Netmaker host and are reconciled in the priority lane
+func (c *Controller) trackMissingHosts(missing []*corev1.Node) {
+	now := time.Now()
+
+	c.missingHostsMu.Lock()
+	tracked := make(map[string]*missingHost, len(missing))
+	var warn []*corev1.Node
+	for _, node := range missing {
+		entry, ok := c.missingHosts[node.Name]
+		if !ok {
+			entry = &missingHost{since: now}
+		}
+		tracked[node.Name] = entry
+
+		if now.Sub(entry.since) >= c.options.HostNotFoundThreshold && now.Sub(entry.warnedAt) >= hostNotFoundWarnInterval {
+			entry.warnedAt = now
+			warn = append(warn, node)
+		}
+	}
+	var appeared []string
+	for name := range c.missingHosts {
+		if _, ok := tracked[name]; !ok {
+			appeared = append(appeared, name)
+		}
+	}
+	c.missingHosts = tracked
+
+	overdue := 0
+	for _, entry := range tracked {
+		if now.Sub(entry.since) >= c.options.HostNotFoundThreshold {
+			overdue++
+		}
+	}
+	c.missingHostsMu.Unlock()
+
+	metrics.NodesWithoutNetmakerHost.Set(float64(overdue))
+
+	for _, name := range appeared {
+		if _, exists, err := c.nodeInformer.GetIndexer().GetByKey(name); err == nil && exists {
+			log.Printf("Netmaker host of node %s appeared, reconciling it with priority", name)
+			c.enqueuePriority(name)
+		}
+	}
+
+	for _, node := range warn {
+		since := c.missingHostSince(node.Name)
+		log.Printf("WARNING: node %s has had no Netmaker host for %s - is netclient enrolled with hostname %q?",
+			node.Name, time.Since(since).Round(time.Second), node.Name)
+		c.recorder.Eventf(node, corev1.EventTypeWarning, hostNotFoundReason,
+			"No Netmaker host named %q for %s, so no egress rules are published for this node",
+			node.Name, time.Since(since).Round(time.Second))
+	}
+}
+
+// missingHostSince returns when a node was first seen without a Netmaker host (zero if it has one)
+func (c *Controller) missingHostSince(name string) time.Time {
+	c.missingHostsMu.Lock()
+	defer c.missingHostsMu.Unlock()
+
+	if entry, ok := c.missingHosts[name]; ok {
+		return entry.since
+	}
+	return time.Time{}
+}
+
+// missingHostNodes returns the names of nodes without a Netmaker host for longer than HostNotFoundThreshold
+func (c *Controller) missingHostNodes() []string {
+	c.missingHostsMu.Lock()
+	names := make([]string, 0, len(c.missingHosts))
+	for name, entry := range c.missingHosts {
+		if time.Since(entry.since) >= c.options.HostNotFoundThreshold {
+			names = append(names, name)
+		}
+	}
+	c.missingHostsMu.Unlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// HostsEnrolled reconciles the nodes of newly enrolled Netmaker hosts in the priority lane (see hostwatcher)
+// Hosts without a Kubernetes node of the same name are ignored; the caller invalidates the host cache first
+func (c *Controller) HostsEnrolled(hostNames []string) {
+	for _, name := range hostNames {
+		obj, exists, err := c.nodeInformer.GetIndexer().GetByKey(name)
+		if err != nil || !exists {
+			continue
+		}
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			continue
+		}
+
+		// Don't report the host as appeared again at the next cleanup cycle
+		c.missingHostsMu.Lock()
+		delete(c.missingHosts, name)
+		c.missingHostsMu.Unlock()
+
+		log.Printf("Netmaker host of node %s enrolled, reconciling it with priority", name)
+		c.enqueuePriority(name)
+		if c.options.PoolLabel != "" {
+			c.enqueueNodePool(node)
+		}
+	}
+}
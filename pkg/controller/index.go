@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+)
+
+const (
+	// podCIDRIndex indexes the nodes with spec.podCIDRs under podCIDRIndexValue; nodes without are not indexed
+	podCIDRIndex      = "podCIDRs"
+	podCIDRIndexValue = "true"
+
+	// nodeChunkSize is the number of informer cache entries read at a time by forEachCandidateNode
+	nodeChunkSize = 500
+)
+
+// indexByPodCIDRs is the informer index function of podCIDRIndex
+func indexByPodCIDRs(obj interface{}) ([]string, error) {
+	node, ok := obj.(*corev1.Node)
+	if !ok || len(node.Spec.PodCIDRs) == 0 {
+		return nil, nil
+	}
+	return []string{podCIDRIndexValue}, nil
+}
+
+// candidateNodeKeys returns the keys of the nodes that may have egress rules: the nodes with spec.podCIDRs, or every
+// node when pod CIDRs come from IP pools or nodes publish cluster-wide CIDRs (neither is visible to the index)
+// On large clusters most of the nodes without pod CIDRs (e.g. still joining) are skipped without being read
+func (c *Controller) candidateNodeKeys() ([]string, error) {
+	indexer := c.nodeInformer.GetIndexer()
+	if c.options.PodIPPools != nil || c.options.Reconciler.AggregatesClusterCIDRs() || c.options.SummarizePodCIDRs ||
+		len(c.ClusterNetworkCIDRs()) > 0 {
+		return indexer.ListKeys(), nil
+	}
+	return indexer.IndexKeys(podCIDRIndex, podCIDRIndexValue)
+}
+
+// forEachCandidateNode calls fn for every candidate node (see candidateNodeKeys), reading the informer cache in
+// chunks of nodeChunkSize instead of copying all nodes at once; stops early when ctx is canceled
+func (c *Controller) forEachCandidateNode(ctx context.Context, fn func(node *corev1.Node) error) error {
+	keys, err := c.candidateNodeKeys()
+	if err != nil {
+		return fmt.Errorf("failed to list nodes from the informer index: %w", err)
+	}
+
+	indexer := c.nodeInformer.GetIndexer()
+	for start := 0; start < len(keys); start += nodeChunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, key := range keys[start:min(start+nodeChunkSize, len(keys))] {
+			obj, exists, err := indexer.GetByKey(key)
+			if err != nil || !exists {
+				continue // Deleted since the keys were listed
+			}
+			node, ok := obj.(*corev1.Node)
+			if !ok {
+				runtime.HandleError(fmt.Errorf("expected Node but got %T", obj))
+				continue
+			}
+			if err := fn(node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// cacheStatsProvider is implemented by Netmaker clients that cache API responses
+type cacheStatsProvider interface {
+	Stats() netmaker.CacheStats
+}
+
+// collectSelfMetrics samples informer and Netmaker cache occupancy
+// Logs a warning when a cache grows beyond its configured threshold
+func (c *Controller) collectSelfMetrics(_ context.Context) {
+	informerObjects := len(c.nodeInformer.GetIndexer().ListKeys())
+	metrics.InformerCachedObjects.Set(float64(informerObjects))
+
+	settings := c.settings.Load()
+	if threshold := settings.InformerCacheWarnThreshold; threshold > 0 && informerObjects > threshold {
+		log.Printf("WARNING: informer cache holds %d objects (threshold %d) - consider raising memory limits",
+			informerObjects, threshold)
+	}
+
+	provider, ok := c.options.NetmakerClient.(cacheStatsProvider)
+	if !ok {
+		return
+	}
+
+	stats := provider.Stats()
+	metrics.NetmakerCacheEntries.WithLabelValues("hosts").Set(float64(stats.Hosts))
+	metrics.NetmakerCacheEntries.WithLabelValues("nodes").Set(float64(stats.Nodes))
+	metrics.NetmakerCacheEntries.WithLabelValues("networks").Set(float64(stats.Networks))
+	metrics.NetmakerCacheEntries.WithLabelValues("egress").Set(float64(stats.EgressEntries))
+
+	if threshold := settings.EgressCacheWarnThreshold; threshold > 0 && stats.EgressEntries > threshold {
+		log.Printf("WARNING: Netmaker cache holds %d egress rules across %d networks (threshold %d)",
+			stats.EgressEntries, stats.EgressNetworks, threshold)
+	}
+}
+
+// handleWatchError records node watch failures and delegates to client-go's default logging
+// The reflector reconnects (and relists if the resourceVersion expired) after every failure; failures other
+// than expired resource versions count towards a watch failure storm (see watchhealth.go)
+func (c *Controller) handleWatchError(ctx context.Context, r *cache.Reflector, err error) {
+	reason := "other"
+	switch {
+	case apierrors.IsResourceExpired(err) || apierrors.IsGone(err):
+		reason = "expired"
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		reason = "eof"
+	}
+	metrics.InformerWatchErrors.WithLabelValues(reason).Inc()
+	if reason != "expired" {
+		c.recordWatchFailure(err)
+	}
+
+	cache.DefaultWatchErrorHandler(ctx, r, err)
+}
+
+// recordNetworkResults counts a node reconcile in each network it reached, as failed in the networks err names
+func recordNetworkResults(zone string, result reconciler.NodeResult, err error) {
+	failed := make(map[string]bool)
+	for _, network := range reconciler.FailedNetworks(err) {
+		failed[network] = true
+	}
+	for _, network := range result.Networks {
+		outcome := "success"
+		if failed[network] {
+			outcome = "error"
+		}
+		metrics.NetworkReconcileTotal.WithLabelValues(network, zone, outcome).Inc()
+	}
+}
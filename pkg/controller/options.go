@@ -1,36 +1,266 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
 	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
 )
 
+// Reconciler is the reconciliation logic consumed by the controller
+// Implemented by *reconciler.Reconciler; alternate implementations and test doubles can be plugged in
+type Reconciler interface {
+	// ReconcileNode syncs a node's pod CIDRs to Netmaker egress rules
+	// Returns the networks touched and the mutations made
+	ReconcileNode(ctx context.Context, node *corev1.Node, topology reconciler.Topology) (reconciler.NodeResult, error)
+
+	// DeleteNode removes the egress rules of a deleted node
+	DeleteNode(ctx context.Context, nodeName string) error
+
+	// CleanupOrphanedEgresses removes egress rules of Netmaker nodes not in validNodeIDs
+	CleanupOrphanedEgresses(ctx context.Context, validNodeIDs map[string]bool) error
+
+	// CleanupExpiredEgresses removes egress rules whose lease expired
+	CleanupExpiredEgresses(ctx context.Context) error
+
+	// CleanupRecordedEgresses removes recorded egress rules of nodes not in managedNodes
+	CleanupRecordedEgresses(ctx context.Context, managedNodes map[string]bool) error
+
+	// RenamedHostNodeIDs returns the Netmaker node IDs of a node whose host was renamed in Netmaker (nil if none)
+	RenamedHostNodeIDs(ctx context.Context, nodeName string) ([]string, error)
+
+	// ResyncNodes reconciles many nodes against a single snapshot of Netmaker state
+	// Returns per-node results and errors, or an error if the snapshot could not be taken
+	ResyncNodes(ctx context.Context, requests []reconciler.NodeRequest) (map[string]reconciler.NodeResult, map[string]error, error)
+
+	// AggregatesClusterCIDRs reports whether nodes publish cluster-wide CIDRs instead of their own
+	AggregatesClusterCIDRs() bool
+
+	// SyncExtClients publishes granted pod CIDRs to Netmaker external clients
+	SyncExtClients(ctx context.Context, grants []reconciler.ExtClientGrant) error
+
+	// ReconcileRule syncs a ClusterEgressRule to Netmaker egress rules
+	ReconcileRule(ctx context.Context, rule reconciler.EgressRule) error
+
+	// DeleteRule removes the egress rules of a deleted ClusterEgressRule
+	DeleteRule(ctx context.Context, name string) error
+
+	// CleanupRules removes egress rules of ClusterEgressRules not in validRules
+	CleanupRules(ctx context.Context, validRules map[string]bool) error
+
+	// ReconcilePool syncs the shared egress rules of a node pool to Netmaker
+	ReconcilePool(ctx context.Context, pool reconciler.EgressRule) error
+
+	// DeletePool removes the egress rules of a node pool without nodes
+	DeletePool(ctx context.Context, name string) error
+
+	// CleanupPools removes egress rules of node pools not in validPools
+	CleanupPools(ctx context.Context, validPools map[string]bool) error
+
+	// EnsureNetworks creates the configured Netmaker networks that don't exist yet
+	EnsureNetworks(ctx context.Context) error
+
+	// PlanNodes computes the egress rules of the given nodes and the changes to reach them, without mutating
+	PlanNodes(ctx context.Context, requests []reconciler.NodeRequest, validNodeIDs map[string]bool) (*reconciler.Plan, error)
+
+	// VerifyNode reads a node's egress rules back from Netmaker and fails if they differ from the desired state
+	VerifyNode(ctx context.Context, node *corev1.Node, topology reconciler.Topology) error
+}
+
+// PodIPPools maps nodes to the pod CIDRs allocated from the advertised IP pools
+// Implemented by *ippools.Watcher
+type PodIPPools interface {
+	// NodeCIDRs returns the advertised CIDRs of a node (empty if none)
+	NodeCIDRs(nodeName string) []string
+}
+
+// NodeClaims reports nodes Karpenter is removing
+// Implemented by *nodeclaims.Watcher
+type NodeClaims interface {
+	// Terminating reports whether the NodeClaim of a node is being deleted
+	Terminating(nodeName string) bool
+}
+
+// Ensure the default implementation satisfies the interface
+var _ Reconciler = (*reconciler.Reconciler)(nil)
+
 // Options contains configuration for the controller
 type Options struct {
 	// KubeClient is the Kubernetes client
 	KubeClient kubernetes.Interface
 
+	// DynamicClient reads the kaput-not custom resources (required when ManageEgressRules is set)
+	DynamicClient dynamic.Interface
+
 	// NetmakerClient is the Netmaker API client
-	NetmakerClient netmaker.Client
+	NetmakerClient netmaker.BasicClient
 
 	// Reconciler is the reconciliation logic
-	Reconciler *reconciler.Reconciler
+	Reconciler Reconciler
 
 	// ClusterName is the name of this Kubernetes cluster (optional, for multi-cluster deployments)
 	ClusterName string
 
-	// ResyncPeriod is how often to resync all nodes
+	// InstanceID selects the ClusterEgressRules labeled v1alpha1.InstanceLabel=InstanceID when several kaput-not
+	// instances run in one cluster (must match the reconciler's InstanceID)
+	// Default: empty (ClusterEgressRules without the label)
+	InstanceID string
+
+	// ResyncPeriod is how often to resync all nodes (against one Netmaker snapshot per cycle)
 	// Default: 10 minutes
 	ResyncPeriod time.Duration
 
+	// IncludeWindowsNodes enables reconciliation of Windows nodes
+	// Changeable at runtime with ApplySettings
+	// Default: false (netclient support on Windows differs, so they are skipped)
+	IncludeWindowsNodes bool
+
+	// GatewaySelector is a label selector for HA gateway nodes (e.g. "kaput-not.io/gateway=true")
+	// Matching nodes are attached to every egress rule as backup gateways with higher metrics,
+	// so traffic to a pod CIDR survives failure of the node owning it
+	// Default: empty (disabled)
+	GatewaySelector string
+
+	// PublisherSelector is a label selector for nodes allowed to publish egress rules
+	// (e.g. "node-role.kubernetes.io/control-plane"); other nodes are skipped and their rules cleaned up
+	// Changeable at runtime with ApplySettings
+	// Default: empty (all nodes publish)
+	PublisherSelector string
+
+	// SummarizePodCIDRs makes publishers advertise the minimal set of CIDRs covering all nodes'
+	// pod CIDRs instead of their own (see reconciler.SummarizeCIDRs); requires PublisherSelector
+	// Default: false
+	SummarizePodCIDRs bool
+
+	// PodIPPools replaces each node's spec.podCIDRs with the CIDRs allocated to it from the advertised
+	// IP pools (CNIs with per-namespace pools); call EnqueueNodes when a node's pool CIDRs change
+	// Default: nil (spec.podCIDRs are published)
+	PodIPPools PodIPPools
+
+	// GatingTaints are taint keys (e.g. "node.kubernetes.io/not-ready") that gate a node's egress rules:
+	// while the node carries one of them, its existing rules are turned off (Status=false) rather than
+	// deleted and no new rules are created; they are turned back on once the taint clears
+	// Default: empty (disabled)
+	GatingTaints []string
+
+	// AutoscalerScaleDown gates nodes cluster-autoscaler is about to remove, like GatingTaints, so their
+	// rules are turned off (and HA gateways detached) while the node still routes, instead of breaking at
+	// VM deletion: ScaleDownDeleting once ToBeDeletedByClusterAutoscaler is set, ScaleDownCandidates already
+	// on DeletionCandidateOfClusterAutoscaler (earlier, but candidates that stay turn their rules back on)
+	// Default: empty (disabled)
+	AutoscalerScaleDown string
+
+	// NodeClaims gates nodes whose Karpenter NodeClaim is being deleted, like AutoscalerScaleDown, usually before
+	// Karpenter taints or deletes the Node; call LeavingNodes when a node's NodeClaim starts or stops terminating
+	// and EnqueuePriority when a node registers for a NodeClaim
+	// Default: nil (NodeClaims are not watched)
+	NodeClaims NodeClaims
+
+	// MatchHostsByAddress keeps the rules of nodes whose Netmaker host is only matched by IP address during orphan
+	// cleanup (see reconciler.Options.MatchHostsByAddress, which must be set alike)
+	// Default: false
+	MatchHostsByAddress bool
+
+	// ManageExtClients exposes pod CIDRs of nodes carrying ExtClientsAnnotation to Netmaker
+	// external clients by adding them to the clients' extra allowed IPs
+	// Default: false (external clients are never modified)
+	ManageExtClients bool
+
+	// ManageEgressRules routes the CIDRs of ClusterEgressRule resources through their selected nodes
+	// Requires the ClusterEgressRule CRD
+	// Default: false
+	ManageEgressRules bool
+
+	// PoolLabel is a node label (e.g. "cloud.google.com/gke-nodepool") grouping nodes into node pools:
+	// all nodes with the same value share egress rules covering their summarized pod CIDRs, routed via
+	// every member, instead of publishing rules of their own; keeps rule counts flat for autoscaling pools
+	// Incompatible with SummarizePodCIDRs and aggregated cluster CIDRs, which already share rules
+	// Default: empty (disabled)
+	PoolLabel string
+
+	// WatchedLabels are node label keys whose changes re-reconcile the node, e.g. the labels embedded in
+	// egress rule descriptions or selecting egress metrics (see reconciler.Options.DescriptionLabels and NodeMetrics)
+	// Default: empty
+	WatchedLabels []string
+
+	// DisableWatchBookmarks turns off watch bookmarks for the node informer
+	// Bookmarks let the informer resume watches after reconnects without a full relist
+	// Default: false (bookmarks enabled)
+	DisableWatchBookmarks bool
+
+	// MaxInformerRestarts is how often the node informer may be replaced by a fresh one (new connection, list
+	// and watch) while its watch keeps failing, at most once per 5 minutes; watch failure storms are reported
+	// by WatchFailing (readiness), the kaput_not_informer_watch_failing metric and a warning log either way
+	// Default: 0 (never restarted)
+	MaxInformerRestarts int
+
+	// DeletionDelay is how long to wait after a node delete event before verifying with a live GET
+	// that the node is really gone and deleting its egress rules
+	// Guards against informer relist artifacts and apiserver hiccups causing route flaps
+	// Default: 10 seconds
+	DeletionDelay time.Duration
+
+	// QuarantineThreshold is the number of consecutive failures after which a node is quarantined:
+	// retried only every QuarantineRetryInterval instead of with exponential backoff
+	// Default: 0 (disabled)
+	QuarantineThreshold int
+
+	// QuarantineRetryInterval is how often quarantined nodes are retried
+	// Default: 10 minutes
+	QuarantineRetryInterval time.Duration
+
+	// ChurnThreshold is the number of node add/delete events within ChurnWindow that switches the controller
+	// to bulk reconciliation during mass node churn, e.g. upgrades replacing every node (see churn.go)
+	// Default: 0 (disabled)
+	ChurnThreshold int
+
+	// ChurnWindow is the sliding window node add/delete events are counted in, and how often all nodes are
+	// resynced in bulk while the churn lasts
+	// Default: 1 minute
+	ChurnWindow time.Duration
+
+	// HostNotFoundThreshold is how long a managed node may lack a Netmaker host before it is reported
+	// (warning log, NetmakerHostNotFound Node event and the nodes_without_netmaker_host metric)
+	// Checked on each cleanup cycle (every ResyncPeriod)
+	// Default: 15 minutes
+	HostNotFoundThreshold time.Duration
+
+	// CanaryNode is reconciled alone after startup and its egress rules read back from Netmaker
+	// before any other node is reconciled or orphaned rules are cleaned up; Run fails if the node
+	// is missing or its rules don't match, so a misbehaving release stops after a single node
+	// Default: empty (disabled)
+	CanaryNode string
+
 	// WorkerCount is the number of concurrent reconciliation workers
 	// Default: 1
 	WorkerCount int
+
+	// SelfMetricsInterval is how often cache occupancy metrics are sampled
+	// Default: 30 seconds
+	SelfMetricsInterval time.Duration
+
+	// InformerCacheWarnThreshold logs a warning when the informer cache holds more objects
+	// Changeable at runtime with ApplySettings
+	// Default: 0 (disabled)
+	InformerCacheWarnThreshold int
+
+	// EgressCacheWarnThreshold logs a warning when the Netmaker cache holds more egress rules
+	// Changeable at runtime with ApplySettings
+	// Default: 0 (disabled)
+	EgressCacheWarnThreshold int
+
+	// OnReconcileResult is called after every node reconcile (event-driven, resync or canary) with the
+	// networks touched, the egress rule mutations made and the error, so embedders can hook in metrics or
+	// notifications without parsing logs; not called for nodes skipped as unchanged
+	// Called synchronously from the workers, so it must not block
+	// Default: nil (disabled)
+	OnReconcileResult func(ReconcileResult)
 }
 
 // Validate validates the options
@@ -44,6 +274,27 @@ func (o *Options) Validate() error {
 	if o.Reconciler == nil {
 		return fmt.Errorf("Reconciler is required")
 	}
+	if o.SummarizePodCIDRs && o.PublisherSelector == "" {
+		return fmt.Errorf("PublisherSelector is required when SummarizePodCIDRs is set")
+	}
+	if o.QuarantineThreshold < 0 {
+		return fmt.Errorf("QuarantineThreshold must not be negative")
+	}
+	if o.MaxInformerRestarts < 0 {
+		return fmt.Errorf("MaxInformerRestarts must not be negative")
+	}
+	if o.ChurnThreshold < 0 {
+		return fmt.Errorf("ChurnThreshold must not be negative")
+	}
+	if o.ManageEgressRules && o.DynamicClient == nil {
+		return fmt.Errorf("DynamicClient is required when ManageEgressRules is set")
+	}
+	if o.AutoscalerScaleDown != "" && o.AutoscalerScaleDown != ScaleDownDeleting && o.AutoscalerScaleDown != ScaleDownCandidates {
+		return fmt.Errorf("AutoscalerScaleDown must be %q or %q, got %q", ScaleDownDeleting, ScaleDownCandidates, o.AutoscalerScaleDown)
+	}
+	if o.PoolLabel != "" && (o.SummarizePodCIDRs || o.Reconciler.AggregatesClusterCIDRs()) {
+		return fmt.Errorf("PoolLabel cannot be combined with summarized or aggregated pod CIDRs")
+	}
 	return nil
 }
 
@@ -52,7 +303,22 @@ func (o *Options) ApplyDefaults() {
 	if o.ResyncPeriod == 0 {
 		o.ResyncPeriod = 10 * time.Minute
 	}
+	if o.DeletionDelay == 0 {
+		o.DeletionDelay = 10 * time.Second
+	}
+	if o.QuarantineRetryInterval == 0 {
+		o.QuarantineRetryInterval = 10 * time.Minute
+	}
+	if o.ChurnWindow == 0 {
+		o.ChurnWindow = time.Minute
+	}
+	if o.HostNotFoundThreshold == 0 {
+		o.HostNotFoundThreshold = 15 * time.Minute
+	}
 	if o.WorkerCount == 0 {
 		o.WorkerCount = 1
 	}
+	if o.SelfMetricsInterval == 0 {
+		o.SelfMetricsInterval = 30 * time.Second
+	}
 }
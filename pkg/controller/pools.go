@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// setupPools creates the node pool queue and enqueues a node's pool whenever the node is added or deleted
+// Updates joining or leaving a pool, or changing pod CIDRs, gating or deletion, are handled by the "pool"
+// rule of handleNodeUpdate (see predicates.go)
+func (c *Controller) setupPools() error {
+	c.poolQueue = newTrackedQueue()
+
+	if _, err := c.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueNodePool,
+		DeleteFunc: c.enqueueNodePool,
+	}); err != nil {
+		return fmt.Errorf("failed to add node event handler for node pools: %w", err)
+	}
+
+	return nil
+}
+
+// poolName returns the node pool a node belongs to: the value of its PoolLabel (empty if none)
+func (c *Controller) poolName(node *corev1.Node) string {
+	if c.options.PoolLabel == "" {
+		return ""
+	}
+	return node.Labels[c.options.PoolLabel]
+}
+
+// enqueueNodePool adds the node pool of a node (or its tombstone) to the pool queue
+func (c *Controller) enqueueNodePool(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	if pool := c.poolName(node); pool != "" {
+		c.poolQueue.Add(pool)
+	}
+}
+
+// poolNames returns the set of node pools with at least one supported node in the informer cache
+func (c *Controller) poolNames() map[string]bool {
+	pools := make(map[string]bool)
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !c.isSupportedNode(node) {
+			continue
+		}
+		if pool := c.poolName(node); pool != "" {
+			pools[pool] = true
+		}
+	}
+	return pools
+}
+
+// enqueueAllPools adds every known node pool to the pool queue
+func (c *Controller) enqueueAllPools() {
+	for pool := range c.poolNames() {
+		c.poolQueue.Add(pool)
+	}
+}
+
+// runPools syncs node pools until ctx is canceled (leader only)
+// Rules of pools without nodes are cleaned up at startup and once per ResyncPeriod, which also
+// covers pools emptied while no leader was running; every pool is re-reconciled on the same period
+func (c *Controller) runPools(ctx context.Context) {
+	defer runtime.HandleCrash()
+	defer c.poolQueue.ShutDown()
+
+	go wait.UntilWithContext(ctx, c.runPoolWorker, time.Second)
+
+	ticker := time.NewTicker(c.options.ResyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := c.options.Reconciler.CleanupPools(ctx, c.poolNames()); err != nil {
+			runtime.HandleError(fmt.Errorf("node pool cleanup failed: %w", err))
+		}
+		c.enqueueAllPools()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runPoolWorker processes items from the pool queue
+func (c *Controller) runPoolWorker(ctx context.Context) {
+	for c.processNextPool(ctx) {
+	}
+}
+
+// processNextPool processes a single item from the pool queue
+func (c *Controller) processNextPool(ctx context.Context) bool {
+	key, shutdown := c.poolQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.poolQueue.Done(key)
+
+	if err := c.poolSyncHandler(ctx, key); err != nil {
+		requeue(c.poolQueue, key, err)
+		runtime.HandleError(fmt.Errorf("error syncing node pool '%s': %w, requeuing", key, err))
+		return true
+	}
+
+	c.poolQueue.Forget(key)
+	return true
+}
+
+// poolSyncHandler reconciles the shared egress rules of a node pool, or deletes them once it has no nodes
+func (c *Controller) poolSyncHandler(ctx context.Context, name string) error {
+	pool, err := c.nodePool(name)
+	if err != nil {
+		metrics.PoolReconcileTotal.WithLabelValues("error").Inc()
+		return fmt.Errorf("failed to resolve node pool %s: %w", name, err)
+	}
+
+	if len(pool.CIDRs) == 0 {
+		if err := c.options.Reconciler.DeletePool(ctx, name); err != nil {
+			metrics.PoolReconcileTotal.WithLabelValues("error").Inc()
+			return fmt.Errorf("failed to delete egress rules of node pool %s: %w", name, err)
+		}
+		metrics.PoolReconcileTotal.WithLabelValues("deleted").Inc()
+		return nil
+	}
+
+	if err := c.options.Reconciler.ReconcilePool(ctx, pool); err != nil {
+		metrics.PoolReconcileTotal.WithLabelValues("error").Inc()
+		return fmt.Errorf("failed to reconcile node pool %s: %w", name, err)
+	}
+	metrics.PoolReconcileTotal.WithLabelValues("success").Inc()
+	return nil
+}
+
+// nodePool resolves a node pool to the reconciler input
+// CIDRs summarize the pod CIDRs of all supported members; gateways are the members neither being deleted
+// nor gated, sorted by name (stable metrics) - traffic for a drained member's pods still enters via the others
+func (c *Controller) nodePool(name string) (reconciler.EgressRule, error) {
+	var podCIDRs, gateways []string
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok || !c.isSupportedNode(node) || c.poolName(node) != name {
+			continue
+		}
+		podCIDRs = append(podCIDRs, c.podCIDRs(node)...)
+		if node.DeletionTimestamp == nil && !c.isGated(node) {
+			gateways = append(gateways, node.Name)
+		}
+	}
+	sort.Strings(gateways)
+
+	summary, err := reconciler.SummarizeCIDRs(podCIDRs)
+	if err != nil {
+		return reconciler.EgressRule{}, err
+	}
+	if len(summary) > 0 && len(gateways) == 0 {
+		log.Printf("Node pool %s has no ungated nodes, its egress rules are removed until one is ready", name)
+	}
+
+	return reconciler.EgressRule{
+		Name:         name,
+		CIDRs:        summary,
+		GatewayNodes: gateways,
+	}, nil
+}
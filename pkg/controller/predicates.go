@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+// Node updates are filtered in two steps:
+//   - status-only updates (heartbeats, conditions, images) are dropped before any rule is evaluated: nothing
+//     kaput-not reads lives in the node status, so only generation, spec, labels, annotations and the deletion
+//     timestamp can matter
+//   - the remaining updates run through nodeUpdateRules; each rule that matches contributes its reaction
+//
+// A feature reacting to node changes adds a rule here instead of another check in handleNodeUpdate
+
+// nodeReaction is the work a matching rule asks for
+type nodeReaction int
+
+const (
+	// reactReconcileNode reconciles the updated node
+	reactReconcileNode nodeReaction = 1 << iota
+	// reactReconcileAll reconciles every node (the HA gateway set changed)
+	reactReconcileAll
+	// reactReconcilePublishers reconciles every publisher (the summarized pod CIDRs may have changed)
+	reactReconcilePublishers
+	// reactReconcilePools reconciles the node pools the node left and joined
+	reactReconcilePools
+	// reactSyncExtClients resyncs the external clients
+	reactSyncExtClients
+)
+
+// nodeUpdateRule is a predicate on node updates and the reaction it triggers
+type nodeUpdateRule struct {
+	// name identifies the rule in the node_updates_total metric
+	name string
+
+	// matches reports whether the update is relevant to the rule
+	matches func(c *Controller, oldNode, newNode *corev1.Node) bool
+
+	// reaction is the work to do when the rule matches
+	reaction nodeReaction
+}
+
+// nodeUpdateRules are all rules node updates are checked against, see nodeUpdateReactions
+var nodeUpdateRules = []nodeUpdateRule{
+	{
+		name: "ext-clients",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			return c.options.ManageExtClients && c.extClientsChanged(oldNode, newNode)
+		},
+		reaction: reactSyncExtClients,
+	},
+	{
+		// Gateway membership changed, or a gateway is being scaled down - every node's egress rules change
+		name: "gateway",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			return c.isGatewayNode(oldNode) != c.isGatewayNode(newNode) ||
+				(c.isGatewayNode(newNode) && c.isScalingDown(oldNode) != c.isScalingDown(newNode))
+		},
+		reaction: reactReconcileAll,
+	},
+	{
+		name: "summary",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			return c.options.SummarizePodCIDRs && c.podCIDRsChanged(oldNode, newNode)
+		},
+		reaction: reactReconcilePublishers,
+	},
+	{
+		name: "pool",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			return c.options.PoolLabel != "" && (c.poolName(oldNode) != c.poolName(newNode) ||
+				c.podCIDRsChanged(oldNode, newNode) || c.isGated(oldNode) != c.isGated(newNode) ||
+				(oldNode.DeletionTimestamp == nil) != (newNode.DeletionTimestamp == nil))
+		},
+		reaction: reactReconcilePools,
+	},
+	{
+		name: "pod-cidrs",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			return c.podCIDRsChanged(oldNode, newNode)
+		},
+		reaction: reactReconcileNode,
+	},
+	{
+		name: "publisher",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			return c.isPublisherNode(oldNode) != c.isPublisherNode(newNode)
+		},
+		reaction: reactReconcileNode,
+	},
+	{
+		name: "gating",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			return c.isGated(oldNode) != c.isGated(newNode)
+		},
+		reaction: reactReconcileNode,
+	},
+	{
+		name: "hold",
+		matches: func(_ *Controller, oldNode, newNode *corev1.Node) bool {
+			return isHeld(oldNode) != isHeld(newNode)
+		},
+		reaction: reactReconcileNode,
+	},
+	{
+		// Label values embedded in the node's rule descriptions or selecting its egress metric
+		name: "labels",
+		matches: func(c *Controller, oldNode, newNode *corev1.Node) bool {
+			for _, key := range c.options.WatchedLabels {
+				if oldNode.Labels[key] != newNode.Labels[key] {
+					return true
+				}
+			}
+			return false
+		},
+		reaction: reactReconcileNode,
+	},
+}
+
+// statusOnlyUpdate reports whether a node update changed nothing but the status (or nothing at all)
+func statusOnlyUpdate(oldNode, newNode *corev1.Node) bool {
+	return oldNode.Generation == newNode.Generation &&
+		maps.Equal(oldNode.Labels, newNode.Labels) &&
+		maps.Equal(oldNode.Annotations, newNode.Annotations) &&
+		oldNode.DeletionTimestamp.Equal(newNode.DeletionTimestamp) &&
+		apiequality.Semantic.DeepEqual(oldNode.Spec, newNode.Spec)
+}
+
+// nodeUpdateReactions evaluates the rules against a node update that is not status-only
+// Returns the combined reactions and the names of the matching rules (nil for irrelevant updates)
+func (c *Controller) nodeUpdateReactions(oldNode, newNode *corev1.Node) (nodeReaction, []string) {
+	var reactions nodeReaction
+	var matched []string
+	for _, rule := range nodeUpdateRules {
+		if rule.matches(c, oldNode, newNode) {
+			reactions |= rule.reaction
+			matched = append(matched, rule.name)
+		}
+	}
+	return reactions, matched
+}
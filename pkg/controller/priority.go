@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+)
+
+// Work is split into lanes so that urgent route changes never wait behind bulk work:
+//   - deleteQueue: deleted nodes, whose routes must be removed (own worker, see runDeleteWorker)
+//   - priorityQueue: nodes whose Netmaker host appeared and nodes recreated after a delete event
+//   - workqueue: everything else, including the bulky enqueueAllNodes/enqueuePublisherNodes fan-outs
+//
+// A node key can sit in both node lanes at once; nodeLocks keeps their workers from reconciling it concurrently
+
+// keyLocks serializes work on the same key across workers of different queues
+type keyLocks struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	held map[string]bool
+}
+
+// newKeyLocks creates an empty set of key locks
+func newKeyLocks() *keyLocks {
+	l := &keyLocks{held: make(map[string]bool)}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// lock blocks until no other worker holds key, then holds it
+func (l *keyLocks) lock(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.held[key] {
+		l.cond.Wait()
+	}
+	l.held[key] = true
+}
+
+// unlock releases key
+func (l *keyLocks) unlock(key string) {
+	l.mu.Lock()
+	delete(l.held, key)
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// enqueuePriority adds a node key to the priority lane
+func (c *Controller) enqueuePriority(key string) {
+	metrics.PriorityEnqueues.Inc()
+	c.priorityQueue.Add(key)
+}
+
+// runPriorityWorker processes items from the priority lane
+func (c *Controller) runPriorityWorker(ctx context.Context) {
+	for c.processNextPriorityItem(ctx) {
+	}
+}
+
+// processNextPriorityItem processes a single item from the priority lane
+// Failures are retried in the regular workqueue, where backoff and quarantine apply
+func (c *Controller) processNextPriorityItem(ctx context.Context) bool {
+	key, shutdown := c.priorityQueue.Get()
+	if shutdown {
+		return false
+	}
+
+	defer c.priorityQueue.Done(key)
+
+	if err := c.syncHandler(ctx, key); err != nil {
+		c.requeueNode(key, err)
+		return true
+	}
+
+	c.releaseNode(key)
+	return true
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// requeueNode adds a node that failed to reconcile back to the workqueue
+// After QuarantineThreshold consecutive failures the node is quarantined: it is only retried every
+// QuarantineRetryInterval (node events still enqueue it right away), so one broken node doesn't keep
+// a worker busy and flood the logs; the next successful reconcile releases it
+// Rate-limited failures don't count - they are Netmaker's fault, not the node's
+func (c *Controller) requeueNode(key string, err error) {
+	if since, quarantined := c.quarantinedSince(key); quarantined {
+		log.Printf("Quarantined node %s failed again (quarantined %s ago, next retry in %s): %v",
+			key, time.Since(since).Round(time.Second), c.options.QuarantineRetryInterval, err)
+		c.workqueue.AddAfter(key, c.options.QuarantineRetryInterval)
+		return
+	}
+
+	var rateLimited *netmaker.RateLimitError
+	threshold := c.options.QuarantineThreshold
+	if threshold > 0 && !errors.As(err, &rateLimited) && c.workqueue.NumRequeues(key)+1 >= threshold {
+		c.quarantinedMu.Lock()
+		c.quarantined[key] = time.Now()
+		count := len(c.quarantined)
+		c.quarantinedMu.Unlock()
+
+		metrics.QuarantinedNodes.Set(float64(count))
+		metrics.QuarantinedTotal.Inc()
+		log.Printf("WARNING: quarantining node %s after %d consecutive failures, retrying every %s: %v",
+			key, threshold, c.options.QuarantineRetryInterval, err)
+
+		c.workqueue.Forget(key)
+		c.workqueue.AddAfter(key, c.options.QuarantineRetryInterval)
+		return
+	}
+
+	requeue(c.workqueue, key, err)
+	runtime.HandleError(fmt.Errorf("error syncing '%s': %w, requeuing", key, err))
+}
+
+// releaseNode takes a node out of quarantine (no-op if it isn't quarantined)
+// Called after a successful reconcile and when the node is deleted
+func (c *Controller) releaseNode(key string) {
+	c.quarantinedMu.Lock()
+	since, quarantined := c.quarantined[key]
+	delete(c.quarantined, key)
+	count := len(c.quarantined)
+	c.quarantinedMu.Unlock()
+
+	if !quarantined {
+		return
+	}
+
+	metrics.QuarantinedNodes.Set(float64(count))
+	log.Printf("Released node %s from quarantine after %s", key, time.Since(since).Round(time.Second))
+}
+
+// quarantinedSince returns when a node was quarantined
+func (c *Controller) quarantinedSince(key string) (time.Time, bool) {
+	c.quarantinedMu.Lock()
+	defer c.quarantinedMu.Unlock()
+	since, quarantined := c.quarantined[key]
+	return since, quarantined
+}
+
+// quarantinedNodes returns the names of all quarantined nodes, sorted
+func (c *Controller) quarantinedNodes() []string {
+	c.quarantinedMu.Lock()
+	defer c.quarantinedMu.Unlock()
+
+	names := make([]string, 0, len(c.quarantined))
+	for name := range c.quarantined {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
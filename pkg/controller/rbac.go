@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"github.com/bsure-analytics/kaput-not/pkg/apis/v1alpha1"
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+// Permissions returns the RBAC rules the controller needs with these options
+func (o *Options) Permissions() []rbac.Permission {
+	permissions := []rbac.Permission{
+		// Node informer, plus a live GET to confirm deletions (see deleteHandler)
+		{Rule: rbac.Rule("", "nodes", "get", "list", "watch")},
+		// Node events, e.g. for nodes without a Netmaker host (see trackMissingHosts)
+		{Rule: rbac.Rule("", "events", "create", "patch")},
+	}
+
+	if o.ManageEgressRules {
+		group := v1alpha1.ClusterEgressRuleResource.Group
+		permissions = append(permissions,
+			rbac.Permission{Rule: rbac.Rule(group, v1alpha1.ClusterEgressRuleResource.Resource, "list", "watch")},
+			rbac.Permission{Rule: rbac.Rule(group, v1alpha1.ClusterEgressRuleResource.Resource+"/status", "update")},
+		)
+	}
+
+	return permissions
+}
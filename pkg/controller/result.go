@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+const (
+	// egressRulesChangedReason is the reason of the Node event emitted when a reconcile changed egress rules
+	egressRulesChangedReason = "NetmakerEgressRulesChanged"
+	// egressCollisionReason is the reason of the Node event emitted for unmanaged egress rules overlapping the node's
+	egressCollisionReason = "NetmakerEgressCollision"
+	// hostWithoutNetworkReason is the reason of the Node event emitted for nodes whose Netmaker host is in no network
+	hostWithoutNetworkReason = "NetmakerHostWithoutNetwork"
+	// hostJoinedNetworkReason is the reason of the Node event emitted when a node's host was joined to the default network
+	hostJoinedNetworkReason = "NetmakerHostJoinedNetwork"
+)
+
+// ReconcileResult describes one reconcile of a node, passed to Options.OnReconcileResult
+type ReconcileResult struct {
+	// Node is the name of the Kubernetes node
+	Node string
+
+	// RequestID is the correlation ID sent with the reconcile's Netmaker requests (X-Request-ID)
+	RequestID string
+
+	// Networks are the Netmaker networks the node was reconciled in
+	Networks []string
+
+	// Mutations are the egress rule changes made (empty if the node was already in sync)
+	Mutations []reconciler.Mutation
+
+	// Counts are the created, updated, deleted and skipped (already in sync) rules per network
+	Counts map[string]reconciler.NetworkCounts
+
+	// Collisions are the egress rules not managed by kaput-not whose ranges overlap the node's rules
+	Collisions []reconciler.Collision
+
+	// HostWithoutNetwork is true if the node's Netmaker host is in no network, so no egress rules are published
+	HostWithoutNetwork bool
+
+	// JoinedNetwork is the network the node's host was joined to because it was in none
+	JoinedNetwork string
+
+	// Resync is true if the node was reconciled by a periodic resync rather than an event
+	Resync bool
+
+	// Err is the reconcile error (nil on success); Mutations still lists the changes made before it
+	Err error
+}
+
+// networkDescriber is implemented by reconcilers describing networks with their address ranges (*reconciler.Reconciler)
+type networkDescriber interface {
+	DescribeNetwork(name string) string
+}
+
+// reportResult counts a node reconcile's egress rule outcomes, logs and emits a Node event if rules changed,
+// emits a warning Node event per unmanaged rule overlapping the node's and for a host in no network (a Node event
+// once the host was joined to the default network), and passes the result to the OnReconcileResult callback (if any)
+func (c *Controller) reportResult(node *corev1.Node, result reconciler.NodeResult, resync bool, err error) {
+	describe := func(network string) string { return network }
+	if describer, ok := c.options.Reconciler.(networkDescriber); ok {
+		describe = describer.DescribeNetwork
+	}
+	if summary := changeSummary(result.Counts, describe); summary != "" {
+		log.Printf("Changed egress rules of node %s (request %s): %s", node.Name, result.RequestID, summary)
+		c.recorder.Eventf(node, corev1.EventTypeNormal, egressRulesChangedReason, "Changed Netmaker egress rules: %s", summary)
+	}
+	for network, counts := range result.Counts {
+		for outcome, count := range map[string]int{
+			"created": counts.Created, "updated": counts.Updated, "deleted": counts.Deleted, "skipped": counts.Skipped,
+		} {
+			if count > 0 {
+				metrics.EgressRuleChanges.WithLabelValues(network, outcome).Add(float64(count))
+			}
+		}
+	}
+	for _, collision := range result.Collisions {
+		c.recorder.Eventf(node, corev1.EventTypeWarning, egressCollisionReason,
+			"Netmaker %s - traffic to the shared addresses may take either rule's gateways", collision)
+	}
+	if result.HostWithoutNetwork {
+		log.Printf("WARNING: Netmaker host %q of node %s is in no network - join it to one (or configure a default network)",
+			node.Name, node.Name)
+		c.recorder.Eventf(node, corev1.EventTypeWarning, hostWithoutNetworkReason,
+			"Netmaker host %q is in no network, so no egress rules are published for this node", node.Name)
+	}
+	if result.JoinedNetwork != "" {
+		c.recorder.Eventf(node, corev1.EventTypeNormal, hostJoinedNetworkReason,
+			"Joined Netmaker host %q to network %s, as it was in no network", node.Name, result.JoinedNetwork)
+	}
+
+	if c.options.OnReconcileResult == nil {
+		return
+	}
+	c.options.OnReconcileResult(ReconcileResult{
+		Node:               node.Name,
+		RequestID:          result.RequestID,
+		Networks:           result.Networks,
+		Mutations:          result.Mutations,
+		Counts:             result.Counts,
+		Collisions:         result.Collisions,
+		HostWithoutNetwork: result.HostWithoutNetwork,
+		JoinedNetwork:      result.JoinedNetwork,
+		Resync:             resync,
+		Err:                err,
+	})
+}
+
+// changeSummary describes the networks with changed rules, e.g. "mesh: 1 created, 0 updated, 1 deleted, 2 skipped"
+// describe names a network, e.g. with its address ranges; returns "" if no rule changed
+func changeSummary(counts map[string]reconciler.NetworkCounts, describe func(network string) string) string {
+	var parts []string
+	for _, network := range slices.Sorted(maps.Keys(counts)) {
+		count := counts[network]
+		if !count.Changed() {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d created, %d updated, %d deleted, %d skipped",
+			describe(network), count.Created, count.Updated, count.Deleted, count.Skipped))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+func TestReportResultHostWithoutNetwork(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     reconciler.NodeResult
+		wantEvents []string // "<type> <reason>" prefixes, in order
+	}{
+		{name: "host in a network", result: reconciler.NodeResult{}},
+		{
+			name:       "no default network only warns",
+			result:     reconciler.NodeResult{HostWithoutNetwork: true},
+			wantEvents: []string{corev1.EventTypeWarning + " " + hostWithoutNetworkReason},
+		},
+		{
+			name:       "joined to the default network",
+			result:     reconciler.NodeResult{JoinedNetwork: "mesh"},
+			wantEvents: []string{corev1.EventTypeNormal + " " + hostJoinedNetworkReason},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			var reported *ReconcileResult
+			c := &Controller{
+				options:  &Options{OnReconcileResult: func(result ReconcileResult) { reported = &result }},
+				recorder: recorder,
+			}
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}
+
+			c.reportResult(node, tt.result, false, nil)
+			close(recorder.Events)
+
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if len(events) != len(tt.wantEvents) {
+				t.Fatalf("events = %q, want %d", events, len(tt.wantEvents))
+			}
+			for i, want := range tt.wantEvents {
+				if !strings.HasPrefix(events[i], want) {
+					t.Errorf("event %d = %q, want %q", i, events[i], want)
+				}
+			}
+
+			if reported == nil {
+				t.Fatal("OnReconcileResult was not called")
+			}
+			if reported.HostWithoutNetwork != tt.result.HostWithoutNetwork || reported.JoinedNetwork != tt.result.JoinedNetwork {
+				t.Errorf("reported %+v, want HostWithoutNetwork = %v and JoinedNetwork = %q",
+					reported, tt.result.HostWithoutNetwork, tt.result.JoinedNetwork)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Settings are the controller options that can be changed while it runs (see ApplySettings)
+// Initialized from the Options fields of the same names
+type Settings struct {
+	PublisherSelector          string
+	IncludeWindowsNodes        bool
+	InformerCacheWarnThreshold int
+	EgressCacheWarnThreshold   int
+}
+
+// settings are Settings with the publisher selector parsed
+type settings struct {
+	Settings
+	publisherSelector labels.Selector // nil means all nodes publish
+}
+
+// settings returns the initial runtime settings
+func (o *Options) settings() Settings {
+	return Settings{
+		PublisherSelector:          o.PublisherSelector,
+		IncludeWindowsNodes:        o.IncludeWindowsNodes,
+		InformerCacheWarnThreshold: o.InformerCacheWarnThreshold,
+		EgressCacheWarnThreshold:   o.EgressCacheWarnThreshold,
+	}
+}
+
+// parseSettings validates and parses runtime settings
+func parseSettings(s Settings) (*settings, error) {
+	parsed := &settings{Settings: s}
+	if s.PublisherSelector != "" {
+		selector, err := labels.Parse(s.PublisherSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid publisher selector %q: %w", s.PublisherSelector, err)
+		}
+		parsed.publisherSelector = selector
+	}
+	return parsed, nil
+}
+
+// Settings returns the runtime settings in effect
+func (c *Controller) Settings() Settings {
+	return c.settings.Load().Settings
+}
+
+// ApplySettings changes the runtime settings without a restart
+// If node selection changed, every node (and ClusterEgressRule) is reconciled again; nodes that stopped
+// publishing keep their egress rules until the next cleanup cycle (every ResyncPeriod)
+// Returns an error and keeps the current settings if s is invalid
+func (c *Controller) ApplySettings(s Settings) error {
+	parsed, err := parseSettings(s)
+	if err != nil {
+		return err
+	}
+	if parsed.publisherSelector == nil && (c.options.SummarizePodCIDRs || c.options.Reconciler.AggregatesClusterCIDRs()) {
+		return fmt.Errorf("a publisher selector is required when publishers summarize or aggregate pod CIDRs")
+	}
+
+	previous := c.settings.Swap(parsed)
+	if previous.Settings == s {
+		return nil
+	}
+	log.Printf("Applied runtime settings: publisherSelector=%q includeWindowsNodes=%t informerCacheWarnThreshold=%d egressCacheWarnThreshold=%d",
+		s.PublisherSelector, s.IncludeWindowsNodes, s.InformerCacheWarnThreshold, s.EgressCacheWarnThreshold)
+
+	if previous.PublisherSelector != s.PublisherSelector || previous.IncludeWindowsNodes != s.IncludeWindowsNodes {
+		c.enqueueAllNodes()
+		if c.options.ManageEgressRules {
+			c.enqueueAllRules()
+		}
+		if c.options.PoolLabel != "" {
+			c.enqueueAllPools()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// State is a read-only snapshot of the controller, served on /debug/state
+type State struct {
+	Leading          bool                 `json:"leading"`
+	InformerSynced   bool                 `json:"informerSynced"`
+	WatchFailing     bool                 `json:"watchFailing,omitempty"`     // Node watch failure storm - cache may be stale
+	InformerRestarts int                  `json:"informerRestarts,omitempty"` // Node informer restarts after watch failure storms
+	QueueLength      int                  `json:"queueLength"`
+	PriorityQueue    int                  `json:"priorityQueueLength"`
+	PendingDeletes   int                  `json:"pendingDeletes"`
+	Quarantined      []string             `json:"quarantined"`
+	MissingHosts     []string             `json:"missingHosts"` // Nodes without a Netmaker host beyond HostNotFoundThreshold
+	Nodes            []NodeState          `json:"nodes"`
+	NetmakerCache    *netmaker.CacheStats `json:"netmakerCache,omitempty"`
+}
+
+// NodeState describes a single Kubernetes node as seen by the informer cache
+type NodeState struct {
+	Name      string   `json:"name"`
+	PodCIDRs  []string `json:"podCIDRs,omitempty"`
+	OS        string   `json:"os,omitempty"`
+	Arch      string   `json:"arch,omitempty"`
+	Supported bool     `json:"supported"`
+	Publisher bool     `json:"publisher"`
+	Gateway   bool     `json:"gateway,omitempty"`
+	Gated     bool     `json:"gated,omitempty"` // Carries a gating taint - rules turned off
+	Pool      string   `json:"pool,omitempty"`  // Node pool sharing egress rules (see PoolLabel)
+
+	// Netmaker is the node's Netmaker host from the Netmaker cache (nil if unknown or not cached yet)
+	Netmaker *NetmakerHostState `json:"netmaker,omitempty"`
+}
+
+// NetmakerHostState describes a Netmaker host and its per-network nodes
+type NetmakerHostState struct {
+	ID           string              `json:"id"`
+	EndpointIP   string              `json:"endpointIP,omitempty"`
+	EndpointIPv6 string              `json:"endpointIPv6,omitempty"`
+	Version      string              `json:"version,omitempty"`
+	Nodes        []NetmakerNodeState `json:"nodes"`
+}
+
+// NetmakerNodeState describes a Netmaker node (a host's membership in one network)
+type NetmakerNodeState struct {
+	ID          string     `json:"id"`
+	Network     string     `json:"network"`
+	Address     string     `json:"address,omitempty"`
+	Address6    string     `json:"address6,omitempty"`
+	Connected   bool       `json:"connected"`
+	LastCheckIn *time.Time `json:"lastCheckIn,omitempty"`
+}
+
+// cachedInventoryProvider is implemented by Netmaker clients that cache hosts and nodes
+type cachedInventoryProvider interface {
+	Cached() ([]netmaker.Host, []netmaker.Node)
+}
+
+// State returns a snapshot of the controller state
+// Reads only from the informer cache and Netmaker cache - never calls any API
+func (c *Controller) State() State {
+	state := State{
+		Leading:          c.IsLeading(),
+		InformerSynced:   c.HasSynced(),
+		WatchFailing:     c.WatchFailing(),
+		InformerRestarts: c.InformerRestarts(),
+		QueueLength:      c.workqueue.Len(),
+		PriorityQueue:    c.priorityQueue.Len(),
+		PendingDeletes:   c.deleteQueue.Len(),
+		Quarantined:      c.quarantinedNodes(),
+		MissingHosts:     c.missingHostNodes(),
+		Nodes:            []NodeState{},
+	}
+
+	hosts := c.cachedNetmakerHosts()
+
+	for _, obj := range c.nodeInformer.GetIndexer().List() {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			continue
+		}
+
+		nodeOS, nodeArch := nodePlatform(node)
+		state.Nodes = append(state.Nodes, NodeState{
+			Name:      node.Name,
+			PodCIDRs:  c.podCIDRs(node),
+			OS:        nodeOS,
+			Arch:      nodeArch,
+			Supported: c.isSupportedNode(node),
+			Publisher: c.isPublisherNode(node),
+			Gateway:   c.isGatewayNode(node),
+			Gated:     c.isGated(node),
+			Pool:      c.poolName(node),
+			Netmaker:  hosts[node.Name],
+		})
+	}
+
+	sort.Slice(state.Nodes, func(i, j int) bool {
+		return state.Nodes[i].Name < state.Nodes[j].Name
+	})
+
+	if provider, ok := c.options.NetmakerClient.(cacheStatsProvider); ok {
+		stats := provider.Stats()
+		state.NetmakerCache = &stats
+	}
+
+	return state
+}
+
+// cachedNetmakerHosts returns the cached Netmaker hosts by name, with their nodes (never calls the API)
+func (c *Controller) cachedNetmakerHosts() map[string]*NetmakerHostState {
+	provider, ok := c.options.NetmakerClient.(cachedInventoryProvider)
+	if !ok {
+		return nil
+	}
+	hosts, nodes := provider.Cached()
+
+	nodesByID := make(map[string]netmaker.Node, len(nodes))
+	for _, n := range nodes {
+		nodesByID[n.ID] = n
+	}
+
+	states := make(map[string]*NetmakerHostState, len(hosts))
+	for _, host := range hosts {
+		state := &NetmakerHostState{
+			ID:           host.ID,
+			EndpointIP:   host.EndpointIP,
+			EndpointIPv6: host.EndpointIPv6,
+			Version:      host.Version,
+			Nodes:        []NetmakerNodeState{},
+		}
+		for _, id := range host.Nodes {
+			n, ok := nodesByID[id]
+			if !ok {
+				continue
+			}
+			nodeState := NetmakerNodeState{
+				ID:        n.ID,
+				Network:   n.Network,
+				Address:   n.Address,
+				Address6:  n.Address6,
+				Connected: n.Connected,
+			}
+			if n.LastCheckIn > 0 {
+				lastCheckIn := time.Unix(n.LastCheckIn, 0).UTC()
+				nodeState.LastCheckIn = &lastCheckIn
+			}
+			state.Nodes = append(state.Nodes, nodeState)
+		}
+		sort.Slice(state.Nodes, func(i, j int) bool {
+			return state.Nodes[i].Network < state.Nodes[j].Network
+		})
+		states[host.Name] = state
+	}
+	return states
+}
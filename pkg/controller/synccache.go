@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// generationProvider is implemented by Netmaker clients that track changes of their cached data
+type generationProvider interface {
+	Generation() uint64
+}
+
+// syncedNode is what a node's last successful reconcile was based on
+type syncedNode struct {
+	resourceVersion string
+	topology        string
+	generation      uint64
+}
+
+// syncKey returns what a reconcile of node is based on, or false if the Netmaker client tracks no generation
+// The generation is read before reconciling, so changes made during the reconcile (including its own writes)
+// make the next event reconcile again
+func (c *Controller) syncKey(node *corev1.Node, topology reconciler.Topology) (syncedNode, bool) {
+	provider, ok := c.options.NetmakerClient.(generationProvider)
+	if !ok {
+		return syncedNode{}, false
+	}
+	return syncedNode{
+		resourceVersion: node.ResourceVersion,
+		topology:        fmt.Sprintf("%+v", topology),
+		generation:      provider.Generation(),
+	}, true
+}
+
+// alreadySynced reports whether a node was reconciled successfully with the same node resourceVersion,
+// topology and Netmaker cache generation, e.g. when an informer relist redelivers unchanged nodes
+// Drift is still corrected by the periodic resync, which never consults this cache
+func (c *Controller) alreadySynced(name string, key syncedNode) bool {
+	c.syncedMu.Lock()
+	defer c.syncedMu.Unlock()
+
+	synced, ok := c.synced[name]
+	return ok && synced == key
+}
+
+// recordSynced remembers what a node's successful reconcile was based on
+func (c *Controller) recordSynced(name string, key syncedNode) {
+	c.syncedMu.Lock()
+	defer c.syncedMu.Unlock()
+
+	c.synced[name] = key
+}
+
+// forgetSynced makes the next event of a node reconcile it (after failures and deletions)
+func (c *Controller) forgetSynced(name string) {
+	c.syncedMu.Lock()
+	defer c.syncedMu.Unlock()
+
+	delete(c.synced, name)
+}
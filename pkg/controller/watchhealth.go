@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+)
+
+const (
+	// watchStormThreshold node watch failures within watchStormWindow mark the informer as failing
+	// Expired resource versions don't count: they only trigger a relist. A persistently failing watch (e.g. RBAC
+	// removed at runtime) fails every 30 seconds once the reflector backoff is maxed out
+	watchStormThreshold = 5
+	watchStormWindow    = 5 * time.Minute
+
+	// watchHealthInterval is how often a watch failure storm is checked for recovery or an informer restart
+	watchHealthInterval = 30 * time.Second
+
+	// informerRestartTimeout is how long a restarted informer may take to sync before the restart counts as failed
+	informerRestartTimeout = 2 * time.Minute
+)
+
+// watchHealth tracks node watch failures to detect failure storms (see handleWatchError)
+type watchHealth struct {
+	mu          sync.Mutex
+	failures    []time.Time // Watch failures within watchStormWindow
+	lastRestart time.Time
+
+	// failing is set during a storm: the informer cache may be stale
+	failing  atomic.Bool
+	restarts atomic.Int32
+}
+
+// recordWatchFailure records a node watch failure and flags a storm once watchStormThreshold are seen within
+// watchStormWindow
+func (c *Controller) recordWatchFailure(err error) {
+	health := &c.watchHealth
+	health.mu.Lock()
+	health.failures = append(recentWatchFailures(health.failures, time.Now()), time.Now())
+	count := len(health.failures)
+	health.mu.Unlock()
+
+	if count < watchStormThreshold || health.failing.Swap(true) {
+		return
+	}
+	metrics.InformerWatchFailing.Set(1)
+	log.Printf("WARNING: node watch failed %d times within %s, last with: %v - the node cache may be stale "+
+		"(check the RBAC permissions and API server connectivity); reporting not ready", count, watchStormWindow, err)
+}
+
+// recentWatchFailures drops the failures older than watchStormWindow
+func recentWatchFailures(failures []time.Time, now time.Time) []time.Time {
+	recent := failures[:0]
+	for _, at := range failures {
+		if now.Sub(at) < watchStormWindow {
+			recent = append(recent, at)
+		}
+	}
+	return recent
+}
+
+// WatchFailing reports whether the node watch is in a failure storm, so the informer cache may be stale
+func (c *Controller) WatchFailing() bool {
+	return c.watchHealth.failing.Load()
+}
+
+// InformerRestarts returns how often the node informer was restarted after watch failure storms
+func (c *Controller) InformerRestarts() int {
+	return int(c.watchHealth.restarts.Load())
+}
+
+// runWatchHealth clears watch failure storms once the watch recovers, and restarts the node informer
+// (up to MaxInformerRestarts times, once per watchStormWindow) while a storm lasts
+func (c *Controller) runWatchHealth(ctx context.Context) {
+	ticker := time.NewTicker(watchHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		health := &c.watchHealth
+		health.mu.Lock()
+		now := time.Now()
+		health.failures = recentWatchFailures(health.failures, now)
+		recovered := len(health.failures) == 0
+		restart := !recovered && int(health.restarts.Load()) < c.options.MaxInformerRestarts &&
+			now.Sub(health.lastRestart) >= watchStormWindow
+		if restart {
+			health.lastRestart = now
+		}
+		health.mu.Unlock()
+
+		switch {
+		case !health.failing.Load():
+		case recovered:
+			c.clearWatchStorm("no watch failures within " + watchStormWindow.String())
+		case restart:
+			c.restartNodeInformer(ctx)
+		}
+	}
+}
+
+// restartNodeInformer replaces the node informer by a fresh one with a new connection, list and watch
+// The current informer keeps serving its cache until the new one has synced; if it doesn't sync within
+// informerRestartTimeout, it is dropped and the current one kept
+func (c *Controller) restartNodeInformer(ctx context.Context) {
+	restarts := c.watchHealth.restarts.Add(1)
+	metrics.InformerRestarts.Inc()
+	log.Printf("Restarting the node informer after repeated watch failures (restart %d of %d)",
+		restarts, c.options.MaxInformerRestarts)
+
+	if err := c.nodeInformer.restart(ctx, informerRestartTimeout); err != nil {
+		log.Printf("WARNING: node informer restart failed: %v", err)
+		return
+	}
+
+	// Deletions missed while the watch was failing have no events; their rules go with the next orphan cleanup
+	c.clearWatchStorm("node informer restarted")
+	c.enqueueAllNodes()
+}
+
+// clearWatchStorm ends a watch failure storm
+func (c *Controller) clearWatchStorm(reason string) {
+	health := &c.watchHealth
+	health.mu.Lock()
+	health.failures = nil
+	health.mu.Unlock()
+
+	if health.failing.Swap(false) {
+		metrics.InformerWatchFailing.Set(0)
+		log.Printf("Node watch recovered (%s)", reason)
+	}
+}
+
+// restartableInformer is the node informer, replaced by a fresh one on restart
+// Event handlers are registered again with every new informer; callers look up the indexer on every use
+type restartableInformer struct {
+	newInformer func() (cache.SharedIndexInformer, error)
+
+	mu       sync.RWMutex
+	informer cache.SharedIndexInformer
+	handlers []cache.ResourceEventHandler
+	ctx      context.Context    // Context of Run, parent of every informer's context (nil until Run)
+	stop     context.CancelFunc // Stops the current informer
+}
+
+// newRestartableInformer creates the first informer
+func newRestartableInformer(newInformer func() (cache.SharedIndexInformer, error)) (*restartableInformer, error) {
+	informer, err := newInformer()
+	if err != nil {
+		return nil, err
+	}
+	return &restartableInformer{newInformer: newInformer, informer: informer}, nil
+}
+
+// newNodeInformer creates a node informer reporting watch failures to the controller
+// No informer resync: periodic resyncs are done in bulk by resyncAllNodes
+func (c *Controller) newNodeInformer() (cache.SharedIndexInformer, error) {
+	informer := coreinformers.NewFilteredNodeInformer(
+		c.options.KubeClient,
+		0,
+		cache.Indexers{podCIDRIndex: indexByPodCIDRs},
+		func(listOptions *metav1.ListOptions) {
+			listOptions.AllowWatchBookmarks = !c.options.DisableWatchBookmarks
+		},
+	)
+
+	// Count watch failures (each one is followed by a reconnect) before delegating to default logging
+	if err := informer.SetWatchErrorHandlerWithContext(c.handleWatchError); err != nil {
+		return nil, fmt.Errorf("failed to set watch error handler: %w", err)
+	}
+	return informer, nil
+}
+
+// current returns the informer in use
+func (i *restartableInformer) current() cache.SharedIndexInformer {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.informer
+}
+
+// AddEventHandler registers an event handler with the current and all future informers
+func (i *restartableInformer) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.handlers = append(i.handlers, handler)
+	return i.informer.AddEventHandler(handler)
+}
+
+// GetIndexer returns the indexer of the current informer
+func (i *restartableInformer) GetIndexer() cache.Indexer {
+	return i.current().GetIndexer()
+}
+
+// GetStore returns the store of the current informer
+func (i *restartableInformer) GetStore() cache.Store {
+	return i.current().GetStore()
+}
+
+// HasSynced reports whether the current informer has synced
+func (i *restartableInformer) HasSynced() bool {
+	return i.current().HasSynced()
+}
+
+// Run runs the informer (and its replacements) until ctx is canceled
+func (i *restartableInformer) Run(ctx context.Context) {
+	i.mu.Lock()
+	i.ctx = ctx
+	informerCtx, stop := context.WithCancel(ctx)
+	i.stop = stop
+	informer := i.informer
+	i.mu.Unlock()
+
+	informer.Run(informerCtx.Done())
+}
+
+// restart starts a new informer and switches to it once synced, then stops the previous one
+func (i *restartableInformer) restart(ctx context.Context, timeout time.Duration) error {
+	i.mu.RLock()
+	parent := i.ctx
+	handlers := append([]cache.ResourceEventHandler(nil), i.handlers...)
+	i.mu.RUnlock()
+	if parent == nil {
+		return fmt.Errorf("informer is not running")
+	}
+
+	informer, err := i.newInformer()
+	if err != nil {
+		return err
+	}
+	for _, handler := range handlers {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return fmt.Errorf("failed to add event handler: %w", err)
+		}
+	}
+
+	informerCtx, stop := context.WithCancel(parent)
+	go informer.Run(informerCtx.Done())
+
+	syncCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		stop()
+		return fmt.Errorf("new informer did not sync within %s", timeout)
+	}
+
+	i.mu.Lock()
+	previousStop := i.stop
+	i.informer, i.stop = informer, stop
+	i.mu.Unlock()
+	previousStop()
+	return nil
+}
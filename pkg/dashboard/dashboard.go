@@ -0,0 +1,253 @@
+// Package dashboard serves a read-only status page: the Kubernetes nodes with their pod CIDRs and matched Netmaker
+// hosts, and the managed egress rules with their status - for people without kubectl or Netmaker access
+// Templates and styles are embedded in the binary; the page only reads the informer and Netmaker caches
+package dashboard
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// Path is the URL prefix the dashboard is served under
+const Path = "/dashboard/"
+
+//go:embed templates static
+var assets embed.FS
+
+// StateSource provides the controller state
+// Implemented by *controller.Controller
+type StateSource interface {
+	State() controller.State
+}
+
+// EgressCache provides the cached egress rules without calling the API
+// Implemented by *netmaker.CachedClient
+type EgressCache interface {
+	CachedEgress() map[string][]netmaker.Egress
+}
+
+// RuleOwner tells the egress rules managed by our cluster identity apart from the rest
+// Implemented by *reconciler.Reconciler
+type RuleOwner interface {
+	OwnsEgress(egress *netmaker.Egress) bool
+}
+
+// Options contains configuration for the dashboard
+type Options struct {
+	// State provides the nodes and their Netmaker hosts
+	State StateSource
+
+	// Egress provides the egress rules
+	Egress EgressCache
+
+	// Owner selects the egress rules shown
+	Owner RuleOwner
+
+	// RefreshInterval is how often the page reloads itself
+	// Default: 30 seconds
+	RefreshInterval time.Duration
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.State == nil {
+		return fmt.Errorf("State is required")
+	}
+	if o.Egress == nil {
+		return fmt.Errorf("Egress is required")
+	}
+	if o.Owner == nil {
+		return fmt.Errorf("Owner is required")
+	}
+	if o.RefreshInterval < 0 {
+		return fmt.Errorf("RefreshInterval must not be negative")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.RefreshInterval == 0 {
+		o.RefreshInterval = 30 * time.Second
+	}
+}
+
+// Dashboard is an http.Handler serving the status page and its static assets under Path
+type Dashboard struct {
+	options *Options
+
+	page   *template.Template
+	static http.Handler
+}
+
+// New creates a new dashboard
+// Returns error for validation failures or broken templates, never panics
+func New(opts *Options) (*Dashboard, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	page, err := template.New("dashboard.html").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"ago":  ago,
+	}).ParseFS(assets, "templates/dashboard.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard template: %w", err)
+	}
+
+	static, err := fs.Sub(assets, "static")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dashboard assets: %w", err)
+	}
+
+	return &Dashboard{
+		options: opts,
+		page:    page,
+		static:  http.StripPrefix(Path+"static/", http.FileServerFS(static)),
+	}, nil
+}
+
+// ServeHTTP serves the page on Path and the assets below Path/static/
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, Path+"static/") {
+		d.static.ServeHTTP(w, r)
+		return
+	}
+	if r.URL.Path != Path {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Render into a buffer, so a template error yields a clean 500 instead of half a page
+	var buf bytes.Buffer
+	if err := d.page.Execute(&buf, d.view()); err != nil {
+		log.Printf("Failed to render dashboard: %v", err)
+		http.Error(w, "failed to render dashboard", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+// view is the data the page template renders
+type view struct {
+	State     controller.State
+	Rendered  time.Time
+	Refresh   int // Seconds
+	Networks  []networkView
+	Rules     int
+	Disabled  int
+	Unmatched int // Nodes without a Netmaker host
+}
+
+// networkView holds the managed egress rules of one network
+type networkView struct {
+	Name  string
+	Rules []ruleView
+}
+
+// ruleView is a managed egress rule
+type ruleView struct {
+	Name     string
+	Range    string
+	Enabled  bool
+	Gateways []string // Host names (node IDs for hosts not matched to a Kubernetes node), primary first
+}
+
+// view assembles the page data from the caches
+func (d *Dashboard) view() view {
+	state := d.options.State.State()
+	v := view{
+		State:    state,
+		Rendered: time.Now(),
+		Refresh:  int(d.options.RefreshInterval.Seconds()),
+	}
+
+	hostNames := make(map[string]string) // Netmaker node ID -> Kubernetes node name
+	for _, node := range state.Nodes {
+		if node.Netmaker == nil {
+			v.Unmatched++
+			continue
+		}
+		for _, n := range node.Netmaker.Nodes {
+			hostNames[n.ID] = node.Name
+		}
+	}
+
+	for network, egresses := range d.options.Egress.CachedEgress() {
+		nv := networkView{Name: network}
+		for _, egress := range egresses {
+			if !d.options.Owner.OwnsEgress(&egress) {
+				continue
+			}
+			nv.Rules = append(nv.Rules, ruleView{
+				Name:     egress.Name,
+				Range:    egress.Range,
+				Enabled:  egress.Status,
+				Gateways: gatewayNames(egress.Nodes, hostNames),
+			})
+			v.Rules++
+			if !egress.Status {
+				v.Disabled++
+			}
+		}
+		if len(nv.Rules) == 0 {
+			continue
+		}
+		sort.Slice(nv.Rules, func(i, j int) bool {
+			return nv.Rules[i].Name < nv.Rules[j].Name
+		})
+		v.Networks = append(v.Networks, nv)
+	}
+	sort.Slice(v.Networks, func(i, j int) bool {
+		return v.Networks[i].Name < v.Networks[j].Name
+	})
+	return v
+}
+
+// gatewayNames returns the gateways of a rule by ascending metric (primary first), named where known
+func gatewayNames(nodes map[string]int, hostNames map[string]string) []string {
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if nodes[ids[i]] != nodes[ids[j]] {
+			return nodes[ids[i]] < nodes[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = id
+		if name, ok := hostNames[id]; ok {
+			names[i] = name
+		}
+	}
+	return names
+}
+
+// ago formats the time since t coarsely, e.g. "3m ago" ("never" for nil)
+func ago(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return time.Since(*t).Round(time.Second).String() + " ago"
+}
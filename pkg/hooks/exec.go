@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxOutput is how much of a failing hook's output (or response body) is included in its error
+const maxOutput = 1024
+
+// Exec runs a command for every mutation, with the Mutation as JSON on stdin
+// The phase, action and network are also passed as KAPUT_NOT_HOOK_PHASE, KAPUT_NOT_HOOK_ACTION and
+// KAPUT_NOT_HOOK_NETWORK; a non-zero exit in the before phase rejects the mutation
+type Exec struct {
+	// Command is the executable and its arguments
+	Command []string
+
+	// Timeout limits each run
+	// Default: 10 seconds
+	Timeout time.Duration
+}
+
+// Name implements Hook
+func (e *Exec) Name() string {
+	return "exec"
+}
+
+// Before implements Hook
+func (e *Exec) Before(ctx context.Context, m Mutation) error {
+	return e.run(ctx, m)
+}
+
+// After implements Hook
+func (e *Exec) After(ctx context.Context, m Mutation) error {
+	return e.run(ctx, m)
+}
+
+// run executes the command once
+func (e *Exec) run(ctx context.Context, m Mutation) error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("no command configured")
+	}
+
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeoutOrDefault(e.Timeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.Command[0], e.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"KAPUT_NOT_HOOK_PHASE="+m.Phase,
+		"KAPUT_NOT_HOOK_ACTION="+m.Action,
+		"KAPUT_NOT_HOOK_NETWORK="+m.Network,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", e.Command[0], err, truncate(strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// timeoutOrDefault returns timeout, or 10 seconds if it is not set
+func timeoutOrDefault(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return 10 * time.Second
+	}
+	return timeout
+}
+
+// truncate shortens s to maxOutput bytes
+func truncate(s string) string {
+	if len(s) <= maxOutput {
+		return s
+	}
+	return s[:maxOutput] + "..."
+}
@@ -0,0 +1,201 @@
+// Package hooks runs custom logic before and after kaput-not changes Netmaker egress rules
+// Hooks can validate (and reject) a mutation, open tickets or sync external systems without forking
+// the reconciler; besides the Hook interface, Exec and Webhook hooks are provided for the binary
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// Mutation phases
+const (
+	PhaseBefore = "before"
+	PhaseAfter  = "after"
+)
+
+// Mutation actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// ErrRejected is returned for mutations rejected by a hook in the before phase
+var ErrRejected = errors.New("rejected by hook")
+
+// Mutation is the payload passed to hooks (and sent as JSON by Exec and Webhook)
+type Mutation struct {
+	Phase    string `json:"phase"`
+	Action   string `json:"action"`
+	Network  string `json:"network,omitempty"` // Empty for deletes of rules never listed
+	EgressID string `json:"egressId,omitempty"`
+
+	// RequestID is the correlation ID of the reconcile making the mutation (see netmaker.RequestIDHeader)
+	RequestID string `json:"requestId,omitempty"`
+
+	// Request is the create or update request (nil for deletes)
+	Request *netmaker.EgressReq `json:"request,omitempty"`
+
+	// Egress is the rule as last listed (before, update and delete) or as returned by Netmaker (after create and update)
+	Egress *netmaker.Egress `json:"egress,omitempty"`
+
+	// Error is the Netmaker error of a failed mutation (after phase only)
+	Error string `json:"error,omitempty"`
+}
+
+// Hook is invoked around every egress rule mutation
+type Hook interface {
+	// Name identifies the hook in logs and metrics
+	Name() string
+
+	// Before is called before the mutation is sent to Netmaker; an error rejects the mutation
+	Before(ctx context.Context, m Mutation) error
+
+	// After is called once Netmaker answered; errors are only logged and counted
+	After(ctx context.Context, m Mutation) error
+}
+
+// Client decorates a Netmaker client, running hooks around CreateEgress, UpdateEgress and DeleteEgress
+// Wrap the HTTP client, not the CachedClient, so every egress listing passes through it
+type Client struct {
+	netmaker.Client // Embedded interface - automatic delegation
+
+	hooks []Hook
+
+	mu     sync.Mutex
+	egress map[string]netmaker.Egress // egress ID -> rule, learned from ListEgress
+}
+
+// NewClient wraps a client, running the hooks in order around each mutation
+func NewClient(client netmaker.Client, hooks ...Hook) *Client {
+	return &Client{
+		Client: client,
+		hooks:  hooks,
+		egress: make(map[string]netmaker.Egress),
+	}
+}
+
+// ListEgress lists the egress rules of a network and remembers them for the hook payloads
+func (c *Client) ListEgress(ctx context.Context, network string) ([]netmaker.Egress, error) {
+	egresses, err := c.Client.ListEgress(ctx, network)
+	if err != nil {
+		return egresses, err
+	}
+
+	c.mu.Lock()
+	for id, known := range c.egress {
+		if known.Network == network {
+			delete(c.egress, id)
+		}
+	}
+	for _, egress := range egresses {
+		c.egress[egress.ID] = egress
+	}
+	c.mu.Unlock()
+
+	return egresses, nil
+}
+
+// CreateEgress creates an egress rule unless a hook rejects it
+func (c *Client) CreateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	m := Mutation{Action: ActionCreate, Network: req.Network, RequestID: netmaker.RequestID(ctx), Request: &req}
+	if err := c.before(ctx, m); err != nil {
+		return nil, err
+	}
+
+	created, err := c.Client.CreateEgress(ctx, req)
+	if created != nil {
+		m.EgressID = created.ID
+	}
+	c.after(ctx, m, created, err)
+	return created, err
+}
+
+// UpdateEgress updates an egress rule unless a hook rejects it
+func (c *Client) UpdateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	m := Mutation{Action: ActionUpdate, Network: req.Network, EgressID: req.ID, RequestID: netmaker.RequestID(ctx),
+		Request: &req, Egress: c.known(req.ID)}
+	if err := c.before(ctx, m); err != nil {
+		return nil, err
+	}
+
+	updated, err := c.Client.UpdateEgress(ctx, req)
+	c.after(ctx, m, updated, err)
+	return updated, err
+}
+
+// DeleteEgress deletes an egress rule unless a hook rejects it
+func (c *Client) DeleteEgress(ctx context.Context, egressID string) error {
+	m := Mutation{Action: ActionDelete, EgressID: egressID, RequestID: netmaker.RequestID(ctx), Egress: c.known(egressID)}
+	if m.Egress != nil {
+		m.Network = m.Egress.Network
+	}
+	if err := c.before(ctx, m); err != nil {
+		return err
+	}
+
+	err := c.Client.DeleteEgress(ctx, egressID)
+	c.after(ctx, m, m.Egress, err)
+	return err
+}
+
+// before runs the before hooks in order, stopping at the first rejection
+func (c *Client) before(ctx context.Context, m Mutation) error {
+	m.Phase = PhaseBefore
+	for _, hook := range c.hooks {
+		if err := hook.Before(ctx, m); err != nil {
+			metrics.HookErrors.WithLabelValues(hook.Name(), PhaseBefore).Inc()
+			log.Printf("Hook %s rejected %s of egress rule %s in network %s: %v",
+				hook.Name(), m.Action, egressLabel(m), m.Network, err)
+			return fmt.Errorf("%w %s: %w", ErrRejected, hook.Name(), err)
+		}
+	}
+	return nil
+}
+
+// after runs every after hook, logging their errors
+func (c *Client) after(ctx context.Context, m Mutation, egress *netmaker.Egress, err error) {
+	m.Phase = PhaseAfter
+	m.Egress = egress
+	if err != nil {
+		m.Error = err.Error()
+	}
+	for _, hook := range c.hooks {
+		if err := hook.After(ctx, m); err != nil {
+			metrics.HookErrors.WithLabelValues(hook.Name(), PhaseAfter).Inc()
+			log.Printf("Hook %s failed after %s of egress rule %s in network %s: %v",
+				hook.Name(), m.Action, egressLabel(m), m.Network, err)
+		}
+	}
+}
+
+// known returns the last listed copy of an egress rule (nil if it was never listed)
+func (c *Client) known(egressID string) *netmaker.Egress {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	egress, ok := c.egress[egressID]
+	if !ok {
+		return nil
+	}
+	return &egress
+}
+
+// egressLabel names the rule of a mutation for log output
+func egressLabel(m Mutation) string {
+	switch {
+	case m.Request != nil:
+		return fmt.Sprintf("%q", m.Request.Name)
+	case m.Egress != nil:
+		return fmt.Sprintf("%q", m.Egress.Name)
+	default:
+		return m.EgressID
+	}
+}
@@ -0,0 +1,80 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// Webhook POSTs the Mutation as JSON to a URL for every mutation
+// A non-2xx response in the before phase rejects the mutation
+type Webhook struct {
+	// URL receives the POST requests
+	URL string
+
+	// Timeout limits each request
+	// Default: 10 seconds
+	Timeout time.Duration
+
+	// Client sends the requests
+	// Default: http.DefaultClient
+	Client *http.Client
+}
+
+// Name implements Hook
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Before implements Hook
+func (w *Webhook) Before(ctx context.Context, m Mutation) error {
+	return w.post(ctx, m)
+}
+
+// After implements Hook
+func (w *Webhook) After(ctx context.Context, m Mutation) error {
+	return w.post(ctx, m)
+}
+
+// post sends one request
+func (w *Webhook) post(ctx context.Context, m Mutation) error {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeoutOrDefault(w.Timeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.RequestID != "" {
+		req.Header.Set(netmaker.RequestIDHeader, m.RequestID)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package hostwatcher polls Netmaker for newly enrolled hosts, so that the Kubernetes nodes they belong to are
+// reconciled as soon as netclient enrollment completes instead of on the next resync
+// Netmaker has no watch API for hosts; listing them is a single cheap request
+package hostwatcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// HostLister lists Netmaker hosts
+// Pass an uncached client: a CachedClient would only report new hosts once its TTL expired
+type HostLister interface {
+	ListHosts(ctx context.Context) ([]netmaker.Host, error)
+}
+
+// Options contains configuration for the watcher
+type Options struct {
+	// Client lists the Netmaker hosts
+	Client HostLister
+
+	// Interval is how often the hosts are listed
+	// Default: 30 seconds
+	Interval time.Duration
+
+	// OnEnrolled is called with the names of hosts that appeared since the previous listing
+	OnEnrolled func(hostNames []string)
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.Client == nil {
+		return fmt.Errorf("Client is required")
+	}
+	if o.OnEnrolled == nil {
+		return fmt.Errorf("OnEnrolled is required")
+	}
+	if o.Interval < 0 {
+		return fmt.Errorf("Interval must not be negative")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.Interval == 0 {
+		o.Interval = 30 * time.Second
+	}
+}
+
+// Watcher reports Netmaker hosts that appeared between two listings
+type Watcher struct {
+	options *Options
+
+	known map[string]bool // Host names of the last successful listing, nil before the first
+}
+
+// New creates a new watcher
+// Returns error for validation failures, never panics
+func New(opts *Options) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	return &Watcher{options: opts}, nil
+}
+
+// Run lists the hosts every Interval and blocks until the context is canceled
+// The first listing only establishes the baseline: hosts enrolled before are handled by the regular reconcile
+func (w *Watcher) Run(ctx context.Context) {
+	defer runtime.HandleCrash()
+
+	ticker := time.NewTicker(w.options.Interval)
+	defer ticker.Stop()
+
+	w.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+// poll lists the hosts and notifies OnEnrolled about the new ones
+// A failed listing keeps the previous baseline, so hosts enrolled meanwhile are reported by the next one
+func (w *Watcher) poll(ctx context.Context) {
+	hosts, err := w.options.Client.ListHosts(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to list Netmaker hosts for enrollment detection: %v", err)
+		}
+		return
+	}
+
+	known := make(map[string]bool, len(hosts))
+	var enrolled []string
+	for _, host := range hosts {
+		known[host.Name] = true
+		if w.known != nil && !w.known[host.Name] {
+			enrolled = append(enrolled, host.Name)
+		}
+	}
+	w.known = known
+
+	if len(enrolled) > 0 {
+		sort.Strings(enrolled)
+		w.options.OnEnrolled(enrolled)
+	}
+}
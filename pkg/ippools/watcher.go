@@ -0,0 +1,212 @@
+// Package ippools tracks the pod CIDRs Cilium multi-pool IPAM allocates to each node
+// Only pools matching a label selector are advertised, so namespaces assigned to other pools
+// (ipam.cilium.io/ip-pool annotation) keep their pod IPs out of the mesh
+package ippools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+var (
+	// PoolResource is the CiliumPodIPPool resource (cluster-scoped)
+	PoolResource = schema.GroupVersionResource{Group: "cilium.io", Version: "v2alpha1", Resource: "ciliumpodippools"}
+	// NodeResource is the CiliumNode resource (cluster-scoped, named after the Kubernetes node)
+	NodeResource = schema.GroupVersionResource{Group: "cilium.io", Version: "v2", Resource: "ciliumnodes"}
+)
+
+// Options contains configuration for the watcher
+type Options struct {
+	// DynamicClient reads CiliumPodIPPools and CiliumNodes
+	DynamicClient dynamic.Interface
+
+	// Selector is a label selector for the advertised CiliumPodIPPools (e.g. "kaput-not.io/advertise=true")
+	// Default: empty (all pools are advertised)
+	Selector string
+
+	// ResyncPeriod is how often the informers resync
+	// Default: 10 minutes
+	ResyncPeriod time.Duration
+
+	// OnChange is called with the names of nodes whose advertised CIDRs changed (optional)
+	OnChange func(nodeNames []string)
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.DynamicClient == nil {
+		return fmt.Errorf("DynamicClient is required")
+	}
+	if _, err := labels.Parse(o.Selector); err != nil {
+		return fmt.Errorf("invalid pool selector %q: %w", o.Selector, err)
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.ResyncPeriod == 0 {
+		o.ResyncPeriod = 10 * time.Minute
+	}
+}
+
+// Watcher maps nodes to the CIDRs allocated to them from the selected pools
+// CiliumNode spec.ipam.pools.allocated lists the CIDRs per pool; pool labels decide what is advertised
+type Watcher struct {
+	options  *Options
+	selector labels.Selector
+
+	poolInformer cache.SharedIndexInformer
+	nodeInformer cache.SharedIndexInformer
+	synced       []cache.InformerSynced
+
+	mu        sync.RWMutex
+	nodeCIDRs map[string][]string // node name -> advertised CIDRs, sorted
+}
+
+// New creates a new watcher
+// Returns error for validation failures, never panics
+func New(opts *Options) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	selector, _ := labels.Parse(opts.Selector) // Validated above
+
+	w := &Watcher{
+		options:   opts,
+		selector:  selector,
+		nodeCIDRs: make(map[string][]string),
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(opts.DynamicClient, opts.ResyncPeriod)
+	w.poolInformer = factory.ForResource(PoolResource).Informer()
+	w.nodeInformer = factory.ForResource(NodeResource).Informer()
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { w.refresh() },
+		UpdateFunc: func(_, _ interface{}) { w.refresh() },
+		DeleteFunc: func(interface{}) { w.refresh() },
+	}
+	for _, informer := range []cache.SharedIndexInformer{w.poolInformer, w.nodeInformer} {
+		registration, err := informer.AddEventHandler(handler)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add event handler: %w", err)
+		}
+		w.synced = append(w.synced, registration.HasSynced)
+	}
+
+	return w, nil
+}
+
+// Run starts the informers and blocks until the context is canceled
+func (w *Watcher) Run(ctx context.Context) {
+	defer runtime.HandleCrash()
+	go w.poolInformer.Run(ctx.Done())
+	w.nodeInformer.Run(ctx.Done())
+}
+
+// WaitForSync blocks until the initial pools and nodes have been processed or ctx is canceled
+// Returns false if ctx was canceled first
+func (w *Watcher) WaitForSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), w.synced...)
+}
+
+// NodeCIDRs returns the CIDRs allocated to a node from the selected pools (empty if none)
+func (w *Watcher) NodeCIDRs(nodeName string) []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.nodeCIDRs[nodeName]
+}
+
+// refresh recomputes the advertised CIDRs of every node and notifies OnChange about the changed ones
+func (w *Watcher) refresh() {
+	selected := make(map[string]bool)
+	for _, obj := range w.poolInformer.GetStore().List() {
+		pool, ok := obj.(*unstructured.Unstructured)
+		if ok && w.selector.Matches(labels.Set(pool.GetLabels())) {
+			selected[pool.GetName()] = true
+		}
+	}
+
+	nodeCIDRs := make(map[string][]string)
+	for _, obj := range w.nodeInformer.GetStore().List() {
+		node, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if cidrs := allocatedCIDRs(node, selected); len(cidrs) > 0 {
+			nodeCIDRs[node.GetName()] = cidrs
+		}
+	}
+
+	w.mu.Lock()
+	var changed []string
+	for name, cidrs := range nodeCIDRs {
+		if strings.Join(w.nodeCIDRs[name], ",") != strings.Join(cidrs, ",") {
+			changed = append(changed, name)
+		}
+	}
+	for name := range w.nodeCIDRs {
+		if _, exists := nodeCIDRs[name]; !exists {
+			changed = append(changed, name)
+		}
+	}
+	w.nodeCIDRs = nodeCIDRs
+	w.mu.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+	sort.Strings(changed)
+	log.Printf("Advertised IP pool CIDRs changed for %d node(s): %s", len(changed), strings.Join(changed, ", "))
+	if w.options.OnChange != nil {
+		w.options.OnChange(changed)
+	}
+}
+
+// allocatedCIDRs returns the CIDRs a CiliumNode was allocated from the selected pools, sorted
+func allocatedCIDRs(node *unstructured.Unstructured, selected map[string]bool) []string {
+	allocations, _, _ := unstructured.NestedSlice(node.Object, "spec", "ipam", "pools", "allocated")
+
+	var cidrs []string
+	for _, item := range allocations {
+		allocation, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pool, _, _ := unstructured.NestedString(allocation, "pool")
+		if !selected[pool] {
+			continue
+		}
+		poolCIDRs, _, _ := unstructured.NestedStringSlice(allocation, "cidrs")
+		cidrs = append(cidrs, poolCIDRs...)
+	}
+
+	sort.Strings(cidrs)
+	return cidrs
+}
+
+// Permissions returns the RBAC rules the watcher's informers need
+func (o *Options) Permissions() []rbac.Permission {
+	return []rbac.Permission{
+		{Rule: rbac.Rule(PoolResource.Group, PoolResource.Resource, "list", "watch")},
+		{Rule: rbac.Rule(NodeResource.Group, NodeResource.Resource, "list", "watch")},
+	}
+}
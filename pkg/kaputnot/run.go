@@ -0,0 +1,100 @@
+// Package kaputnot embeds kaput-not's node to Netmaker egress sync in another process (e.g. an operator's manager)
+// Everything is configured through Options - nothing is read from the environment, flags or files
+//
+// Run only does the leader work: the embedding process is responsible for leader election, health probes
+// and serving metrics (see metrics.Registry)
+package kaputnot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bsure-analytics/kaput-not/pkg/controller"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/reconciler"
+)
+
+// Options configures an embedded kaput-not instance
+type Options struct {
+	// KubeClient is the Kubernetes client
+	KubeClient kubernetes.Interface
+
+	// DynamicClient reads the kaput-not custom resources (required when Controller.ManageEgressRules is set)
+	DynamicClient dynamic.Interface
+
+	// NetmakerClient is the Netmaker API client, e.g. from netmaker.NewHTTPClientWithAuthenticator
+	// Run wraps it in the caching layer; authentication happens on the first request
+	// Clients without ListNetworks work without network metadata (see netmaker.AsClient)
+	NetmakerClient netmaker.BasicClient
+
+	// NetmakerCacheTTL is how long Netmaker hosts, nodes and egress rules are cached
+	// Default: 30 seconds
+	NetmakerCacheTTL time.Duration
+
+	// Reconciler configures the egress rule logic; Run sets its NetmakerClient
+	// A StateStore with Load and Run methods (e.g. *statestore.ConfigMapStore) is loaded and flushed by Run
+	Reconciler reconciler.Options
+
+	// Controller configures node watching; Run sets its KubeClient, DynamicClient, NetmakerClient and Reconciler
+	Controller controller.Options
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.KubeClient == nil {
+		return fmt.Errorf("KubeClient is required")
+	}
+	if o.NetmakerClient == nil {
+		return fmt.Errorf("NetmakerClient is required")
+	}
+	if o.NetmakerCacheTTL < 0 {
+		return fmt.Errorf("NetmakerCacheTTL must not be negative")
+	}
+	return nil
+}
+
+// persistentStore is implemented by state stores that must be loaded before reconciling and flushed in the background
+type persistentStore interface {
+	Load(ctx context.Context) error
+	Run(ctx context.Context)
+}
+
+// Run syncs node pod CIDRs to Netmaker egress rules and blocks until ctx is canceled
+// Returns an error for invalid options or if the instance fails to start, never panics
+func Run(ctx context.Context, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+
+	cachedClient := netmaker.NewCachedClient(netmaker.AsClient(opts.NetmakerClient), opts.NetmakerCacheTTL)
+
+	recOpts := opts.Reconciler
+	recOpts.NetmakerClient = cachedClient
+	rec, err := reconciler.New(&recOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create reconciler: %w", err)
+	}
+
+	ctrlOpts := opts.Controller
+	ctrlOpts.KubeClient = opts.KubeClient
+	ctrlOpts.DynamicClient = opts.DynamicClient
+	ctrlOpts.NetmakerClient = cachedClient
+	ctrlOpts.Reconciler = rec
+	ctrl, err := controller.New(&ctrlOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create controller: %w", err)
+	}
+
+	if store, ok := recOpts.StateStore.(persistentStore); ok {
+		if err := store.Load(ctx); err != nil {
+			return fmt.Errorf("failed to load state store: %w", err)
+		}
+		go store.Run(ctx)
+	}
+
+	return ctrl.Run(ctx)
+}
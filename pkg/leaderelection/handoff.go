@@ -0,0 +1,85 @@
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// HandoffAnnotation holds the state a leader leaves for its successor when it releases the lease
+	HandoffAnnotation = "kaput-not.io/handoff"
+	// HandoffTransitionsAnnotation holds the lease's leader transitions at the release, so only the next leader
+	// trusts the handoff - not one taking over after a successor crashed
+	HandoffTransitionsAnnotation = "kaput-not.io/handoff-transitions"
+)
+
+// handoffLock decorates a resource lock to annotate the lease with the handoff state when it is released
+// client-go releases the lease (ReleaseOnCancel) with an update that has no holder, after re-reading the lease
+type handoffLock struct {
+	resourcelock.Interface // Embedded interface - automatic delegation
+
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	handoff   func() string
+}
+
+// Update annotates the lease before a release, then delegates
+func (l *handoffLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	if ler.HolderIdentity != "" {
+		return l.Interface.Update(ctx, ler)
+	}
+
+	if err := l.annotate(ctx, ler.LeaderTransitions); err != nil {
+		log.Printf("Failed to store the leader handoff state (the next leader runs a full cleanup): %v", err)
+	} else if _, _, err := l.Interface.Get(ctx); err != nil {
+		return err // Re-read, the annotation changed the lease's resource version
+	}
+	return l.Interface.Update(ctx, ler)
+}
+
+// annotate stores the handoff state in the lease annotations
+// Only called while this replica still holds the lease, so nobody else writes it in between
+func (l *handoffLock) annotate(ctx context.Context, transitions int) error {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	lease, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.Identity() {
+		return fmt.Errorf("lease %s/%s is no longer held by %s", l.namespace, l.name, l.Identity())
+	}
+
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string)
+	}
+	lease.Annotations[HandoffAnnotation] = l.handoff()
+	lease.Annotations[HandoffTransitionsAnnotation] = strconv.Itoa(transitions)
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// ReadHandoff returns the state the previous leader left on a graceful release, or "" if there is none
+// Call it after becoming the leader: the state is only returned to the direct successor of the releasing leader
+func ReadHandoff(ctx context.Context, client kubernetes.Interface, lockNamespace, lockName, identity string) (string, error) {
+	lease, err := client.CoordinationV1().Leases(lockNamespace).Get(ctx, lockName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read lease %s/%s: %w", lockNamespace, lockName, err)
+	}
+
+	state, ok := lease.Annotations[HandoffAnnotation]
+	if !ok || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != identity || lease.Spec.LeaseTransitions == nil {
+		return "", nil
+	}
+	transitions, err := strconv.Atoi(lease.Annotations[HandoffTransitionsAnnotation])
+	if err != nil || *lease.Spec.LeaseTransitions != int32(transitions)+1 {
+		return "", nil // Left for an earlier leader
+	}
+	return state, nil
+}
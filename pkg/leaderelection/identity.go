@@ -0,0 +1,71 @@
+package leaderelection
+
+import (
+	"os"
+	"sync"
+)
+
+// Identity returns the election identity of this replica: the pod name and UID from the downward API
+// (POD_NAME and POD_UID), falling back to the hostname
+// Pods using hostNetwork share the node's hostname, so the pod name keeps their identities apart, and the
+// UID tells a restarted pod (e.g. of a StatefulSet) from its predecessor still holding the lease
+func Identity(podName, podUID string) string {
+	switch {
+	case podName != "" && podUID != "":
+		return podName + "_" + podUID
+	case podName != "":
+		return podName
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "unknown"
+	}
+	return hostname
+}
+
+// Status is this replica's view of the leader election, served on /debug/leader
+type Status struct {
+	LeaderElection bool   `json:"leaderElection"`   // False for a single replica running without election
+	Identity       string `json:"identity"`         // Identity of this replica
+	Holder         string `json:"holder,omitempty"` // Identity of the current leader (empty until observed)
+	Leading        bool   `json:"leading"`
+}
+
+// Tracker records the leader election status of this replica
+// Pass it as Config.Tracker; safe for concurrent use
+type Tracker struct {
+	mu     sync.RWMutex
+	status Status
+}
+
+// NewTracker creates a tracker for a replica with the given identity
+// Without leader election, the replica is its own leader from the start
+func NewTracker(identity string, leaderElection bool) *Tracker {
+	t := &Tracker{status: Status{LeaderElection: leaderElection, Identity: identity}}
+	if !leaderElection {
+		t.status.Holder = identity
+		t.status.Leading = true
+	}
+	return t
+}
+
+// Status returns the current status
+func (t *Tracker) Status() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.status
+}
+
+// setHolder records the identity of a newly observed leader
+func (t *Tracker) setHolder(holder string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Holder = holder
+}
+
+// setLeading records whether this replica leads
+func (t *Tracker) setLeading(leading bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status.Leading = leading
+}
@@ -3,13 +3,14 @@ package leaderelection
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
 )
 
 // Config contains configuration for leader election
@@ -23,9 +24,12 @@ type Config struct {
 	// LockNamespace is the namespace for the lease resource
 	LockNamespace string
 
-	// Identity is the unique identity of this replica (defaults to hostname)
+	// Identity is the unique identity of this replica (defaults to hostname, see Identity for pods)
 	Identity string
 
+	// Tracker records the election status of this replica (optional)
+	Tracker *Tracker
+
 	// LeaseDuration is how long the leader lease is valid
 	// Default: 15 seconds
 	LeaseDuration time.Duration
@@ -46,6 +50,10 @@ type Config struct {
 
 	// OnNewLeader is called when a new leader is elected
 	OnNewLeader func(identity string)
+
+	// Handoff returns the state stored for the next leader when the lease is released on shutdown (optional)
+	// The next leader reads it with ReadHandoff
+	Handoff func() string
 }
 
 // Validate validates the configuration
@@ -68,12 +76,7 @@ func (c *Config) Validate() error {
 // ApplyDefaults applies default values to the configuration
 func (c *Config) ApplyDefaults() {
 	if c.Identity == "" {
-		hostname, err := os.Hostname()
-		if err != nil {
-			c.Identity = "unknown"
-		} else {
-			c.Identity = hostname
-		}
+		c.Identity = Identity("", "")
 	}
 
 	if c.LeaseDuration == 0 {
@@ -106,8 +109,30 @@ func Run(ctx context.Context, config *Config) error {
 	}
 	config.ApplyDefaults()
 
+	callbacks := leaderelection.LeaderCallbacks{
+		OnStartedLeading: config.OnStartedLeading,
+		OnStoppedLeading: config.OnStoppedLeading,
+		OnNewLeader:      config.OnNewLeader,
+	}
+	if tracker := config.Tracker; tracker != nil {
+		callbacks = leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				tracker.setLeading(true)
+				config.OnStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				tracker.setLeading(false)
+				config.OnStoppedLeading()
+			},
+			OnNewLeader: func(identity string) {
+				tracker.setHolder(identity)
+				config.OnNewLeader(identity)
+			},
+		}
+	}
+
 	// Create resource lock using Lease (recommended for Kubernetes 1.14+)
-	lock := &resourcelock.LeaseLock{
+	var lock resourcelock.Interface = &resourcelock.LeaseLock{
 		LeaseMeta: metav1.ObjectMeta{
 			Name:      config.LockName,
 			Namespace: config.LockNamespace,
@@ -117,6 +142,15 @@ func Run(ctx context.Context, config *Config) error {
 			Identity: config.Identity,
 		},
 	}
+	if config.Handoff != nil {
+		lock = &handoffLock{
+			Interface: lock,
+			client:    config.KubeClient,
+			namespace: config.LockNamespace,
+			name:      config.LockName,
+			handoff:   config.Handoff,
+		}
+	}
 
 	// Create leader elector
 	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
@@ -125,11 +159,7 @@ func Run(ctx context.Context, config *Config) error {
 		LeaseDuration:   config.LeaseDuration,
 		RenewDeadline:   config.RenewDeadline,
 		RetryPeriod:     config.RetryPeriod,
-		Callbacks: leaderelection.LeaderCallbacks{
-			OnStartedLeading: config.OnStartedLeading,
-			OnStoppedLeading: config.OnStoppedLeading,
-			OnNewLeader:      config.OnNewLeader,
-		},
+		Callbacks:       callbacks,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create leader elector: %w", err)
@@ -140,3 +170,11 @@ func Run(ctx context.Context, config *Config) error {
 
 	return nil
 }
+
+// Permissions returns the RBAC rules for a Lease lock (the lease is created on first use)
+func Permissions(lockNamespace, lockName string) []rbac.Permission {
+	return []rbac.Permission{
+		{Namespace: lockNamespace, Rule: rbac.Rule("coordination.k8s.io", "leases", "create")},
+		{Namespace: lockNamespace, Rule: rbac.NamedRule("coordination.k8s.io", "leases", []string{lockName}, "get", "update")},
+	}
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// zoneKey is the context key of the topology zone of the node being synced
+type zoneKey struct{}
+
+// WithZone returns a context whose Netmaker calls are attributed to a topology zone (see InstrumentNetmaker)
+func WithZone(ctx context.Context, zone string) context.Context {
+	return context.WithValue(ctx, zoneKey{}, zone)
+}
+
+// Zone returns the topology zone of ctx (empty if none)
+func Zone(ctx context.Context) string {
+	zone, _ := ctx.Value(zoneKey{}).(string)
+	return zone
+}
+
+// instrumentedClient observes the duration and result of every Netmaker call in NetmakerRequestDuration
+type instrumentedClient struct {
+	netmaker.Client // Embedded interface - automatic delegation
+}
+
+// InstrumentNetmaker wraps a client to record NetmakerRequestDuration
+// Wrap the HTTP client, so only real API calls (no cache hits) are observed
+func InstrumentNetmaker(client netmaker.Client) netmaker.Client {
+	return &instrumentedClient{Client: client}
+}
+
+// observe records a call that started at start
+// Deletes carry no network, so their network label is empty
+func observe(ctx context.Context, operation, network string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	NetmakerRequestDuration.WithLabelValues(operation, network, Zone(ctx), result).Observe(time.Since(start).Seconds())
+}
+
+// Authenticate implements netmaker.Client
+func (c *instrumentedClient) Authenticate(ctx context.Context) error {
+	start := time.Now()
+	err := c.Client.Authenticate(ctx)
+	observe(ctx, "authenticate", "", start, err)
+	return err
+}
+
+// ListHosts implements netmaker.Client
+func (c *instrumentedClient) ListHosts(ctx context.Context) ([]netmaker.Host, error) {
+	start := time.Now()
+	hosts, err := c.Client.ListHosts(ctx)
+	observe(ctx, "list_hosts", "", start, err)
+	return hosts, err
+}
+
+// ListNodes implements netmaker.Client
+func (c *instrumentedClient) ListNodes(ctx context.Context) ([]netmaker.Node, error) {
+	start := time.Now()
+	nodes, err := c.Client.ListNodes(ctx)
+	observe(ctx, "list_nodes", "", start, err)
+	return nodes, err
+}
+
+// ListEgress implements netmaker.Client
+func (c *instrumentedClient) ListEgress(ctx context.Context, network string) ([]netmaker.Egress, error) {
+	start := time.Now()
+	egresses, err := c.Client.ListEgress(ctx, network)
+	observe(ctx, "list_egress", network, start, err)
+	return egresses, err
+}
+
+// CreateEgress implements netmaker.Client
+func (c *instrumentedClient) CreateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	start := time.Now()
+	egress, err := c.Client.CreateEgress(ctx, req)
+	observe(ctx, "create_egress", req.Network, start, err)
+	return egress, err
+}
+
+// UpdateEgress implements netmaker.Client
+func (c *instrumentedClient) UpdateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	start := time.Now()
+	egress, err := c.Client.UpdateEgress(ctx, req)
+	observe(ctx, "update_egress", req.Network, start, err)
+	return egress, err
+}
+
+// DeleteEgress implements netmaker.Client
+func (c *instrumentedClient) DeleteEgress(ctx context.Context, egressID string) error {
+	start := time.Now()
+	err := c.Client.DeleteEgress(ctx, egressID)
+	observe(ctx, "delete_egress", "", start, err)
+	return err
+}
+
+// ListExtClients implements netmaker.Client
+func (c *instrumentedClient) ListExtClients(ctx context.Context, network string) ([]netmaker.ExtClient, error) {
+	start := time.Now()
+	extClients, err := c.Client.ListExtClients(ctx, network)
+	observe(ctx, "list_extclients", network, start, err)
+	return extClients, err
+}
+
+// UpdateExtClientAllowedIPs implements netmaker.Client
+func (c *instrumentedClient) UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error {
+	start := time.Now()
+	err := c.Client.UpdateExtClientAllowedIPs(ctx, network, clientID, allowedIPs)
+	observe(ctx, "update_extclient", network, start, err)
+	return err
+}
+
+// ListNetworks implements netmaker.Client
+func (c *instrumentedClient) ListNetworks(ctx context.Context) ([]netmaker.Network, error) {
+	start := time.Now()
+	networks, err := c.Client.ListNetworks(ctx)
+	observe(ctx, "list_networks", "", start, err)
+	return networks, err
+}
+
+// GetNetwork implements netmaker.Client
+func (c *instrumentedClient) GetNetwork(ctx context.Context, netID string) (*netmaker.Network, error) {
+	start := time.Now()
+	network, err := c.Client.GetNetwork(ctx, netID)
+	observe(ctx, "get_network", netID, start, err)
+	return network, err
+}
+
+// CreateNetwork implements netmaker.Client
+func (c *instrumentedClient) CreateNetwork(ctx context.Context, network netmaker.Network) (*netmaker.Network, error) {
+	start := time.Now()
+	created, err := c.Client.CreateNetwork(ctx, network)
+	observe(ctx, "create_network", network.NetID, start, err)
+	return created, err
+}
+
+// AddHostToNetwork implements netmaker.Client
+func (c *instrumentedClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	start := time.Now()
+	err := c.Client.AddHostToNetwork(ctx, hostID, network)
+	observe(ctx, "add_host_to_network", network, start, err)
+	return err
+}
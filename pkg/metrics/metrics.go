@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/bsure-analytics/kaput-not/pkg/version"
+)
+
+// Namespace is the common prefix for all kaput-not metrics
+const Namespace = "kaput_not"
+
+// Registry holds all kaput-not metrics
+// A dedicated registry keeps our output free of client-go's global registrations
+var Registry = prometheus.NewRegistry()
+
+var (
+	// InformerCachedObjects is the number of Node objects held in the informer cache
+	InformerCachedObjects = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "informer_cached_objects",
+		Help:      "Number of Kubernetes Node objects held in the informer cache.",
+	})
+
+	// NetmakerCacheEntries is the number of entries held in the Netmaker cache by kind
+	NetmakerCacheEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "netmaker_cache_entries",
+		Help:      "Number of entries held in the Netmaker response cache.",
+	}, []string{"kind"})
+
+	// ReconcileTotal counts node reconciliations by node OS, architecture, topology zone and result
+	ReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "reconcile_total",
+		Help:      "Number of node reconciliations by node OS, architecture, topology zone and result (success, error, skipped, unchanged).",
+	}, []string{"os", "arch", "zone", "result"})
+
+	// NetworkReconcileTotal counts node reconciliations per Netmaker network by node topology zone and result
+	NetworkReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "network_reconcile_total",
+		Help:      "Number of node reconciliations in each Netmaker network by node topology zone and result (success, error).",
+	}, []string{"network", "zone", "result"})
+
+	// NetmakerRequestDuration observes Netmaker API calls (see InstrumentNetmaker)
+	NetmakerRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "netmaker_request_duration_seconds",
+		Help:      "Duration of Netmaker API calls by operation, network, topology zone of the node being synced and result (success, error).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "network", "zone", "result"})
+
+	// InformerWatchErrors counts informer watch failures by reason; each failure triggers a reconnect
+	InformerWatchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "informer_watch_errors_total",
+		Help:      "Number of informer watch failures (each followed by a reconnect) by reason (expired, eof, other).",
+	}, []string{"reason"})
+
+	// InformerWatchFailing is 1 during a node watch failure storm, while the informer cache may be stale
+	InformerWatchFailing = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "informer_watch_failing",
+		Help:      "Whether the node watch fails repeatedly, so the informer cache may be stale (1) or not (0).",
+	})
+
+	// InformerRestarts counts node informer restarts after watch failure storms
+	InformerRestarts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "informer_restarts_total",
+		Help:      "Number of node informer restarts after repeated watch failures.",
+	})
+
+	// RateLimitedRequeues counts work items requeued after Netmaker's Retry-After (HTTP 429/503)
+	RateLimitedRequeues = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "rate_limited_requeues_total",
+		Help:      "Number of work items requeued after Netmaker's Retry-After because of HTTP 429 or 503 responses.",
+	})
+
+	// PriorityEnqueues counts node keys added to the priority lane (host appeared, recreated after delete)
+	PriorityEnqueues = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "priority_enqueues_total",
+		Help:      "Number of node reconciles queued in the priority lane ahead of bulk resync work.",
+	})
+
+	// ExternalChanges counts changes to managed egress rules made outside kaput-not, by network and kind
+	ExternalChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "external_changes_total",
+		Help:      "Number of changes to managed egress rules made outside kaput-not by network and kind (modified, deleted, created).",
+	}, []string{"network", "kind"})
+
+	// UnmanagedEgressCollisions counts reconciles finding an unmanaged egress rule overlapping one of ours, by network
+	UnmanagedEgressCollisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "unmanaged_egress_collisions_total",
+		Help:      "Number of times an egress rule not managed by kaput-not was found with a range identical to or overlapping a managed rule, by network (counted on every reconcile until resolved).",
+	}, []string{"network"})
+
+	// IdentityConflictWrites counts egress rules created or rewritten by another kaput-not using our cluster identity
+	IdentityConflictWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "cluster_identity_conflict_writes_total",
+		Help:      "Number of egress rules created or rewritten outside kaput-not with this cluster's identity (cluster name and instance ID), by network.",
+	}, []string{"network"})
+
+	// IdentityConflict is 1 once another kaput-not was found writing egress rules as our cluster identity
+	IdentityConflict = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "cluster_identity_conflict",
+		Help:      "Whether another kaput-not writes egress rules with this cluster's name and instance ID (1, orphan cleanup paused until restart) or not (0).",
+	})
+
+	// ClusterNetworkInfo exposes the cluster pod and service subnets (value is always 1)
+	ClusterNetworkInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "cluster_network_info",
+		Help:      "Cluster-wide subnets by kind (pod, service) and source ConfigMap (kubeadm-config, kube-proxy); value is always 1.",
+	}, []string{"kind", "cidr", "source"})
+
+	// NodesWithoutNetmakerHost is the number of managed nodes without a Netmaker host beyond the threshold
+	NodesWithoutNetmakerHost = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "nodes_without_netmaker_host",
+		Help:      "Number of nodes with pod CIDRs but no matching Netmaker host for longer than the host-not-found threshold.",
+	})
+
+	// QuarantinedNodes is the number of nodes currently quarantined after repeated reconcile failures
+	QuarantinedNodes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "quarantined_nodes",
+		Help:      "Number of nodes quarantined after repeated reconcile failures (retried at a slow interval).",
+	})
+
+	// QuarantinedTotal counts nodes entering quarantine
+	QuarantinedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "quarantined_total",
+		Help:      "Number of times a node was quarantined after repeated reconcile failures.",
+	})
+
+	// NodeUpdates counts node update events by the predicate rule they matched
+	NodeUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "node_updates_total",
+		Help:      "Number of node update events by matching rule (status-only and ignored for updates without reaction).",
+	}, []string{"rule"})
+
+	// ChurnStormActive is 1 while mass node churn has switched the controller to bulk reconciliation
+	ChurnStormActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "churn_storm_active",
+		Help:      "Whether mass node churn switched the controller to bulk reconciliation (1) or not (0).",
+	})
+
+	// ChurnSuppressedEvents counts node events left to the bulk resync (add, fanout) or deferred (delete) during churn
+	ChurnSuppressedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "churn_suppressed_events_total",
+		Help:      "Number of node events not processed individually during mass node churn, by event (add, fanout, delete).",
+	}, []string{"event"})
+
+	// EgressRuleChanges counts the outcome of node reconciles per egress rule
+	EgressRuleChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "egress_rule_changes_total",
+		Help:      "Number of node egress rules by reconcile outcome (created, updated, deleted, skipped) and network.",
+	}, []string{"network", "outcome"})
+
+	// StaleCacheRetries counts node reconciles retried because a cached egress rule was already gone in Netmaker
+	StaleCacheRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "stale_cache_retries_total",
+		Help:      "Number of node reconciles retried in a network after an egress rule from the cache was not found in Netmaker.",
+	}, []string{"network"})
+
+	// HeldEgressRules is the number of held egress rules kept for nodes no longer in Kubernetes, by network
+	HeldEgressRules = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "held_egress_rules",
+		Help:      "Number of egress rules marked held=true whose Kubernetes node is gone, by network; purge them once the node is not coming back.",
+	}, []string{"network"})
+
+	// EgressRuleReconcileTotal counts ClusterEgressRule reconciliations by result
+	EgressRuleReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "egress_rule_reconcile_total",
+		Help:      "Number of ClusterEgressRule reconciliations by result (success, error, deleted).",
+	}, []string{"result"})
+
+	// PoolReconcileTotal counts node pool reconciliations by result
+	PoolReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "pool_reconcile_total",
+		Help:      "Number of node pool reconciliations by result (success, error, deleted).",
+	}, []string{"result"})
+
+	// HookErrors counts failed mutation hooks by hook and phase; before-phase errors rejected the mutation
+	HookErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "hook_errors_total",
+		Help:      "Number of failed mutation hook runs by hook and phase (before-phase failures rejected the mutation).",
+	}, []string{"hook", "phase"})
+
+	// BuildInfo exposes the build information of the running binary (value is always 1)
+	BuildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "build_info",
+		Help:      "Build information (version, commit, Go version, supported Netmaker versions); value is always 1.",
+	}, []string{"version", "commit", "go_version", "netmaker_api"})
+)
+
+func init() {
+	// Go and process collectors expose goroutines, heap and RSS (go_goroutines, go_memstats_*, process_*)
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		InformerCachedObjects,
+		NetmakerCacheEntries,
+		ReconcileTotal,
+		NetworkReconcileTotal,
+		NetmakerRequestDuration,
+		InformerWatchErrors,
+		InformerWatchFailing,
+		InformerRestarts,
+		RateLimitedRequeues,
+		PriorityEnqueues,
+		ExternalChanges,
+		UnmanagedEgressCollisions,
+		IdentityConflictWrites,
+		IdentityConflict,
+		ClusterNetworkInfo,
+		EgressRuleReconcileTotal,
+		PoolReconcileTotal,
+		QuarantinedNodes,
+		QuarantinedTotal,
+		NodeUpdates,
+		ChurnStormActive,
+		ChurnSuppressedEvents,
+		EgressRuleChanges,
+		StaleCacheRetries,
+		HeldEgressRules,
+		NodesWithoutNetmakerHost,
+		HookErrors,
+		BuildInfo,
+	)
+
+	info := version.Get()
+	BuildInfo.WithLabelValues(info.Version, info.Commit, info.GoVersion, info.NetmakerAPI).Set(1)
+}
+
+// Handler returns an HTTP handler serving the registry in Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+var (
+	netmakerListAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "netmaker", "last_successful_list_age_seconds"),
+		"Seconds since the last successful Netmaker list call by kind (hosts, nodes, egress); egress lists are per network.",
+		[]string{"kind", "network"}, nil,
+	)
+	netmakerCacheHitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "netmaker_cache", "hits_total"),
+		"Number of Netmaker reads served from the cache by kind.",
+		[]string{"kind"}, nil,
+	)
+	netmakerCacheMissesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "netmaker_cache", "misses_total"),
+		"Number of Netmaker reads that went to the API (expired or empty cache) by kind.",
+		[]string{"kind"}, nil,
+	)
+	netmakerCacheEvictionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "netmaker_cache", "evictions_total"),
+		"Number of fresh Netmaker cache entries dropped by writes or invalidation by kind.",
+		[]string{"kind"}, nil,
+	)
+)
+
+// netmakerCacheCollector exports Netmaker cache freshness and counters, computed at scrape time
+type netmakerCacheCollector struct {
+	stats func() netmaker.CacheStats
+}
+
+// RegisterNetmakerCache registers the cache freshness and hit/miss/eviction metrics of a Netmaker cache
+// Only one cache can be registered per process
+func RegisterNetmakerCache(stats func() netmaker.CacheStats) error {
+	err := Registry.Register(&netmakerCacheCollector{stats: stats})
+	if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+		return errors.New("a Netmaker cache is already registered")
+	}
+	return err
+}
+
+// Describe implements prometheus.Collector
+func (c *netmakerCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- netmakerListAgeDesc
+	ch <- netmakerCacheHitsDesc
+	ch <- netmakerCacheMissesDesc
+	ch <- netmakerCacheEvictionsDesc
+}
+
+// Collect implements prometheus.Collector
+// Kinds that were never listed successfully have no age series
+func (c *netmakerCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	listAge := func(kind, network string, listedAt time.Time) {
+		if listedAt.IsZero() {
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(netmakerListAgeDesc, prometheus.GaugeValue,
+			time.Since(listedAt).Seconds(), kind, network)
+	}
+	listAge(string(netmaker.CacheKindHosts), "", stats.LastListed.Hosts)
+	listAge(string(netmaker.CacheKindNodes), "", stats.LastListed.Nodes)
+	listAge(string(netmaker.CacheKindNetworks), "", stats.LastListed.Networks)
+	for network, listedAt := range stats.LastListed.Egress {
+		listAge(string(netmaker.CacheKindEgress), network, listedAt)
+	}
+
+	for kind, counters := range stats.Counters {
+		ch <- prometheus.MustNewConstMetric(netmakerCacheHitsDesc, prometheus.CounterValue, float64(counters.Hits), string(kind))
+		ch <- prometheus.MustNewConstMetric(netmakerCacheMissesDesc, prometheus.CounterValue, float64(counters.Misses), string(kind))
+		ch <- prometheus.MustNewConstMetric(netmakerCacheEvictionsDesc, prometheus.CounterValue, float64(counters.Evictions), string(kind))
+	}
+}
+
+var readOnlySkippedDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(Namespace, "read_only", "skipped_mutations_total"),
+	"Number of Netmaker mutations skipped in read-only networks by action (create, update, delete, extclient); a growing count means drift.",
+	[]string{"network", "action"}, nil,
+)
+
+// readOnlyCollector exports the mutations skipped in read-only networks, computed at scrape time
+type readOnlyCollector struct {
+	skipped func() map[string]map[string]uint64
+}
+
+// RegisterReadOnlyNetworks registers the skipped mutation counters of a netmaker.ReadOnlyClient
+func RegisterReadOnlyNetworks(skipped func() map[string]map[string]uint64) error {
+	return Registry.Register(&readOnlyCollector{skipped: skipped})
+}
+
+// Describe implements prometheus.Collector
+func (c *readOnlyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- readOnlySkippedDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *readOnlyCollector) Collect(ch chan<- prometheus.Metric) {
+	for network, actions := range c.skipped() {
+		for action, count := range actions {
+			ch <- prometheus.MustNewConstMetric(readOnlySkippedDesc, prometheus.CounterValue, float64(count), network, action)
+		}
+	}
+}
+
+var unhealthyGatewaysDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(Namespace, "", "unhealthy_gateway_nodes"),
+	"Number of nodes whose egress rules are turned off because none of their gateways is healthy, by network.",
+	[]string{"network"}, nil,
+)
+
+// unhealthyGatewaysCollector exports the nodes with unhealthy gateways, computed at scrape time
+type unhealthyGatewaysCollector struct {
+	unhealthy func() map[string]int
+}
+
+// RegisterUnhealthyGateways registers the unhealthy gateway gauge of a reconciler (see reconciler.Options.GatewayHealthCheck)
+func RegisterUnhealthyGateways(unhealthy func() map[string]int) error {
+	return Registry.Register(&unhealthyGatewaysCollector{unhealthy: unhealthy})
+}
+
+// Describe implements prometheus.Collector
+func (c *unhealthyGatewaysCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- unhealthyGatewaysDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *unhealthyGatewaysCollector) Collect(ch chan<- prometheus.Metric) {
+	for network, count := range c.unhealthy() {
+		ch <- prometheus.MustNewConstMetric(unhealthyGatewaysDesc, prometheus.GaugeValue, float64(count), network)
+	}
+}
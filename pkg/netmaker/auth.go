@@ -0,0 +1,208 @@
+package netmaker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// tokenExchangeGrantType is the RFC 8693 grant type for token exchange
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// tokenTypeJWT is the RFC 8693 token type for Kubernetes service account tokens
+	tokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// Authenticator obtains a bearer token for the Netmaker API
+// Implementations must be safe for concurrent use
+type Authenticator interface {
+	// Authenticate returns a fresh bearer token, using client for any HTTP calls
+	Authenticate(ctx context.Context, client *http.Client) (string, error)
+}
+
+// PasswordAuthenticator logs in with a Netmaker username and password
+// Uses POST /api/users/adm/authenticate unless changed with SetLoginEndpoint
+type PasswordAuthenticator struct {
+	baseURL  string
+	authURL  string
+	endpoint LoginEndpoint
+	source   CredentialSource
+}
+
+// NewPasswordAuthenticator creates an authenticator for fixed Netmaker user credentials
+// Returns error for validation failures, never panics
+func NewPasswordAuthenticator(baseURL, username, password string) (*PasswordAuthenticator, error) {
+	source, err := NewStaticCredentialSource(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPasswordAuthenticatorWithSource(baseURL, source)
+}
+
+// NewPasswordAuthenticatorWithSource creates an authenticator that fetches credentials on every login
+// Returns error for validation failures, never panics
+func NewPasswordAuthenticatorWithSource(baseURL string, source CredentialSource) (*PasswordAuthenticator, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("baseURL is required")
+	}
+	if source == nil {
+		return nil, fmt.Errorf("credential source is required")
+	}
+
+	authenticator := &PasswordAuthenticator{baseURL: baseURL, source: source}
+	_ = authenticator.SetLoginEndpoint(LoginEndpoint{}) // Stock login - always valid
+	return authenticator, nil
+}
+
+// Authenticate obtains a JWT token from Netmaker API
+func (a *PasswordAuthenticator) Authenticate(ctx context.Context, client *http.Client) (string, error) {
+	credentials, err := a.source.Credentials(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	payload := map[string]string{
+		a.endpoint.UsernameField: credentials.Username,
+		a.endpoint.PasswordField: credentials.Password,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal auth payload: %w", err)
+	}
+	defer clear(body) // Holds the password
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.authURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create auth request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setRequestID(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("authentication request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		return "", fmt.Errorf("authentication failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	// Validate Content-Type is JSON
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "application/json") {
+		return "", fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
+	}
+
+	// Decoded generically, the token may be anywhere in customized responses (see LoginEndpoint.TokenField)
+	var document interface{}
+	if err := decodeLimited(resp.Body, maxAuthResponseBytes, &document); err != nil {
+		return "", fmt.Errorf("failed to decode auth response: %w", err)
+	}
+
+	// Check JSON Code field if present
+	if code, message := apiStatus(document); code != 0 && code != http.StatusOK {
+		return "", fmt.Errorf("authentication failed with API code %d: %s", code, message)
+	}
+
+	// Validate we got a token
+	token := tokenAt(document, a.endpoint.TokenField)
+	if token == "" {
+		return "", fmt.Errorf("authentication succeeded but no token in response field %s", a.endpoint.TokenField)
+	}
+
+	return token, nil
+}
+
+// TokenExchangeAuthenticator exchanges the pod's projected service account token for a Netmaker session
+// Implements the RFC 8693 token exchange flow against the OIDC provider Netmaker trusts,
+// removing the need for static Netmaker credentials in Secrets
+type TokenExchangeAuthenticator struct {
+	exchangeURL string
+	tokenFile   string
+	audience    string
+}
+
+// NewTokenExchangeAuthenticator creates an authenticator for the token exchange flow
+// tokenFile is re-read on every authentication because the kubelet rotates projected tokens
+// audience is optional and passed through as the RFC 8693 audience parameter
+func NewTokenExchangeAuthenticator(exchangeURL, tokenFile, audience string) (*TokenExchangeAuthenticator, error) {
+	if exchangeURL == "" {
+		return nil, fmt.Errorf("exchangeURL is required")
+	}
+	if tokenFile == "" {
+		return nil, fmt.Errorf("tokenFile is required")
+	}
+
+	return &TokenExchangeAuthenticator{
+		exchangeURL: exchangeURL,
+		tokenFile:   tokenFile,
+		audience:    audience,
+	}, nil
+}
+
+// Authenticate exchanges the current service account token for a Netmaker bearer token
+func (a *TokenExchangeAuthenticator) Authenticate(ctx context.Context, client *http.Client) (string, error) {
+	subjectToken, err := os.ReadFile(a.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", tokenExchangeGrantType)
+	form.Set("subject_token", strings.TrimSpace(string(subjectToken)))
+	form.Set("subject_token_type", tokenTypeJWT)
+	if a.audience != "" {
+		form.Set("audience", a.audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.exchangeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token exchange request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token exchange request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		return "", fmt.Errorf("token exchange failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	// Validate Content-Type is JSON
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "application/json") {
+		return "", fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
+	}
+
+	var exchangeResp TokenExchangeResponse
+	if err := decodeLimited(resp.Body, maxAuthResponseBytes, &exchangeResp); err != nil {
+		return "", fmt.Errorf("failed to decode token exchange response: %w", err)
+	}
+
+	// Check OAuth error field if present
+	if exchangeResp.Error != "" {
+		return "", fmt.Errorf("token exchange failed with error %s: %s", exchangeResp.Error, exchangeResp.ErrorDescription)
+	}
+
+	// Validate we got a token
+	if exchangeResp.AccessToken == "" {
+		return "", fmt.Errorf("token exchange succeeded but no access token in response")
+	}
+
+	return exchangeResp.AccessToken, nil
+}
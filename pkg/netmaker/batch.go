@@ -0,0 +1,48 @@
+package netmaker
+
+import (
+	"context"
+	"sync"
+)
+
+// EgressDeleter deletes egress rules by ID (Client, its decorators and the reconciler's views of it)
+type EgressDeleter interface {
+	DeleteEgress(ctx context.Context, egressID string) error
+}
+
+// DeleteEgressBatch deletes egress rules with at most concurrency deletes in flight, continuing past failures
+// Netmaker has no bulk delete endpoint, so the batch fans out into single deletes, each in the context of its rule's
+// network (see WithNetwork); MutationLimitClient still bounds the writes of the whole process
+// Deletes not started yet when ctx is done fail with its error
+// Returns the error of each rule (nil if deleted), in the order of egresses
+func DeleteEgressBatch(ctx context.Context, deleter EgressDeleter, egresses []Egress, concurrency int) []error {
+	errs := make([]error, len(egresses))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range egresses {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			errs[i] = deleter.DeleteEgress(WithNetwork(ctx, egresses[i].Network), egresses[i].ID)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,127 @@
+package netmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDeleter deletes egress rules slowly, tracking the deletes in flight
+// Rules in fail fail; deleting the rule in cancelOn calls cancel; deletes in a done context fail with its error
+type recordingDeleter struct {
+	delay    time.Duration
+	fail     map[string]bool
+	cancelOn string
+	cancel   context.CancelFunc
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	deleted     []string
+}
+
+// DeleteEgress implements EgressDeleter
+func (d *recordingDeleter) DeleteEgress(ctx context.Context, egressID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.inFlight++
+	d.maxInFlight = max(d.maxInFlight, d.inFlight)
+	d.mu.Unlock()
+
+	time.Sleep(d.delay)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if egressID == d.cancelOn {
+		d.cancel()
+	}
+	if d.fail[egressID] {
+		return fmt.Errorf("delete %s failed", egressID)
+	}
+	d.deleted = append(d.deleted, egressID)
+	return nil
+}
+
+// egressIDs returns n egress rules e0..e<n-1>
+func egressIDs(n int) []Egress {
+	egresses := make([]Egress, n)
+	for i := range egresses {
+		egresses[i] = Egress{ID: fmt.Sprintf("e%d", i), Network: "mesh"}
+	}
+	return egresses
+}
+
+func TestDeleteEgressBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       int
+		concurrency int
+		fail        []string
+		cancelOn    string
+		wantPeak    int
+		wantFailed  []int // Indexes failing with the deleter's error
+		wantCancel  []int // Indexes failing with context.Canceled
+		wantDeleted int
+	}{
+		{name: "sequential", rules: 3, concurrency: 1, wantPeak: 1, wantDeleted: 3},
+		{name: "concurrency below 1 is sequential", rules: 3, concurrency: 0, wantPeak: 1, wantDeleted: 3},
+		{name: "bounded concurrency", rules: 8, concurrency: 3, wantPeak: 3, wantDeleted: 8},
+		{name: "fewer rules than slots", rules: 2, concurrency: 4, wantPeak: 2, wantDeleted: 2},
+		{name: "empty batch", rules: 0, concurrency: 4, wantPeak: 0, wantDeleted: 0},
+		{
+			name: "continues past failures", rules: 6, concurrency: 2, fail: []string{"e1", "e4"},
+			wantPeak: 2, wantFailed: []int{1, 4}, wantDeleted: 4,
+		},
+		{
+			name: "cancelled deletes fail with the context error", rules: 4, concurrency: 1, cancelOn: "e1",
+			wantPeak: 1, wantCancel: []int{2, 3}, wantDeleted: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			deleter := &recordingDeleter{delay: 10 * time.Millisecond, fail: make(map[string]bool), cancelOn: tt.cancelOn, cancel: cancel}
+			for _, id := range tt.fail {
+				deleter.fail[id] = true
+			}
+
+			egresses := egressIDs(tt.rules)
+			errs := DeleteEgressBatch(ctx, deleter, egresses, tt.concurrency)
+
+			if len(errs) != len(egresses) {
+				t.Fatalf("DeleteEgressBatch() returned %d errors, want one per rule (%d)", len(errs), len(egresses))
+			}
+			for i, err := range errs {
+				switch {
+				case slices.Contains(tt.wantFailed, i):
+					if err == nil || err.Error() != fmt.Sprintf("delete %s failed", egresses[i].ID) {
+						t.Errorf("errs[%d] = %v, want the failure of %s", i, err, egresses[i].ID)
+					}
+				case slices.Contains(tt.wantCancel, i):
+					if !errors.Is(err, context.Canceled) {
+						t.Errorf("errs[%d] = %v, want %v", i, err, context.Canceled)
+					}
+				case err != nil:
+					t.Errorf("errs[%d] = %v, want nil", i, err)
+				}
+			}
+			if deleter.maxInFlight != tt.wantPeak {
+				t.Errorf("deletes in flight peaked at %d, want %d", deleter.maxInFlight, tt.wantPeak)
+			}
+			if len(deleter.deleted) != tt.wantDeleted {
+				t.Errorf("deleted %v, want %d rules", deleter.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
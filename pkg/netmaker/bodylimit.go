@@ -0,0 +1,61 @@
+package netmaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultMaxResponseBytes is the default size limit of Netmaker API response bodies
+	// Generous for the egress lists of large meshes, but keeps a misbehaving endpoint from exhausting memory
+	DefaultMaxResponseBytes int64 = 64 << 20
+
+	// maxAuthResponseBytes limits login, token exchange and Vault responses, which only carry tokens
+	maxAuthResponseBytes int64 = 1 << 20
+
+	// maxErrorBodyBytes limits the error response bodies quoted in error messages
+	maxErrorBodyBytes int64 = 4 << 10
+)
+
+// ErrResponseTooLarge is returned when a response body exceeds its size limit
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// readLimited reads r up to limit bytes, failing with ErrResponseTooLarge if there is more
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return body, nil
+}
+
+// decodeLimited decodes a JSON body of at most limit bytes into out
+func decodeLimited(r io.Reader, limit int64, out any) error {
+	body, err := readLimited(r, limit)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// errorBody reads the start of an error response body for error messages, ignoring read errors
+func errorBody(r io.Reader) []byte {
+	body, _ := io.ReadAll(io.LimitReader(r, maxErrorBodyBytes))
+	return body
+}
+
+// SetMaxResponseBytes limits the size of API response bodies (default DefaultMaxResponseBytes)
+// Larger responses fail with ErrResponseTooLarge instead of being read into memory
+// Must be called before the client is used
+func (c *HTTPClient) SetMaxResponseBytes(limit int64) error {
+	if limit <= 0 {
+		return fmt.Errorf("max response size must be positive, got %d", limit)
+	}
+	c.maxResponseBytes = limit
+	return nil
+}
@@ -3,7 +3,10 @@ package netmaker
 import (
 	"context"
 	"fmt"
+	"maps"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,13 +26,37 @@ type CachedClient struct {
 	nodes          []Node
 	nodesFetchedAt time.Time
 
+	// Networks cache (global) - address ranges and default settings change rarely
+	networks          []Network
+	networksFetchedAt time.Time
+
 	// Per-network caches
 	egressByNetwork map[string][]Egress
 	egressFetchedAt map[string]time.Time
+	egressEvictions uint64 // Number of egress evictions and stored writes so far, lets PrefetchEgress detect writes during its lists
+
+	// Last successful list per kind, kept across invalidations (see CacheStats.LastListed)
+	hostsListedAt    time.Time
+	nodesListedAt    time.Time
+	networksListedAt time.Time
+	egressListedAt   map[string]time.Time
+
+	// Hit, miss and eviction counters per kind (hosts, nodes, networks, egress)
+	counters map[CacheKind]*cacheCounters
+
+	// generation changes whenever cached data changes (see Generation)
+	generation atomic.Uint64
 
 	ttl time.Duration
 }
 
+// cacheCounters counts lookups and evictions of one cache kind
+type cacheCounters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
 // NewCachedClient wraps a client with TTL-based caching
 // Default TTL is 30 seconds if ttl is 0
 func NewCachedClient(client Client, ttl time.Duration) *CachedClient {
@@ -41,7 +68,14 @@ func NewCachedClient(client Client, ttl time.Duration) *CachedClient {
 		Client:          client, // Embedded interface
 		egressByNetwork: make(map[string][]Egress),
 		egressFetchedAt: make(map[string]time.Time),
-		ttl:             ttl,
+		egressListedAt:  make(map[string]time.Time),
+		counters: map[CacheKind]*cacheCounters{
+			CacheKindHosts:    {},
+			CacheKindNodes:    {},
+			CacheKindNetworks: {},
+			CacheKindEgress:   {},
+		},
+		ttl: ttl,
 	}
 }
 
@@ -55,6 +89,7 @@ func (c *CachedClient) ListHosts(ctx context.Context) ([]Host, error) {
 	if time.Since(c.hostsFetchedAt) < c.ttl {
 		hosts := c.hosts
 		c.mu.RUnlock()
+		c.counters[CacheKindHosts].hits.Add(1)
 		return hosts, nil
 	}
 	c.mu.RUnlock()
@@ -65,18 +100,24 @@ func (c *CachedClient) ListHosts(ctx context.Context) ([]Host, error) {
 
 	// Double-checked locking: another goroutine might have fetched while we waited
 	if time.Since(c.hostsFetchedAt) < c.ttl {
+		c.counters[CacheKindHosts].hits.Add(1)
 		return c.hosts, nil
 	}
 
 	// Fetch fresh data
+	c.counters[CacheKindHosts].misses.Add(1)
 	hosts, err := c.Client.ListHosts(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update cache
+	if !reflect.DeepEqual(c.hosts, hosts) {
+		c.generation.Add(1)
+	}
 	c.hosts = hosts
 	c.hostsFetchedAt = time.Now()
+	c.hostsListedAt = c.hostsFetchedAt
 
 	return hosts, nil
 }
@@ -88,6 +129,7 @@ func (c *CachedClient) ListNodes(ctx context.Context) ([]Node, error) {
 	if time.Since(c.nodesFetchedAt) < c.ttl {
 		nodes := c.nodes
 		c.mu.RUnlock()
+		c.counters[CacheKindNodes].hits.Add(1)
 		return nodes, nil
 	}
 	c.mu.RUnlock()
@@ -98,22 +140,97 @@ func (c *CachedClient) ListNodes(ctx context.Context) ([]Node, error) {
 
 	// Double-checked locking
 	if time.Since(c.nodesFetchedAt) < c.ttl {
+		c.counters[CacheKindNodes].hits.Add(1)
 		return c.nodes, nil
 	}
 
 	// Fetch fresh data
+	c.counters[CacheKindNodes].misses.Add(1)
 	nodes, err := c.Client.ListNodes(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update cache
+	if !reflect.DeepEqual(c.nodes, nodes) {
+		c.generation.Add(1)
+	}
 	c.nodes = nodes
 	c.nodesFetchedAt = time.Now()
+	c.nodesListedAt = c.nodesFetchedAt
 
 	return nodes, nil
 }
 
+// ListNetworks returns cached networks or fetches fresh data if cache is stale
+func (c *CachedClient) ListNetworks(ctx context.Context) ([]Network, error) {
+	// Fast path: check cache with read lock
+	c.mu.RLock()
+	if time.Since(c.networksFetchedAt) < c.ttl {
+		networks := c.networks
+		c.mu.RUnlock()
+		c.counters[CacheKindNetworks].hits.Add(1)
+		return networks, nil
+	}
+	c.mu.RUnlock()
+
+	// Cache miss - acquire write lock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Double-checked locking
+	if time.Since(c.networksFetchedAt) < c.ttl {
+		c.counters[CacheKindNetworks].hits.Add(1)
+		return c.networks, nil
+	}
+
+	// Fetch fresh data
+	c.counters[CacheKindNetworks].misses.Add(1)
+	networks, err := c.Client.ListNetworks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update cache
+	if !reflect.DeepEqual(c.networks, networks) {
+		c.generation.Add(1)
+	}
+	c.networks = networks
+	c.networksFetchedAt = time.Now()
+	c.networksListedAt = c.networksFetchedAt
+
+	return networks, nil
+}
+
+// CreateNetwork delegates to underlying client and drops the networks cache
+func (c *CachedClient) CreateNetwork(ctx context.Context, network Network) (*Network, error) {
+	created, err := c.Client.CreateNetwork(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.evictNetworks()
+	c.mu.Unlock()
+
+	return created, nil
+}
+
+// AddHostToNetwork delegates to underlying client and drops the hosts and nodes caches
+func (c *CachedClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	if err := c.Client.AddHostToNetwork(ctx, hostID, network); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.evictHosts()
+	c.evictNodes()
+	c.mu.Unlock()
+	c.generation.Add(1)
+
+	return nil
+}
+
 // GetNodeIDsByHostname returns all Netmaker node IDs for a host by matching the hostname
 // This is a CachedClient-specific helper method (not part of the Client interface)
 // It uses cached ListHosts() to get node IDs directly from the host.Nodes field
@@ -141,6 +258,7 @@ func (c *CachedClient) ListEgress(ctx context.Context, network string) ([]Egress
 		if time.Since(fetchedAt) < c.ttl {
 			egresses := c.egressByNetwork[network]
 			c.mu.RUnlock()
+			c.counters[CacheKindEgress].hits.Add(1)
 			return egresses, nil
 		}
 	}
@@ -153,53 +271,91 @@ func (c *CachedClient) ListEgress(ctx context.Context, network string) ([]Egress
 	// Double-checked locking
 	if fetchedAt, exists := c.egressFetchedAt[network]; exists {
 		if time.Since(fetchedAt) < c.ttl {
+			c.counters[CacheKindEgress].hits.Add(1)
 			return c.egressByNetwork[network], nil
 		}
 	}
 
 	// Fetch fresh data
+	c.counters[CacheKindEgress].misses.Add(1)
 	egresses, err := c.Client.ListEgress(ctx, network)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update cache
+	if !reflect.DeepEqual(c.egressByNetwork[network], egresses) {
+		c.generation.Add(1)
+	}
 	c.egressByNetwork[network] = egresses
 	c.egressFetchedAt[network] = time.Now()
+	c.egressListedAt[network] = c.egressFetchedAt[network]
 
 	return egresses, nil
 }
 
-// CreateEgress invalidates cache and delegates to underlying client
+// CreateEgress delegates to underlying client and adds the created rule to the cache (see storeEgress)
 func (c *CachedClient) CreateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
 	egress, err := c.Client.CreateEgress(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	// Invalidate egress cache for this network
-	c.mu.Lock()
-	delete(c.egressByNetwork, req.Network)
-	delete(c.egressFetchedAt, req.Network)
-	c.mu.Unlock()
-
+	c.storeEgress(req.Network, egress)
 	return egress, nil
 }
 
-// UpdateEgress invalidates cache and delegates to underlying client
+// UpdateEgress delegates to underlying client and replaces the rule in the cache (see storeEgress)
 func (c *CachedClient) UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
 	egress, err := c.Client.UpdateEgress(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	// Invalidate egress cache for this network
+	c.storeEgress(req.Network, egress)
+	return egress, nil
+}
+
+// writeSkipper is implemented by clients that report writes as successful without sending them (*ReadOnlyClient)
+type writeSkipper interface {
+	skipsWrites(network string) bool
+}
+
+// storeEgress puts a rule returned by a write into the network's cached list, replacing the rule with the same ID,
+// so reconciles within the TTL read our own writes without listing again; the TTL still runs from the last list
+// Falls back to evicting the network when it isn't cached, the response has no ID, or the write was skipped
+// (read-only networks must keep showing their drift)
+func (c *CachedClient) storeEgress(network string, egress *Egress) {
 	c.mu.Lock()
-	delete(c.egressByNetwork, req.Network)
-	delete(c.egressFetchedAt, req.Network)
-	c.mu.Unlock()
+	defer c.mu.Unlock()
+	defer c.generation.Add(1)
 
-	return egress, nil
+	fetchedAt, cached := c.egressFetchedAt[network]
+	skipper, skips := c.Client.(writeSkipper)
+	if !cached || time.Since(fetchedAt) >= c.ttl || egress == nil || egress.ID == "" || (skips && skipper.skipsWrites(network)) {
+		c.evictEgress(network)
+		return
+	}
+
+	stored := *egress
+	if stored.Network == "" {
+		stored.Network = network
+	}
+	// Callers may hold the cached slice, so it is copied instead of modified in place
+	egresses := make([]Egress, 0, len(c.egressByNetwork[network])+1)
+	replaced := false
+	for _, existing := range c.egressByNetwork[network] {
+		if existing.ID == stored.ID {
+			existing = stored
+			replaced = true
+		}
+		egresses = append(egresses, existing)
+	}
+	if !replaced {
+		egresses = append(egresses, stored)
+	}
+	c.egressByNetwork[network] = egresses
+	c.egressEvictions++ // Lists running concurrently must not replace the stored rule (see PrefetchEgress)
 }
 
 // DeleteEgress invalidates cache and delegates to underlying client
@@ -211,9 +367,218 @@ func (c *CachedClient) DeleteEgress(ctx context.Context, egressID string) error
 
 	// Invalidate ALL egress caches (we don't know which network the egress belongs to)
 	c.mu.Lock()
-	c.egressByNetwork = make(map[string][]Egress)
-	c.egressFetchedAt = make(map[string]time.Time)
+	c.evictEgress("")
 	c.mu.Unlock()
+	c.generation.Add(1)
+
+	return nil
+}
+
+// ListExtClients, UpdateExtClientAllowedIPs and GetNetwork are not overridden -
+// automatically delegate to embedded Client
+// (External clients are synced rarely and should always be read fresh; GetNetwork checks existence before creating)
+
+// CacheKind identifies a cache for Invalidate
+type CacheKind string
+
+const (
+	// CacheKindAll invalidates every cache
+	CacheKindAll CacheKind = "all"
+	// CacheKindHosts invalidates the host cache
+	CacheKindHosts CacheKind = "hosts"
+	// CacheKindNodes invalidates the node cache
+	CacheKindNodes CacheKind = "nodes"
+	// CacheKindNetworks invalidates the network cache
+	CacheKindNetworks CacheKind = "networks"
+	// CacheKindEgress invalidates the egress cache of one network (or all networks)
+	CacheKindEgress CacheKind = "egress"
+)
+
+// Invalidate drops cached data so the next read fetches fresh data from Netmaker
+// network only applies to CacheKindEgress - empty invalidates all networks
+// Useful after manual changes in Netmaker that should be picked up before the TTL expires
+func (c *CachedClient) Invalidate(kind CacheKind, network string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch kind {
+	case CacheKindAll:
+		c.evictHosts()
+		c.evictNodes()
+		c.evictNetworks()
+		c.evictEgress("")
+	case CacheKindHosts:
+		c.evictHosts()
+	case CacheKindNodes:
+		c.evictNodes()
+	case CacheKindNetworks:
+		c.evictNetworks()
+	case CacheKindEgress:
+		c.evictEgress(network)
+	default:
+		return fmt.Errorf("unknown cache kind %q", kind)
+	}
 
 	return nil
 }
+
+// evictHosts drops the host cache, counting an eviction if it was still fresh
+// Must be called with mu held
+func (c *CachedClient) evictHosts() {
+	if time.Since(c.hostsFetchedAt) < c.ttl {
+		c.counters[CacheKindHosts].evictions.Add(1)
+	}
+	c.hostsFetchedAt = time.Time{}
+}
+
+// evictNodes drops the node cache, counting an eviction if it was still fresh
+// Must be called with mu held
+func (c *CachedClient) evictNodes() {
+	if time.Since(c.nodesFetchedAt) < c.ttl {
+		c.counters[CacheKindNodes].evictions.Add(1)
+	}
+	c.nodesFetchedAt = time.Time{}
+}
+
+// evictNetworks drops the network cache, counting an eviction if it was still fresh
+// Must be called with mu held
+func (c *CachedClient) evictNetworks() {
+	if time.Since(c.networksFetchedAt) < c.ttl {
+		c.counters[CacheKindNetworks].evictions.Add(1)
+	}
+	c.networksFetchedAt = time.Time{}
+}
+
+// evictEgress drops the egress cache of one network (empty: all networks), counting one eviction
+// per network that was still fresh
+// Must be called with mu held
+func (c *CachedClient) evictEgress(network string) {
+	c.egressEvictions++
+	if network == "" {
+		for _, fetchedAt := range c.egressFetchedAt {
+			if time.Since(fetchedAt) < c.ttl {
+				c.counters[CacheKindEgress].evictions.Add(1)
+			}
+		}
+		c.egressByNetwork = make(map[string][]Egress)
+		c.egressFetchedAt = make(map[string]time.Time)
+		return
+	}
+
+	if fetchedAt, cached := c.egressFetchedAt[network]; cached && time.Since(fetchedAt) < c.ttl {
+		c.counters[CacheKindEgress].evictions.Add(1)
+	}
+	delete(c.egressByNetwork, network)
+	delete(c.egressFetchedAt, network)
+}
+
+// Generation returns a counter that changes whenever a list returns different hosts, nodes, networks or egress rules
+// than cached before, and on every egress write; equal generations mean nothing changed in between
+// Data that wasn't read again is not checked, so an unchanged generation doesn't rule out changes in Netmaker
+func (c *CachedClient) Generation() uint64 {
+	return c.generation.Load()
+}
+
+// Cached returns the cached hosts and nodes without fetching, even if expired (nil if never listed)
+// For status reporting, which must never call the API
+func (c *CachedClient) Cached() ([]Host, []Node) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hosts, c.nodes
+}
+
+// CachedNetworks returns the cached networks by name without fetching, even if expired (nil if never listed)
+// For logs and status reporting, which must never call the API
+func (c *CachedClient) CachedNetworks() map[string]Network {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.networks == nil {
+		return nil
+	}
+	networks := make(map[string]Network, len(c.networks))
+	for _, network := range c.networks {
+		networks[network.NetID] = network
+	}
+	return networks
+}
+
+// CachedEgress returns the cached egress rules by network without fetching, even if expired (evicted networks are missing)
+// For status reporting, which must never call the API
+func (c *CachedClient) CachedEgress() map[string][]Egress {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.egressByNetwork)
+}
+
+// TTL returns the cache time-to-live
+func (c *CachedClient) TTL() time.Duration {
+	return c.ttl
+}
+
+// CacheStats is a point-in-time snapshot of cache occupancy
+type CacheStats struct {
+	Hosts          int `json:"hosts"`          // Number of cached hosts
+	Nodes          int `json:"nodes"`          // Number of cached nodes
+	Networks       int `json:"networks"`       // Number of cached networks
+	EgressNetworks int `json:"egressNetworks"` // Number of networks with cached egress lists
+	EgressEntries  int `json:"egressEntries"`  // Total number of cached egress rules across all networks
+
+	// Counters are the cumulative hits, misses and evictions per kind (hosts, nodes, networks, egress)
+	Counters map[CacheKind]CacheCounters `json:"counters"`
+
+	// LastListed is the time of the last successful list per kind; egress lists are keyed by network
+	LastListed CacheListTimes `json:"lastListed"`
+}
+
+// CacheCounters are cumulative cache lookup and eviction counts
+// A miss is a lookup that went to Netmaker (whether or not it succeeded); an eviction is a populated
+// entry dropped before expiry by a write or Invalidate
+type CacheCounters struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// CacheListTimes holds the time of the last successful list calls (zero if never listed)
+type CacheListTimes struct {
+	Hosts    time.Time            `json:"hosts"`
+	Nodes    time.Time            `json:"nodes"`
+	Networks time.Time            `json:"networks"`
+	Egress   map[string]time.Time `json:"egress"` // network -> time
+}
+
+// Stats returns the current cache occupancy
+// Expired entries are still counted until they are refreshed or invalidated
+func (c *CachedClient) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := CacheStats{
+		Hosts:          len(c.hosts),
+		Nodes:          len(c.nodes),
+		Networks:       len(c.networks),
+		EgressNetworks: len(c.egressByNetwork),
+		Counters:       make(map[CacheKind]CacheCounters, len(c.counters)),
+		LastListed: CacheListTimes{
+			Hosts:    c.hostsListedAt,
+			Nodes:    c.nodesListedAt,
+			Networks: c.networksListedAt,
+			Egress:   make(map[string]time.Time, len(c.egressListedAt)),
+		},
+	}
+	for _, egresses := range c.egressByNetwork {
+		stats.EgressEntries += len(egresses)
+	}
+	for kind, counters := range c.counters {
+		stats.Counters[kind] = CacheCounters{
+			Hits:      counters.hits.Load(),
+			Misses:    counters.misses.Load(),
+			Evictions: counters.evictions.Load(),
+		}
+	}
+	for network, listedAt := range c.egressListedAt {
+		stats.LastListed.Egress[network] = listedAt
+	}
+
+	return stats
+}
@@ -0,0 +1,215 @@
+package netmaker
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+)
+
+// External change kinds reported by ChangeDetector
+const (
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+	ChangeCreated  = "created"
+)
+
+// writeRetention is how long writes are remembered for skipping listings in flight (well above the HTTP timeout)
+const writeRetention = 10 * time.Minute
+
+// ExternalChange is a change to a tracked egress rule that was not made through the ChangeDetector
+type ExternalChange struct {
+	Kind    string
+	Network string
+
+	// Before is the rule as last listed or written (nil for created rules)
+	Before *Egress
+
+	// After is the rule as listed now (nil for deleted rules)
+	After *Egress
+
+	// Fields lists the changed fields of modified rules (name, description, range, nat, nodes, status)
+	Fields []string
+}
+
+// ChangeDetector decorates a client to detect out-of-band changes to egress rules, e.g. edits in the Netmaker UI
+// Every ListEgress result is diffed against the previous listing of the network plus the writes made through
+// the detector since; differences are reported to OnChange before the reconciler overwrites them
+// Only rules for which tracked returns true (in either state) are compared
+// Wrap the HTTP client (below any ReadOnlyClient), not the CachedClient, so every listing and real write passes through it
+type ChangeDetector struct {
+	Client // Embedded interface - automatic delegation
+
+	tracked  func(Egress) bool
+	onChange func(ExternalChange)
+
+	mu        sync.Mutex
+	known     map[string]map[string]Egress // network -> egress ID -> rule, as last listed or written
+	writtenAt map[string]time.Time         // egress ID -> last write through the detector
+}
+
+// NewChangeDetector wraps a client, reporting out-of-band changes of tracked egress rules to onChange
+// onChange is called synchronously from ListEgress, possibly while a CachedClient above holds its lock,
+// so it must neither block nor call back into the client stack
+func NewChangeDetector(client Client, tracked func(Egress) bool, onChange func(ExternalChange)) *ChangeDetector {
+	return &ChangeDetector{
+		Client:    client,
+		tracked:   tracked,
+		onChange:  onChange,
+		known:     make(map[string]map[string]Egress),
+		writtenAt: make(map[string]time.Time),
+	}
+}
+
+// ListEgress lists the egress rules of a network and reports changes since the last listing
+// The first listing of a network only establishes the baseline; rules written while the listing was
+// in flight are skipped, since the listing may predate the write
+func (d *ChangeDetector) ListEgress(ctx context.Context, network string) ([]Egress, error) {
+	started := time.Now()
+	egresses, err := d.Client.ListEgress(ctx, network)
+	if err != nil {
+		return egresses, err
+	}
+
+	listed := make(map[string]Egress, len(egresses))
+	for _, egress := range egresses {
+		listed[egress.ID] = egress
+	}
+
+	d.mu.Lock()
+	previous, baseline := d.known[network]
+	var changes []ExternalChange
+	if baseline {
+		changes = d.diff(network, previous, listed, started)
+	}
+	d.known[network] = listed
+	for id, writtenAt := range d.writtenAt {
+		if time.Since(writtenAt) > writeRetention {
+			delete(d.writtenAt, id)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, change := range changes {
+		d.onChange(change)
+	}
+	return egresses, nil
+}
+
+// diff compares two states of a network's rules, skipping rules written after since
+// Must be called with mu held
+func (d *ChangeDetector) diff(network string, previous, listed map[string]Egress, since time.Time) []ExternalChange {
+	var changes []ExternalChange
+	for id, before := range previous {
+		if d.writtenAt[id].After(since) {
+			continue
+		}
+		after, exists := listed[id]
+		switch {
+		case !exists:
+			if d.tracked(before) {
+				changes = append(changes, ExternalChange{Kind: ChangeDeleted, Network: network, Before: &before})
+			}
+		case d.tracked(before) || d.tracked(after):
+			if fields := changedFields(before, after); len(fields) > 0 {
+				changes = append(changes, ExternalChange{Kind: ChangeModified, Network: network, Before: &before, After: &after, Fields: fields})
+			}
+		}
+	}
+	for id, after := range listed {
+		if _, seen := previous[id]; !seen && !d.writtenAt[id].After(since) && d.tracked(after) {
+			changes = append(changes, ExternalChange{Kind: ChangeCreated, Network: network, After: &after})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changeID(changes[i]) < changeID(changes[j]) })
+	return changes
+}
+
+// changeID returns the ID of the rule a change is about
+func changeID(change ExternalChange) string {
+	if change.After != nil {
+		return change.After.ID
+	}
+	return change.Before.ID
+}
+
+// changedFields returns the names of the fields that differ between two states of a rule
+// UpdatedAt is ignored, it changes with every write
+func changedFields(before, after Egress) []string {
+	var fields []string
+	if before.Name != after.Name {
+		fields = append(fields, "name")
+	}
+	if before.Description != after.Description {
+		fields = append(fields, "description")
+	}
+	if !cidr.Equal(before.Range, after.Range) {
+		fields = append(fields, "range")
+	}
+	if before.NAT != after.NAT {
+		fields = append(fields, "nat")
+	}
+	if !reflect.DeepEqual(before.Nodes, after.Nodes) && (len(before.Nodes) > 0 || len(after.Nodes) > 0) {
+		fields = append(fields, "nodes")
+	}
+	if before.Status != after.Status {
+		fields = append(fields, "status")
+	}
+	return fields
+}
+
+// CreateEgress creates an egress rule and records it as expected state
+func (d *ChangeDetector) CreateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	created, err := d.Client.CreateEgress(ctx, req)
+	if err != nil {
+		return created, err
+	}
+	egress := egressFromRequest(req)
+	egress.ID = created.ID
+	egress.UpdatedAt = created.UpdatedAt
+	d.record(req.Network, *egress)
+	return created, nil
+}
+
+// UpdateEgress updates an egress rule and records it as expected state
+func (d *ChangeDetector) UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	updated, err := d.Client.UpdateEgress(ctx, req)
+	if err != nil {
+		return updated, err
+	}
+	egress := egressFromRequest(req)
+	egress.UpdatedAt = updated.UpdatedAt
+	d.record(req.Network, *egress)
+	return updated, nil
+}
+
+// DeleteEgress deletes an egress rule and forgets it
+func (d *ChangeDetector) DeleteEgress(ctx context.Context, egressID string) error {
+	if err := d.Client.DeleteEgress(ctx, egressID); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, rules := range d.known {
+		delete(rules, egressID)
+	}
+	d.writtenAt[egressID] = time.Now()
+	return nil
+}
+
+// record stores a rule written through the detector
+func (d *ChangeDetector) record(network string, egress Egress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if rules, ok := d.known[network]; ok {
+		rules[egress.ID] = egress
+	}
+	d.writtenAt[egress.ID] = time.Now()
+}
@@ -4,18 +4,77 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// ErrConflict is returned by UpdateEgress when the egress rule was modified since it was read (HTTP 409)
+// Callers should re-read the rule and recompute the update
+var ErrConflict = errors.New("egress rule was modified concurrently")
+
+// ErrNotFound is returned by GetNetwork when the network does not exist, and by UpdateEgress and DeleteEgress
+// when the egress rule does not exist (e.g. deleted since it was listed)
+var ErrNotFound = errors.New("not found")
+
+// ErrRateLimited is returned when Netmaker keeps answering HTTP 429 or 503 after all retries
+// Use errors.As with *RateLimitError to get the server's Retry-After hint
+var ErrRateLimited = errors.New("rate limited by Netmaker")
+
+// RateLimitError carries the Retry-After hint of a rate-limited request
+type RateLimitError struct {
+	StatusCode int
+	RetryAfter time.Duration // How long the server asked us to wait (default backoff if it didn't say)
+}
+
+// Error implements error
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d, retry after %s)", ErrRateLimited, e.StatusCode, e.RetryAfter)
+}
+
+// Unwrap makes errors.Is(err, ErrRateLimited) work
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+const (
+	// maxRateLimitRetries is how often a request is retried after HTTP 429/503
+	maxRateLimitRetries = 3
+	// maxRateLimitWait is the longest Retry-After honored in-line; longer waits are returned to the caller
+	maxRateLimitWait = 30 * time.Second
+	// defaultRateLimitBackoff is the first wait when the server sends no Retry-After (doubled per retry)
+	defaultRateLimitBackoff = time.Second
+)
+
 // Client is the interface for Netmaker API operations
 // This allows easy mocking in tests
 // The client works with ALL networks - network is passed as parameter where needed
 type Client interface {
+	BasicClient
+	NetworkLister
+	HostNetworkJoiner
+}
+
+// NetworkLister lists the networks with their metadata
+type NetworkLister interface {
+	// ListNetworks returns all networks with their address ranges and default settings
+	ListNetworks(ctx context.Context) ([]Network, error)
+}
+
+// HostNetworkJoiner joins hosts to networks
+type HostNetworkJoiner interface {
+	// AddHostToNetwork joins a host to a network; Netmaker creates the host's node in it
+	AddHostToNetwork(ctx context.Context, hostID, network string) error
+}
+
+// BasicClient is Client without NetworkLister and HostNetworkJoiner, the API of clients written before
+// ListNetworks existed (api/v1 NetmakerClient); entry points accepting such clients adapt them with AsClient
+type BasicClient interface {
 	// Authenticate obtains a JWT token from Netmaker API
 	Authenticate(ctx context.Context) error
 
@@ -36,124 +95,187 @@ type Client interface {
 
 	// DeleteEgress removes an egress gateway by ID
 	DeleteEgress(ctx context.Context, egressID string) error
+
+	// ListExtClients returns all external clients for the specified network
+	ListExtClients(ctx context.Context, network string) ([]ExtClient, error)
+
+	// UpdateExtClientAllowedIPs replaces the extra allowed IPs (routes) of an external client
+	UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error
+
+	// GetNetwork returns a network by name (error wraps ErrNotFound if it doesn't exist)
+	GetNetwork(ctx context.Context, netID string) (*Network, error)
+
+	// CreateNetwork creates a new network
+	CreateNetwork(ctx context.Context, network Network) (*Network, error)
+}
+
+// basicClient adapts a BasicClient without NetworkLister to Client
+type basicClient struct {
+	BasicClient // Embedded interface - automatic delegation
+}
+
+// ListNetworks implements Client; always fails, so callers go on without network metadata
+func (c basicClient) ListNetworks(ctx context.Context) ([]Network, error) {
+	return nil, errors.New("the Netmaker client does not list networks")
+}
+
+// AddHostToNetwork implements Client; always fails, so hosts without networks are only reported
+func (c basicClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	return errors.New("the Netmaker client does not join hosts to networks")
+}
+
+// AsClient returns client as Client; clients that can't list networks fail every ListNetworks and
+// AddHostToNetwork call
+func AsClient(client BasicClient) Client {
+	if full, ok := client.(Client); ok {
+		return full
+	}
+	return basicClient{BasicClient: client}
 }
 
 // HTTPClient implements Client using Netmaker REST API
 // Works with all networks - network is passed as parameter to methods that need it
 type HTTPClient struct {
-	baseURL  string
-	username string
-	password string
-	client   *http.Client
+	baseURL       string
+	authenticator Authenticator
+	client        *http.Client
+	authHeader    string // AuthHeaderBearer (default) or AuthHeaderAPIKey
+
+	// maxResponseBytes limits API response bodies (see SetMaxResponseBytes)
+	maxResponseBytes int64
+
+	// networkTimeouts overrides the timeout and retry budget of some networks (see SetNetworkTimeouts)
+	networkTimeouts map[string]NetworkTimeout
 
 	// Token management (internal state)
-	tokenMu sync.RWMutex
-	token   string
+	tokenMu     sync.RWMutex
+	token       *secret   // Locked and wiped on renewal, never printed (see secret)
+	tokenExpiry time.Time // From the JWT exp claim; zero for opaque tokens
 }
 
-// NewHTTPClient creates a new Netmaker HTTP client for all networks
+// NewHTTPClient creates a new Netmaker HTTP client for all networks using username/password login
 // Returns error for validation failures, never panics
 func NewHTTPClient(baseURL, username, password string) (*HTTPClient, error) {
+	authenticator, err := NewPasswordAuthenticator(baseURL, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHTTPClientWithAuthenticator(baseURL, authenticator)
+}
+
+// NewHTTPClientWithAuthenticator creates a new Netmaker HTTP client with a custom authentication flow
+// Returns error for validation failures, never panics
+func NewHTTPClientWithAuthenticator(baseURL string, authenticator Authenticator) (*HTTPClient, error) {
 	if baseURL == "" {
 		return nil, fmt.Errorf("baseURL is required")
 	}
-	if username == "" {
-		return nil, fmt.Errorf("username is required")
-	}
-	if password == "" {
-		return nil, fmt.Errorf("password is required")
+	if authenticator == nil {
+		return nil, fmt.Errorf("authenticator is required")
 	}
 
 	return &HTTPClient{
-		baseURL:  baseURL,
-		username: username,
-		password: password,
-		client:   &http.Client{Timeout: 10 * time.Second},
+		baseURL:          baseURL,
+		authenticator:    authenticator,
+		client:           &http.Client{Timeout: DefaultRequestTimeout},
+		maxResponseBytes: DefaultMaxResponseBytes,
+		token:            newSecret(""),
 	}, nil
 }
 
-// Authenticate obtains a JWT token from Netmaker API
+// Authenticate obtains a bearer token via the configured authenticator
 func (c *HTTPClient) Authenticate(ctx context.Context) error {
-	authURL := fmt.Sprintf("%s/api/users/adm/authenticate", c.baseURL)
-
-	payload := AuthRequest{
-		Username: c.username,
-		Password: c.password,
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal auth payload: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, authURL, bytes.NewReader(body))
+	token, err := c.authenticator.Authenticate(ctx, c.client)
 	if err != nil {
-		return fmt.Errorf("failed to create auth request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("authentication request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP status first
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("authentication failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	// Validate Content-Type is JSON
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/json") {
-		return fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
-	}
-
-	var authResp AuthResponse
-	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return fmt.Errorf("failed to decode auth response: %w", err)
-	}
-
-	// Check JSON Code field if present
-	if authResp.Code != 0 && authResp.Code != http.StatusOK {
-		return fmt.Errorf("authentication failed with API code %d: %s", authResp.Code, authResp.Message)
-	}
-
-	// Validate we got a token
-	if authResp.Response.AuthToken == "" {
-		return fmt.Errorf("authentication succeeded but no token in response")
+		return err
 	}
 
 	c.tokenMu.Lock()
-	c.token = authResp.Response.AuthToken
+	c.token.set(token)
+	c.tokenExpiry = jwtExpiry(token)
 	c.tokenMu.Unlock()
 
 	return nil
 }
 
 // getToken returns the current token, authenticating if needed
+// Tokens past their exp claim are renewed up front instead of waiting for a 401
 func (c *HTTPClient) getToken(ctx context.Context) (string, error) {
 	c.tokenMu.RLock()
-	token := c.token
+	token := c.token.reveal()
+	expired := !c.tokenExpiry.IsZero() && time.Now().After(c.tokenExpiry.Add(-tokenExpirySkew))
 	c.tokenMu.RUnlock()
 
-	if token == "" {
-		// No token yet - authenticate
+	if token == "" || expired {
+		// No (valid) token yet - authenticate
 		if err := c.Authenticate(ctx); err != nil {
 			return "", err
 		}
 		c.tokenMu.RLock()
-		token = c.token
+		token = c.token.reveal()
 		c.tokenMu.RUnlock()
 	}
 
 	return token, nil
 }
 
-// doRequest performs an HTTP request with automatic token management
-// Handles authentication, 401 retry, and error response parsing
+// doRequest performs an HTTP request with automatic token management and rate limit handling
+// HTTP 429 and 503 are retried after the server's Retry-After (or an exponential backoff) up to
+// maxRateLimitRetries times (or the retries of the network's NetworkTimeout); if the server keeps refusing,
+// or asks for more than maxRateLimitWait, a *RateLimitError is returned so callers can requeue instead of retrying hot
 func (c *HTTPClient) doRequest(ctx context.Context, method, url string, body interface{}) (*http.Response, error) {
+	retries := c.networkTimeout(ctx).Retries
+	backoff := defaultRateLimitBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.doAuthenticatedRequest(ctx, method, url, body)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			wait = backoff
+			backoff *= 2
+		}
+
+		if attempt >= retries || wait > maxRateLimitWait {
+			return nil, &RateLimitError{StatusCode: resp.StatusCode, RetryAfter: wait}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// parseRetryAfter parses a Retry-After header (delay in seconds or HTTP date)
+// Returns false if the header is missing or invalid
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// doAuthenticatedRequest performs an HTTP request with automatic token management
+// Handles authentication and 401 retry
+func (c *HTTPClient) doAuthenticatedRequest(ctx context.Context, method, url string, body interface{}) (*http.Response, error) {
 	// Get current token (authenticates if needed)
 	token, err := c.getToken(ctx)
 	if err != nil {
@@ -175,11 +297,13 @@ func (c *HTTPClient) doRequest(ctx context.Context, method, url string, body int
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	c.setAuthHeader(req, token)
 	req.Header.Set("Content-Type", "application/json")
+	setRequestID(req)
 
-	// Execute request
-	resp, err := c.client.Do(req)
+	// Execute request with the timeout of its network
+	client := c.httpClientFor(ctx)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -205,10 +329,11 @@ func (c *HTTPClient) doRequest(ctx context.Context, method, url string, body int
 		if err != nil {
 			return nil, fmt.Errorf("failed to create retry request: %w", err)
 		}
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		c.setAuthHeader(req, token)
 		req.Header.Set("Content-Type", "application/json")
+		setRequestID(req)
 
-		resp, err = c.client.Do(req)
+		resp, err = client.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("retry request failed: %w", err)
 		}
@@ -229,19 +354,13 @@ func (c *HTTPClient) ListHosts(ctx context.Context) ([]Host, error) {
 
 	// Check HTTP status first
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+		bodyBytes := errorBody(resp.Body)
 		return nil, fmt.Errorf("ListHosts failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Validate Content-Type is JSON
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
-	}
-
 	var hosts []Host
-	if err := json.NewDecoder(resp.Body).Decode(&hosts); err != nil {
-		return nil, fmt.Errorf("failed to decode hosts list: %w", err)
+	if err := decodeResponse(resp, c.maxResponseBytes, "ListHosts", "hosts list", &hosts); err != nil {
+		return nil, err
 	}
 
 	return hosts, nil
@@ -259,19 +378,13 @@ func (c *HTTPClient) ListNodes(ctx context.Context) ([]Node, error) {
 
 	// Check HTTP status first
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+		bodyBytes := errorBody(resp.Body)
 		return nil, fmt.Errorf("ListNodes failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Validate Content-Type is JSON
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
-	}
-
 	var nodes []Node
-	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
-		return nil, fmt.Errorf("failed to decode nodes list: %w", err)
+	if err := decodeResponse(resp, c.maxResponseBytes, "ListNodes", "nodes list", &nodes); err != nil {
+		return nil, err
 	}
 
 	return nodes, nil
@@ -279,6 +392,7 @@ func (c *HTTPClient) ListNodes(ctx context.Context) ([]Node, error) {
 
 // ListEgress implements Client interface
 func (c *HTTPClient) ListEgress(ctx context.Context, network string) ([]Egress, error) {
+	ctx = WithNetwork(ctx, network)
 	url := fmt.Sprintf("%s/api/v1/egress?network=%s", c.baseURL, network)
 
 	resp, err := c.doRequest(ctx, http.MethodGet, url, nil)
@@ -289,31 +403,21 @@ func (c *HTTPClient) ListEgress(ctx context.Context, network string) ([]Egress,
 
 	// Check HTTP status first
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+		bodyBytes := errorBody(resp.Body)
 		return nil, fmt.Errorf("ListEgress failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Validate Content-Type is JSON
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
-	}
-
-	var egressResp EgressResponse
-	if err := json.NewDecoder(resp.Body).Decode(&egressResp); err != nil {
-		return nil, fmt.Errorf("failed to decode egress list: %w", err)
-	}
-
-	// Check JSON Code field if present
-	if egressResp.Code != 0 && egressResp.Code != http.StatusOK {
-		return nil, fmt.Errorf("ListEgress failed with API code %d: %s", egressResp.Code, egressResp.Message)
+	var egresses []Egress
+	if err := decodeResponse(resp, c.maxResponseBytes, "ListEgress", "egress list", &egresses); err != nil {
+		return nil, err
 	}
 
-	return egressResp.Response, nil
+	return egresses, nil
 }
 
 // CreateEgress implements Client interface
 func (c *HTTPClient) CreateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	ctx = WithNetwork(ctx, req.Network)
 	url := fmt.Sprintf("%s/api/v1/egress", c.baseURL)
 
 	resp, err := c.doRequest(ctx, http.MethodPost, url, req)
@@ -324,31 +428,27 @@ func (c *HTTPClient) CreateEgress(ctx context.Context, req EgressReq) (*Egress,
 
 	// Check HTTP status first
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+		bodyBytes := errorBody(resp.Body)
 		return nil, fmt.Errorf("CreateEgress failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Validate Content-Type is JSON
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
-	}
-
-	var createResp EgressCreateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&createResp); err != nil {
-		return nil, fmt.Errorf("failed to decode egress response: %w", err)
+	var created Egress
+	if err := decodeResponse(resp, c.maxResponseBytes, "CreateEgress", "egress response", &created); err != nil {
+		return nil, err
 	}
 
-	// Check JSON Code field if present
-	if createResp.Code != 0 && createResp.Code != http.StatusOK && createResp.Code != http.StatusCreated {
-		return nil, fmt.Errorf("CreateEgress failed with API code %d: %s", createResp.Code, createResp.Message)
-	}
+	return &created, nil
+}
 
-	return &createResp.Response, nil
+// isNotFound reports whether an error response means the requested object does not exist
+// Netmaker reports missing objects as HTTP 404 or as an error with "no result found" from its database layer
+func isNotFound(statusCode int, body []byte) bool {
+	return statusCode == http.StatusNotFound || strings.Contains(string(body), "no result found")
 }
 
 // UpdateEgress implements Client interface
 func (c *HTTPClient) UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	ctx = WithNetwork(ctx, req.Network)
 	url := fmt.Sprintf("%s/api/v1/egress", c.baseURL)
 
 	resp, err := c.doRequest(ctx, http.MethodPut, url, req)
@@ -358,31 +458,28 @@ func (c *HTTPClient) UpdateEgress(ctx context.Context, req EgressReq) (*Egress,
 	defer resp.Body.Close()
 
 	// Check HTTP status first
+	if resp.StatusCode == http.StatusConflict {
+		bodyBytes := errorBody(resp.Body)
+		return nil, fmt.Errorf("UpdateEgress failed with HTTP status %d: %s: %w", resp.StatusCode, string(bodyBytes), ErrConflict)
+	}
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+		bodyBytes := errorBody(resp.Body)
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return nil, fmt.Errorf("UpdateEgress failed with HTTP status %d: %s: %w", resp.StatusCode, string(bodyBytes), ErrNotFound)
+		}
 		return nil, fmt.Errorf("UpdateEgress failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Validate Content-Type is JSON
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
-	}
-
-	var updateResp EgressUpdateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&updateResp); err != nil {
-		return nil, fmt.Errorf("failed to decode egress response: %w", err)
-	}
-
-	// Check JSON Code field if present
-	if updateResp.Code != 0 && updateResp.Code != http.StatusOK {
-		return nil, fmt.Errorf("UpdateEgress failed with API code %d: %s", updateResp.Code, updateResp.Message)
+	var updated Egress
+	if err := decodeResponse(resp, c.maxResponseBytes, "UpdateEgress", "egress response", &updated); err != nil {
+		return nil, err
 	}
 
-	return &updateResp.Response, nil
+	return &updated, nil
 }
 
 // DeleteEgress implements Client interface
+// The request has no network; the NetworkTimeout of the context's network (see WithNetwork) applies
 func (c *HTTPClient) DeleteEgress(ctx context.Context, egressID string) error {
 	url := fmt.Sprintf("%s/api/v1/egress?id=%s", c.baseURL, egressID)
 
@@ -393,9 +490,192 @@ func (c *HTTPClient) DeleteEgress(ctx context.Context, egressID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+		bodyBytes := errorBody(resp.Body)
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return fmt.Errorf("DeleteEgress failed with status %d: %s: %w", resp.StatusCode, string(bodyBytes), ErrNotFound)
+		}
 		return fmt.Errorf("DeleteEgress failed with status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	return nil
 }
+
+// AddHostToNetwork implements Client interface
+func (c *HTTPClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	ctx = WithNetwork(ctx, network)
+	url := fmt.Sprintf("%s/api/hosts/%s/networks/%s", c.baseURL, hostID, network)
+
+	resp, err := c.doRequest(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		bodyBytes := errorBody(resp.Body)
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return fmt.Errorf("AddHostToNetwork failed with status %d: %s: %w", resp.StatusCode, string(bodyBytes), ErrNotFound)
+		}
+		return fmt.Errorf("AddHostToNetwork failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// ListExtClients implements Client interface
+func (c *HTTPClient) ListExtClients(ctx context.Context, network string) ([]ExtClient, error) {
+	ctx = WithNetwork(ctx, network)
+	url := fmt.Sprintf("%s/api/extclients/%s", c.baseURL, network)
+
+	resp, err := c.doRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		return nil, fmt.Errorf("ListExtClients failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var extClients []ExtClient
+	if err := decodeResponse(resp, c.maxResponseBytes, "ListExtClients", "ext client list", &extClients); err != nil {
+		return nil, err
+	}
+
+	return extClients, nil
+}
+
+// UpdateExtClientAllowedIPs implements Client interface
+// Netmaker's update replaces most fields, so the full client is read and written back
+// with only extraallowedips changed - fields this client doesn't model are preserved
+func (c *HTTPClient) UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error {
+	ctx = WithNetwork(ctx, network)
+	url := fmt.Sprintf("%s/api/extclients/%s/%s", c.baseURL, network, clientID)
+
+	current, err := c.getExtClientRaw(ctx, url)
+	if err != nil {
+		return err
+	}
+	current["extraallowedips"] = allowedIPs
+
+	resp, err := c.doRequest(ctx, http.MethodPut, url, current)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		return fmt.Errorf("UpdateExtClient failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	// Validate Content-Type is JSON
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "application/json") {
+		return fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
+	}
+
+	return nil
+}
+
+// getExtClientRaw fetches a single external client as a generic JSON object
+func (c *HTTPClient) getExtClientRaw(ctx context.Context, url string) (map[string]interface{}, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		return nil, fmt.Errorf("GetExtClient failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var extClient map[string]interface{}
+	if err := decodeResponse(resp, c.maxResponseBytes, "GetExtClient", "ext client", &extClient); err != nil {
+		return nil, err
+	}
+
+	return extClient, nil
+}
+
+// ListNetworks implements Client interface
+func (c *HTTPClient) ListNetworks(ctx context.Context) ([]Network, error) {
+	url := fmt.Sprintf("%s/api/networks", c.baseURL)
+
+	resp, err := c.doRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		return nil, fmt.Errorf("ListNetworks failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var networks []Network
+	if err := decodeResponse(resp, c.maxResponseBytes, "ListNetworks", "network list", &networks); err != nil {
+		return nil, err
+	}
+
+	return networks, nil
+}
+
+// GetNetwork implements Client interface
+func (c *HTTPClient) GetNetwork(ctx context.Context, netID string) (*Network, error) {
+	ctx = WithNetwork(ctx, netID)
+	url := fmt.Sprintf("%s/api/networks/%s", c.baseURL, netID)
+
+	resp, err := c.doRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return nil, fmt.Errorf("network %s: %w", netID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("GetNetwork failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var network Network
+	if err := decodeResponse(resp, c.maxResponseBytes, "GetNetwork", "network", &network); err != nil {
+		return nil, err
+	}
+
+	return &network, nil
+}
+
+// CreateNetwork implements Client interface
+func (c *HTTPClient) CreateNetwork(ctx context.Context, network Network) (*Network, error) {
+	ctx = WithNetwork(ctx, network.NetID)
+	url := fmt.Sprintf("%s/api/networks", c.baseURL)
+
+	resp, err := c.doRequest(ctx, http.MethodPost, url, network)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes := errorBody(resp.Body)
+		return nil, fmt.Errorf("CreateNetwork failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var created Network
+	if err := decodeResponse(resp, c.maxResponseBytes, "CreateNetwork", "network", &created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
@@ -0,0 +1,115 @@
+package netmaker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Credentials are Netmaker user credentials for password login
+// Formatting never prints the password (see Format)
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialSource provides Netmaker user credentials on demand
+// Called on every authentication, so implementations can pick up rotated credentials
+type CredentialSource interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentialSource returns fixed credentials (e.g. from environment variables)
+// The password is kept in locked memory between logins (see secret)
+type StaticCredentialSource struct {
+	username string
+	password *secret
+}
+
+// NewStaticCredentialSource creates a credential source for fixed credentials
+// Returns error for validation failures, never panics
+func NewStaticCredentialSource(username, password string) (*StaticCredentialSource, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if password == "" {
+		return nil, fmt.Errorf("password is required")
+	}
+
+	return &StaticCredentialSource{username: username, password: newSecret(password)}, nil
+}
+
+// Credentials implements CredentialSource
+func (s *StaticCredentialSource) Credentials(_ context.Context) (Credentials, error) {
+	return Credentials{Username: s.username, Password: s.password.reveal()}, nil
+}
+
+// FileCredentialSource reads credentials from files (e.g. mounted Secrets or CSI secret stores)
+// Files are re-read on every authentication, so rotated credentials are picked up without a restart
+type FileCredentialSource struct {
+	usernameFile     string
+	passwordFile     string
+	fallbackUsername string
+	fallbackPassword *secret
+}
+
+// NewFileCredentialSource creates a credential source backed by files
+// Either file may be empty, in which case the corresponding fallback value is used instead
+// Returns error for validation failures, never panics
+func NewFileCredentialSource(usernameFile, passwordFile string, fallback Credentials) (*FileCredentialSource, error) {
+	if usernameFile == "" && fallback.Username == "" {
+		return nil, fmt.Errorf("usernameFile or username is required")
+	}
+	if passwordFile == "" && fallback.Password == "" {
+		return nil, fmt.Errorf("passwordFile or password is required")
+	}
+
+	return &FileCredentialSource{
+		usernameFile:     usernameFile,
+		passwordFile:     passwordFile,
+		fallbackUsername: fallback.Username,
+		fallbackPassword: newSecret(fallback.Password),
+	}, nil
+}
+
+// Credentials implements CredentialSource
+func (s *FileCredentialSource) Credentials(_ context.Context) (Credentials, error) {
+	credentials := Credentials{Username: s.fallbackUsername}
+	if s.passwordFile == "" {
+		credentials.Password = s.fallbackPassword.reveal()
+	}
+
+	if s.usernameFile != "" {
+		username, err := readCredentialFile(s.usernameFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("failed to read username file: %w", err)
+		}
+		credentials.Username = username
+	}
+
+	if s.passwordFile != "" {
+		password, err := readCredentialFile(s.passwordFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("failed to read password file: %w", err)
+		}
+		credentials.Password = password
+	}
+
+	return credentials, nil
+}
+
+// readCredentialFile reads a single credential value, trimming the trailing newline editors add
+func readCredentialFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,134 @@
+package netmaker
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// AuthHeaderBearer sends the token as "Authorization: Bearer <token>" (stock Netmaker)
+	AuthHeaderBearer = "bearer"
+	// AuthHeaderAPIKey sends the token as "X-Api-Key: <token>" (e.g. API gateways in front of Netmaker)
+	AuthHeaderAPIKey = "x-api-key"
+
+	// defaultLoginPath is the login path of stock Netmaker (CE and Pro)
+	defaultLoginPath = "/api/users/adm/authenticate"
+)
+
+// LoginEndpoint describes the login request of a PasswordAuthenticator, for proxied or customized Netmaker
+// deployments whose login differs from stock Netmaker; the zero value is the stock login
+type LoginEndpoint struct {
+	// Path is the login path relative to the base URL
+	// Default: /api/users/adm/authenticate
+	Path string
+
+	// UsernameField and PasswordField are the JSON keys of the credentials in the login payload
+	// Default: username and password
+	UsernameField string
+	PasswordField string
+
+	// TokenField is the dot-separated path of the token in the JSON login response
+	// Default: Response.AuthToken
+	TokenField string
+}
+
+// ApplyDefaults fills in the stock Netmaker login for unset fields
+func (e *LoginEndpoint) ApplyDefaults() {
+	if e.Path == "" {
+		e.Path = defaultLoginPath
+	}
+	if e.UsernameField == "" {
+		e.UsernameField = "username"
+	}
+	if e.PasswordField == "" {
+		e.PasswordField = "password"
+	}
+	if e.TokenField == "" {
+		e.TokenField = "Response.AuthToken"
+	}
+}
+
+// Validate checks the endpoint (after ApplyDefaults)
+func (e *LoginEndpoint) Validate() error {
+	if !strings.HasPrefix(e.Path, "/") {
+		return fmt.Errorf("login path %q must start with /", e.Path)
+	}
+	if e.UsernameField == e.PasswordField {
+		return fmt.Errorf("username and password fields must differ, both are %q", e.UsernameField)
+	}
+	for _, key := range strings.Split(e.TokenField, ".") {
+		if key == "" {
+			return fmt.Errorf("invalid token field %q", e.TokenField)
+		}
+	}
+	return nil
+}
+
+// IsZero reports whether the endpoint is the stock login
+func (e LoginEndpoint) IsZero() bool {
+	return e == LoginEndpoint{}
+}
+
+// String describes the endpoint (for log output)
+func (e LoginEndpoint) String() string {
+	e.ApplyDefaults()
+	return fmt.Sprintf("path=%s, fields=%s/%s, token=%s", e.Path, e.UsernameField, e.PasswordField, e.TokenField)
+}
+
+// SetLoginEndpoint changes the login request (see LoginEndpoint)
+// Must be called before the authenticator is used
+func (a *PasswordAuthenticator) SetLoginEndpoint(endpoint LoginEndpoint) error {
+	endpoint.ApplyDefaults()
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	a.endpoint = endpoint
+	a.authURL = a.baseURL + endpoint.Path
+	return nil
+}
+
+// tokenAt returns the string at a dot-separated path of a decoded JSON document (empty if missing)
+func tokenAt(document interface{}, path string) string {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := document.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		document = object[key]
+	}
+	token, _ := document.(string)
+	return token
+}
+
+// apiStatus returns the Code and Message fields of a decoded Netmaker response (see AuthResponse), zero if absent
+func apiStatus(document interface{}) (int, string) {
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return 0, ""
+	}
+	code, _ := object["Code"].(float64)
+	message, _ := object["Message"].(string)
+	return int(code), message
+}
+
+// SetAuthHeader selects how the token is sent with API requests: AuthHeaderBearer (default) or AuthHeaderAPIKey
+// Must be called before the client is used
+func (c *HTTPClient) SetAuthHeader(scheme string) error {
+	switch scheme {
+	case AuthHeaderBearer, AuthHeaderAPIKey:
+	default:
+		return fmt.Errorf("unsupported auth header %q (must be %s or %s)", scheme, AuthHeaderBearer, AuthHeaderAPIKey)
+	}
+	c.authHeader = scheme
+	return nil
+}
+
+// setAuthHeader adds the token to a request using the configured scheme
+func (c *HTTPClient) setAuthHeader(req *http.Request, token string) {
+	if c.authHeader == AuthHeaderAPIKey {
+		req.Header.Set("X-Api-Key", token)
+		return
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+}
@@ -0,0 +1,84 @@
+package netmaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// apiEnvelope is the {Code, Message, Response} wrapper Netmaker puts around some response bodies
+// Which endpoints are wrapped differs between Netmaker versions (e.g. egress lists are bare arrays in some)
+type apiEnvelope struct {
+	Code     int             `json:"Code"`
+	Message  string          `json:"Message"`
+	Response json.RawMessage `json:"Response"`
+}
+
+// envelopeFields are the (case-insensitive) keys of an apiEnvelope
+var envelopeFields = map[string]bool{"code": true, "message": true, "response": true}
+
+// decodeResponse decodes a JSON response body into out, unwrapping an apiEnvelope if the response has one
+// The shape is detected per response, so one binary works against Netmaker versions that wrap an endpoint and
+// versions that don't: an object whose keys are all envelope fields (including Response) is an envelope,
+// anything else is the bare value (error envelopes may lack Response)
+// Non-2xx API codes in an envelope are returned as errors (wrapping ErrConflict for 409, ErrNotFound for 404)
+// Bodies larger than limit bytes fail with ErrResponseTooLarge without being read completely
+func decodeResponse(resp *http.Response, limit int64, operation, what string, out any) error {
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "application/json") {
+		return fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
+	}
+
+	body, err := readLimited(resp.Body, limit)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", what, err)
+	}
+
+	payload := body
+	if envelope, ok := unwrapEnvelope(body); ok {
+		if envelope.Code == http.StatusConflict {
+			return fmt.Errorf("%s failed with API code %d: %s: %w", operation, envelope.Code, envelope.Message, ErrConflict)
+		}
+		if envelope.Code == http.StatusNotFound {
+			return fmt.Errorf("%s failed with API code %d: %s: %w", operation, envelope.Code, envelope.Message, ErrNotFound)
+		}
+		if envelope.Code != 0 && (envelope.Code < 200 || envelope.Code > 299) {
+			return fmt.Errorf("%s failed with API code %d: %s", operation, envelope.Code, envelope.Message)
+		}
+		payload = envelope.Response
+	}
+
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", what, err)
+	}
+	return nil
+}
+
+// unwrapEnvelope parses body as an apiEnvelope, reporting false if it is a bare value
+func unwrapEnvelope(body []byte) (*apiEnvelope, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false // Bare array, scalar or null
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil || len(fields) == 0 {
+		return nil, false // Let the caller report decode errors
+	}
+	for key := range fields {
+		if !envelopeFields[strings.ToLower(key)] {
+			return nil, false
+		}
+	}
+
+	var envelope apiEnvelope
+	if err := json.Unmarshal(trimmed, &envelope); err != nil {
+		return nil, false
+	}
+	if len(envelope.Response) == 0 {
+		envelope.Response = json.RawMessage("null") // Error envelopes without a Response
+	}
+	return &envelope, true
+}
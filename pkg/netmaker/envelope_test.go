@@ -0,0 +1,133 @@
+package netmaker
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// jsonResponse builds a 200 response with a JSON body
+func jsonResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		want    []string
+		wantErr string
+		wantIs  error
+	}{
+		{name: "bare array", body: `["a","b"]`, want: []string{"a", "b"}},
+		{name: "bare null", body: `null`, want: nil},
+		{name: "envelope", body: `{"Code":200,"Message":"ok","Response":["a"]}`, want: []string{"a"}},
+		{name: "envelope with lowercase keys", body: `{"code":200,"message":"ok","response":["a"]}`, want: []string{"a"}},
+		{name: "envelope without code", body: `{"Response":["a"]}`, want: []string{"a"}},
+		{name: "empty response", body: `{"Code":200,"Message":"ok"}`, want: nil},
+		{name: "null response", body: `{"Code":200,"Message":"ok","Response":null}`, want: nil},
+		{
+			name:    "error envelope with 2xx status",
+			body:    `{"Code":500,"Message":"database locked"}`,
+			wantErr: "ListThings failed with API code 500: database locked",
+		},
+		{
+			name:    "conflict",
+			body:    `{"Code":409,"Message":"egress was modified"}`,
+			wantErr: "API code 409: egress was modified",
+			wantIs:  ErrConflict,
+		},
+		{
+			name:    "not found",
+			body:    `{"Code":404,"Message":"no such egress","Response":null}`,
+			wantErr: "API code 404: no such egress",
+			wantIs:  ErrNotFound,
+		},
+		{name: "undecodable", body: `{"Code":200,"Response":{"a":1}}`, wantErr: "failed to decode things"},
+		{name: "too large", body: `["a","b"]`, limit: 4, wantErr: "failed to read things", wantIs: ErrResponseTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.limit
+			if limit == 0 {
+				limit = 1 << 20
+			}
+
+			var got []string
+			err := decodeResponse(jsonResponse(tt.body), limit, "ListThings", "things", &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeResponse() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+					t.Errorf("decodeResponse() error = %v, want wrapping %v", err, tt.wantIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeResponse() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("decodeResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeResponseRequiresJSON(t *testing.T) {
+	resp := jsonResponse(`["a"]`)
+	resp.Header.Set("Content-Type", "text/html")
+
+	var got []string
+	if err := decodeResponse(resp, 1<<20, "ListThings", "things", &got); err == nil {
+		t.Fatal("decodeResponse() error = nil, want an error for a non-JSON response")
+	}
+}
+
+func TestUnwrapEnvelope(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantEnvelope bool
+		wantCode     int
+		wantResponse string
+	}{
+		{name: "bare array", body: `[{"Code":200}]`},
+		{name: "bare scalar", body: `42`},
+		{name: "bare null", body: `null`},
+		{name: "empty body", body: "  "},
+		{name: "empty object", body: `{}`},
+		{name: "object with other fields", body: `{"Code":200,"Response":[],"id":"e1"}`},
+		{name: "invalid JSON", body: `{"Code":`},
+		{name: "envelope", body: ` {"Code":200,"Message":"ok","Response":[1]} `, wantEnvelope: true, wantCode: 200, wantResponse: `[1]`},
+		{name: "error envelope without response", body: `{"Code":409,"Message":"conflict"}`, wantEnvelope: true, wantCode: 409, wantResponse: `null`},
+		{name: "null response", body: `{"Code":200,"Response":null}`, wantEnvelope: true, wantCode: 200, wantResponse: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, ok := unwrapEnvelope([]byte(tt.body))
+			if ok != tt.wantEnvelope {
+				t.Fatalf("unwrapEnvelope() ok = %v, want %v", ok, tt.wantEnvelope)
+			}
+			if !ok {
+				return
+			}
+			if envelope.Code != tt.wantCode {
+				t.Errorf("Code = %d, want %d", envelope.Code, tt.wantCode)
+			}
+			if string(envelope.Response) != tt.wantResponse {
+				t.Errorf("Response = %s, want %s", envelope.Response, tt.wantResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package netmaker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Fixture is a recorded Netmaker state, used to run the reconciler offline (see kaput-not simulate)
+// The JSON fields match the Netmaker API, so fixtures can also be assembled from raw API responses
+type Fixture struct {
+	Hosts      []Host                 `json:"hosts"`
+	Nodes      []Node                 `json:"nodes"`
+	Egress     map[string][]Egress    `json:"egress"`               // Network -> egress rules
+	ExtClients map[string][]ExtClient `json:"extClients,omitempty"` // Network -> external clients
+	Networks   []Network              `json:"networks,omitempty"`
+}
+
+// LoadFixture reads a fixture from a JSON file
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Netmaker fixture: %w", err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse Netmaker fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// RecordFixture reads the current state from Netmaker: hosts, nodes, and the egress rules and external
+// clients of every network a node participates in
+func RecordFixture(ctx context.Context, client Client) (*Fixture, error) {
+	hosts, err := client.ListHosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	nodes, err := client.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	fixture := &Fixture{
+		Hosts:      hosts,
+		Nodes:      nodes,
+		Egress:     make(map[string][]Egress),
+		ExtClients: make(map[string][]ExtClient),
+	}
+	for _, node := range nodes {
+		if _, seen := fixture.Egress[node.Network]; seen {
+			continue
+		}
+		egresses, err := client.ListEgress(ctx, node.Network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list egress rules in network %s: %w", node.Network, err)
+		}
+		fixture.Egress[node.Network] = egresses
+
+		extClients, err := client.ListExtClients(ctx, node.Network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list external clients in network %s: %w", node.Network, err)
+		}
+		fixture.ExtClients[node.Network] = extClients
+	}
+
+	networks, err := client.ListNetworks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, network := range networks {
+		if _, used := fixture.Egress[network.NetID]; used {
+			fixture.Networks = append(fixture.Networks, network)
+		}
+	}
+	sort.Slice(fixture.Networks, func(i, j int) bool { return fixture.Networks[i].NetID < fixture.Networks[j].NetID })
+
+	return fixture, nil
+}
+
+// Save writes the fixture to a JSON file
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode Netmaker fixture: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write Netmaker fixture: %w", err)
+	}
+	return nil
+}
+
+// FixtureClient implements Client on top of a fixture, without any network access
+// Mutations only change the in-memory state, so later reads see them like they would in Netmaker
+type FixtureClient struct {
+	mu      sync.Mutex
+	fixture *Fixture
+	nextID  int
+}
+
+// NewFixtureClient creates a client serving a copy of the fixture
+func NewFixtureClient(fixture *Fixture) *FixtureClient {
+	c := &FixtureClient{fixture: &Fixture{
+		Hosts:      append([]Host(nil), fixture.Hosts...),
+		Nodes:      append([]Node(nil), fixture.Nodes...),
+		Egress:     make(map[string][]Egress, len(fixture.Egress)),
+		ExtClients: make(map[string][]ExtClient, len(fixture.ExtClients)),
+		Networks:   append([]Network(nil), fixture.Networks...),
+	}}
+	for network, egresses := range fixture.Egress {
+		c.fixture.Egress[network] = append([]Egress(nil), egresses...)
+	}
+	for network, extClients := range fixture.ExtClients {
+		c.fixture.ExtClients[network] = append([]ExtClient(nil), extClients...)
+	}
+	return c
+}
+
+// Authenticate always succeeds
+func (c *FixtureClient) Authenticate(ctx context.Context) error {
+	return nil
+}
+
+// ListHosts returns the fixture's hosts
+func (c *FixtureClient) ListHosts(ctx context.Context) ([]Host, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Host(nil), c.fixture.Hosts...), nil
+}
+
+// ListNodes returns the fixture's nodes
+func (c *FixtureClient) ListNodes(ctx context.Context) ([]Node, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Node(nil), c.fixture.Nodes...), nil
+}
+
+// ListEgress returns the egress rules of a network
+func (c *FixtureClient) ListEgress(ctx context.Context, network string) ([]Egress, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Egress(nil), c.fixture.Egress[network]...), nil
+}
+
+// CreateEgress adds an egress rule with a generated ID
+func (c *FixtureClient) CreateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	egress := egressFromReq(req)
+	egress.ID = fmt.Sprintf("fixture-%d", c.nextID)
+	c.fixture.Egress[req.Network] = append(c.fixture.Egress[req.Network], egress)
+	return &egress, nil
+}
+
+// UpdateEgress replaces an egress rule
+func (c *FixtureClient) UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, egress := range c.fixture.Egress[req.Network] {
+		if egress.ID == req.ID {
+			updated := egressFromReq(req)
+			c.fixture.Egress[req.Network][i] = updated
+			return &updated, nil
+		}
+	}
+	return nil, fmt.Errorf("egress %s: %w", req.ID, ErrNotFound)
+}
+
+// DeleteEgress removes an egress rule from whichever network holds it
+func (c *FixtureClient) DeleteEgress(ctx context.Context, egressID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for network, egresses := range c.fixture.Egress {
+		for i, egress := range egresses {
+			if egress.ID == egressID {
+				c.fixture.Egress[network] = append(egresses[:i:i], egresses[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("egress %s: %w", egressID, ErrNotFound)
+}
+
+// ListExtClients returns the external clients of a network
+func (c *FixtureClient) ListExtClients(ctx context.Context, network string) ([]ExtClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ExtClient(nil), c.fixture.ExtClients[network]...), nil
+}
+
+// UpdateExtClientAllowedIPs replaces the extra allowed IPs of an external client
+func (c *FixtureClient) UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, extClient := range c.fixture.ExtClients[network] {
+		if extClient.ClientID == clientID {
+			c.fixture.ExtClients[network][i].ExtraAllowedIPs = append([]string(nil), allowedIPs...)
+			return nil
+		}
+	}
+	return fmt.Errorf("external client %s: %w", clientID, ErrNotFound)
+}
+
+// ListNetworks returns the networks of the fixture
+func (c *FixtureClient) ListNetworks(ctx context.Context) ([]Network, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Network(nil), c.fixture.Networks...), nil
+}
+
+// GetNetwork returns a network of the fixture
+func (c *FixtureClient) GetNetwork(ctx context.Context, netID string) (*Network, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, network := range c.fixture.Networks {
+		if network.NetID == netID {
+			return &network, nil
+		}
+	}
+	return nil, fmt.Errorf("network %s: %w", netID, ErrNotFound)
+}
+
+// CreateNetwork adds a network
+func (c *FixtureClient) CreateNetwork(ctx context.Context, network Network) (*Network, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fixture.Networks = append(c.fixture.Networks, network)
+	return &network, nil
+}
+
+// AddHostToNetwork adds a node of a host to a network
+func (c *FixtureClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, host := range c.fixture.Hosts {
+		if host.ID != hostID {
+			continue
+		}
+		c.nextID++
+		node := Node{ID: fmt.Sprintf("fixture-node-%d", c.nextID), HostID: hostID, Network: network, Connected: true}
+		c.fixture.Nodes = append(c.fixture.Nodes, node)
+		c.fixture.Hosts[i].Nodes = append(append([]string(nil), host.Nodes...), node.ID)
+		return nil
+	}
+	return fmt.Errorf("host %s: %w", hostID, ErrNotFound)
+}
+
+// egressFromReq builds the egress rule Netmaker would store for a request
+func egressFromReq(req EgressReq) Egress {
+	return Egress{
+		ID:          req.ID,
+		Name:        req.Name,
+		Network:     req.Network,
+		Description: req.Description,
+		Range:       req.Range,
+		NAT:         req.NAT,
+		Nodes:       req.Nodes,
+		Status:      req.Status,
+		UpdatedAt:   req.UpdatedAt,
+	}
+}
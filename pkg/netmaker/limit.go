@@ -0,0 +1,104 @@
+package netmaker
+
+import (
+	"context"
+	"fmt"
+)
+
+// MutationLimitClient decorates a client so that at most a fixed number of writes run against Netmaker at once
+// Some Netmaker servers corrupt state or fail with HTTP 500 under concurrent egress writes, independently
+// of how many workers kaput-not runs; reads are never limited
+type MutationLimitClient struct {
+	Client // Embedded interface - automatic delegation
+
+	slots chan struct{}
+}
+
+// NewMutationLimitClient wraps a client, allowing at most limit concurrent mutations
+// Returns error for a limit below 1, never panics
+func NewMutationLimitClient(client Client, limit int) (*MutationLimitClient, error) {
+	if client == nil {
+		return nil, fmt.Errorf("client is required")
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be at least 1, got %d", limit)
+	}
+	return &MutationLimitClient{
+		Client: client,
+		slots:  make(chan struct{}, limit),
+	}, nil
+}
+
+// acquire waits for a free mutation slot or until ctx is done
+func (c *MutationLimitClient) acquire(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for a Netmaker mutation slot: %w", ctx.Err())
+	}
+}
+
+// release frees a mutation slot
+func (c *MutationLimitClient) release() {
+	<-c.slots
+}
+
+// CreateEgress creates an egress rule once a mutation slot is free
+func (c *MutationLimitClient) CreateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return c.Client.CreateEgress(ctx, req)
+}
+
+// UpdateEgress updates an egress rule once a mutation slot is free
+func (c *MutationLimitClient) UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return c.Client.UpdateEgress(ctx, req)
+}
+
+// DeleteEgress deletes an egress rule once a mutation slot is free
+func (c *MutationLimitClient) DeleteEgress(ctx context.Context, egressID string) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.Client.DeleteEgress(ctx, egressID)
+}
+
+// UpdateExtClientAllowedIPs updates an external client's routes once a mutation slot is free
+func (c *MutationLimitClient) UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.Client.UpdateExtClientAllowedIPs(ctx, network, clientID, allowedIPs)
+}
+
+// CreateNetwork creates a network once a mutation slot is free
+func (c *MutationLimitClient) CreateNetwork(ctx context.Context, network Network) (*Network, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	return c.Client.CreateNetwork(ctx, network)
+}
+
+// AddHostToNetwork joins a host to a network once a mutation slot is free
+func (c *MutationLimitClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.Client.AddHostToNetwork(ctx, hostID, network)
+}
+
+// InFlight returns the number of mutations currently running
+func (c *MutationLimitClient) InFlight() int {
+	return len(c.slots)
+}
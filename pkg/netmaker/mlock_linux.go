@@ -0,0 +1,17 @@
+package netmaker
+
+import "syscall"
+
+// lockBuffer keeps buf out of swap (best effort: fails without CAP_IPC_LOCK beyond RLIMIT_MEMLOCK)
+func lockBuffer(buf []byte) {
+	if len(buf) > 0 {
+		_ = syscall.Mlock(buf)
+	}
+}
+
+// unlockBuffer releases a buffer locked by lockBuffer
+func unlockBuffer(buf []byte) {
+	if len(buf) > 0 {
+		_ = syscall.Munlock(buf)
+	}
+}
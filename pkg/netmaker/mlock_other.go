@@ -0,0 +1,9 @@
+//go:build !linux
+
+package netmaker
+
+// lockBuffer is a no-op where memory locking is not supported
+func lockBuffer([]byte) {}
+
+// unlockBuffer is a no-op where memory locking is not supported
+func unlockBuffer([]byte) {}
@@ -0,0 +1,175 @@
+package netmaker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NetworkCredentialsClient routes requests to a client authenticated as a per-network Netmaker user
+// Network-scoped requests (egress rules, external clients, networks) go to the client of their network;
+// global requests (hosts, nodes) and networks without a client of their own go to the default client
+// This allows least-privilege users per network instead of one super-admin account for everything
+// Wrap the HTTP clients, not the CachedClient, so every egress listing passes through it
+type NetworkCredentialsClient struct {
+	Client // Default client, embedded interface - automatic delegation
+
+	networks map[string]Client
+
+	mu            sync.Mutex
+	egressNetwork map[string]string // egress ID -> network, learned from ListEgress and CreateEgress
+}
+
+// NewNetworkCredentialsClient creates a client routing the given networks to their own clients
+// Returns error for validation failures, never panics
+func NewNetworkCredentialsClient(defaultClient Client, networks map[string]Client) (*NetworkCredentialsClient, error) {
+	if defaultClient == nil {
+		return nil, fmt.Errorf("defaultClient is required")
+	}
+	for network, client := range networks {
+		if client == nil {
+			return nil, fmt.Errorf("client for network %s is nil", network)
+		}
+	}
+
+	return &NetworkCredentialsClient{
+		Client:        defaultClient,
+		networks:      maps.Clone(networks),
+		egressNetwork: make(map[string]string),
+	}, nil
+}
+
+// Networks returns the networks with their own credentials, sorted
+func (c *NetworkCredentialsClient) Networks() []string {
+	return slices.Sorted(maps.Keys(c.networks))
+}
+
+// NetworkClient returns the client with the credentials of a network, false for networks using the default client
+func (c *NetworkCredentialsClient) NetworkClient(network string) (Client, bool) {
+	client, ok := c.networks[network]
+	return client, ok
+}
+
+// forNetwork returns the client for a network
+func (c *NetworkCredentialsClient) forNetwork(network string) Client {
+	if client, ok := c.networks[network]; ok {
+		return client
+	}
+	return c.Client
+}
+
+// Authenticate authenticates the default client and the client of every network
+func (c *NetworkCredentialsClient) Authenticate(ctx context.Context) error {
+	if err := c.Client.Authenticate(ctx); err != nil {
+		return err
+	}
+	for _, network := range c.Networks() {
+		if err := c.networks[network].Authenticate(ctx); err != nil {
+			return fmt.Errorf("network %s: %w", network, err)
+		}
+	}
+	return nil
+}
+
+// ListEgress lists the egress rules of a network and remembers their network for DeleteEgress
+func (c *NetworkCredentialsClient) ListEgress(ctx context.Context, network string) ([]Egress, error) {
+	egresses, err := c.forNetwork(network).ListEgress(ctx, network)
+	if err != nil {
+		return egresses, err
+	}
+
+	c.mu.Lock()
+	for id, known := range c.egressNetwork {
+		if known == network {
+			delete(c.egressNetwork, id)
+		}
+	}
+	for _, egress := range egresses {
+		c.egressNetwork[egress.ID] = network
+	}
+	c.mu.Unlock()
+
+	return egresses, nil
+}
+
+// CreateEgress creates an egress rule as the user of req.Network
+func (c *NetworkCredentialsClient) CreateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	created, err := c.forNetwork(req.Network).CreateEgress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.egressNetwork[created.ID] = req.Network
+	c.mu.Unlock()
+
+	return created, nil
+}
+
+// UpdateEgress updates an egress rule as the user of req.Network
+func (c *NetworkCredentialsClient) UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	return c.forNetwork(req.Network).UpdateEgress(ctx, req)
+}
+
+// DeleteEgress deletes an egress rule as the user of the network it was listed in
+// Rules not listed through this client are deleted by the default client
+func (c *NetworkCredentialsClient) DeleteEgress(ctx context.Context, egressID string) error {
+	c.mu.Lock()
+	network := c.egressNetwork[egressID]
+	c.mu.Unlock()
+
+	if err := c.forNetwork(network).DeleteEgress(ctx, egressID); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.egressNetwork, egressID)
+	c.mu.Unlock()
+	return nil
+}
+
+// ListExtClients lists the external clients of a network as the user of the network
+func (c *NetworkCredentialsClient) ListExtClients(ctx context.Context, network string) ([]ExtClient, error) {
+	return c.forNetwork(network).ListExtClients(ctx, network)
+}
+
+// UpdateExtClientAllowedIPs updates an external client's routes as the user of its network
+func (c *NetworkCredentialsClient) UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error {
+	return c.forNetwork(network).UpdateExtClientAllowedIPs(ctx, network, clientID, allowedIPs)
+}
+
+// GetNetwork returns a network as the user of the network
+func (c *NetworkCredentialsClient) GetNetwork(ctx context.Context, netID string) (*Network, error) {
+	return c.forNetwork(netID).GetNetwork(ctx, netID)
+}
+
+// CreateNetwork creates a network as the user of the network
+func (c *NetworkCredentialsClient) CreateNetwork(ctx context.Context, network Network) (*Network, error) {
+	return c.forNetwork(network.NetID).CreateNetwork(ctx, network)
+}
+
+// AddHostToNetwork joins a host to a network as the user of the network
+func (c *NetworkCredentialsClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	return c.forNetwork(network).AddHostToNetwork(ctx, hostID, network)
+}
+
+// tokenRefresher is implemented by clients that refresh their token proactively (*HTTPClient)
+type tokenRefresher interface {
+	RunTokenRefresher(ctx context.Context, margin time.Duration)
+}
+
+// RunTokenRefresher refreshes the tokens of the default and all network clients until ctx is canceled
+func (c *NetworkCredentialsClient) RunTokenRefresher(ctx context.Context, margin time.Duration) {
+	var wg sync.WaitGroup
+	for _, client := range append([]Client{c.Client}, slices.Collect(maps.Values(c.networks))...) {
+		if refresher, ok := client.(tokenRefresher); ok {
+			wg.Go(func() {
+				refresher.RunTokenRefresher(ctx, margin)
+			})
+		}
+	}
+	wg.Wait()
+}
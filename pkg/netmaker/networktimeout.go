@@ -0,0 +1,86 @@
+package netmaker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultRequestTimeout is the HTTP timeout of a request attempt without a NetworkTimeout
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultRateLimitRetries is how often a rate-limited request is retried without a NetworkTimeout
+	DefaultRateLimitRetries = maxRateLimitRetries
+)
+
+// networkKey is the context key of the Netmaker network a request belongs to
+type networkKey struct{}
+
+// WithNetwork returns a context whose Netmaker requests belong to a network, so its NetworkTimeout applies
+// HTTPClient tags the requests that name their network itself; DeleteEgress only knows the egress ID, so callers
+// deleting rules tag the context with the rule's network
+func WithNetwork(ctx context.Context, network string) context.Context {
+	return context.WithValue(ctx, networkKey{}, network)
+}
+
+// NetworkFromContext returns the network of ctx (empty if none)
+func NetworkFromContext(ctx context.Context) string {
+	network, _ := ctx.Value(networkKey{}).(string)
+	return network
+}
+
+// NetworkTimeout is the request timeout and rate limit retry budget of the requests of one network,
+// e.g. a network whose gateways are only reachable through a slow satellite link
+type NetworkTimeout struct {
+	// Timeout is the HTTP timeout of each request attempt (including reading the response)
+	Timeout time.Duration
+
+	// Retries is how often a request answered with HTTP 429 or 503 is retried before a *RateLimitError is returned
+	Retries int
+}
+
+// Validate validates the timeout
+func (t NetworkTimeout) Validate() error {
+	if t.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive, got %s", t.Timeout)
+	}
+	if t.Retries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", t.Retries)
+	}
+	return nil
+}
+
+// SetNetworkTimeouts overrides the request timeout and retry budget of the given networks
+// Requests of other networks, and requests without a network (hosts, nodes, authentication), keep the defaults
+// Must be called before the client is used
+func (c *HTTPClient) SetNetworkTimeouts(timeouts map[string]NetworkTimeout) error {
+	for network, timeout := range timeouts {
+		if network == "" {
+			return fmt.Errorf("network timeout without a network name")
+		}
+		if err := timeout.Validate(); err != nil {
+			return fmt.Errorf("network %s: %w", network, err)
+		}
+	}
+	c.networkTimeouts = timeouts
+	return nil
+}
+
+// networkTimeout returns the timeout and retry budget of a request, by the network of its context
+func (c *HTTPClient) networkTimeout(ctx context.Context) NetworkTimeout {
+	if timeout, ok := c.networkTimeouts[NetworkFromContext(ctx)]; ok {
+		return timeout
+	}
+	return NetworkTimeout{Timeout: c.client.Timeout, Retries: DefaultRateLimitRetries}
+}
+
+// httpClientFor returns the HTTP client for a request: the shared one, or a copy with the network's timeout
+// The copy shares the transport (connection pool, proxy and TLS policy)
+func (c *HTTPClient) httpClientFor(ctx context.Context) *http.Client {
+	timeout := c.networkTimeout(ctx).Timeout
+	if timeout == c.client.Timeout {
+		return c.client
+	}
+	return &http.Client{Transport: c.client.Transport, Timeout: timeout}
+}
@@ -0,0 +1,133 @@
+package netmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrUserUnknown is returned by CurrentUser when the authentication flow has no username (token exchange, API keys)
+var ErrUserUnknown = errors.New("authenticated user is unknown")
+
+const (
+	// PlatformRoleSuperAdmin and PlatformRoleAdmin may manage every network
+	PlatformRoleSuperAdmin = "super-admin"
+	PlatformRoleAdmin      = "admin"
+
+	// allNetworks is the NetworkRoles key of roles granted on every network
+	allNetworks = "all_networks"
+	// networkAdminRole is the suffix of network admin role IDs ("<network>-network-admin", "global-network-admin")
+	networkAdminRole = "network-admin"
+)
+
+// User is a Netmaker user account as returned by GET /api/users/{username}
+// Netmaker before the role model (v0.26) only sets IsAdmin and IsSuperAdmin
+type User struct {
+	UserName       string                         `json:"username"`
+	IsAdmin        bool                           `json:"isadmin"`
+	IsSuperAdmin   bool                           `json:"issuperadmin"`
+	PlatformRoleID string                         `json:"platform_role_id,omitempty"`
+	NetworkRoles   map[string]map[string]struct{} `json:"network_roles,omitempty"`  // network -> role IDs
+	UserGroups     map[string]struct{}            `json:"user_group_ids,omitempty"` // Group roles are not resolved
+}
+
+// PlatformAdmin reports whether the user may manage every network, including creating networks
+func (u *User) PlatformAdmin() bool {
+	return u.IsAdmin || u.IsSuperAdmin || u.PlatformRoleID == PlatformRoleSuperAdmin || u.PlatformRoleID == PlatformRoleAdmin
+}
+
+// NetworkAdmin reports whether the user may manage egress rules and external clients in a network
+func (u *User) NetworkAdmin(network string) bool {
+	if u.PlatformAdmin() {
+		return true
+	}
+	for _, roles := range []map[string]struct{}{u.NetworkRoles[network], u.NetworkRoles[allNetworks]} {
+		for role := range roles {
+			if strings.HasSuffix(role, networkAdminRole) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MissingPermissions lists the rights the user lacks to manage the given networks (and to create networks)
+// Returns nil if nothing is missing. Roles granted through user groups are not resolved, so for users in groups
+// (see UserGroups) the result is a lower bound on what the user may do, not proof
+func (u *User) MissingPermissions(networks []string, createNetworks bool) []string {
+	var missing []string
+	if createNetworks && !u.PlatformAdmin() {
+		missing = append(missing, "platform role admin or super-admin (to create networks)")
+	}
+	var networkAdmin []string
+	for _, network := range networks {
+		if !u.NetworkAdmin(network) {
+			networkAdmin = append(networkAdmin, network)
+		}
+	}
+	sort.Strings(networkAdmin)
+	for _, network := range networkAdmin {
+		missing = append(missing, fmt.Sprintf("network-admin role in network %s (egress rules)", network))
+	}
+	return missing
+}
+
+// usernameSource is implemented by authenticators that log in as a known user
+type usernameSource interface {
+	Username(ctx context.Context) (string, error)
+}
+
+// Username returns the username of the current credentials
+func (a *PasswordAuthenticator) Username(ctx context.Context) (string, error) {
+	credentials, err := a.source.Credentials(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get credentials: %w", err)
+	}
+	return credentials.Username, nil
+}
+
+// GetUser returns a Netmaker user (error wraps ErrNotFound if it doesn't exist)
+// Not part of the Client interface; used by the permission audit at startup
+func (c *HTTPClient) GetUser(ctx context.Context, username string) (*User, error) {
+	url := fmt.Sprintf("%s/api/users/%s", c.baseURL, url.PathEscape(username))
+
+	resp, err := c.doRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return nil, fmt.Errorf("user %s: %w", username, ErrNotFound)
+		}
+		return nil, fmt.Errorf("GetUser failed with HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var user User
+	if err := decodeResponse(resp, c.maxResponseBytes, "GetUser", "user", &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CurrentUser returns the Netmaker user the client authenticates as
+// Returns ErrUserUnknown if the authenticator does not log in with a username
+func (c *HTTPClient) CurrentUser(ctx context.Context) (*User, error) {
+	source, ok := c.authenticator.(usernameSource)
+	if !ok {
+		return nil, ErrUserUnknown
+	}
+	username, err := source.Username(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetUser(ctx, username)
+}
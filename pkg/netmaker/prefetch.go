@@ -0,0 +1,86 @@
+package netmaker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// PrefetchEgress lists the egress rules of several networks concurrently (at most concurrency at a time)
+// ListEgress serializes API calls behind the cache lock, so listing many networks one by one adds up their
+// latencies; the results are cached as if listed by ListEgress unless a write evicted egress rules meanwhile
+// Returns the rules by network, or the first error
+func (c *CachedClient) PrefetchEgress(ctx context.Context, networks []string, concurrency int) (map[string][]Egress, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	c.mu.RLock()
+	evictions := c.egressEvictions
+	c.mu.RUnlock()
+
+	type listing struct {
+		network  string
+		egresses []Egress
+		err      error
+	}
+
+	listings := make(chan listing, len(networks))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, network := range networks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				listings <- listing{network: network, err: ctx.Err()}
+				return
+			}
+			defer func() { <-slots }()
+
+			c.counters[CacheKindEgress].misses.Add(1)
+			egresses, err := c.Client.ListEgress(ctx, network)
+			listings <- listing{network: network, egresses: egresses, err: err}
+		}()
+	}
+	wg.Wait()
+	close(listings)
+
+	result := make(map[string][]Egress, len(networks))
+	var firstErr error
+	for l := range listings {
+		if l.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to list egress rules in network %s: %w", l.network, l.err)
+			}
+			continue
+		}
+		result[l.network] = l.egresses
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for network, egresses := range result {
+		c.egressListedAt[network] = now
+		if c.egressEvictions != evictions {
+			continue // A write may have landed after our list - don't cache what it replaced
+		}
+		if !reflect.DeepEqual(c.egressByNetwork[network], egresses) {
+			c.generation.Add(1)
+		}
+		c.egressByNetwork[network] = egresses
+		c.egressFetchedAt[network] = now
+	}
+
+	return result, nil
+}
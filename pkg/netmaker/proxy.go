@@ -0,0 +1,49 @@
+package netmaker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// SetProxy routes all Netmaker requests (including authentication) through an explicit proxy
+// Supported schemes are http, https and socks5; hosts matching noProxy (NO_PROXY syntax) bypass it
+// Without SetProxy the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables apply as usual
+// Must be called before the client is used
+func (c *HTTPClient) SetProxy(proxyURL, noProxy string) error {
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (must be http, https or socks5)", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return fmt.Errorf("proxy URL %q has no host", proxyURL)
+	}
+
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  proxyURL,
+		HTTPSProxy: proxyURL,
+		NoProxy:    noProxy,
+	}).ProxyFunc()
+
+	c.transport().Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+
+	return nil
+}
+
+// RedactedProxyURL returns proxyURL with any password masked (for log output)
+func RedactedProxyURL(proxyURL string) string {
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return "invalid"
+	}
+	return proxy.Redacted()
+}
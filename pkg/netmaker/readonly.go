@@ -0,0 +1,197 @@
+package netmaker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// Actions counted by ReadOnlyClient.Skipped
+const (
+	SkippedCreate         = "create"
+	SkippedUpdate         = "update"
+	SkippedDelete         = "delete"
+	SkippedExtClientRoute = "extclient"
+	SkippedHostJoin       = "join"
+)
+
+// ReadOnlyClient decorates a client so that selected networks are never mutated
+// Creates, updates and deletes of egress rules (and external client route updates and host joins) in these networks
+// are logged as drift, counted and reported as successful instead of being sent to Netmaker
+// SetDryRun makes every network read-only until it is turned off again
+// Wrap the HTTP client, not the CachedClient, so every egress listing passes through it
+type ReadOnlyClient struct {
+	Client // Embedded interface - automatic delegation
+
+	networks map[string]bool
+	dryRun   atomic.Bool
+
+	mu            sync.Mutex
+	egressNetwork map[string]string            // egress ID -> network, learned from ListEgress
+	skipped       map[string]map[string]uint64 // network -> action -> count
+}
+
+// NewReadOnlyClient wraps a client, making the given networks read-only
+func NewReadOnlyClient(client Client, networks []string) *ReadOnlyClient {
+	c := &ReadOnlyClient{
+		Client:        client,
+		networks:      make(map[string]bool, len(networks)),
+		egressNetwork: make(map[string]string),
+		skipped:       make(map[string]map[string]uint64),
+	}
+	for _, network := range networks {
+		c.networks[network] = true
+	}
+	return c
+}
+
+// SetDryRun turns dry-run mode (every network read-only) on or off
+func (c *ReadOnlyClient) SetDryRun(enabled bool) {
+	if c.dryRun.Swap(enabled) == enabled {
+		return
+	}
+	if enabled {
+		log.Printf("Dry-run mode enabled: Netmaker mutations in all networks are only reported")
+	} else {
+		log.Printf("Dry-run mode disabled: Netmaker mutations are applied again (except in read-only networks)")
+	}
+}
+
+// DryRun reports whether dry-run mode is on
+func (c *ReadOnlyClient) DryRun() bool {
+	return c.dryRun.Load()
+}
+
+// readOnly reports whether a network must not be mutated
+func (c *ReadOnlyClient) readOnly(network string) bool {
+	return c.dryRun.Load() || c.networks[network]
+}
+
+// skipsWrites implements writeSkipper, so the CachedClient doesn't cache skipped writes as if they had been applied
+func (c *ReadOnlyClient) skipsWrites(network string) bool {
+	return c.readOnly(network)
+}
+
+// ListEgress lists the egress rules of a network and remembers their network for DeleteEgress
+// Every network is remembered, since dry-run mode can be turned on at any time
+func (c *ReadOnlyClient) ListEgress(ctx context.Context, network string) ([]Egress, error) {
+	egresses, err := c.Client.ListEgress(ctx, network)
+	if err != nil {
+		return egresses, err
+	}
+
+	c.mu.Lock()
+	for id, known := range c.egressNetwork {
+		if known == network {
+			delete(c.egressNetwork, id)
+		}
+	}
+	for _, egress := range egresses {
+		c.egressNetwork[egress.ID] = network
+	}
+	c.mu.Unlock()
+
+	return egresses, nil
+}
+
+// CreateEgress creates an egress rule unless its network is read-only
+func (c *ReadOnlyClient) CreateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	if !c.readOnly(req.Network) {
+		return c.Client.CreateEgress(ctx, req)
+	}
+
+	c.skip(req.Network, SkippedCreate)
+	Logf(ctx, "Drift in read-only network %s: egress rule %q (%s) is missing", req.Network, req.Name, req.Range)
+	return egressFromRequest(req), nil
+}
+
+// UpdateEgress updates an egress rule unless its network is read-only
+func (c *ReadOnlyClient) UpdateEgress(ctx context.Context, req EgressReq) (*Egress, error) {
+	if !c.readOnly(req.Network) {
+		return c.Client.UpdateEgress(ctx, req)
+	}
+
+	c.skip(req.Network, SkippedUpdate)
+	Logf(ctx, "Drift in read-only network %s: egress rule %s should be %q (%s, gateways %v)",
+		req.Network, req.ID, req.Name, req.Range, req.Nodes)
+	return egressFromRequest(req), nil
+}
+
+// DeleteEgress deletes an egress rule unless it was listed in a read-only network or dry-run mode is on
+func (c *ReadOnlyClient) DeleteEgress(ctx context.Context, egressID string) error {
+	c.mu.Lock()
+	network, known := c.egressNetwork[egressID]
+	c.mu.Unlock()
+
+	if !c.dryRun.Load() && !(known && c.networks[network]) {
+		return c.Client.DeleteEgress(ctx, egressID)
+	}
+
+	c.skip(network, SkippedDelete)
+	Logf(ctx, "Drift in read-only network %s: egress rule %s should be deleted", network, egressID)
+	return nil
+}
+
+// UpdateExtClientAllowedIPs updates an external client's routes unless its network is read-only
+func (c *ReadOnlyClient) UpdateExtClientAllowedIPs(ctx context.Context, network, clientID string, allowedIPs []string) error {
+	if !c.readOnly(network) {
+		return c.Client.UpdateExtClientAllowedIPs(ctx, network, clientID, allowedIPs)
+	}
+
+	c.skip(network, SkippedExtClientRoute)
+	Logf(ctx, "Drift in read-only network %s: external client %s should have extra allowed IPs %v", network, clientID, allowedIPs)
+	return nil
+}
+
+// AddHostToNetwork joins a host to a network unless the network is read-only
+func (c *ReadOnlyClient) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	if !c.readOnly(network) {
+		return c.Client.AddHostToNetwork(ctx, hostID, network)
+	}
+
+	c.skip(network, SkippedHostJoin)
+	Logf(ctx, "Drift in read-only network %s: host %s should join it", network, hostID)
+	return nil
+}
+
+// Skipped returns the number of mutations skipped so far, by network and action
+func (c *ReadOnlyClient) Skipped() map[string]map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	skipped := make(map[string]map[string]uint64, len(c.skipped))
+	for network, actions := range c.skipped {
+		skipped[network] = make(map[string]uint64, len(actions))
+		for action, count := range actions {
+			skipped[network][action] = count
+		}
+	}
+	return skipped
+}
+
+// skip counts a skipped mutation
+func (c *ReadOnlyClient) skip(network, action string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.skipped[network] == nil {
+		c.skipped[network] = make(map[string]uint64)
+	}
+	c.skipped[network][action]++
+}
+
+// egressFromRequest returns the egress rule a request would produce (no ID for creates)
+func egressFromRequest(req EgressReq) *Egress {
+	return &Egress{
+		ID:          req.ID,
+		Name:        req.Name,
+		Network:     req.Network,
+		Description: req.Description,
+		Range:       req.Range,
+		NAT:         req.NAT,
+		Nodes:       req.Nodes,
+		Status:      req.Status,
+		UpdatedAt:   req.UpdatedAt,
+	}
+}
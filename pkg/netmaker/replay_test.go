@@ -0,0 +1,83 @@
+package netmaker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker/testproxy"
+)
+
+// TestHTTPClientReplay runs the HTTP client against the egress lifecycle in testdata/egress-lifecycle.json:
+// a login, bare and enveloped lists, a create, an update rejected as modified and a delete
+// Re-record it with NETMAKER_RECORD_URL, NETMAKER_USERNAME and NETMAKER_PASSWORD against a Netmaker with the same
+// hosts and rules; replays accept any credentials
+func TestHTTPClientReplay(t *testing.T) {
+	ctx := context.Background()
+	url := testproxy.New(t, "testdata/egress-lifecycle.json")
+	client, err := NewHTTPClient(url, getenvDefault("NETMAKER_USERNAME", "kaput-not"), getenvDefault("NETMAKER_PASSWORD", "secret"))
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	if err := client.Authenticate(ctx); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	hosts, err := client.ListHosts(ctx)
+	if err != nil {
+		t.Fatalf("ListHosts() error = %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Name != "worker-1" || len(hosts[0].Nodes) != 1 || hosts[0].Nodes[0] != "node-1" {
+		t.Fatalf("ListHosts() = %+v, want worker-1 with node-1", hosts)
+	}
+
+	egresses, err := client.ListEgress(ctx, "mesh")
+	if err != nil {
+		t.Fatalf("ListEgress() error = %v", err)
+	}
+	if len(egresses) != 1 || egresses[0].ID != "e1" || egresses[0].Nodes["node-1"] != 500 {
+		t.Fatalf("ListEgress() = %+v, want e1 routed through node-1", egresses)
+	}
+
+	created, err := client.CreateEgress(ctx, EgressReq{
+		Name:        "worker-1 pods (2/2)",
+		Network:     "mesh",
+		Description: "Managed by kaput-not (DO NOT EDIT): index=1",
+		Range:       "fd00:10:244:1::/64",
+		Nodes:       map[string]int{"node-1": 500},
+		Status:      true,
+	})
+	if err != nil {
+		t.Fatalf("CreateEgress() error = %v", err)
+	}
+	if created.ID != "e2" || created.Range != "fd00:10:244:1::/64" {
+		t.Errorf("CreateEgress() = %+v, want e2 for fd00:10:244:1::/64", created)
+	}
+
+	_, err = client.UpdateEgress(ctx, EgressReq{
+		ID:          "e1",
+		Name:        "worker-1 pods (1/2)",
+		Network:     "mesh",
+		Description: egresses[0].Description,
+		Range:       egresses[0].Range,
+		Nodes:       egresses[0].Nodes,
+		Status:      true,
+		UpdatedAt:   "2026-10-14T23:59:00Z",
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("UpdateEgress() error = %v, want %v", err, ErrConflict)
+	}
+
+	if err := client.DeleteEgress(ctx, "e1"); err != nil {
+		t.Errorf("DeleteEgress() error = %v", err)
+	}
+}
+
+// getenvDefault returns the environment variable key, or fallback if it is unset
+func getenvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,60 @@
+package netmaker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// RequestIDHeader carries the correlation ID of a reconcile on every Netmaker request, so a node's sync
+// can be traced from the controller logs into the Netmaker server (or proxy) logs
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// NewRequestID returns a random correlation ID (16 hex characters)
+func NewRequestID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:]) // Never fails
+	return hex.EncodeToString(id[:])
+}
+
+// WithRequestID returns a context carrying a request ID, sent as RequestIDHeader by HTTPClient
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of a context (empty if none)
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// EnsureRequestID returns ctx and its request ID, adding a new ID if it has none
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestID(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewRequestID()
+	return WithRequestID(ctx, id), id
+}
+
+// Logf logs a message with the request ID of ctx appended (if any)
+func Logf(ctx context.Context, format string, args ...interface{}) {
+	if id := RequestID(ctx); id != "" {
+		log.Printf("%s (request %s)", fmt.Sprintf(format, args...), id)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// setRequestID adds the request ID of the request's context as RequestIDHeader (no-op without one)
+func setRequestID(req *http.Request) {
+	if id := RequestID(req.Context()); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+}
@@ -0,0 +1,89 @@
+package netmaker
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// redacted replaces secret values in formatted output
+const redacted = "[REDACTED]"
+
+// secret holds a token or password in a buffer that is locked into memory where supported (never swapped to disk)
+// and wiped when the value is replaced or the secret is garbage collected
+// Formatting a secret (logs, panics, %v dumps) never prints the value
+// The strings returned by reveal are ordinary heap strings: use them for the request at hand and don't keep them
+type secret struct {
+	mu  sync.RWMutex
+	buf *[]byte // Separate allocation, so the cleanup can wipe it without keeping the secret reachable
+}
+
+// newSecret copies value into a locked buffer
+func newSecret(value string) *secret {
+	s := &secret{buf: new([]byte)}
+	runtime.AddCleanup(s, wipeBuffer, s.buf)
+	s.set(value)
+	return s
+}
+
+// set replaces the value, wiping the previous buffer
+func (s *secret) set(value string) {
+	buf := make([]byte, len(value))
+	copy(buf, value)
+	lockBuffer(buf)
+
+	s.mu.Lock()
+	old := *s.buf
+	*s.buf = buf
+	s.mu.Unlock()
+
+	wipeBuffer(&old)
+}
+
+// reveal returns a copy of the value ("" once wiped)
+func (s *secret) reveal() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return string(*s.buf)
+}
+
+// empty reports whether the secret holds no value
+func (s *secret) empty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(*s.buf) == 0
+}
+
+// wipe zeroes and releases the value
+func (s *secret) wipe() {
+	s.mu.Lock()
+	old := *s.buf
+	*s.buf = nil
+	s.mu.Unlock()
+
+	wipeBuffer(&old)
+}
+
+// String implements fmt.Stringer without revealing the value
+func (s *secret) String() string {
+	return redacted
+}
+
+// GoString implements fmt.GoStringer without revealing the value
+func (s *secret) GoString() string {
+	return redacted
+}
+
+// wipeBuffer zeroes and unlocks a buffer
+func wipeBuffer(buf *[]byte) {
+	if len(*buf) == 0 {
+		return
+	}
+	clear(*buf)
+	unlockBuffer(*buf)
+}
+
+// Format implements fmt.Formatter: the password is never printed, whatever the verb
+func (c Credentials) Format(f fmt.State, _ rune) {
+	fmt.Fprintf(f, "{Username:%s Password:%s}", c.Username, redacted)
+}
@@ -0,0 +1,109 @@
+package testproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// forwardedHeaders are the request headers passed on to Netmaker (never recorded)
+var forwardedHeaders = []string{"Authorization", "X-API-Key", "Content-Type", "X-Request-ID"}
+
+// Recorder is a proxy to a Netmaker API recording every request and response
+type Recorder struct {
+	target *url.URL
+	client *http.Client
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder creates a recorder forwarding to the Netmaker API at targetURL
+// Returns error for validation failures, never panics
+func NewRecorder(targetURL string) (*Recorder, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Netmaker API URL: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("invalid Netmaker API URL %q: must be http or https", targetURL)
+	}
+
+	return &Recorder{
+		target:   target,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		cassette: Cassette{RecordedAt: time.Now().UTC()},
+	}, nil
+}
+
+// ServeHTTP implements http.Handler, forwarding the request and recording the interaction
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	requestBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("testproxy: failed to read request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	target := *r.target
+	target.Path = strings.TrimSuffix(target.Path, "/") + req.URL.Path
+	target.RawQuery = req.URL.RawQuery
+	forward, err := http.NewRequestWithContext(req.Context(), req.Method, target.String(), bytes.NewReader(requestBody))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("testproxy: %v", err), http.StatusBadGateway)
+		return
+	}
+	for _, header := range forwardedHeaders {
+		if value := req.Header.Get(header); value != "" {
+			forward.Header.Set(header, value)
+		}
+	}
+
+	resp, err := r.client.Do(forward)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("testproxy: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("testproxy: failed to read response: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	interaction := Interaction{
+		Request: Request{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery},
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			RetryAfter:  resp.Header.Get("Retry-After"),
+		},
+	}
+	interaction.Request.Body, interaction.Request.Text = encodeBody(requestBody)
+	interaction.Response.Body, interaction.Response.Text = encodeBody(responseBody)
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	for _, header := range []string{"Content-Type", "Retry-After"} {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(responseBody) // The client sees the real response, credentials included
+}
+
+// Cassette returns the interactions recorded so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cassette := r.cassette
+	cassette.Interactions = append([]Interaction(nil), r.cassette.Interactions...)
+	return &cassette
+}
@@ -0,0 +1,80 @@
+package testproxy
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Replayer is a fake Netmaker API answering requests with the responses of a cassette
+// Interactions are matched by method, path and query; each answers one request, in recorded order. Once a GET
+// has used up its interactions, the last one is repeated (reads may happen more often than recorded, e.g. with
+// a different cache TTL); writes without a recorded answer get HTTP 501 and are reported by Unmatched
+// Request bodies and headers are not compared, so redacted logins replay with any credentials
+type Replayer struct {
+	mu        sync.Mutex
+	pending   map[string][]Interaction // Request key -> interactions not used yet
+	last      map[string]Interaction   // Request key -> last used interaction
+	unmatched []string
+}
+
+// NewReplayer creates a replayer for a cassette
+func NewReplayer(cassette *Cassette) *Replayer {
+	r := &Replayer{
+		pending: make(map[string][]Interaction),
+		last:    make(map[string]Interaction),
+	}
+	for _, interaction := range cassette.Interactions {
+		key := interaction.Request.key()
+		r.pending[key] = append(r.pending[key], interaction)
+	}
+	return r
+}
+
+// ServeHTTP implements http.Handler, answering with the next recorded response for the request
+func (r *Replayer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	request := Request{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery}
+	key := request.key()
+
+	r.mu.Lock()
+	interaction, ok := r.next(key, req.Method)
+	if !ok {
+		r.unmatched = append(r.unmatched, key)
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("testproxy: no recorded response for %s", key), http.StatusNotImplemented)
+		return
+	}
+
+	if interaction.Response.ContentType != "" {
+		w.Header().Set("Content-Type", interaction.Response.ContentType)
+	}
+	if interaction.Response.RetryAfter != "" {
+		w.Header().Set("Retry-After", interaction.Response.RetryAfter)
+	}
+	w.WriteHeader(interaction.Response.Status)
+	_, _ = w.Write(decodeBody(interaction.Response.Body, interaction.Response.Text))
+}
+
+// next returns the interaction answering a request
+// Must be called with mu held
+func (r *Replayer) next(key, method string) (Interaction, bool) {
+	if pending := r.pending[key]; len(pending) > 0 {
+		r.pending[key] = pending[1:]
+		r.last[key] = pending[0]
+		return pending[0], true
+	}
+	if last, ok := r.last[key]; ok && method == http.MethodGet {
+		return last, true
+	}
+	return Interaction{}, false
+}
+
+// Unmatched returns the requests (method, path and query) that had no recorded response
+func (r *Replayer) Unmatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.unmatched...)
+}
@@ -0,0 +1,123 @@
+package netmaker
+
+import (
+	"crypto/fips140"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// TLSPolicy restricts the TLS connections to Netmaker (API and authentication requests)
+// The zero value keeps the Go defaults (TLS 1.2+ with Go's secure cipher suites)
+type TLSPolicy struct {
+	// MinVersion is the minimum TLS version, "1.2" or "1.3" (empty: 1.2)
+	MinVersion string
+
+	// CipherSuites are the allowed TLS 1.2 cipher suites by IANA name, e.g. "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"
+	// Empty uses the Go defaults; TLS 1.3 suites are not configurable
+	CipherSuites []string
+
+	// FIPS restricts versions, cipher suites and curves to FIPS 140-3 approved ones
+	// Requires the Go Cryptographic Module: run with GODEBUG=fips140=on or build with GOFIPS140=v1.0.0
+	FIPS bool
+}
+
+// fipsCipherSuites are the FIPS 140-3 approved TLS 1.2 cipher suites supported by crypto/tls
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// IsZero reports whether the policy keeps the Go defaults
+func (p TLSPolicy) IsZero() bool {
+	return p.MinVersion == "" && len(p.CipherSuites) == 0 && !p.FIPS
+}
+
+// String describes the policy (for log output)
+func (p TLSPolicy) String() string {
+	minVersion := p.MinVersion
+	if minVersion == "" {
+		minVersion = "1.2"
+	}
+	parts := []string{"min=" + minVersion}
+	if len(p.CipherSuites) > 0 {
+		parts = append(parts, "ciphers="+strings.Join(p.CipherSuites, ","))
+	}
+	if p.FIPS {
+		parts = append(parts, "fips")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Validate checks the policy as SetTLSPolicy would, without applying it
+func (p TLSPolicy) Validate() error {
+	_, err := p.tlsConfig()
+	return err
+}
+
+// tlsConfig builds the tls.Config enforcing the policy
+// Returns error for unknown versions or cipher suites and for FIPS policies the binary can't honor
+func (p TLSPolicy) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	switch p.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported minimum TLS version %q (must be 1.2 or 1.3)", p.MinVersion)
+	}
+
+	if p.FIPS && !fips140.Enabled() {
+		return nil, fmt.Errorf("FIPS TLS policy requires FIPS 140-3 mode (run with GODEBUG=fips140=on or build with GOFIPS140=v1.0.0)")
+	}
+
+	supported := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() { // Secure suites only
+		supported[suite.Name] = suite.ID
+	}
+	for _, name := range p.CipherSuites {
+		id, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported or insecure TLS cipher suite %q", name)
+		}
+		if p.FIPS && !slices.Contains(fipsCipherSuites, id) {
+			return nil, fmt.Errorf("TLS cipher suite %q is not FIPS 140-3 approved", name)
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+
+	if p.FIPS {
+		if len(config.CipherSuites) == 0 {
+			config.CipherSuites = slices.Clone(fipsCipherSuites)
+		}
+		config.CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+	}
+
+	return config, nil
+}
+
+// SetTLSPolicy enforces a TLS policy on all Netmaker requests (including authentication)
+// Can be combined with SetProxy in either order; must be called before the client is used
+func (c *HTTPClient) SetTLSPolicy(policy TLSPolicy) error {
+	config, err := policy.tlsConfig()
+	if err != nil {
+		return err
+	}
+	c.transport().TLSClientConfig = config
+	return nil
+}
+
+// transport returns the client's own transport, cloning http.DefaultTransport on first use
+func (c *HTTPClient) transport() *http.Transport {
+	if transport, ok := c.client.Transport.(*http.Transport); ok {
+		return transport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c.client.Transport = transport
+	return transport
+}
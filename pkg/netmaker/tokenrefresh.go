@@ -0,0 +1,94 @@
+package netmaker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// tokenExpirySkew treats tokens as expired slightly early, so in-flight requests don't race the expiry
+	tokenExpirySkew = 5 * time.Second
+	// tokenRefreshRetryInterval is the wait after a failed background refresh
+	tokenRefreshRetryInterval = 10 * time.Second
+	// tokenRefreshPollInterval is how often tokens without an exp claim are re-checked
+	tokenRefreshPollInterval = time.Minute
+)
+
+// jwtExpiry decodes the exp claim of a JWT without verifying its signature
+// Returns the zero time for opaque tokens or tokens without exp
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
+
+// TokenExpiry returns the expiry of the current token (zero if unknown or not authenticated)
+func (c *HTTPClient) TokenExpiry() time.Time {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.tokenExpiry
+}
+
+// RunTokenRefresher re-authenticates margin before the token's exp claim, until ctx is canceled
+// Avoids the 401-retry round trip on the first request after expiry
+// Tokens without an exp claim are left to the 401 retry in doRequest
+func (c *HTTPClient) RunTokenRefresher(ctx context.Context, margin time.Duration) {
+	for {
+		wait := tokenRefreshPollInterval
+		if expiry := c.TokenExpiry(); !expiry.IsZero() {
+			// Wait until margin before expiry, but at least half the remaining lifetime,
+			// so a margin longer than the token lifetime doesn't refresh in a tight loop
+			wait = time.Until(expiry.Add(-margin))
+			if half := time.Until(expiry) / 2; wait < half {
+				wait = half
+			}
+		}
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		// Re-check: the token may have been renewed by a 401 retry meanwhile
+		expiry := c.TokenExpiry()
+		if expiry.IsZero() || time.Until(expiry) > margin {
+			continue
+		}
+
+		if err := c.Authenticate(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Proactive Netmaker token refresh failed (retrying in %s): %v", tokenRefreshRetryInterval, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(tokenRefreshRetryInterval):
+			}
+			continue
+		}
+		log.Printf("Refreshed Netmaker token proactively (expires %s)", c.TokenExpiry().Format(time.RFC3339))
+	}
+}
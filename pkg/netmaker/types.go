@@ -1,13 +1,18 @@
 package netmaker
 
-// AuthRequest is the request payload for authentication
+import (
+	"fmt"
+	"strings"
+)
+
+// AuthRequest is the request payload of the stock Netmaker login (see LoginEndpoint for other shapes)
 type AuthRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
 // AuthResponse is the response from POST /api/users/adm/authenticate
-// Code and Message are used for error handling
+// Code and Message are used for error handling; decoded generically by PasswordAuthenticator (see LoginEndpoint)
 type AuthResponse struct {
 	Code     int    `json:"Code,omitempty"`
 	Message  string `json:"Message,omitempty"`
@@ -16,28 +21,51 @@ type AuthResponse struct {
 	} `json:"Response"`
 }
 
-// Host represents a Netmaker host - minimal fields for node lookup
+// Host represents a Netmaker host - fields for node lookup and status reporting
 // Unknown fields from the API are silently ignored
 type Host struct {
-	ID    string   `json:"id"`
-	Name  string   `json:"name"`            // Matches Kubernetes node name
-	Nodes []string `json:"nodes,omitempty"` // Array of node UUIDs
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`                   // Matches Kubernetes node name
+	Nodes        []string `json:"nodes,omitempty"`        // Array of node UUIDs
+	EndpointIP   string   `json:"endpointip,omitempty"`   // Public IPv4 endpoint
+	EndpointIPv6 string   `json:"endpointipv6,omitempty"` // Public IPv6 endpoint
+	Version      string   `json:"version,omitempty"`      // netclient version
+
+	Interfaces []HostInterface `json:"interfaces,omitempty"` // Local interfaces reported by netclient
 }
 
-// Node represents a Netmaker node - minimal fields for host mapping
-// Unknown fields from the API are silently ignored
-type Node struct {
-	ID      string `json:"id"`      // Node UUID
-	HostID  string `json:"hostid"`  // Parent host UUID
-	Network string `json:"network"` // Network this node belongs to
+// HostInterface is a local network interface of a Netmaker host
+type HostInterface struct {
+	Name          string `json:"name"`
+	AddressString string `json:"addressString"` // Interface address in CIDR notation, e.g. "10.0.1.7/24"
+}
+
+// IPs returns the endpoint and interface IP addresses of the host (without prefix lengths)
+func (h *Host) IPs() []string {
+	var ips []string
+	for _, ip := range []string{h.EndpointIP, h.EndpointIPv6} {
+		if ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	for _, iface := range h.Interfaces {
+		if ip, _, _ := strings.Cut(iface.AddressString, "/"); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
 }
 
-// EgressResponse is the response from GET /api/v1/egress?network={network}
-// Code and Message are used for error handling
-type EgressResponse struct {
-	Code     int      `json:"Code,omitempty"`
-	Message  string   `json:"Message,omitempty"`
-	Response []Egress `json:"Response"`
+// Node represents a Netmaker node - fields for host mapping and status reporting
+// Unknown fields from the API are silently ignored
+type Node struct {
+	ID          string `json:"id"`                    // Node UUID
+	HostID      string `json:"hostid"`                // Parent host UUID
+	Network     string `json:"network"`               // Network this node belongs to
+	Address     string `json:"address,omitempty"`     // IPv4 mesh address (empty if the network has no IPv4 range)
+	Address6    string `json:"address6,omitempty"`    // IPv6 mesh address (empty if the network has no IPv6 range)
+	Connected   bool   `json:"connected"`             // Whether the node is connected to its network
+	LastCheckIn int64  `json:"lastcheckin,omitempty"` // Unix timestamp of the last netclient check-in
 }
 
 // Egress represents a Netmaker egress gateway
@@ -51,6 +79,7 @@ type Egress struct {
 	NAT         bool           `json:"nat"`
 	Nodes       map[string]int `json:"nodes,omitempty"` // Map of node UUID to metric
 	Status      bool           `json:"status"`
+	UpdatedAt   string         `json:"updated_at,omitempty"` // Modification timestamp, if the server provides one
 }
 
 // EgressReq is used for creating and updating egress gateways
@@ -63,20 +92,54 @@ type EgressReq struct {
 	NAT         bool           `json:"nat"`
 	Nodes       map[string]int `json:"nodes,omitempty"` // Map of node UUID to metric
 	Status      bool           `json:"status"`
+	UpdatedAt   string         `json:"updated_at,omitempty"` // Echoed on PUT so the server can detect concurrent edits
 }
 
-// EgressCreateResponse wraps the POST /api/v1/egress response
-// Code and Message are used for error handling
-type EgressCreateResponse struct {
-	Code     int    `json:"Code,omitempty"`
-	Message  string `json:"Message,omitempty"`
-	Response Egress `json:"Response"`
+// ExtClient represents a Netmaker external (WireGuard) client - minimal fields for route publishing
+// Unknown fields from the API are silently ignored (updates preserve them, see UpdateExtClientAllowedIPs)
+type ExtClient struct {
+	ClientID        string   `json:"clientid"`
+	Network         string   `json:"network"`
+	ExtraAllowedIPs []string `json:"extraallowedips,omitempty"` // Additional routes pushed to the client
 }
 
-// EgressUpdateResponse wraps the PUT /api/v1/egress response
-// Code and Message are used for error handling
-type EgressUpdateResponse struct {
-	Code     int    `json:"Code,omitempty"`
-	Message  string `json:"Message,omitempty"`
-	Response Egress `json:"Response"`
+// Network represents a Netmaker network - its address ranges and the default settings kaput-not reports
+// Unknown fields from the API are silently ignored; Netmaker applies its defaults to the unset ones on create
+type Network struct {
+	NetID         string `json:"netid"`
+	AddressRange  string `json:"addressrange,omitempty"`  // IPv4 CIDR
+	AddressRange6 string `json:"addressrange6,omitempty"` // IPv6 CIDR (optional)
+
+	// Default settings of the network's nodes, as reported by Netmaker (kaput-not never sets them)
+	DefaultKeepalive int32  `json:"defaultkeepalive,omitempty"` // WireGuard persistent keepalive in seconds
+	DefaultMTU       int32  `json:"defaultmtu,omitempty"`
+	DefaultACL       string `json:"defaultacl,omitempty"` // "yes" if nodes may reach each other by default
+}
+
+// AddressRanges returns the network's address ranges, IPv4 first (empty ones left out)
+func (n *Network) AddressRanges() []string {
+	var ranges []string
+	for _, addressRange := range []string{n.AddressRange, n.AddressRange6} {
+		if addressRange != "" {
+			ranges = append(ranges, addressRange)
+		}
+	}
+	return ranges
+}
+
+// String describes the network with its address ranges for logs, e.g. "mesh (10.101.0.0/16, fd00::/64)"
+func (n *Network) String() string {
+	ranges := n.AddressRanges()
+	if len(ranges) == 0 {
+		return n.NetID
+	}
+	return fmt.Sprintf("%s (%s)", n.NetID, strings.Join(ranges, ", "))
+}
+
+// TokenExchangeResponse is the RFC 8693 token exchange response
+// Error and ErrorDescription are used for error handling
+type TokenExchangeResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
 }
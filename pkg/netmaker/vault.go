@@ -0,0 +1,291 @@
+package netmaker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultConfig contains configuration for reading Netmaker credentials from HashiCorp Vault
+// Authenticates with the Kubernetes auth method using the pod's service account token
+type VaultConfig struct {
+	// Address is the Vault server URL (e.g. https://vault.example.com:8200)
+	Address string
+
+	// Role is the Vault Kubernetes auth role to log in as
+	Role string
+
+	// SecretPath is the API path of the secret, without the /v1/ prefix
+	// KV v2: "secret/data/kaput-not", KV v1: "secret/kaput-not"
+	SecretPath string
+
+	// AuthMount is the mount path of the Kubernetes auth method
+	// Default: "kubernetes"
+	AuthMount string
+
+	// Namespace is the Vault Enterprise namespace (optional)
+	Namespace string
+
+	// TokenFile is the service account token used to log in
+	// Default: /var/run/secrets/kubernetes.io/serviceaccount/token
+	TokenFile string
+
+	// UsernameKey and PasswordKey are the keys inside the secret
+	// Default: "username" and "password"
+	UsernameKey string
+	PasswordKey string
+}
+
+// Validate validates the configuration
+func (c *VaultConfig) Validate() error {
+	if c.Address == "" {
+		return fmt.Errorf("Address is required")
+	}
+	if c.Role == "" {
+		return fmt.Errorf("Role is required")
+	}
+	if c.SecretPath == "" {
+		return fmt.Errorf("SecretPath is required")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to the configuration
+func (c *VaultConfig) ApplyDefaults() {
+	c.Address = strings.TrimSuffix(c.Address, "/")
+	c.SecretPath = strings.Trim(c.SecretPath, "/")
+
+	if c.AuthMount == "" {
+		c.AuthMount = "kubernetes"
+	}
+	if c.TokenFile == "" {
+		c.TokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	}
+	if c.UsernameKey == "" {
+		c.UsernameKey = "username"
+	}
+	if c.PasswordKey == "" {
+		c.PasswordKey = "password"
+	}
+}
+
+// VaultCredentialSource reads Netmaker credentials from a Vault KV secret
+// The Vault token is renewed automatically once half its TTL has passed,
+// and a fresh login is performed if renewal fails or the token is not renewable
+// Tokens without a TTL (lease_duration 0) never expire and are kept until a secret read fails
+type VaultCredentialSource struct {
+	config *VaultConfig
+	client *http.Client
+
+	// Vault token management (internal state)
+	mu          sync.Mutex
+	token       *secret // Locked and wiped on renewal, never printed (see secret)
+	renewable   bool
+	issuedAt    time.Time
+	tokenExpiry time.Time // Zero if the token never expires
+}
+
+// NewVaultCredentialSource creates a credential source backed by Vault
+// Returns error for validation failures, never panics
+func NewVaultCredentialSource(config *VaultConfig) (*VaultCredentialSource, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid vault config: %w", err)
+	}
+	config.ApplyDefaults()
+
+	return &VaultCredentialSource{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		token:  newSecret(""),
+	}, nil
+}
+
+// Credentials implements CredentialSource
+func (s *VaultCredentialSource) Credentials(ctx context.Context) (Credentials, error) {
+	token, err := s.getToken(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var secretResp vaultSecretResponse
+	if err := s.do(ctx, http.MethodGet, s.config.SecretPath, token, nil, &secretResp); err != nil {
+		s.dropToken() // E.g. revoked - log in again next time
+		return Credentials{}, fmt.Errorf("failed to read vault secret %s: %w", s.config.SecretPath, err)
+	}
+
+	// KV v2 nests the secret under data.data, KV v1 returns it directly under data
+	data := secretResp.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	username, _ := data[s.config.UsernameKey].(string)
+	password, _ := data[s.config.PasswordKey].(string)
+	if username == "" || password == "" {
+		return Credentials{}, fmt.Errorf("vault secret %s is missing %q or %q", s.config.SecretPath, s.config.UsernameKey, s.config.PasswordKey)
+	}
+
+	return Credentials{Username: username, Password: password}, nil
+}
+
+// getToken returns a valid Vault token, renewing or logging in as needed
+func (s *VaultCredentialSource) getToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	// Token still fresh (or never expiring) - use as is
+	if !s.token.empty() && (s.tokenExpiry.IsZero() || now.Before(s.renewAt())) {
+		return s.token.reveal(), nil
+	}
+
+	// Past half its TTL - try to renew before it expires
+	if !s.token.empty() && s.renewable && now.Before(s.tokenExpiry) {
+		if err := s.renew(ctx); err == nil {
+			return s.token.reveal(), nil
+		}
+		// Renewal failed - fall through to a fresh login
+	}
+
+	if err := s.login(ctx); err != nil {
+		return "", err
+	}
+
+	return s.token.reveal(), nil
+}
+
+// renewAt returns the point in time after which the token should be renewed (half its TTL)
+// Meaningless for tokens that never expire (zero tokenExpiry); must be called with mu held
+func (s *VaultCredentialSource) renewAt() time.Time {
+	return s.issuedAt.Add(s.tokenExpiry.Sub(s.issuedAt) / 2)
+}
+
+// login authenticates with the Kubernetes auth method
+// Must be called with mu held
+func (s *VaultCredentialSource) login(ctx context.Context) error {
+	jwt, err := os.ReadFile(s.config.TokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	payload := map[string]string{
+		"role": s.config.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+
+	var authResp vaultAuthResponse
+	path := fmt.Sprintf("auth/%s/login", strings.Trim(s.config.AuthMount, "/"))
+	if err := s.do(ctx, http.MethodPost, path, "", payload, &authResp); err != nil {
+		return fmt.Errorf("vault login failed: %w", err)
+	}
+
+	return s.storeToken(authResp)
+}
+
+// renew extends the current token's lease
+// Must be called with mu held
+func (s *VaultCredentialSource) renew(ctx context.Context) error {
+	var authResp vaultAuthResponse
+	if err := s.do(ctx, http.MethodPost, "auth/token/renew-self", s.token.reveal(), map[string]string{}, &authResp); err != nil {
+		return fmt.Errorf("vault token renewal failed: %w", err)
+	}
+
+	return s.storeToken(authResp)
+}
+
+// storeToken saves the token from an auth response
+// Must be called with mu held
+func (s *VaultCredentialSource) storeToken(authResp vaultAuthResponse) error {
+	if authResp.Auth.ClientToken == "" {
+		return fmt.Errorf("vault response contains no client token")
+	}
+
+	s.token.set(authResp.Auth.ClientToken)
+	s.renewable = authResp.Auth.Renewable
+	s.issuedAt = time.Now()
+	s.tokenExpiry = time.Time{}
+	if authResp.Auth.LeaseDuration > 0 {
+		s.tokenExpiry = s.issuedAt.Add(time.Duration(authResp.Auth.LeaseDuration) * time.Second)
+	}
+
+	return nil
+}
+
+// dropToken forgets the current token, so the next request logs in again
+func (s *VaultCredentialSource) dropToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token.wipe()
+	s.tokenExpiry = time.Time{}
+}
+
+// do performs a Vault API request and decodes the JSON response into out
+func (s *VaultCredentialSource) do(ctx context.Context, method, path, token string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/v1/%s", s.config.Address, path), reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if s.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.config.Namespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check HTTP status first
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes := errorBody(resp.Body)
+		return fmt.Errorf("HTTP status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	// Validate Content-Type is JSON
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "application/json") {
+		return fmt.Errorf("expected JSON response, got Content-Type: %s", contentType)
+	}
+
+	if err := decodeLimited(resp.Body, maxAuthResponseBytes, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// vaultAuthResponse is the response from Vault login and token renewal endpoints
+type vaultAuthResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"` // Seconds
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// vaultSecretResponse is the response from reading a KV secret
+type vaultSecretResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
@@ -0,0 +1,184 @@
+// Package nodeclaims tracks Karpenter NodeClaims to learn about node churn before the Node object changes
+// Karpenter deletes a NodeClaim as soon as it decides to disrupt the node, and records the node name once the
+// node registered, so egress rules can be turned off ahead of the VM deletion and created as soon as possible
+package nodeclaims
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+// NodeClaimResource is the Karpenter NodeClaim resource (cluster-scoped)
+var NodeClaimResource = schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodeclaims"}
+
+// Options contains configuration for the watcher
+type Options struct {
+	// DynamicClient reads NodeClaims
+	DynamicClient dynamic.Interface
+
+	// ResyncPeriod is how often the informer resyncs
+	// Default: 10 minutes
+	ResyncPeriod time.Duration
+
+	// OnTerminating is called with the names of nodes whose NodeClaim started or stopped terminating (optional)
+	OnTerminating func(nodeNames []string)
+
+	// OnRegistered is called with the names of nodes that newly registered for a NodeClaim (optional)
+	OnRegistered func(nodeNames []string)
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.DynamicClient == nil {
+		return fmt.Errorf("DynamicClient is required")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.ResyncPeriod == 0 {
+		o.ResyncPeriod = 10 * time.Minute
+	}
+}
+
+// Watcher maps nodes to the state of their NodeClaims
+// status.nodeName links a NodeClaim to its node; a deletion timestamp means Karpenter is removing the node
+type Watcher struct {
+	options *Options
+
+	informer cache.SharedIndexInformer
+	synced   cache.InformerSynced
+
+	mu          sync.RWMutex
+	nodes       map[string]bool // Names of nodes with a NodeClaim
+	terminating map[string]bool // Names of nodes whose NodeClaim is being deleted
+}
+
+// New creates a new watcher
+// Returns error for validation failures, never panics
+func New(opts *Options) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	w := &Watcher{
+		options:     opts,
+		nodes:       make(map[string]bool),
+		terminating: make(map[string]bool),
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(opts.DynamicClient, opts.ResyncPeriod)
+	w.informer = factory.ForResource(NodeClaimResource).Informer()
+
+	registration, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { w.refresh() },
+		UpdateFunc: func(_, _ interface{}) { w.refresh() },
+		DeleteFunc: func(interface{}) { w.refresh() },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add event handler: %w", err)
+	}
+	w.synced = registration.HasSynced
+
+	return w, nil
+}
+
+// Run starts the informer and blocks until the context is canceled
+func (w *Watcher) Run(ctx context.Context) {
+	defer runtime.HandleCrash()
+	w.informer.Run(ctx.Done())
+}
+
+// WaitForSync blocks until the initial NodeClaims have been processed or ctx is canceled
+// Returns false if ctx was canceled first
+func (w *Watcher) WaitForSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), w.synced)
+}
+
+// Terminating reports whether Karpenter is removing a node (its NodeClaim is being deleted)
+func (w *Watcher) Terminating(nodeName string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.terminating[nodeName]
+}
+
+// refresh recomputes the node states and notifies the callbacks about the changed ones
+// The initial NodeClaims are not notified: nodes are reconciled after WaitForSync anyway
+func (w *Watcher) refresh() {
+	nodes := make(map[string]bool)
+	terminating := make(map[string]bool)
+	for _, obj := range w.informer.GetStore().List() {
+		claim, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		nodeName, _, _ := unstructured.NestedString(claim.Object, "status", "nodeName")
+		if nodeName == "" {
+			continue // Not registered yet
+		}
+		nodes[nodeName] = true
+		if claim.GetDeletionTimestamp() != nil {
+			terminating[nodeName] = true
+		}
+	}
+
+	w.mu.Lock()
+	var registered, changed []string
+	for name := range nodes {
+		if !w.nodes[name] {
+			registered = append(registered, name)
+		}
+	}
+	for name := range terminating {
+		if !w.terminating[name] {
+			changed = append(changed, name)
+		}
+	}
+	for name := range w.terminating {
+		if !terminating[name] {
+			changed = append(changed, name)
+		}
+	}
+	w.nodes = nodes
+	w.terminating = terminating
+	w.mu.Unlock()
+
+	if w.synced == nil || !w.synced() {
+		return
+	}
+
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		log.Printf("Karpenter NodeClaim termination changed for %d node(s): %s", len(changed), strings.Join(changed, ", "))
+		if w.options.OnTerminating != nil {
+			w.options.OnTerminating(changed)
+		}
+	}
+	if len(registered) > 0 && w.options.OnRegistered != nil {
+		sort.Strings(registered)
+		w.options.OnRegistered(registered)
+	}
+}
+
+// Permissions returns the RBAC rules the watcher's informer needs
+func (o *Options) Permissions() []rbac.Permission {
+	return []rbac.Permission{
+		{Rule: rbac.Rule(NodeClaimResource.Group, NodeClaimResource.Resource, "list", "watch")},
+	}
+}
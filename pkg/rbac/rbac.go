@@ -0,0 +1,109 @@
+// Package rbac collects the Kubernetes RBAC rules declared by the packages performing the API calls
+// and renders them as ClusterRole/Role manifests (see the rbac command)
+package rbac
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Permission is an RBAC rule needed by a component
+type Permission struct {
+	// Namespace scopes the rule to a Role in this namespace; empty means cluster-wide (ClusterRole)
+	Namespace string
+
+	// Rule is the granted access
+	Rule rbacv1.PolicyRule
+}
+
+// Subject is the service account the roles are bound to
+type Subject struct {
+	Name      string
+	Namespace string
+}
+
+// Render returns multi-document YAML: a ClusterRole and ClusterRoleBinding for the cluster-wide
+// permissions, plus a Role and RoleBinding per namespace (in namespace order)
+func Render(name string, subject Subject, permissions []Permission) ([]byte, error) {
+	var clusterRules []rbacv1.PolicyRule
+	namespaceRules := make(map[string][]rbacv1.PolicyRule)
+	for _, p := range permissions {
+		if p.Namespace == "" {
+			clusterRules = append(clusterRules, p.Rule)
+		} else {
+			namespaceRules[p.Namespace] = append(namespaceRules[p.Namespace], p.Rule)
+		}
+	}
+
+	labels := map[string]string{"app.kubernetes.io/name": "kaput-not"}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: subject.Name, Namespace: subject.Namespace}}
+
+	var objects []interface{}
+	if len(clusterRules) > 0 {
+		objects = append(objects,
+			&rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+				Rules:      clusterRules,
+			},
+			&rbacv1.ClusterRoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+				Subjects:   subjects,
+			},
+		)
+	}
+
+	namespaces := make([]string, 0, len(namespaceRules))
+	for namespace := range namespaceRules {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+				Rules:      namespaceRules[namespace],
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+				Subjects:   subjects,
+			},
+		)
+	}
+
+	var out bytes.Buffer
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %T: %w", obj, err)
+		}
+		out.WriteString("---\n")
+		out.Write(data)
+	}
+
+	return out.Bytes(), nil
+}
+
+// Rule is shorthand for a PolicyRule on one resource of an API group ("" is the core group)
+func Rule(apiGroup, resource string, verbs ...string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{APIGroups: []string{apiGroup}, Resources: []string{resource}, Verbs: verbs}
+}
+
+// NamedRule is like Rule, restricted to the given object names
+// Note that create and list/watch cannot be restricted by name
+func NamedRule(apiGroup, resource string, names []string, verbs ...string) rbacv1.PolicyRule {
+	rule := Rule(apiGroup, resource, verbs...)
+	rule.ResourceNames = names
+	return rule
+}
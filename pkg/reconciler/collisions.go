@@ -0,0 +1,73 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// Collision is an egress rule not managed by kaput-not whose range is identical to or overlaps one of ours
+// in the same network; Netmaker may route the shared addresses through either rule's gateways
+type Collision struct {
+	Network string
+
+	// EgressID, Name and Range describe our rule
+	EgressID string
+	Name     string
+	Range    string
+
+	// UnmanagedID, UnmanagedName and UnmanagedRange describe the rule created outside kaput-not
+	UnmanagedID    string
+	UnmanagedName  string
+	UnmanagedRange string
+}
+
+// String describes the collision naming both rules
+func (c Collision) String() string {
+	relation := "overlaps"
+	if cidr.Equal(c.Range, c.UnmanagedRange) {
+		relation = "has the same range as"
+	}
+	return fmt.Sprintf("egress rule %s (%q, %s) in network %s %s unmanaged egress rule %s (%q, %s)",
+		c.EgressID, c.Name, c.Range, c.Network, relation, c.UnmanagedID, c.UnmanagedName, c.UnmanagedRange)
+}
+
+// unmanagedCollisions returns the rules of existing without a kaput-not marker that overlap one of ours
+// Rules managed by other clusters or instances are not unmanaged: their overlaps are reported by their own kaput-not
+func unmanagedCollisions(network string, ours []netmaker.Egress, existing []netmaker.Egress) []Collision {
+	var collisions []Collision
+	for i := range existing {
+		unmanaged := &existing[i]
+		if parseEgress(unmanaged) != nil {
+			continue
+		}
+		for _, egress := range ours {
+			if egress.ID == unmanaged.ID || !cidr.Overlaps(egress.Range, unmanaged.Range) {
+				continue // Adopted in this reconcile (see Options.AdoptExisting), or no overlap
+			}
+			collisions = append(collisions, Collision{
+				Network:        network,
+				EgressID:       egress.ID,
+				Name:           egress.Name,
+				Range:          egress.Range,
+				UnmanagedID:    unmanaged.ID,
+				UnmanagedName:  unmanaged.Name,
+				UnmanagedRange: unmanaged.Range,
+			})
+		}
+	}
+	return collisions
+}
+
+// reportCollisions logs and counts collisions with unmanaged egress rules
+// They are reported on every reconcile until an operator resolves them, e.g. by deleting or narrowing the
+// unmanaged rule; kaput-not never touches rules it doesn't manage
+func reportCollisions(ctx context.Context, collisions []Collision) {
+	for _, collision := range collisions {
+		metrics.UnmanagedEgressCollisions.WithLabelValues(collision.Network).Inc()
+		netmaker.Logf(ctx, "WARNING: %s - Netmaker may route the shared addresses through either rule's gateways", collision)
+	}
+}
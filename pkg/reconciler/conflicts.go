@@ -0,0 +1,34 @@
+package reconciler
+
+import (
+	"strings"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// ConflictingWrite reports whether an out-of-band change (see netmaker.ChangeDetector) looks like another kaput-not
+// writing egress rules as the cluster identity clusterName/instanceID, e.g. a second cluster with the same name:
+// a rule created with the identity's metadata, or a rule whose metadata (not just an operator note) was rewritten
+// Edits in the Netmaker UI rarely produce either; deletes are never counted, operators delete rules by hand
+func ConflictingWrite(change netmaker.ExternalChange, clusterName string, instanceID string) bool {
+	if change.After == nil {
+		return false
+	}
+	metadata := parseEgressDescription(change.After.Description) // A stripped description is no kaput-not write
+	if metadata == nil || !metadata.ownedBy(clusterName, instanceID) {
+		return false
+	}
+
+	switch change.Kind {
+	case netmaker.ChangeCreated:
+		return true
+	case netmaker.ChangeModified:
+		if change.Before == nil || parseEgressDescription(change.Before.Description) == nil {
+			return false // Our metadata restored, e.g. by an operator undoing an edit
+		}
+		before, _, _ := strings.Cut(change.Before.Description, noteSeparator)
+		after, _, _ := strings.Cut(change.After.Description, noteSeparator)
+		return before != after
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// ExtClientsAll selects every external client in the node's networks
+const ExtClientsAll = "*"
+
+// ExtClientGrant exposes a node's pod CIDRs to Netmaker external clients
+type ExtClientGrant struct {
+	NodeName  string
+	PodCIDRs  []string
+	ClientIDs []string // External client IDs or ExtClientsAll; empty exposes the node to no client
+
+	// Networks the grant was last published in (see Reconciler.ExtClientNetworks); its pod CIDRs are withdrawn from
+	// their clients once the node's Netmaker host is gone, e.g. for retired grants of deleted nodes
+	Networks []string
+}
+
+// extClientNetworks remembers the networks the grant of each node was last resolved to
+type extClientNetworks struct {
+	mu     sync.Mutex
+	byNode map[string][]string
+}
+
+// update remembers the networks of the resolved grants and keeps those of unresolved ones; nodes without a grant
+// are forgotten
+func (m *extClientNetworks) update(grants []ExtClientGrant, resolved map[string][]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	byNode := make(map[string][]string, len(grants))
+	for _, grant := range grants {
+		if networks, ok := resolved[grant.NodeName]; ok {
+			byNode[grant.NodeName] = networks
+		} else if networks, ok := m.byNode[grant.NodeName]; ok {
+			byNode[grant.NodeName] = networks
+		}
+	}
+	m.byNode = byNode
+}
+
+// get returns the remembered networks of a node
+func (m *extClientNetworks) get(nodeName string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.byNode[nodeName]
+}
+
+// ExtClientNetworks returns the networks the external client grant of a node was last published in, or else the
+// networks of its egress rules in the state store; retired grants keep them as ExtClientGrant.Networks
+func (r *Reconciler) ExtClientNetworks(nodeName string) []string {
+	if networks := r.extClientNetworks.get(nodeName); len(networks) > 0 {
+		return networks
+	}
+	if r.options.StateStore == nil {
+		return nil
+	}
+
+	var networks []string
+	seen := make(map[string]bool)
+	for _, ref := range r.options.StateStore.Get(nodeName) {
+		if !seen[ref.Network] {
+			seen[ref.Network] = true
+			networks = append(networks, ref.Network)
+		}
+	}
+	return networks
+}
+
+// SyncExtClients publishes granted pod CIDRs as extra allowed IPs (routes) on Netmaker external clients
+// grants must cover every cluster node (with empty ClientIDs if not exposed), because all their pod CIDRs
+// count as managed: managed entries that are no longer granted are removed from a client, everything
+// else in its extra allowed IPs is left untouched
+// Clients are only visited in networks that at least one cluster node participates in, or that the grant of a node
+// whose Netmaker host is gone was last published in (ExtClientGrant.Networks)
+func (r *Reconciler) SyncExtClients(ctx context.Context, grants []ExtClientGrant) error {
+	allNodes, err := r.options.NetmakerClient.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodeNetworks := make(map[string]string, len(allNodes)) // nodeID -> network
+	for _, n := range allNodes {
+		nodeNetworks[n.ID] = n.Network
+	}
+
+	// Resolve grants to desired CIDRs per network and client ("*" applies to every client)
+	desired := make(map[string]map[string][]string) // network -> clientID -> CIDRs
+	managed := make(map[string]bool)
+	resolved := make(map[string][]string) // node name -> networks
+	visit := func(network string) {
+		// Visit the network even without clients granted, so revoked grants are cleaned up
+		if desired[network] == nil {
+			desired[network] = make(map[string][]string)
+		}
+	}
+	for _, grant := range grants {
+		for _, cidr := range grant.PodCIDRs {
+			managed[cidr] = true
+		}
+
+		nodeIDs, err := r.options.NetmakerClient.GetNodeIDsByHostname(ctx, grant.NodeName)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			return fmt.Errorf("failed to get node IDs for node %s: %w", grant.NodeName, err)
+		}
+
+		var networks []string
+		for _, id := range nodeIDs {
+			if network, ok := nodeNetworks[id]; ok {
+				networks = append(networks, network)
+			}
+		}
+		if len(networks) == 0 {
+			// Host gone or not (yet) joined to Netmaker - nothing to publish, but withdraw the CIDRs where they were
+			for _, network := range grant.Networks {
+				visit(network)
+			}
+			continue
+		}
+
+		resolved[grant.NodeName] = networks
+		for _, network := range networks {
+			visit(network)
+			for _, clientID := range grant.ClientIDs {
+				desired[network][clientID] = append(desired[network][clientID], grant.PodCIDRs...)
+			}
+		}
+	}
+	r.extClientNetworks.update(grants, resolved)
+
+	var syncErrors []error
+	for network, clients := range desired {
+		if err := r.syncExtClientsInNetwork(ctx, network, clients, managed); err != nil {
+			syncErrors = append(syncErrors, fmt.Errorf("network %s: %w", network, err))
+		}
+	}
+
+	if len(syncErrors) > 0 {
+		return fmt.Errorf("failed to sync external clients in some networks: %v", syncErrors)
+	}
+
+	return nil
+}
+
+// syncExtClientsInNetwork updates the extra allowed IPs of every client in a network
+func (r *Reconciler) syncExtClientsInNetwork(ctx context.Context, network string, desired map[string][]string, managed map[string]bool) error {
+	extClients, err := r.options.NetmakerClient.ListExtClients(ctx, network)
+	if err != nil {
+		return fmt.Errorf("failed to list external clients: %w", err)
+	}
+
+	for _, extClient := range extClients {
+		granted := append(append([]string{}, desired[ExtClientsAll]...), desired[extClient.ClientID]...)
+		allowedIPs := mergeAllowedIPs(extClient.ExtraAllowedIPs, granted, managed)
+		if stringSlicesEqual(allowedIPs, extClient.ExtraAllowedIPs) {
+			continue
+		}
+
+		if err := r.options.NetmakerClient.UpdateExtClientAllowedIPs(ctx, network, extClient.ClientID, allowedIPs); err != nil {
+			return fmt.Errorf("failed to update external client %s: %w", extClient.ClientID, err)
+		}
+		log.Printf("Updated external client %s in network %s: extra allowed IPs %v", extClient.ClientID, network, allowedIPs)
+	}
+
+	return nil
+}
+
+// mergeAllowedIPs keeps unmanaged entries in their original order, drops managed entries that
+// are no longer granted and appends newly granted entries
+func mergeAllowedIPs(current, granted []string, managed map[string]bool) []string {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, cidr := range granted {
+		grantedSet[cidr] = true
+	}
+
+	result := make([]string, 0, len(current)+len(granted))
+	seen := make(map[string]bool, len(current)+len(granted))
+	for _, cidr := range current {
+		if seen[cidr] || (managed[cidr] && !grantedSet[cidr]) {
+			continue
+		}
+		seen[cidr] = true
+		result = append(result, cidr)
+	}
+	for _, cidr := range granted {
+		if !seen[cidr] {
+			seen[cidr] = true
+			result = append(result, cidr)
+		}
+	}
+
+	return result
+}
+
+// stringSlicesEqual checks if two string slices are equal, including order
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
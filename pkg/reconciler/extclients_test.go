@@ -0,0 +1,107 @@
+package reconciler
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// extClientFixture has node n1 (host h1) in network mesh and client c1 routing n1's pod CIDR next to an unmanaged route
+func extClientFixture() *netmaker.Fixture {
+	return &netmaker.Fixture{
+		Hosts: []netmaker.Host{{ID: "h1", Name: "n1", Nodes: []string{"node-1"}}},
+		Nodes: []netmaker.Node{{ID: "node-1", HostID: "h1", Network: "mesh"}},
+		ExtClients: map[string][]netmaker.ExtClient{
+			"mesh": {{ClientID: "c1", Network: "mesh", ExtraAllowedIPs: []string{"192.168.0.0/24", "10.244.1.0/24"}}},
+		},
+	}
+}
+
+// extraAllowedIPs returns the extra allowed IPs of a client in the mesh network
+func extraAllowedIPs(t *testing.T, client netmaker.Client, clientID string) []string {
+	t.Helper()
+	extClients, err := client.ListExtClients(context.Background(), "mesh")
+	if err != nil {
+		t.Fatalf("ListExtClients() error = %v", err)
+	}
+	for _, extClient := range extClients {
+		if extClient.ClientID == clientID {
+			return extClient.ExtraAllowedIPs
+		}
+	}
+	t.Fatalf("external client %s not found", clientID)
+	return nil
+}
+
+func TestSyncExtClientsWithdrawsRetiredGrants(t *testing.T) {
+	tests := []struct {
+		name      string
+		hostGone  bool
+		networks  []string
+		wantRoute bool
+	}{
+		{name: "host still there", hostGone: false, wantRoute: false},
+		{name: "host deleted, networks known", hostGone: true, networks: []string{"mesh"}, wantRoute: false},
+		{name: "host deleted, networks unknown", hostGone: true, wantRoute: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &vanishingHosts{Client: netmaker.NewFixtureClient(extClientFixture())}
+			client.gone.Store(tt.hostGone)
+			r := newFixtureReconciler(t, client, nil)
+
+			retired := ExtClientGrant{NodeName: "n1", PodCIDRs: []string{"10.244.1.0/24"}, Networks: tt.networks}
+			if err := r.SyncExtClients(context.Background(), []ExtClientGrant{retired}); err != nil {
+				t.Fatalf("SyncExtClients() error = %v", err)
+			}
+
+			got := extraAllowedIPs(t, client, "c1")
+			if slices.Contains(got, "10.244.1.0/24") != tt.wantRoute {
+				t.Errorf("extra allowed IPs = %v, want pod CIDR routed = %v", got, tt.wantRoute)
+			}
+			if !slices.Contains(got, "192.168.0.0/24") {
+				t.Errorf("extra allowed IPs = %v, unmanaged route was removed", got)
+			}
+		})
+	}
+}
+
+func TestSyncExtClientsHostDeletedBeforeWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	client := &vanishingHosts{Client: netmaker.NewFixtureClient(extClientFixture())}
+	r := newFixtureReconciler(t, client, nil)
+
+	// Published while the node exists
+	grant := ExtClientGrant{NodeName: "n1", PodCIDRs: []string{"10.244.1.0/24"}, ClientIDs: []string{"c1"}}
+	if err := r.SyncExtClients(ctx, []ExtClientGrant{grant}); err != nil {
+		t.Fatalf("SyncExtClients() error = %v", err)
+	}
+	if got := r.ExtClientNetworks("n1"); !slices.Equal(got, []string{"mesh"}) {
+		t.Fatalf("ExtClientNetworks() = %v, want [mesh]", got)
+	}
+
+	// The node is deleted, its grant retired with the remembered networks, and the host is gone before the next sync
+	retired := ExtClientGrant{NodeName: "n1", PodCIDRs: grant.PodCIDRs, Networks: r.ExtClientNetworks("n1")}
+	client.gone.Store(true)
+	if err := r.options.NetmakerClient.Invalidate(netmaker.CacheKindAll, ""); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if err := r.SyncExtClients(ctx, []ExtClientGrant{retired}); err != nil {
+		t.Fatalf("SyncExtClients() error = %v", err)
+	}
+
+	if got := extraAllowedIPs(t, client, "c1"); !slices.Equal(got, []string{"192.168.0.0/24"}) {
+		t.Errorf("extra allowed IPs = %v, want [192.168.0.0/24]", got)
+	}
+
+	// Forgotten once the retired grant is gone
+	if err := r.SyncExtClients(ctx, nil); err != nil {
+		t.Fatalf("SyncExtClients() error = %v", err)
+	}
+	if got := r.ExtClientNetworks("n1"); got != nil {
+		t.Errorf("ExtClientNetworks() = %v after the grant was dropped, want nil", got)
+	}
+}
@@ -0,0 +1,46 @@
+package reconciler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// newFixtureReconciler returns a reconciler backed by a cached client serving client
+// opts may be nil; its NetmakerClient is set
+func newFixtureReconciler(t *testing.T, client netmaker.Client, opts *Options) *Reconciler {
+	t.Helper()
+	if opts == nil {
+		opts = &Options{}
+	}
+	opts.NetmakerClient = netmaker.NewCachedClient(client, 0)
+	r, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r
+}
+
+// vanishingHosts hides all hosts and nodes of a client once gone is set, like hosts deleted from Netmaker
+type vanishingHosts struct {
+	netmaker.Client
+	gone atomic.Bool
+}
+
+// ListHosts returns no hosts once gone is set
+func (c *vanishingHosts) ListHosts(ctx context.Context) ([]netmaker.Host, error) {
+	if c.gone.Load() {
+		return nil, nil
+	}
+	return c.Client.ListHosts(ctx)
+}
+
+// ListNodes returns no nodes once gone is set
+func (c *vanishingHosts) ListNodes(ctx context.Context) ([]netmaker.Node, error) {
+	if c.gone.Load() {
+		return nil, nil
+	}
+	return c.Client.ListNodes(ctx)
+}
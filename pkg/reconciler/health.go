@@ -0,0 +1,123 @@
+package reconciler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// GatewayHealth is a change in the gateway health of a node's egress rules in a network (see Options.GatewayHealthCheck)
+type GatewayHealth struct {
+	Node    string // Kubernetes node name
+	Network string
+	Healthy bool // False if rules were turned off because none of their gateways is healthy
+}
+
+// gatewayHealth remembers the nodes whose rules are turned off because their gateways are unhealthy
+type gatewayHealth struct {
+	mu        sync.Mutex
+	unhealthy map[string]map[string]bool // Kubernetes node name -> networks
+}
+
+// set records the gateway health of a node in a network; returns true if it changed
+func (h *gatewayHealth) set(nodeName, network string, healthy bool) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if healthy {
+		if !h.unhealthy[nodeName][network] {
+			return false
+		}
+		delete(h.unhealthy[nodeName], network)
+		if len(h.unhealthy[nodeName]) == 0 {
+			delete(h.unhealthy, nodeName)
+		}
+		return true
+	}
+
+	if h.unhealthy[nodeName][network] {
+		return false
+	}
+	if h.unhealthy == nil {
+		h.unhealthy = make(map[string]map[string]bool)
+	}
+	if h.unhealthy[nodeName] == nil {
+		h.unhealthy[nodeName] = make(map[string]bool)
+	}
+	h.unhealthy[nodeName][network] = true
+	return true
+}
+
+// forget drops a deleted node
+func (h *gatewayHealth) forget(nodeName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.unhealthy, nodeName)
+}
+
+// UnhealthyGateways returns the number of nodes whose rules are turned off for unhealthy gateways, by network
+func (r *Reconciler) UnhealthyGateways() map[string]int {
+	r.health.mu.Lock()
+	defer r.health.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, networks := range r.health.unhealthy {
+		for network := range networks {
+			counts[network]++
+		}
+	}
+	return counts
+}
+
+// gatedRules returns for each published CIDR whether its rule must be off: the node is gated, or gateway health
+// checks are enabled and none of the rule's gateways is healthy
+// unhealthy tells whether any rule is off for unhealthy gateways only
+func (r *Reconciler) gatedRules(gated bool, egressNodes []map[string]int, nodesByID map[string]netmaker.Node) (rules []bool, unhealthy bool) {
+	rules = make([]bool, len(egressNodes))
+	now := time.Now()
+	for index, gateways := range egressNodes {
+		rules[index] = gated
+		if gated || gateways == nil || !r.options.GatewayHealthCheck {
+			continue
+		}
+		if !r.anyHealthyGateway(gateways, nodesByID, now) {
+			rules[index] = true
+			unhealthy = true
+		}
+	}
+	return rules, unhealthy
+}
+
+// anyHealthyGateway reports whether one of the gateways routes traffic according to Netmaker: connected to its
+// network and checked in within GatewayStaleAfter (nodes that never reported a check-in only need to be connected)
+func (r *Reconciler) anyHealthyGateway(gateways map[string]int, nodesByID map[string]netmaker.Node, now time.Time) bool {
+	for nodeID := range gateways {
+		node, ok := nodesByID[nodeID]
+		if !ok || !node.Connected {
+			continue
+		}
+		if node.LastCheckIn == 0 || now.Sub(time.Unix(node.LastCheckIn, 0)) <= r.options.GatewayStaleAfter {
+			return true
+		}
+	}
+	return false
+}
+
+// recordGatewayHealth remembers the gateway health of a node in a network, logging and reporting changes
+// (see Options.OnGatewayHealthChange)
+func (r *Reconciler) recordGatewayHealth(ctx context.Context, nodeName, network string, healthy bool) {
+	if !r.health.set(nodeName, network, healthy) {
+		return
+	}
+	if healthy {
+		netmaker.Logf(ctx, "Gateways of node %s in network %s are healthy again, turning its egress rules back on", nodeName, network)
+	} else {
+		netmaker.Logf(ctx, "WARNING: no healthy gateway for node %s in network %s (disconnected or no recent check-in), "+
+			"turning its egress rules off instead of advertising blackhole routes", nodeName, network)
+	}
+	if r.options.OnGatewayHealthChange != nil {
+		r.options.OnGatewayHealthChange(GatewayHealth{Node: nodeName, Network: network, Healthy: healthy})
+	}
+}
@@ -0,0 +1,37 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
+)
+
+// The rules of a held node (see Topology.Held) are marked held=true and survive the deletion of the node, e.g.
+// while it is removed from the cluster for re-imaging. DeleteNode, the orphan and recorded-rule cleanups and the lease janitor leave marked rules alone; the node takes them over again once it is back (same
+// name, see reconcilePodCIDR), and the marker is removed when it is reconciled without the hold
+// Held rules of nodes that never come back have to be purged by hand, so they are counted in the
+// held_egress_rules metric and the report command
+
+// updateHeldEgressMetrics sets the held_egress_rules metric: held node rules whose owning Netmaker node has no
+// Kubernetes node (not in validNodeIDs), by network
+func (r *Reconciler) updateHeldEgressMetrics(ctx context.Context, validNodeIDs map[string]bool) error {
+	egresses, err := r.ManagedEgresses(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count held egress rules: %w", err)
+	}
+
+	held := make(map[string]int)
+	for i := range egresses {
+		metadata := parseEgress(&egresses[i])
+		if metadata.held && r.isNodeEgress(metadata) && !validNodeIDs[OwnerNodeID(&egresses[i])] {
+			held[egresses[i].Network]++
+		}
+	}
+
+	metrics.HeldEgressRules.Reset()
+	for network, count := range held {
+		metrics.HeldEgressRules.WithLabelValues(network).Set(float64(count))
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// hostMemory remembers the Netmaker host ID of each Kubernetes node
+// A host renamed in Netmaker keeps its ID, so its node keeps its egress rules instead of them being
+// orphaned (and recreated as duplicates once the names match again)
+type hostMemory struct {
+	mu      sync.Mutex
+	byNode  map[string]string // Kubernetes node name -> Netmaker host ID
+	renamed map[string]bool   // Kubernetes node names whose host was reported as renamed
+}
+
+// remember records the host of a node; the host is no longer attributed to any other node
+// renamed tells whether the host was found by ID rather than by name; returns true if it newly became renamed
+func (m *hostMemory) remember(nodeName, hostID string, renamed bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.byNode == nil {
+		m.byNode = make(map[string]string)
+		m.renamed = make(map[string]bool)
+	}
+	for name, id := range m.byNode {
+		if id == hostID && name != nodeName {
+			delete(m.byNode, name)
+			delete(m.renamed, name)
+		}
+	}
+	m.byNode[nodeName] = hostID
+
+	newlyRenamed := renamed && !m.renamed[nodeName]
+	m.renamed[nodeName] = renamed
+	return newlyRenamed
+}
+
+// hostID returns the remembered host of a node (empty if unknown)
+func (m *hostMemory) hostID(nodeName string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.byNode[nodeName]
+}
+
+// claimedByOther reports whether another node is remembered with the host
+func (m *hostMemory) claimedByOther(nodeName, hostID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, id := range m.byNode {
+		if id == hostID && name != nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// forget drops a node
+func (m *hostMemory) forget(nodeName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.byNode, nodeName)
+	delete(m.renamed, nodeName)
+}
+
+// hostNodeIDs returns the Netmaker node IDs of the host of a Kubernetes node
+// The host is looked up by name first; if there is none, a host the node was seen with before
+// (remembered, or recorded as host= in the metadata of the node's rules in the state store) is used
+// as long as it still exists and no other node has claimed it by name; failing that, a host is matched by the
+// node's addresses (nil unless Options.MatchHostsByAddress is set, see addressHostNodeIDs)
+// Returns the "not found" error of the name lookup if none finds a host
+func (r *Reconciler) hostNodeIDs(ctx context.Context, api netmakerAPI, nodeName string, addresses []string) ([]string, error) {
+	nodeIDs, err := api.GetNodeIDsByHostname(ctx, nodeName)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		return nil, err
+	}
+	if err == nil && len(nodeIDs) == 0 {
+		return nodeIDs, nil
+	}
+
+	allNodes, listErr := api.ListNodes(ctx)
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", listErr)
+	}
+
+	if err == nil {
+		// Found by name - the node owns this host from now on
+		for _, n := range allNodes {
+			if n.ID == nodeIDs[0] && n.HostID != "" {
+				r.hosts.remember(nodeName, n.HostID, false)
+				break
+			}
+		}
+		return nodeIDs, nil
+	}
+
+	hostID := r.hosts.hostID(nodeName)
+	if hostID == "" {
+		hostID = r.recordedHostID(ctx, api, nodeName)
+	}
+	if hostID == "" || r.hosts.claimedByOther(nodeName, hostID) {
+		return r.addressHostNodeIDs(ctx, api, nodeName, addresses, err)
+	}
+
+	var renamedNodeIDs []string
+	for _, n := range allNodes {
+		if n.HostID == hostID {
+			renamedNodeIDs = append(renamedNodeIDs, n.ID)
+		}
+	}
+	if len(renamedNodeIDs) == 0 {
+		return r.addressHostNodeIDs(ctx, api, nodeName, addresses, err) // Host is gone, not renamed
+	}
+
+	if r.hosts.remember(nodeName, hostID, true) {
+		netmaker.Logf(ctx, "Netmaker host %s of node %s no longer has the node's name (renamed?), keeping its egress rules",
+			hostID, nodeName)
+	}
+	return renamedNodeIDs, nil
+}
+
+// addressHostNodeIDs matches a node without a host of its name to the host sharing one of its addresses
+// The match is remembered like a renamed host, so deletes and orphan cleanup find it by name afterwards
+// Returns notFound unless exactly one unclaimed host matches
+func (r *Reconciler) addressHostNodeIDs(ctx context.Context, api netmakerAPI, nodeName string, addresses []string, notFound error) ([]string, error) {
+	if len(addresses) == 0 {
+		return nil, notFound
+	}
+
+	hosts, err := api.ListHosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	host, ok := MatchHostByAddress(hosts, addresses)
+	if !ok || len(host.Nodes) == 0 || r.hosts.claimedByOther(nodeName, host.ID) {
+		return nil, notFound
+	}
+
+	if r.hosts.remember(nodeName, host.ID, true) {
+		netmaker.Logf(ctx, "Node %s has no Netmaker host of its name, matched host %s (%q) by IP address",
+			nodeName, host.ID, host.Name)
+	}
+	return host.Nodes, nil
+}
+
+// joinDefaultNetwork joins the host of a node, found by name without any network, to Options.DefaultNetwork
+// Returns the host's node IDs after the join (nil without a default network, or if the join created no node yet)
+func (r *Reconciler) joinDefaultNetwork(ctx context.Context, api netmakerAPI, nodeName string) ([]string, error) {
+	if r.options.DefaultNetwork == "" {
+		return nil, nil
+	}
+
+	hosts, err := api.ListHosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	var hostID string
+	for _, host := range hosts {
+		if host.Name == nodeName {
+			hostID = host.ID
+			break
+		}
+	}
+	if hostID == "" {
+		return nil, nil // Gone since the lookup
+	}
+
+	if err := api.AddHostToNetwork(ctx, hostID, r.options.DefaultNetwork); err != nil {
+		return nil, err
+	}
+	netmaker.Logf(ctx, "Joined Netmaker host %s of node %s to network %s, as it was in no network", hostID, nodeName, r.options.DefaultNetwork)
+
+	return api.GetNodeIDsByHostname(ctx, nodeName)
+}
+
+// hostAddresses returns the addresses to match a node's host by (nil unless Options.MatchHostsByAddress is set)
+func (r *Reconciler) hostAddresses(node *corev1.Node) []string {
+	if !r.options.MatchHostsByAddress {
+		return nil
+	}
+	return NodeAddresses(node)
+}
+
+// NodeAddresses returns the InternalIP and ExternalIP addresses of a node
+func NodeAddresses(node *corev1.Node) []string {
+	var addresses []string
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP || address.Type == corev1.NodeExternalIP {
+			addresses = append(addresses, address.Address)
+		}
+	}
+	return addresses
+}
+
+// MatchHostByAddress returns the host whose endpoint or interface IPs include one of the addresses
+// Returns false if no host or more than one host matches (e.g. several hosts behind the same NAT endpoint)
+func MatchHostByAddress(hosts []netmaker.Host, addresses []string) (netmaker.Host, bool) {
+	wanted := make(map[netip.Addr]bool, len(addresses))
+	for _, address := range addresses {
+		if ip, err := netip.ParseAddr(address); err == nil {
+			wanted[ip.Unmap()] = true
+		}
+	}
+
+	var match netmaker.Host
+	matches := 0
+	for i := range hosts {
+		for _, address := range hosts[i].IPs() {
+			if ip, err := netip.ParseAddr(address); err == nil && wanted[ip.Unmap()] {
+				match = hosts[i]
+				matches++
+				break
+			}
+		}
+	}
+	return match, matches == 1
+}
+
+// RenamedHostNodeIDs returns the Netmaker node IDs of a node's host if it was renamed in Netmaker
+// (see hostNodeIDs), or nil if the node has no host; lets orphan cleanup keep the rules of such nodes
+func (r *Reconciler) RenamedHostNodeIDs(ctx context.Context, nodeName string) ([]string, error) {
+	nodeIDs, err := r.hostNodeIDs(ctx, r.options.NetmakerClient, nodeName, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return nodeIDs, nil
+}
+
+// recordedHostID returns the host ID recorded in the node's egress rules in the state store
+// Lets a restarted controller recognize renamed hosts; empty without a state store or record
+func (r *Reconciler) recordedHostID(ctx context.Context, api netmakerAPI, nodeName string) string {
+	if r.options.StateStore == nil {
+		return ""
+	}
+	for _, ref := range r.options.StateStore.Get(nodeName) {
+		egresses, err := api.ListEgress(ctx, ref.Network)
+		if err != nil {
+			continue
+		}
+		for _, egress := range egresses {
+			if egress.ID != ref.ID {
+				continue
+			}
+			if metadata := parseEgress(&egress); r.isNodeEgress(metadata) && metadata.host != "" {
+				return metadata.host
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// hostlessFixture has the host of node n1 enrolled, but in no network yet
+func hostlessFixture() *netmaker.Fixture {
+	return &netmaker.Fixture{
+		Hosts:    []netmaker.Host{{ID: "h1", Name: "n1"}},
+		Networks: []netmaker.Network{{NetID: "mesh"}},
+	}
+}
+
+func TestReconcileHostWithoutNetwork(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultNetwork string
+		resync         bool
+		wantJoined     string
+		wantWarning    bool
+		wantRules      int
+	}{
+		{name: "default network, event", defaultNetwork: "mesh", wantJoined: "mesh", wantRules: 1},
+		{name: "default network, resync", defaultNetwork: "mesh", resync: true, wantJoined: "mesh", wantRules: 1},
+		{name: "no default network, event", wantWarning: true},
+		{name: "no default network, resync", resync: true, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := netmaker.NewFixtureClient(hostlessFixture())
+			r := newFixtureReconciler(t, client, &Options{DefaultNetwork: tt.defaultNetwork})
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+				Spec:       corev1.NodeSpec{PodCIDRs: []string{"10.244.1.0/24"}},
+			}
+
+			var result NodeResult
+			if tt.resync {
+				results, nodeErrors, err := r.ResyncNodes(ctx, []NodeRequest{{Node: node}})
+				if err != nil || nodeErrors["n1"] != nil {
+					t.Fatalf("ResyncNodes() error = %v, node errors = %v", err, nodeErrors)
+				}
+				result = results["n1"]
+			} else {
+				var err error
+				if result, err = r.ReconcileNode(ctx, node, Topology{}); err != nil {
+					t.Fatalf("ReconcileNode() error = %v", err)
+				}
+			}
+
+			if result.JoinedNetwork != tt.wantJoined {
+				t.Errorf("JoinedNetwork = %q, want %q", result.JoinedNetwork, tt.wantJoined)
+			}
+			if result.HostWithoutNetwork != tt.wantWarning {
+				t.Errorf("HostWithoutNetwork = %v, want %v", result.HostWithoutNetwork, tt.wantWarning)
+			}
+
+			hosts, err := client.ListHosts(ctx)
+			if err != nil {
+				t.Fatalf("ListHosts() error = %v", err)
+			}
+			nodeIDs := hosts[0].Nodes
+			if joined := len(nodeIDs) > 0; joined != (tt.wantJoined != "") {
+				t.Fatalf("host nodes = %v, want joined = %v", nodeIDs, tt.wantJoined != "")
+			}
+
+			egresses, err := client.ListEgress(ctx, "mesh")
+			if err != nil {
+				t.Fatalf("ListEgress() error = %v", err)
+			}
+			if len(egresses) != tt.wantRules {
+				t.Fatalf("%d egress rules in mesh, want %d: %+v", len(egresses), tt.wantRules, egresses)
+			}
+			for _, egress := range egresses {
+				if egress.Range != "10.244.1.0/24" || OwnerNodeID(&egress) != nodeIDs[0] {
+					t.Errorf("egress rule %+v, want 10.244.1.0/24 routed through the joined node %s", egress, nodeIDs[0])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package reconciler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reservedDescriptionKeys are the description keys of our own metadata (see parseEgressDescription)
+var reservedDescriptionKeys = map[string]bool{
+	"cluster": true, "instance": true, "rule": true, "pool": true, "host": true, "index": true, "expires": true, "gated": true,
+	"held": true, "metric": true,
+}
+
+// descriptionKeyPattern matches valid description label keys (lowercase, no separators of the description format)
+var descriptionKeyPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9_.]*[a-z0-9])?$`)
+
+// ValidateDescriptionLabels checks the description key -> node label key mapping of Options.DescriptionLabels
+func ValidateDescriptionLabels(labels map[string]string) error {
+	for key, label := range labels {
+		if !descriptionKeyPattern.MatchString(key) {
+			return fmt.Errorf("description key %q must be lowercase alphanumerics, '-', '_' and '.'", key)
+		}
+		if reservedDescriptionKeys[key] {
+			return fmt.Errorf("description key %q is reserved for kaput-not's own metadata", key)
+		}
+		if label == "" {
+			return fmt.Errorf("description key %q needs a node label", key)
+		}
+	}
+	return nil
+}
+
+// descriptionLabels returns the description labels of a node's rules: the values of the mapped node labels
+// Missing and empty node labels are left out
+func (r *Reconciler) descriptionLabels(node *corev1.Node) map[string]string {
+	if len(r.options.DescriptionLabels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(r.options.DescriptionLabels))
+	for key, label := range r.options.DescriptionLabels {
+		if value := node.Labels[label]; value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// NodeZone returns the topology zone of a node (topology.kubernetes.io/zone, or the deprecated beta label),
+// empty if it has none; used to split metrics by failure domain
+func NodeZone(node *corev1.Node) string {
+	if zone := node.Labels[corev1.LabelTopologyZone]; zone != "" {
+		return zone
+	}
+	return node.Labels[corev1.LabelFailureDomainBetaZone]
+}
+
+// labelFields formats description labels as key=value fields, sorted by key
+// Label values never contain spaces, '=' or '|' (Kubernetes label value syntax), so they need no escaping
+func labelFields(labels map[string]string) []string {
+	fields := make([]string, 0, len(labels))
+	for key, value := range labels {
+		fields = append(fields, key+"="+value)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,68 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// withoutMember returns the nodes map of an egress rule without nodeID (nil if no gateway remains)
+// If nodeID was the primary gateway (at metric primary), the remaining gateway with the lowest metric (then the
+// lowest ID) takes over the primary metric, so the rule keeps a primary
+func withoutMember(nodes map[string]int, nodeID string, primary int) map[string]int {
+	remaining := make(map[string]int, len(nodes))
+	for id, metric := range nodes {
+		if id != nodeID {
+			remaining[id] = metric
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	if nodes[nodeID] == primary {
+		promoted := ""
+		for id, metric := range remaining {
+			if promoted == "" || metric < remaining[promoted] || (metric == remaining[promoted] && id < promoted) {
+				promoted = id
+			}
+		}
+		remaining[promoted] = primary
+	}
+	return remaining
+}
+
+// removeMembers drops Netmaker nodes from the gateways of an egress rule, in a single update
+// The rule is updated to route through the remaining gateways and only deleted once none is left
+func (r *Reconciler) removeMembers(ctx context.Context, api netmakerAPI, egress *netmaker.Egress, nodeIDs ...string) error {
+	primary := primaryMetric(parseEgress(egress))
+	remaining := egress.Nodes
+	for _, nodeID := range nodeIDs {
+		if remaining = withoutMember(remaining, nodeID, primary); remaining == nil {
+			break
+		}
+	}
+	if remaining == nil {
+		if err := api.DeleteEgress(ctx, egress.ID); err != nil {
+			return fmt.Errorf("failed to delete egress %s without gateways in network %s: %w", egress.ID, egress.Network, err)
+		}
+		return nil
+	}
+
+	req := netmaker.EgressReq{
+		ID:          egress.ID,
+		Name:        egress.Name,
+		Network:     egress.Network,
+		Description: egress.Description,
+		Range:       egress.Range,
+		NAT:         egress.NAT,
+		Nodes:       remaining,
+		Status:      egress.Status,
+		UpdatedAt:   egress.UpdatedAt,
+	}
+	if _, err := api.UpdateEgress(ctx, req); err != nil {
+		return fmt.Errorf("failed to remove nodes %v from egress %s in network %s: %w", nodeIDs, egress.ID, egress.Network, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package reconciler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+var (
+	// nameMarkerPattern matches the ownership marker appended to egress names (see Options.NameMarker):
+	// "node-1 pods (1/1) [kn-1a2b3c4d-0]" carries the ownership key 1a2b3c4d and index 0, node rules with another
+	// primary metric than EgressMetric also carry it: "node-1 pods (1/1) [kn-1a2b3c4d-0-m400]"
+	nameMarkerPattern = regexp.MustCompile(` \[kn-([0-9a-f]{8})-([0-9]+)(?:-m([0-9]+))?\]$`)
+
+	// groupNamePattern matches the names of ClusterEgressRule and node pool rules (see buildGroupEgressName)
+	// Node names have no spaces, so node rule names never match
+	groupNamePattern = regexp.MustCompile(`^(\S+) (rule|pool pods) \(\d+/\d+\)$`)
+)
+
+// ownershipKey identifies the rules of a cluster identity (cluster name and instance ID) in name markers
+func ownershipKey(clusterName string, instanceID string) string {
+	sum := sha256.Sum256([]byte(clusterName + "/" + instanceID))
+	return hex.EncodeToString(sum[:4])
+}
+
+// markName appends our ownership marker with the rule's index to an egress name if NameMarker is set
+// metric is the primary metric of the rule, recorded unless it is EgressMetric
+func (r *Reconciler) markName(name string, index int, metric int) string {
+	if !r.options.NameMarker {
+		return name
+	}
+	marked := fmt.Sprintf("%s [kn-%s-%d", name, ownershipKey(r.options.ClusterName, r.options.InstanceID), index)
+	if metric != EgressMetric {
+		marked += fmt.Sprintf("-m%d", metric)
+	}
+	return marked + "]"
+}
+
+// parseEgressName recovers the metadata of an egress rule from the ownership marker in its name
+// Only the owner, the index, the primary metric and the ClusterEgressRule or node pool name survive; the host ID,
+// lease, labels and markers like gated=true are lost until the next update rewrites the description
+// Returns nil if the name carries no marker
+func parseEgressName(name string) *egressMetadata {
+	match := nameMarkerPattern.FindStringSubmatch(name)
+	if match == nil {
+		return nil
+	}
+	index, err := strconv.Atoi(match[2])
+	if err != nil {
+		return nil
+	}
+
+	metadata := &egressMetadata{owner: match[1], index: index}
+	if match[3] != "" {
+		// Ignore error - if parsing fails, the rule's primary gateway is expected at EgressMetric
+		metadata.metric, _ = strconv.Atoi(match[3])
+	}
+	if group := groupNamePattern.FindStringSubmatch(strings.TrimSuffix(name, match[0])); group != nil {
+		if group[2] == "rule" {
+			metadata.rule = group[1]
+		} else {
+			metadata.pool = group[1]
+		}
+	}
+	return metadata
+}
+
+// parseEgress parses the metadata of an egress rule from its description, or from the ownership marker in its name
+// if the description carries none (some Netmaker UI versions truncate or strip descriptions)
+// Returns nil if the rule is not managed by kaput-not
+func parseEgress(egress *netmaker.Egress) *egressMetadata {
+	if metadata := parseEgressDescription(egress.Description); metadata != nil {
+		return metadata
+	}
+	return parseEgressName(egress.Name)
+}
+
+// ownedBy reports whether the metadata marks a rule of the given cluster identity
+func (m *egressMetadata) ownedBy(clusterName string, instanceID string) bool {
+	if m.owner != "" {
+		return m.owner == ownershipKey(clusterName, instanceID)
+	}
+	return m.cluster == clusterName && m.instance == instanceID
+}
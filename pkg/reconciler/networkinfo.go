@@ -0,0 +1,63 @@
+package reconciler
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// networkMetadata returns the Netmaker networks by name from the (cached) network list, or nil if it can't be listed
+// The metadata only refines IP family selection, CIDR validation and logs, so reconciles go on without it
+// (e.g. for a Netmaker user not allowed to list networks); the failure is logged once until listing works again
+func (r *Reconciler) networkMetadata(ctx context.Context) map[string]netmaker.Network {
+	networks, err := r.options.NetmakerClient.ListNetworks(ctx)
+	if err != nil {
+		if !r.networksUnlisted.Swap(true) {
+			log.Printf("WARNING: Failed to list Netmaker networks, reconciling without their address ranges: %v", err)
+		}
+		return nil
+	}
+	if r.networksUnlisted.Swap(false) {
+		log.Printf("Listing Netmaker networks works again")
+	}
+
+	byName := make(map[string]netmaker.Network, len(networks))
+	for _, network := range networks {
+		byName[network.NetID] = network
+	}
+	return byName
+}
+
+// DescribeNetwork returns a network's name with its address ranges for logs, e.g. "mesh (10.101.0.0/16)"
+// Only cached metadata is used, so it never calls the API; networks not listed yet are described by name
+func (r *Reconciler) DescribeNetwork(name string) string {
+	if network, ok := r.options.NetmakerClient.CachedNetworks()[name]; ok {
+		return network.String()
+	}
+	return name
+}
+
+// skipMeshOverlaps drops the gateways of published CIDRs overlapping the network's own address ranges
+// Routing mesh addresses to a gateway would cut the network's nodes off from each other, so such CIDRs
+// (a misconfigured pod or cluster CIDR) are never published and their existing rules are deleted as surplus
+func skipMeshOverlaps(ctx context.Context, network netmaker.Network, podCIDRs []string, egressNodes []map[string]int) {
+	for index, podCIDR := range podCIDRs {
+		if egressNodes[index] == nil {
+			continue
+		}
+		var overlaps []string
+		for _, addressRange := range network.AddressRanges() {
+			if cidr.Overlaps(podCIDR, addressRange) {
+				overlaps = append(overlaps, addressRange)
+			}
+		}
+		if len(overlaps) > 0 {
+			netmaker.Logf(ctx, "WARNING: Not publishing %s in network %s: it overlaps the network's address range %s",
+				podCIDR, network.NetID, strings.Join(overlaps, ", "))
+			egressNodes[index] = nil
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// EnsureNetworks creates the configured Networks that don't exist in Netmaker yet
+// Existing networks are left untouched, even if their address ranges differ from the configuration
+// No-op when no networks are configured
+func (r *Reconciler) EnsureNetworks(ctx context.Context) error {
+	var ensureErrors []error
+	for _, network := range r.options.Networks {
+		_, err := r.options.NetmakerClient.GetNetwork(ctx, network.NetID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, netmaker.ErrNotFound) {
+			ensureErrors = append(ensureErrors, fmt.Errorf("failed to get network %s: %w", network.NetID, err))
+			continue
+		}
+
+		if _, err := r.options.NetmakerClient.CreateNetwork(ctx, network); err != nil {
+			ensureErrors = append(ensureErrors, fmt.Errorf("failed to create network %s: %w", network.NetID, err))
+			continue
+		}
+		log.Printf("Created Netmaker network %s", network.NetID)
+	}
+
+	return errors.Join(ensureErrors...)
+}
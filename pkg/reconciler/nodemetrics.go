@@ -0,0 +1,91 @@
+package reconciler
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NodeMetric gives the node rules of matching nodes another primary metric than EgressMetric
+// Where rules of several nodes overlap (e.g. summarized or aggregated ranges), Netmaker prefers the lowest metric,
+// so beefier gateways can get lower metrics than the rest
+type NodeMetric struct {
+	// Selector is a label selector for the nodes, e.g. "node.kubernetes.io/instance-type=m5.4xlarge"
+	Selector string
+
+	// Metric is the primary metric of their rules (1-999); HA backup gateways follow in HAGatewayMetricStep steps
+	Metric int
+}
+
+// nodeMetricSelector is a NodeMetric with its parsed selector
+type nodeMetricSelector struct {
+	selector labels.Selector
+	metric   int
+}
+
+// ValidateNodeMetrics checks the selectors and metrics of Options.NodeMetrics
+func ValidateNodeMetrics(nodeMetrics []NodeMetric) error {
+	_, err := parseNodeMetrics(nodeMetrics)
+	return err
+}
+
+// parseNodeMetrics parses the selectors of nodeMetrics, keeping their order
+func parseNodeMetrics(nodeMetrics []NodeMetric) ([]nodeMetricSelector, error) {
+	parsed := make([]nodeMetricSelector, 0, len(nodeMetrics))
+	for _, nodeMetric := range nodeMetrics {
+		if nodeMetric.Selector == "" {
+			return nil, fmt.Errorf("node metric %d needs a selector", nodeMetric.Metric)
+		}
+		selector, err := labels.Parse(nodeMetric.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node metric selector %q: %w", nodeMetric.Selector, err)
+		}
+		if nodeMetric.Metric < 1 || nodeMetric.Metric > maxEgressMetric {
+			return nil, fmt.Errorf("node metric of %q must be between 1 and %d, got %d", nodeMetric.Selector, maxEgressMetric, nodeMetric.Metric)
+		}
+		parsed = append(parsed, nodeMetricSelector{selector: selector, metric: nodeMetric.Metric})
+	}
+	return parsed, nil
+}
+
+// NodeMetricLabels returns the node label keys the selectors of nodeMetrics look at, so changes of them can
+// re-reconcile the node (invalid selectors are skipped)
+func NodeMetricLabels(nodeMetrics []NodeMetric) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, nodeMetric := range nodeMetrics {
+		selector, err := labels.Parse(nodeMetric.Selector)
+		if err != nil {
+			continue
+		}
+		requirements, _ := selector.Requirements()
+		for _, requirement := range requirements {
+			if key := requirement.Key(); !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// nodeMetric returns the primary metric of a node's rules: that of the first matching NodeMetric, or EgressMetric
+func (r *Reconciler) nodeMetric(node *corev1.Node) int {
+	for _, nodeMetric := range r.nodeMetrics {
+		if nodeMetric.selector.Matches(labels.Set(node.Labels)) {
+			return nodeMetric.metric
+		}
+	}
+	return EgressMetric
+}
+
+// primaryMetric returns the metric of the primary gateway of a rule with the given metadata
+// Node rules of nodes with a NodeMetric record it as "metric=<n>" in the description and "-m<n>" in the name
+// marker (see Options.NameMarker); all other rules use EgressMetric
+func primaryMetric(metadata *egressMetadata) int {
+	if metadata != nil && metadata.metric != 0 {
+		return metadata.metric
+	}
+	return EgressMetric
+}
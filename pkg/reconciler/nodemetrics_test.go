@@ -0,0 +1,105 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+func TestPrimaryMetric(t *testing.T) {
+	key := ownershipKey("", "")
+	tests := []struct {
+		name   string
+		egress netmaker.Egress
+		want   int
+	}{
+		{name: "unmanaged", egress: netmaker.Egress{Name: "office LAN"}, want: EgressMetric},
+		{name: "default metric", egress: netmaker.Egress{Description: EgressMarker + ": index=0"}, want: EgressMetric},
+		{name: "description", egress: netmaker.Egress{Description: EgressMarker + ": index=0 metric=400"}, want: 400},
+		{name: "name marker", egress: netmaker.Egress{Name: fmt.Sprintf("n1 pods (1/1) [kn-%s-0-m400]", key)}, want: 400},
+		{name: "name marker with default metric", egress: netmaker.Egress{Name: fmt.Sprintf("n1 pods (1/1) [kn-%s-0]", key)}, want: EgressMetric},
+		{
+			name: "description wins over name marker",
+			egress: netmaker.Egress{
+				Name:        fmt.Sprintf("n1 pods (1/1) [kn-%s-0-m400]", key),
+				Description: EgressMarker + ": index=0 metric=300",
+			},
+			want: 300,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := primaryMetric(parseEgress(&tt.egress)); got != tt.want {
+				t.Errorf("primaryMetric() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileNodeKeepsNodeMetricWithStrippedDescriptions(t *testing.T) {
+	ctx := context.Background()
+	client := netmaker.NewFixtureClient(&netmaker.Fixture{
+		Hosts: []netmaker.Host{
+			{ID: "h1", Name: "n1", Nodes: []string{"node-1"}},
+			{ID: "h2", Name: "n2", Nodes: []string{"node-2"}},
+		},
+		Nodes: []netmaker.Node{
+			{ID: "node-1", HostID: "h1", Network: "mesh"},
+			{ID: "node-2", HostID: "h2", Network: "mesh"},
+		},
+	})
+	r := newFixtureReconciler(t, client, &Options{
+		NameMarker:  true,
+		NodeMetrics: []NodeMetric{{Selector: "tier=large", Metric: 400}},
+	})
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "n1", Labels: map[string]string{"tier": "large"}},
+		Spec:       corev1.NodeSpec{PodCIDRs: []string{"10.244.1.0/24"}},
+	}
+	topology := Topology{GatewayNodes: []string{"n2"}}
+
+	var ruleID string
+	for cycle := range 3 {
+		if _, err := r.ReconcileNode(ctx, node, topology); err != nil {
+			t.Fatalf("cycle %d: ReconcileNode() error = %v", cycle, err)
+		}
+
+		egresses, err := client.ListEgress(ctx, "mesh")
+		if err != nil {
+			t.Fatalf("ListEgress() error = %v", err)
+		}
+		if len(egresses) != 1 {
+			t.Fatalf("cycle %d: %d egress rules, want 1: %+v", cycle, len(egresses), egresses)
+		}
+		egress := egresses[0]
+		if ruleID == "" {
+			ruleID = egress.ID
+		} else if egress.ID != ruleID {
+			t.Errorf("cycle %d: rule %s replaced by %s", cycle, ruleID, egress.ID)
+		}
+		if egress.Nodes["node-1"] != 400 || egress.Nodes["node-2"] != 410 {
+			t.Errorf("cycle %d: gateways = %v, want node-1 at 400 and node-2 at 410", cycle, egress.Nodes)
+		}
+		if owner := OwnerNodeID(&egress); owner != "node-1" {
+			t.Errorf("cycle %d: owner = %q, want node-1", cycle, owner)
+		}
+
+		// A Netmaker UI strips the description; only the name marker is left
+		if _, err := client.UpdateEgress(ctx, netmaker.EgressReq{
+			ID: egress.ID, Name: egress.Name, Network: egress.Network, Range: egress.Range, Nodes: egress.Nodes, Status: egress.Status,
+		}); err != nil {
+			t.Fatalf("UpdateEgress() error = %v", err)
+		}
+		stripped := egress
+		stripped.Description = ""
+		if owner := OwnerNodeID(&stripped); owner != "node-1" {
+			t.Errorf("cycle %d: owner of the stripped rule = %q, want node-1", cycle, owner)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package reconciler
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/statestore"
+)
+
+// Options contains configuration for the reconciler
+type Options struct {
+	// NetmakerClient is the cached Netmaker API client (shared across all networks)
+	NetmakerClient *netmaker.CachedClient
+
+	// ClusterName scopes egress rules to this cluster (optional, for multi-cluster deployments)
+	ClusterName string
+
+	// InstanceID scopes egress rules to one of several kaput-not instances in the same cluster
+	// (e.g. per team, each managing its own Netmaker networks); rules of other instances are never touched
+	// Default: empty (the default instance)
+	InstanceID string
+
+	// LeaseDuration embeds an expiry timestamp in managed egress rules, refreshed on each reconcile
+	// Default: 0 (disabled - rules never expire)
+	LeaseDuration time.Duration
+
+	// LeaseGracePeriod is how long after expiry the janitor waits before deleting a rule
+	// Only used when LeaseDuration is set
+	// Default: 7 days
+	LeaseGracePeriod time.Duration
+
+	// ClusterCIDRs are published instead of each node's own pod CIDRs (aggregated mode)
+	// Intended for a small set of publisher nodes, reducing the egress rule count on huge clusters
+	// Default: empty (each node publishes its own pod CIDRs)
+	ClusterCIDRs []string
+
+	// StateStore records the egress rules applied for each node (optional)
+	// Lets DeleteNode remove rules by ID even when the Netmaker host is already gone
+	// Default: nil (rules are always discovered via list + description parsing)
+	StateStore statestore.Store
+
+	// AdoptExisting takes over unmanaged egress rules that exactly match a node's pod CIDR and Netmaker node
+	// (e.g. created by hand before kaput-not was installed) instead of creating a duplicate next to them
+	// Default: false (unmanaged rules are never touched)
+	AdoptExisting bool
+
+	// MatchHostsByAddress matches a node without a Netmaker host of its name to the host whose endpoint or interface
+	// IPs include one of the node's InternalIP/ExternalIP addresses, e.g. when host names diverge from node names
+	// Ambiguous matches (several hosts sharing the address) are ignored
+	// Default: false (hosts are matched by name only)
+	MatchHostsByAddress bool
+
+	// DefaultNetwork is the Netmaker network hosts of nodes are joined to while they are in no network (netclient
+	// enrolled, but not joined); the node is reconciled in it right away
+	// Default: "" (such hosts are only reported, see NodeResult.HostWithoutNetwork)
+	DefaultNetwork string
+
+	// CleanupBatchSize is how many orphaned Netmaker nodes are cleaned up before the time budget is checked again
+	// Default: 50
+	CleanupBatchSize int
+
+	// CleanupTimeBudget bounds one orphan cleanup cycle; orphans left over are cleaned up in the next cycle
+	// Default: 2 minutes
+	CleanupTimeBudget time.Duration
+
+	// CleanupDeleteConcurrency is how many egress rules cleanups (orphaned, expired, recorded and group rules, and
+	// purges) delete in parallel; a netmaker.MutationLimitClient below still bounds all writes of the process
+	// Default: 4
+	CleanupDeleteConcurrency int
+
+	// GatewayHealthCheck turns egress rules off (Status=false) while none of their gateways is healthy according
+	// to Netmaker - connected to the network and checked in within GatewayStaleAfter - instead of advertising
+	// blackhole routes; no rules are created for such nodes, and rules are turned back on once a gateway recovers
+	// Default: false
+	GatewayHealthCheck bool
+
+	// GatewayStaleAfter is how long after its last netclient check-in a gateway counts as unhealthy
+	// Only used when GatewayHealthCheck is set
+	// Default: 5 minutes
+	GatewayStaleAfter time.Duration
+
+	// OnGatewayHealthChange is called when a node's rules in a network are turned off for unhealthy gateways,
+	// and when they are turned back on (e.g. to emit alerts); must not block
+	// Default: nil
+	OnGatewayHealthChange func(GatewayHealth)
+
+	// ResyncListConcurrency is how many networks' egress rules are listed in parallel for a resync snapshot
+	// Default: 4
+	ResyncListConcurrency int
+
+	// Networks are created in Netmaker by EnsureNetworks if they don't exist (bootstrap of new environments)
+	// Default: empty (networks are never created)
+	Networks []netmaker.Network
+
+	// DescriptionLabels embeds node label values in the descriptions of node rules, for cost and ownership
+	// reporting: description key -> node label key, e.g. "team" -> "example.com/team" gives "... team=payments"
+	// Default: empty (no labels)
+	DescriptionLabels map[string]string
+
+	// NameMarker appends the ownership key and index (and a node rule's primary metric other than EgressMetric) to
+	// egress names, e.g. "node-1 pods (1/1) [kn-1a2b3c4d-0]", so rules stay recognized when a Netmaker UI truncates or strips their descriptions; both are always parsed
+	// Default: false (ownership is only recorded in descriptions)
+	NameMarker bool
+
+	// NodeMetrics set the primary metric of node rules by node labels; the first matching entry wins, so Netmaker
+	// prefers e.g. larger gateways where the rules of several nodes overlap (summarized or aggregated ranges)
+	// Default: empty (every node rule uses EgressMetric)
+	NodeMetrics []NodeMetric
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.NetmakerClient == nil {
+		return fmt.Errorf("NetmakerClient is required")
+	}
+	if err := ValidateInstanceID(o.InstanceID); err != nil {
+		return err
+	}
+	if o.LeaseDuration < 0 {
+		return fmt.Errorf("LeaseDuration must not be negative")
+	}
+	if o.LeaseGracePeriod < 0 {
+		return fmt.Errorf("LeaseGracePeriod must not be negative")
+	}
+	if o.CleanupBatchSize < 0 {
+		return fmt.Errorf("CleanupBatchSize must not be negative")
+	}
+	if o.CleanupTimeBudget < 0 {
+		return fmt.Errorf("CleanupTimeBudget must not be negative")
+	}
+	if o.CleanupDeleteConcurrency < 0 {
+		return fmt.Errorf("CleanupDeleteConcurrency must not be negative")
+	}
+	if o.GatewayStaleAfter < 0 {
+		return fmt.Errorf("GatewayStaleAfter must not be negative")
+	}
+	if o.ResyncListConcurrency < 0 {
+		return fmt.Errorf("ResyncListConcurrency must not be negative")
+	}
+	if err := ValidateDescriptionLabels(o.DescriptionLabels); err != nil {
+		return err
+	}
+	if err := ValidateNodeMetrics(o.NodeMetrics); err != nil {
+		return err
+	}
+	for _, clusterCIDR := range o.ClusterCIDRs {
+		if err := cidr.Validate(clusterCIDR); err != nil {
+			return fmt.Errorf("invalid cluster CIDR: %w", err)
+		}
+	}
+	for _, network := range o.Networks {
+		if network.NetID == "" {
+			return fmt.Errorf("network name is required")
+		}
+		if network.AddressRange == "" && network.AddressRange6 == "" {
+			return fmt.Errorf("network %s needs an IPv4 or IPv6 address range", network.NetID)
+		}
+		if network.AddressRange != "" && !cidr.IsIPv4(network.AddressRange) {
+			return fmt.Errorf("network %s: invalid IPv4 address range %q", network.NetID, network.AddressRange)
+		}
+		if network.AddressRange6 != "" && !cidr.IsIPv6(network.AddressRange6) {
+			return fmt.Errorf("network %s: invalid IPv6 address range %q", network.NetID, network.AddressRange6)
+		}
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.LeaseDuration > 0 && o.LeaseGracePeriod == 0 {
+		o.LeaseGracePeriod = 7 * 24 * time.Hour
+	}
+	if o.CleanupBatchSize == 0 {
+		o.CleanupBatchSize = 50
+	}
+	if o.CleanupTimeBudget == 0 {
+		o.CleanupTimeBudget = 2 * time.Minute
+	}
+	if o.CleanupDeleteConcurrency == 0 {
+		o.CleanupDeleteConcurrency = 4
+	}
+	if o.ResyncListConcurrency == 0 {
+		o.ResyncListConcurrency = 4
+	}
+	if o.GatewayHealthCheck && o.GatewayStaleAfter == 0 {
+		o.GatewayStaleAfter = 5 * time.Minute
+	}
+}
+
+// instanceIDPattern matches valid instance IDs (DNS labels, usable as object names)
+var instanceIDPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidateInstanceID checks that an instance ID is empty or a DNS label of at most 63 characters
+func ValidateInstanceID(id string) error {
+	if id != "" && (len(id) > 63 || !instanceIDPattern.MatchString(id)) {
+		return fmt.Errorf("instance ID %q must be a DNS label (lowercase alphanumerics and '-', at most 63 characters)", id)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// failingDeletes fails the deletes of some egress rules
+type failingDeletes struct {
+	netmaker.Client
+	fail map[string]bool
+}
+
+// DeleteEgress fails for the rules in fail
+func (c *failingDeletes) DeleteEgress(ctx context.Context, egressID string) error {
+	if c.fail[egressID] {
+		return fmt.Errorf("egress %s is locked", egressID)
+	}
+	return c.Client.DeleteEgress(ctx, egressID)
+}
+
+// orphanFixture has orphaned node-1 (host n1) and valid node-2 (host n2) in network mesh with rules:
+// e1 and e2 owned by node-1, e3 owned by node-1 but held (never deleted), e4 owned by node-2 with node-1 as HA backup,
+// and the unmanaged e5 routed through node-1
+func orphanFixture() *netmaker.Fixture {
+	rule := func(id, description string, nodes map[string]int) netmaker.Egress {
+		return netmaker.Egress{ID: id, Name: id, Network: "mesh", Description: description, Range: "10.244.0.0/24", Nodes: nodes, Status: true}
+	}
+	return &netmaker.Fixture{
+		Hosts: []netmaker.Host{
+			{ID: "h1", Name: "n1", Nodes: []string{"node-1"}},
+			{ID: "h2", Name: "n2", Nodes: []string{"node-2"}},
+		},
+		Nodes: []netmaker.Node{
+			{ID: "node-1", HostID: "h1", Network: "mesh"},
+			{ID: "node-2", HostID: "h2", Network: "mesh"},
+		},
+		Egress: map[string][]netmaker.Egress{"mesh": {
+			rule("e1", EgressMarker+": index=0", map[string]int{"node-1": EgressMetric}),
+			rule("e2", EgressMarker+": index=1", map[string]int{"node-1": EgressMetric}),
+			rule("e3", EgressMarker+": index=2 held=true", map[string]int{"node-1": EgressMetric}),
+			rule("e4", EgressMarker+": index=0", map[string]int{"node-2": EgressMetric, "node-1": EgressMetric + HAGatewayMetricStep}),
+			rule("e5", "office LAN", map[string]int{"node-1": 100}),
+		}},
+	}
+}
+
+func TestDeleteOrphans(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		fail        []string
+		wantKept    []string
+		wantErrors  int
+	}{
+		{name: "sequential", concurrency: 1, wantKept: []string{"e3", "e4", "e5"}},
+		{name: "concurrent", concurrency: 4, wantKept: []string{"e3", "e4", "e5"}},
+		{name: "failed delete, concurrent", concurrency: 4, fail: []string{"e1"}, wantKept: []string{"e1", "e3", "e4", "e5"}, wantErrors: 1},
+		{name: "failed delete, sequential", concurrency: 1, fail: []string{"e2"}, wantKept: []string{"e2", "e3", "e4", "e5"}, wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fixture := netmaker.NewFixtureClient(orphanFixture())
+			client := &failingDeletes{Client: fixture, fail: make(map[string]bool)}
+			for _, id := range tt.fail {
+				client.fail[id] = true
+			}
+			r := newFixtureReconciler(t, client, &Options{CleanupDeleteConcurrency: tt.concurrency})
+
+			orphans := []netmaker.Node{{ID: "node-1", HostID: "h1", Network: "mesh"}}
+			errs := r.deleteOrphans(ctx, r.options.NetmakerClient, orphans)
+			if len(errs) != tt.wantErrors {
+				t.Errorf("deleteOrphans() errors = %v, want %d", errs, tt.wantErrors)
+			}
+			for _, err := range errs {
+				if !strings.Contains(err.Error(), "is locked") {
+					t.Errorf("deleteOrphans() error = %v, want the failed delete", err)
+				}
+			}
+
+			egresses, err := fixture.ListEgress(ctx, "mesh")
+			if err != nil {
+				t.Fatalf("ListEgress() error = %v", err)
+			}
+			byID := make(map[string]netmaker.Egress, len(egresses))
+			for _, egress := range egresses {
+				byID[egress.ID] = egress
+			}
+			if kept := slices.Sorted(maps.Keys(byID)); !slices.Equal(kept, tt.wantKept) {
+				t.Errorf("rules left = %v, want %v", kept, tt.wantKept)
+			}
+			if nodes := byID["e3"].Nodes; nodes["node-1"] != EgressMetric {
+				t.Errorf("held rule e3 routes through %v, want it untouched", nodes)
+			}
+			if nodes := byID["e4"].Nodes; len(nodes) != 1 || nodes["node-2"] != EgressMetric {
+				t.Errorf("rule e4 routes through %v, want node-2 only", nodes)
+			}
+			if nodes := byID["e5"].Nodes; nodes["node-1"] != 100 {
+				t.Errorf("unmanaged rule e5 routes through %v, want it untouched", nodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+func TestEgressOwnership(t *testing.T) {
+	tests := []struct {
+		name       string
+		egress     netmaker.Egress
+		wantOwner  string
+		wantBackup string // A gateway that must not count as owner
+		wantStale  bool
+	}{
+		{
+			name: "owner at the default metric",
+			egress: netmaker.Egress{
+				Description: EgressMarker + ": index=0",
+				Nodes:       map[string]int{"node-1": EgressMetric, "node-2": EgressMetric + HAGatewayMetricStep},
+			},
+			wantOwner: "node-1", wantBackup: "node-2",
+		},
+		{
+			name: "owner at a node metric",
+			egress: netmaker.Egress{
+				Description: EgressMarker + ": index=0 metric=400",
+				Nodes:       map[string]int{"node-1": 400, "node-2": 410},
+			},
+			wantOwner: "node-1", wantBackup: "node-2",
+		},
+		{
+			name: "owner dropped, backup remains",
+			egress: netmaker.Egress{
+				Description: EgressMarker + ": index=0",
+				Nodes:       map[string]int{"node-2": EgressMetric + HAGatewayMetricStep},
+			},
+			wantBackup: "node-2", wantStale: true,
+		},
+		{
+			name: "owner at a node metric dropped, backup remains",
+			egress: netmaker.Egress{
+				Description: EgressMarker + ": index=0 metric=400",
+				Nodes:       map[string]int{"node-2": 410, "node-3": 420},
+			},
+			wantBackup: "node-2", wantStale: true,
+		},
+		{
+			name: "owner deleted from Netmaker",
+			egress: netmaker.Egress{
+				Description: EgressMarker + ": index=0",
+				Nodes:       map[string]int{"node-9": EgressMetric, "node-2": EgressMetric + HAGatewayMetricStep},
+			},
+			wantOwner: "node-9", wantBackup: "node-2", wantStale: true,
+		},
+	}
+
+	existing := map[string]netmaker.Node{"node-1": {ID: "node-1"}, "node-2": {ID: "node-2"}, "node-3": {ID: "node-3"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OwnerNodeID(&tt.egress); got != tt.wantOwner {
+				t.Errorf("OwnerNodeID() = %q, want %q", got, tt.wantOwner)
+			}
+			if tt.wantOwner != "" && !isOwnedBy(&tt.egress, tt.wantOwner) {
+				t.Errorf("isOwnedBy(%s) = false, want true", tt.wantOwner)
+			}
+			if isOwnedBy(&tt.egress, tt.wantBackup) {
+				t.Errorf("isOwnedBy(%s) = true for an HA backup", tt.wantBackup)
+			}
+			if got := hasStaleOwner(&tt.egress, existing); got != tt.wantStale {
+				t.Errorf("hasStaleOwner() = %v, want %v", got, tt.wantStale)
+			}
+		})
+	}
+}
+
+// droppedOwnerFixture has node n2 with its own rule, and the rule of the replaced node n1 whose Netmaker node was
+// deleted and dropped from the gateways, leaving n2 as its only (backup) gateway; n1 came back as node-3
+func droppedOwnerFixture() *netmaker.Fixture {
+	return &netmaker.Fixture{
+		Hosts: []netmaker.Host{
+			{ID: "h1", Name: "n1", Nodes: []string{"node-3"}},
+			{ID: "h2", Name: "n2", Nodes: []string{"node-2"}},
+		},
+		Nodes: []netmaker.Node{
+			{ID: "node-2", HostID: "h2", Network: "mesh"},
+			{ID: "node-3", HostID: "h1", Network: "mesh"},
+		},
+		Egress: map[string][]netmaker.Egress{"mesh": {
+			{
+				ID: "e1", Name: "n1 pods (1/1)", Network: "mesh", Description: EgressMarker + ": index=0",
+				Range: "10.244.1.0/24", Nodes: map[string]int{"node-2": EgressMetric + HAGatewayMetricStep}, Status: true,
+			},
+			{
+				ID: "e2", Name: "n2 pods (1/1)", Network: "mesh", Description: EgressMarker + ": index=0",
+				Range: "10.244.2.0/24", Nodes: map[string]int{"node-2": EgressMetric}, Status: true,
+			},
+		}},
+	}
+}
+
+func TestReconcileNodeWithDroppedOwner(t *testing.T) {
+	tests := []struct {
+		name      string
+		node      string
+		podCIDR   string
+		wantRules map[string]netmaker.Egress // ID -> expected range and gateways
+	}{
+		{
+			name: "backup keeps its own rule", node: "n2", podCIDR: "10.244.2.0/24",
+			wantRules: map[string]netmaker.Egress{
+				"e1": {Range: "10.244.1.0/24", Nodes: map[string]int{"node-2": EgressMetric + HAGatewayMetricStep}},
+				"e2": {Range: "10.244.2.0/24", Nodes: map[string]int{"node-2": EgressMetric}},
+			},
+		},
+		{
+			name: "replaced node takes its rule over", node: "n1", podCIDR: "10.244.1.0/24",
+			wantRules: map[string]netmaker.Egress{
+				"e1": {Range: "10.244.1.0/24", Nodes: map[string]int{"node-3": EgressMetric}},
+				"e2": {Range: "10.244.2.0/24", Nodes: map[string]int{"node-2": EgressMetric}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := netmaker.NewFixtureClient(droppedOwnerFixture())
+			r := newFixtureReconciler(t, client, nil)
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.node},
+				Spec:       corev1.NodeSpec{PodCIDRs: []string{tt.podCIDR}},
+			}
+
+			if _, err := r.ReconcileNode(ctx, node, Topology{}); err != nil {
+				t.Fatalf("ReconcileNode() error = %v", err)
+			}
+
+			egresses, err := client.ListEgress(ctx, "mesh")
+			if err != nil {
+				t.Fatalf("ListEgress() error = %v", err)
+			}
+			if len(egresses) != len(tt.wantRules) {
+				t.Fatalf("%d egress rules, want %d: %+v", len(egresses), len(tt.wantRules), egresses)
+			}
+			for _, egress := range egresses {
+				want, ok := tt.wantRules[egress.ID]
+				if !ok {
+					t.Errorf("unexpected egress rule %+v", egress)
+					continue
+				}
+				if egress.Range != want.Range || !egressNodesEqual(egress.Nodes, want.Nodes) {
+					t.Errorf("rule %s = %s via %v, want %s via %v", egress.ID, egress.Range, egress.Nodes, want.Range, want.Nodes)
+				}
+			}
+		})
+	}
+}
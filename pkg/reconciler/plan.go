@@ -0,0 +1,355 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// Change actions of a Plan
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Plan is the desired node egress state of the cluster and the Netmaker changes needed to reach it
+type Plan struct {
+	Networks []NetworkPlan `json:"networks"`
+}
+
+// NetworkPlan holds the desired egress rules of one Netmaker network, per node, and the changes to the network
+type NetworkPlan struct {
+	Network string          `json:"network"`
+	Nodes   []NodeEgresses  `json:"nodes"`
+	Changes []PlannedChange `json:"changes,omitempty"`
+}
+
+// NodeEgresses are the egress rules a node is the primary gateway of
+type NodeEgresses struct {
+	Node     string          `json:"node"`
+	Egresses []PlannedEgress `json:"egresses"`
+}
+
+// PlannedEgress is a desired egress rule
+type PlannedEgress struct {
+	Name     string    `json:"name"`
+	Range    string    `json:"range"`
+	Gateways []Gateway `json:"gateways"`
+}
+
+// Gateway is a node routing an egress rule; lower metrics are preferred
+type Gateway struct {
+	Node    string `json:"node"`
+	Metric  int    `json:"metric"`
+	Primary bool   `json:"primary,omitempty"` // The rule's owner (node rules) or first gateway
+}
+
+// PlannedChange is a create, update or delete of a Netmaker egress rule
+// Updates without a previous range or gateways only rewrite the description (e.g. a lease refresh)
+type PlannedChange struct {
+	Action           string    `json:"action"`
+	ID               string    `json:"id,omitempty"` // Empty for creates
+	Name             string    `json:"name"`
+	Range            string    `json:"range"`
+	PreviousRange    string    `json:"previousRange,omitempty"`
+	Gateways         []Gateway `json:"gateways,omitempty"`
+	PreviousGateways []Gateway `json:"previousGateways,omitempty"`
+}
+
+// Changes returns the number of planned creates, updates and deletes across all networks
+func (p *Plan) Changes() (creates, updates, deletes int) {
+	for _, network := range p.Networks {
+		for _, change := range network.Changes {
+			switch change.Action {
+			case ChangeCreate:
+				creates++
+			case ChangeUpdate:
+				updates++
+			case ChangeDelete:
+				deletes++
+			}
+		}
+	}
+	return creates, updates, deletes
+}
+
+// PlanNodes computes the egress rules of the given nodes and the changes needed to reach them
+// Runs the same reconcile and orphan cleanup logic as the controller against a fresh snapshot whose
+// mutations are recorded instead of applied, so Netmaker is never modified
+// ClusterEgressRule rules, expired leases and state store records are not planned
+func (r *Reconciler) PlanNodes(ctx context.Context, requests []NodeRequest, validNodeIDs map[string]bool) (*Plan, error) {
+	snap, err := newSnapshot(ctx, r.options.NetmakerClient, r.options.ResyncListConcurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot Netmaker state: %w", err)
+	}
+	p := newPlanner(snap)
+
+	var planErrors []error
+	for _, req := range requests {
+		if _, _, err := r.reconcileNode(ctx, p, req.Node, req.Topology); err != nil {
+			planErrors = append(planErrors, err)
+		}
+	}
+	if err := r.cleanupOrphanedEgresses(ctx, p, validNodeIDs); err != nil {
+		planErrors = append(planErrors, err)
+	}
+	if len(planErrors) > 0 {
+		return nil, errors.Join(planErrors...)
+	}
+
+	return r.buildPlan(p), nil
+}
+
+// VerifyNode reads a node's egress rules back from Netmaker (bypassing the cache) and checks that
+// reconciling it again would change nothing
+// Returns an error listing the pending changes, or if the node owns no egress rule at all
+// (no pod CIDRs or no Netmaker host), so a write that silently went nowhere is caught too
+func (r *Reconciler) VerifyNode(ctx context.Context, node *corev1.Node, topology Topology) error {
+	snap, err := newSnapshot(ctx, r.options.NetmakerClient, r.options.ResyncListConcurrency)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot Netmaker state: %w", err)
+	}
+	p := newPlanner(snap)
+
+	if _, _, err := r.reconcileNode(ctx, p, node, topology); err != nil {
+		return err
+	}
+	plan := r.buildPlan(p)
+
+	var pending []string
+	owned := 0
+	for _, network := range plan.Networks {
+		for _, change := range network.Changes {
+			pending = append(pending, fmt.Sprintf("%s %q (%s) in network %s", change.Action, change.Name, change.Range, network.Network))
+		}
+		for _, nodeEgresses := range network.Nodes {
+			if nodeEgresses.Node == node.Name {
+				owned += len(nodeEgresses.Egresses)
+			}
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("egress rules differ from the desired state after reconcile: %s", strings.Join(pending, ", "))
+	}
+	if owned == 0 {
+		return fmt.Errorf("node owns no egress rules in Netmaker (no pod CIDRs or no Netmaker host?)")
+	}
+	return nil
+}
+
+// buildPlan groups the node rules of our cluster in the planned state by network and owning node
+func (r *Reconciler) buildPlan(p *planner) *Plan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	networks := make(map[string]bool, len(p.egress))
+	for network := range p.egress {
+		networks[network] = true
+	}
+	for network := range p.changes {
+		networks[network] = true
+	}
+
+	plan := &Plan{Networks: []NetworkPlan{}}
+	for _, network := range sortedKeys(networks) {
+		type ownedEgress struct {
+			index  int
+			egress PlannedEgress
+		}
+		owned := make(map[string][]ownedEgress) // node name -> rules
+		for i := range p.egress[network] {
+			egress := &p.egress[network][i]
+			metadata := parseEgress(egress)
+			if !r.isNodeEgress(metadata) {
+				continue
+			}
+			if ownerID := OwnerNodeID(egress); ownerID != "" {
+				owner := p.nodeName(ownerID)
+				owned[owner] = append(owned[owner], ownedEgress{
+					index:  metadata.index,
+					egress: PlannedEgress{Name: egress.Name, Range: egress.Range, Gateways: p.gateways(egress)},
+				})
+			}
+		}
+
+		if len(owned) == 0 && len(p.changes[network]) == 0 {
+			continue
+		}
+
+		networkPlan := NetworkPlan{
+			Network: network,
+			Nodes:   make([]NodeEgresses, 0, len(owned)),
+			Changes: p.changes[network],
+		}
+		for _, node := range sortedKeys(owned) {
+			rules := owned[node]
+			sort.Slice(rules, func(i, j int) bool { return rules[i].index < rules[j].index })
+
+			egresses := make([]PlannedEgress, 0, len(rules))
+			for _, rule := range rules {
+				egresses = append(egresses, rule.egress)
+			}
+			networkPlan.Nodes = append(networkPlan.Nodes, NodeEgresses{Node: node, Egresses: egresses})
+		}
+		plan.Networks = append(plan.Networks, networkPlan)
+	}
+
+	return plan
+}
+
+// planner is a snapshot that records mutations as planned changes and applies them only to itself
+type planner struct {
+	*snapshot
+
+	hostnames map[string]string          // node ID -> hostname
+	changes   map[string][]PlannedChange // network -> changes, in order
+	created   int
+}
+
+// newPlanner wraps a snapshot
+func newPlanner(snap *snapshot) *planner {
+	hostnames := make(map[string]string)
+	for hostname, nodeIDs := range snap.hostNodeIDs {
+		for _, id := range nodeIDs {
+			hostnames[id] = hostname
+		}
+	}
+	return &planner{
+		snapshot:  snap,
+		hostnames: hostnames,
+		changes:   make(map[string][]PlannedChange),
+	}
+}
+
+// CreateEgress records a create and adds the rule (with a placeholder ID) to the planned state
+func (p *planner) CreateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	// Make sure the network is listed, so the planned rule is added to its existing rules
+	if _, err := p.ListEgress(ctx, req.Network); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.created++
+	req.ID = fmt.Sprintf("planned-%d", p.created)
+	created := egressFromResponse(nil, req)
+	p.egress[req.Network] = append(p.cloneEgress(req.Network), created)
+	p.changes[req.Network] = append(p.changes[req.Network], PlannedChange{
+		Action:   ChangeCreate,
+		Name:     req.Name,
+		Range:    req.Range,
+		Gateways: p.gateways(&created),
+	})
+	return &created, nil
+}
+
+// UpdateEgress records an update and replaces the rule in the planned state
+func (p *planner) UpdateEgress(_ context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	updated := egressFromResponse(nil, req)
+	change := PlannedChange{
+		Action:   ChangeUpdate,
+		ID:       req.ID,
+		Name:     req.Name,
+		Range:    req.Range,
+		Gateways: p.gateways(&updated),
+	}
+
+	egresses := p.cloneEgress(req.Network)
+	for i := range egresses {
+		if egresses[i].ID != req.ID {
+			continue
+		}
+		if !cidr.Equal(egresses[i].Range, req.Range) {
+			change.PreviousRange = egresses[i].Range
+		}
+		if !egressNodesEqual(egresses[i].Nodes, req.Nodes) {
+			change.PreviousGateways = p.gateways(&egresses[i])
+		}
+		egresses[i] = updated
+	}
+	p.egress[req.Network] = egresses
+	p.changes[req.Network] = append(p.changes[req.Network], change)
+	return &updated, nil
+}
+
+// DeleteEgress records a delete and removes the rule from the planned state
+func (p *planner) DeleteEgress(_ context.Context, egressID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for network, egresses := range p.egress {
+		kept := make([]netmaker.Egress, 0, len(egresses))
+		for _, egress := range egresses {
+			if egress.ID != egressID {
+				kept = append(kept, egress)
+				continue
+			}
+			p.changes[network] = append(p.changes[network], PlannedChange{
+				Action:   ChangeDelete,
+				ID:       egress.ID,
+				Name:     egress.Name,
+				Range:    egress.Range,
+				Gateways: p.gateways(&egress),
+			})
+		}
+		p.egress[network] = kept
+	}
+	return nil
+}
+
+// Invalidate is a no-op: nothing is applied, so the planned state never conflicts with Netmaker
+func (p *planner) Invalidate(_ netmaker.CacheKind, _ string) error {
+	return nil
+}
+
+// AddHostToNetwork only logs the join; the host has no node in the planned state, so no rules are planned for it
+func (p *planner) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	netmaker.Logf(ctx, "Would join Netmaker host %s to network %s", hostID, network)
+	return nil
+}
+
+// nodeName returns the hostname of a Netmaker node ID, or the ID if its host is unknown
+func (p *planner) nodeName(nodeID string) string {
+	if hostname, ok := p.hostnames[nodeID]; ok {
+		return hostname
+	}
+	return nodeID
+}
+
+// gateways converts the nodes map of an egress rule to gateways, ordered by metric (preferred first)
+func (p *planner) gateways(egress *netmaker.Egress) []Gateway {
+	primary := primaryMetric(parseEgress(egress))
+	gateways := make([]Gateway, 0, len(egress.Nodes))
+	for nodeID, metric := range egress.Nodes {
+		gateways = append(gateways, Gateway{Node: p.nodeName(nodeID), Metric: metric, Primary: metric == primary})
+	}
+	sort.Slice(gateways, func(i, j int) bool {
+		if gateways[i].Metric != gateways[j].Metric {
+			return gateways[i].Metric < gateways[j].Metric
+		}
+		return gateways[i].Node < gateways[j].Node
+	})
+	return gateways
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,23 @@
+package reconciler
+
+import "context"
+
+// ReconcilePool syncs the shared egress rules of a node pool to Netmaker
+// pool.CIDRs are the aggregated pod CIDRs of the pool's nodes and pool.GatewayNodes its members,
+// so nodes joining or leaving the pool only change the gateways (and the ranges once they no longer summarize)
+// Pool rules are recorded with pool=NAME in their descriptions and never masquerade
+func (r *Reconciler) ReconcilePool(ctx context.Context, pool EgressRule) error {
+	pool.NAT = false
+	return r.reconcileGroup(ctx, poolGroup, pool)
+}
+
+// DeletePool removes all egress rules of a node pool from every network
+func (r *Reconciler) DeletePool(ctx context.Context, name string) error {
+	return r.deleteGroups(ctx, poolGroup, func(pool string) bool { return pool == name })
+}
+
+// CleanupPools removes egress rules of node pools that no longer have nodes
+// validPools is the set of all node pool names; node and ClusterEgressRule rules are never touched
+func (r *Reconciler) CleanupPools(ctx context.Context, validPools map[string]bool) error {
+	return r.deleteGroups(ctx, poolGroup, func(pool string) bool { return !validPools[pool] })
+}
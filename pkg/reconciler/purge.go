@@ -0,0 +1,64 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// ManagedEgresses lists every egress rule owned by our cluster identity, across all networks
+// Includes node rules and ClusterEgressRule rules; rules of other clusters and unmanaged rules are skipped
+// Used to decommission a cluster (see the purge command)
+func (r *Reconciler) ManagedEgresses(ctx context.Context) ([]netmaker.Egress, error) {
+	allNodes, err := r.options.NetmakerClient.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var managed []netmaker.Egress
+	for _, network := range nodeNetworks(allNodes) {
+		egresses, err := r.options.NetmakerClient.ListEgress(ctx, network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list egress rules in network %s: %w", network, err)
+		}
+		for _, egress := range egresses {
+			if r.OwnsEgress(&egress) {
+				managed = append(managed, egress)
+			}
+		}
+	}
+
+	return managed, nil
+}
+
+// OwnsEgress reports whether an egress rule's description (or name marker) marks it as managed by our cluster identity
+func (r *Reconciler) OwnsEgress(egress *netmaker.Egress) bool {
+	return r.belongsToOurCluster(parseEgress(egress))
+}
+
+// DeleteEgresses deletes the given egress rules, CleanupDeleteConcurrency at a time, continuing past failures
+// Returns the number of deleted rules and the joined errors of the failed ones
+func (r *Reconciler) DeleteEgresses(ctx context.Context, egresses []netmaker.Egress) (int, error) {
+	deletionErrors := r.deleteEgresses(ctx, r.options.NetmakerClient, egresses)
+	return len(egresses) - len(deletionErrors), errors.Join(deletionErrors...)
+}
+
+// deleteEgresses deletes egress rules through api, CleanupDeleteConcurrency at a time (one at a time when planning,
+// so planned deletes keep their order), each in the context of its network
+// Returns an error for each rule that could not be deleted
+func (r *Reconciler) deleteEgresses(ctx context.Context, api netmaker.EgressDeleter, egresses []netmaker.Egress) []error {
+	concurrency := r.options.CleanupDeleteConcurrency
+	if _, planning := api.(*planner); planning {
+		concurrency = 1
+	}
+
+	var deletionErrors []error
+	for i, err := range netmaker.DeleteEgressBatch(ctx, api, egresses, concurrency) {
+		if err != nil {
+			deletionErrors = append(deletionErrors, fmt.Errorf("failed to delete egress %s in network %s: %w", egresses[i].ID, egresses[i].Network, err))
+		}
+	}
+	return deletionErrors
+}
@@ -2,39 +2,102 @@ package reconciler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+	"github.com/bsure-analytics/kaput-not/pkg/metrics"
 	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/statestore"
 )
 
 const (
 	// EgressMarker is the prefix for managed egress rule descriptions
 	EgressMarker = "Managed by kaput-not (DO NOT EDIT)"
 	// EgressMetric is the metric value used for egress gateway nodes
+	// The node owning the pod CIDR uses this metric unless Options.NodeMetrics gives it another one, which its rules
+	// then record ("metric=<n>" in the description, "-m<n>" in the name marker); the gateway at the recorded primary
+	// metric is the rule's owner, whichever other gateways remain
 	EgressMetric = 500
+	// HAGatewayMetricStep is the metric increment for each additional HA gateway node
+	// Lower metrics are preferred, so backup gateways only carry traffic when the owner is down
+	HAGatewayMetricStep = 10
+	// maxEgressMetric is the highest metric Netmaker accepts
+	maxEgressMetric = 999
+	// maxConflictRetries is how often a network is re-read and reconciled again after a 409 conflict
+	maxConflictRetries = 2
 )
 
+// Topology holds cluster-wide inputs for reconciling a single node, computed by the controller
+type Topology struct {
+	// GatewayNodes are Kubernetes node names attached to every rule as HA backup gateways
+	// (in order of preference); the reconciled node itself is always the primary gateway
+	GatewayNodes []string
+
+	// PodCIDRs replace the node's spec.podCIDRs when non-nil (e.g. CIDRs allocated from selected IP pools)
+	PodCIDRs []string
+
+	// SummarizedCIDRs are published instead of the node's own pod CIDRs (see SummarizeCIDRs)
+	// Ignored when static ClusterCIDRs are configured
+	SummarizedCIDRs []string
+
+	// ClusterNetworkCIDRs are cluster-level CIDRs (e.g. the service subnet) published in addition
+	// to the node's own; the controller only sets them for HA gateway nodes
+	ClusterNetworkCIDRs []string
+
+	// Gated turns the node's existing rules off (Status=false) and creates no new ones, e.g. while the node
+	// carries a gating taint; rules are turned back on once the node is no longer gated
+	Gated bool
+
+	// Held marks the node's rules held=true: they are kept when the node is deleted, e.g. while it is re-imaged
+	Held bool
+}
+
 // Reconciler handles Node reconciliation logic
 // Networks are auto-discovered by looking up which networks the Netmaker host participates in
 type Reconciler struct {
-	netmakerClient *netmaker.CachedClient
-	clusterName    string // Optional - for multi-cluster deployments sharing a Netmaker network
+	options     *Options
+	nodeMetrics []nodeMetricSelector // Parsed Options.NodeMetrics
+
+	hosts  hostMemory    // Netmaker host ID of each node, for hosts renamed in Netmaker
+	health gatewayHealth // Nodes whose rules are off for unhealthy gateways (see Options.GatewayHealthCheck)
+
+	extClientNetworks extClientNetworks // Networks of the external client grant of each node (see SyncExtClients)
+
+	networksUnlisted atomic.Bool // The last network list failed (see networkMetadata)
 }
 
 // New creates a new reconciler with a single cached client
 // Networks are discovered automatically per K8s node
-// clusterName is optional - if set, egress rules will be scoped to this cluster
-func New(client *netmaker.CachedClient, clusterName string) *Reconciler {
-	return &Reconciler{
-		netmakerClient: client,
-		clusterName:    clusterName,
+// ClusterName is optional - if set, egress rules will be scoped to this cluster
+func New(opts *Options) (*Reconciler, error) {
+	// Validate and apply defaults
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
 	}
+	opts.ApplyDefaults()
+
+	nodeMetrics, err := parseNodeMetrics(opts.NodeMetrics)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err) // Already validated - never happens
+	}
+
+	return &Reconciler{
+		options:     opts,
+		nodeMetrics: nodeMetrics,
+	}, nil
 }
 
 // ReconcileNode syncs a Node's pod CIDRs to Netmaker egress rules
+// In aggregated mode the configured ClusterCIDRs (or the topology's summarized CIDRs)
+// are published instead of the node's own pod CIDRs
 // Networks are auto-discovered from the Netmaker nodes themselves
 // Returns error with full context, never panics
 //
@@ -43,38 +106,78 @@ func New(client *netmaker.CachedClient, clusterName string) *Reconciler {
 //  2. Get all Netmaker node IDs for this host (from host.Nodes field)
 //  3. Get all nodes across all networks
 //  4. For each node belonging to this host, reconcile egress rules in its network
-func (r *Reconciler) ReconcileNode(ctx context.Context, node *corev1.Node) error {
-	podCIDRs := node.Spec.PodCIDRs
+//
+// The result lists the networks touched and the mutations made (also those made before an error)
+// Netmaker requests carry the request ID of ctx, or a new one (returned in the result)
+func (r *Reconciler) ReconcileNode(ctx context.Context, node *corev1.Node, topology Topology) (NodeResult, error) {
+	ctx, requestID := netmaker.EnsureRequestID(ctx)
+	recorder := newRecordingAPI(r.options.NetmakerClient)
+	applied, networks, err := r.reconcileNode(ctx, recorder, node, topology)
+	result := newNodeResult(requestID, networks, recorder, applied)
+	if err != nil {
+		return result, err
+	}
+	reportCollisions(ctx, result.Collisions)
+	r.recordEgresses(node.Name, applied)
+	return result, nil
+}
+
+// reconcileNode reconciles a node against api (the cached client, a resync snapshot or a planner)
+// Returns the rules that now exist for the node (nil if it has none to publish) and the networks it was reconciled in
+func (r *Reconciler) reconcileNode(ctx context.Context, api netmakerAPI, node *corev1.Node, topology Topology) ([]statestore.EgressRef, []string, error) {
+	ctx = metrics.WithZone(ctx, NodeZone(node)) // Attributes the node's Netmaker calls to its zone
+	podCIDRs, names := r.publishedCIDRs(node, topology)
 
 	if len(podCIDRs) == 0 {
 		// Not an error - node might not have CIDRs assigned yet
-		return nil
+		return nil, nil, nil
 	}
 
-	// Get all Netmaker node IDs for this host (from host.Nodes field)
-	nodeIDs, err := r.netmakerClient.GetNodeIDsByHostname(ctx, node.Name)
+	// Get all Netmaker node IDs for this host (from host.Nodes field, or by host ID if it was renamed)
+	nodeIDs, err := r.hostNodeIDs(ctx, api, node.Name, r.hostAddresses(node))
 	if err != nil {
 		// If host doesn't exist, skip silently (not an error)
 		if strings.Contains(err.Error(), "not found") {
-			return nil
+			return nil, nil, nil
 		}
-		return fmt.Errorf("failed to get node IDs for node %s: %w", node.Name, err)
+		return nil, nil, fmt.Errorf("failed to get node IDs for node %s: %w", node.Name, err)
 	}
 
 	if len(nodeIDs) == 0 {
-		// No nodes for this host - skip silently
-		return nil
+		// Host in no network (netclient enrolled, but not joined) - join it to the default network, if any
+		if nodeIDs, err = r.joinDefaultNetwork(ctx, api, node.Name); err != nil {
+			return nil, nil, fmt.Errorf("failed to join the Netmaker host of node %s to network %s: %w", node.Name, r.options.DefaultNetwork, err)
+		}
+		if len(nodeIDs) == 0 {
+			return nil, nil, nil // Reported through NodeResult.HostWithoutNetwork
+		}
 	}
 
 	// Get all nodes - each node contains its network
-	allNodes, err := r.netmakerClient.ListNodes(ctx)
+	allNodes, err := api.ListNodes(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list nodes: %w", err)
+		return nil, nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	// Resolve HA backup gateways to their Netmaker node IDs per network
+	backupGateways, err := r.resolveGateways(ctx, api, node.Name, topology.GatewayNodes, allNodes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve HA gateways for node %s: %w", node.Name, err)
+	}
+
+	nodesByID := make(map[string]netmaker.Node, len(allNodes))
+	for _, n := range allNodes {
+		nodesByID[n.ID] = n
 	}
+	labels := r.descriptionLabels(node)
+	metric := r.nodeMetric(node)
+	networkInfo := r.networkMetadata(ctx)
 
 	// Reconcile each node that belongs to this host
 	// Each node tells us both the nodeID and which network it's in
 	var reconcileErrors []error
+	var applied []statestore.EgressRef
+	var networks []string
 	for _, n := range allNodes {
 		// Check if this node belongs to our host
 		belongsToHost := false
@@ -90,70 +193,334 @@ func (r *Reconciler) ReconcileNode(ctx context.Context, node *corev1.Node) error
 		}
 
 		// Reconcile egress rules for this node in its network
-		if err := r.reconcileNodeInNetwork(ctx, node, podCIDRs, n.ID, n.Network); err != nil {
+		networks = append(networks, n.Network)
+		egressNodes := familyEgressNodes(n, networkInfo[n.Network], podCIDRs, metric, backupGateways[n.Network], nodesByID)
+		skipMeshOverlaps(ctx, networkInfo[n.Network], podCIDRs, egressNodes)
+		gated, unhealthy := r.gatedRules(topology.Gated, egressNodes, nodesByID)
+		refs, err := r.reconcileNodeInNetwork(ctx, api, podCIDRs, names, egressNodes, n.ID, n.HostID, labels, n.Network, nodesByID, gated, topology.Held)
+		for attempt := 0; errors.Is(err, netmaker.ErrConflict) && attempt < maxConflictRetries; attempt++ {
+			// Someone edited a rule concurrently - re-read the network and recompute
+			_ = api.Invalidate(netmaker.CacheKindEgress, n.Network) // Valid kind - never fails
+			refs, err = r.reconcileNodeInNetwork(ctx, api, podCIDRs, names, egressNodes, n.ID, n.HostID, labels, n.Network, nodesByID, gated, topology.Held)
+		}
+		if errors.Is(err, netmaker.ErrNotFound) {
+			// A rule from the cached listing is gone (deleted behind our back) - re-read the network and retry once
+			// instead of waiting for the rate-limited requeue
+			metrics.StaleCacheRetries.WithLabelValues(n.Network).Inc()
+			_ = api.Invalidate(netmaker.CacheKindEgress, n.Network) // Valid kind - never fails
+			refs, err = r.reconcileNodeInNetwork(ctx, api, podCIDRs, names, egressNodes, n.ID, n.HostID, labels, n.Network, nodesByID, gated, topology.Held)
+		}
+		if err != nil {
 			// Collect errors but continue with other nodes
-			reconcileErrors = append(reconcileErrors, fmt.Errorf("network %s: %w", n.Network, err))
+			reconcileErrors = append(reconcileErrors, &NetworkError{Network: n.Network, Err: err})
+			continue
+		}
+		applied = append(applied, refs...)
+		if _, planning := api.(*planner); r.options.GatewayHealthCheck && !planning {
+			r.recordGatewayHealth(ctx, node.Name, n.Network, !unhealthy)
 		}
 	}
 
 	if len(reconcileErrors) > 0 {
-		return fmt.Errorf("failed to reconcile node %s in some networks: %v", node.Name, reconcileErrors)
+		return nil, networks, fmt.Errorf("failed to reconcile node %s in some networks: %w", node.Name, errors.Join(reconcileErrors...))
 	}
 
-	return nil
+	return applied, networks, nil
+}
+
+// recordEgresses records the rules applied for a node in the state store (no-op without one)
+// Only called after the node fully succeeded, so the record is complete
+func (r *Reconciler) recordEgresses(nodeName string, applied []statestore.EgressRef) {
+	if r.options.StateStore != nil {
+		r.options.StateStore.Set(nodeName, applied)
+	}
+}
+
+// AggregatesClusterCIDRs reports whether nodes publish the cluster CIDRs instead of their own pod CIDRs
+// In this mode publisher nodes need egress rules even without pod CIDRs of their own
+func (r *Reconciler) AggregatesClusterCIDRs() bool {
+	return len(r.options.ClusterCIDRs) > 0
+}
+
+// publishedCIDRs returns the CIDRs a node publishes and the egress rule name for each
+// Static ClusterCIDRs take precedence over summarized CIDRs, which take precedence over the node's own;
+// the topology's cluster network CIDRs are appended (unless already published)
+// CIDRs are normalized (see cidr.Normalize), so differently spelled ranges never cause update loops
+func (r *Reconciler) publishedCIDRs(node *corev1.Node, topology Topology) ([]string, []string) {
+	cidrs, aggregated := node.Spec.PodCIDRs, false
+	if topology.PodCIDRs != nil {
+		cidrs = topology.PodCIDRs
+	}
+	if r.AggregatesClusterCIDRs() {
+		cidrs, aggregated = r.options.ClusterCIDRs, true
+	} else if len(topology.SummarizedCIDRs) > 0 {
+		cidrs, aggregated = topology.SummarizedCIDRs, true
+	}
+
+	metric := r.nodeMetric(node)
+	published := make([]string, 0, len(cidrs)+len(topology.ClusterNetworkCIDRs))
+	names := make([]string, 0, len(cidrs)+len(topology.ClusterNetworkCIDRs))
+	seen := make(map[string]bool, len(cidrs))
+	for index, podCIDR := range cidrs {
+		podCIDR = cidr.NormalizeOrKeep(podCIDR)
+		published = append(published, podCIDR)
+		names = append(names, r.markName(buildEgressName(node.Name, index, len(cidrs), aggregated), index, metric))
+		seen[podCIDR] = true
+	}
+
+	var extra []string
+	for _, networkCIDR := range topology.ClusterNetworkCIDRs {
+		networkCIDR = cidr.NormalizeOrKeep(networkCIDR)
+		if !seen[networkCIDR] {
+			extra = append(extra, networkCIDR)
+		}
+	}
+	for index, networkCIDR := range extra {
+		published = append(published, networkCIDR)
+		names = append(names, r.markName(buildClusterNetworkEgressName(node.Name, index, len(extra)), len(cidrs)+index, metric))
+	}
+
+	return published, names
+}
+
+// resolveGateways maps HA gateway node names to their Netmaker node IDs, grouped by network
+// The reconciled node itself and gateways without a Netmaker host are skipped
+// Order is preserved so earlier gateways get lower (preferred) metrics
+func (r *Reconciler) resolveGateways(ctx context.Context, api netmakerAPI, nodeName string, gatewayNodes []string, allNodes []netmaker.Node) (map[string][]string, error) {
+	if len(gatewayNodes) == 0 {
+		return nil, nil
+	}
+
+	nodeNetworks := make(map[string]string, len(allNodes)) // nodeID -> network
+	for _, n := range allNodes {
+		nodeNetworks[n.ID] = n.Network
+	}
+
+	gateways := make(map[string][]string) // network -> []nodeID
+	for _, gatewayNode := range gatewayNodes {
+		if gatewayNode == nodeName {
+			continue
+		}
+
+		nodeIDs, err := r.hostNodeIDs(ctx, api, gatewayNode, nil)
+		if err != nil {
+			// Gateway not (yet) joined to Netmaker - skip it
+			if strings.Contains(err.Error(), "not found") {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get node IDs for gateway %s: %w", gatewayNode, err)
+		}
+
+		for _, id := range nodeIDs {
+			if network, ok := nodeNetworks[id]; ok {
+				gateways[network] = append(gateways[network], id)
+			}
+		}
+	}
+
+	return gateways, nil
+}
+
+// buildEgressNodes builds the nodes map for an egress rule
+// The owning node gets the primary metric, backup gateways get increasing metrics (capped at maxEgressMetric)
+func buildEgressNodes(nodeID string, primary int, backupNodeIDs []string) map[string]int {
+	nodes := map[string]int{nodeID: primary}
+	for i, id := range backupNodeIDs {
+		metric := primary + (i+1)*HAGatewayMetricStep
+		if metric > maxEgressMetric {
+			break
+		}
+		nodes[id] = metric
+	}
+	return nodes
+}
+
+// familyEgressNodes builds the nodes map for each published CIDR: CIDRs of an IP family the node has no
+// address of get nil (not routed through it), and backup gateways without such an address are left out
+// Keeps IPv4 pod CIDRs on IPv4-capable nodes and IPv6 ones on IPv6-capable nodes of dual-network hosts
+// network is the node's network (zero if its metadata is unknown), primary the metric of the node itself
+func familyEgressNodes(node netmaker.Node, network netmaker.Network, podCIDRs []string, primary int, backupNodeIDs []string, nodesByID map[string]netmaker.Node) []map[string]int {
+	egressNodes := make([]map[string]int, len(podCIDRs))
+	for index, podCIDR := range podCIDRs {
+		if !supportsFamily(node, network, podCIDR) {
+			continue
+		}
+		backups := make([]string, 0, len(backupNodeIDs))
+		for _, id := range backupNodeIDs {
+			if supportsFamily(nodesByID[id], network, podCIDR) {
+				backups = append(backups, id)
+			}
+		}
+		egressNodes[index] = buildEgressNodes(node.ID, primary, backups)
+	}
+	return egressNodes
+}
+
+// supportsFamily checks if a Netmaker node has an address of the CIDR's IP family
+// Nodes without any address (e.g. older API responses) route the families their network has an address range of,
+// or both if the network's ranges are unknown
+func supportsFamily(node netmaker.Node, network netmaker.Network, podCIDR string) bool {
+	if node.Address == "" && node.Address6 == "" {
+		if network.AddressRange == "" && network.AddressRange6 == "" {
+			return true
+		}
+		if cidr.IsIPv6(podCIDR) {
+			return network.AddressRange6 != ""
+		}
+		return network.AddressRange != ""
+	}
+	if cidr.IsIPv6(podCIDR) {
+		return node.Address6 != ""
+	}
+	return node.Address != ""
+}
+
+// isOwnedBy checks if nodeID is the primary gateway of an egress rule: the gateway at the primary metric the rule
+// records (see primaryMetric), so an HA backup, which kaput-not gives a higher metric (see buildEgressNodes), never
+// becomes the owner when Netmaker drops the owner from the nodes map
+func isOwnedBy(egress *netmaker.Egress, nodeID string) bool {
+	metric, hasNode := egress.Nodes[nodeID]
+	return hasNode && metric == primaryMetric(parseEgress(egress))
+}
+
+// OwnerNodeID returns the Netmaker node ID of the primary gateway of an egress rule (empty if it has none)
+func OwnerNodeID(egress *netmaker.Egress) string {
+	primary := primaryMetric(parseEgress(egress))
+	for id, metric := range egress.Nodes {
+		if metric == primary {
+			return id
+		}
+	}
+	return ""
+}
+
+// hasStaleOwner checks if the primary gateway of a node rule no longer exists in Netmaker
+// (or was already dropped from the rule's nodes map by Netmaker)
+func hasStaleOwner(egress *netmaker.Egress, nodesByID map[string]netmaker.Node) bool {
+	owner := OwnerNodeID(egress)
+	if owner == "" {
+		return true
+	}
+	_, exists := nodesByID[owner]
+	return !exists
+}
+
+// egressNodesEqual checks if two egress node maps contain the same nodes and metrics
+func egressNodesEqual(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, metric := range a {
+		if other, ok := b[id]; !ok || other != metric {
+			return false
+		}
+	}
+	return true
 }
 
 // reconcileNodeInNetwork reconciles a single node in a single network
-// nodeID is passed as parameter - no lookup needed
-func (r *Reconciler) reconcileNodeInNetwork(ctx context.Context, node *corev1.Node, podCIDRs []string, nodeID string, network string) error {
+// nodeID and its hostID are passed as parameters - no lookup needed; labels are the node's description labels
+// names holds the egress rule name for each published CIDR
+// egressNodes holds the desired nodes map (owner plus any HA backup gateways) for each published CIDR;
+// nil skips the CIDR (IP family not routed through this node)
+// Rules owned by this node with an index beyond the published CIDRs (e.g. a summary shrank) or of a
+// skipped CIDR are deleted
+// nodesByID holds all current Netmaker nodes, to recognize rules still routed through a replaced node
+// gated tells for each published CIDR whether its existing rule is turned off instead of creating a missing one
+// (see Topology.Gated and Options.GatewayHealthCheck)
+// Returns the rules that now exist for this node in the network
+func (r *Reconciler) reconcileNodeInNetwork(ctx context.Context, api netmakerAPI, podCIDRs []string, names []string, egressNodes []map[string]int, nodeID string, hostID string, labels map[string]string, network string, nodesByID map[string]netmaker.Node, gated []bool, held bool) ([]statestore.EgressRef, error) {
+	ctx = netmaker.WithNetwork(ctx, network) // The network's timeout applies to the deletes too
 
 	// List all existing egress rules for this network
-	existingEgresses, err := r.netmakerClient.ListEgress(ctx, network)
+	existingEgresses, err := api.ListEgress(ctx, network)
 	if err != nil {
-		return fmt.Errorf("failed to list egress rules in network %s: %w", network, err)
+		return nil, fmt.Errorf("failed to list egress rules in network %s: %w", network, err)
 	}
 
 	// Reconcile each pod CIDR
+	refs := make([]statestore.EgressRef, 0, len(podCIDRs))
 	for index, podCIDR := range podCIDRs {
-		if err := r.reconcilePodCIDR(ctx, node.Name, nodeID, podCIDR, index, len(podCIDRs), existingEgresses, network); err != nil {
-			return fmt.Errorf("failed to reconcile pod CIDR %s (index=%d) in network %s: %w", podCIDR, index, network, err)
+		if egressNodes[index] == nil {
+			continue
 		}
+		egressID, err := r.reconcilePodCIDR(ctx, api, names[index], nodeID, hostID, labels, egressNodes[index], podCIDR, index, existingEgresses, network, nodesByID, gated[index], held)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reconcile pod CIDR %s (index=%d) in network %s: %w", podCIDR, index, network, err)
+		}
+		if egressID == "" {
+			continue // Gated and never created
+		}
+		refs = append(refs, statestore.EgressRef{ID: egressID, Network: network})
 	}
 
-	return nil
+	// Delete surplus rules left over from a longer CIDR list or routed through a node of the other family
+	for i := range existingEgresses {
+		metadata := parseEgress(&existingEgresses[i])
+		if !r.isNodeEgress(metadata) || !isOwnedBy(&existingEgresses[i], nodeID) {
+			continue
+		}
+		if metadata.index < len(podCIDRs) && egressNodes[metadata.index] != nil {
+			continue
+		}
+
+		if err := api.DeleteEgress(ctx, existingEgresses[i].ID); err != nil {
+			return nil, fmt.Errorf("failed to delete surplus egress %s (index=%d) in network %s: %w",
+				existingEgresses[i].ID, metadata.index, network, err)
+		}
+	}
+
+	return refs, nil
 }
 
 // reconcilePodCIDR reconciles a single pod CIDR in a single network
+// A gated rule is turned off and marked gated=true, so it is turned back on once the node is no longer gated;
+// rules turned off by an operator are left off
+// A held rule is marked held=true, and the marker is removed again once the node is no longer held
+// A rule of the same name whose owner no longer exists in Netmaker (the node was replaced by a new machine with
+// the same name, so its host got new node IDs) is taken over by nodeID instead of creating a second rule
+// Returns the ID of the egress rule that was kept, updated or created (empty if gated and missing)
 func (r *Reconciler) reconcilePodCIDR(
 	ctx context.Context,
-	nodeName string,
+	api netmakerAPI,
+	name string,
 	nodeID string,
+	hostID string,
+	labels map[string]string,
+	egressNodes map[string]int,
 	podCIDR string,
 	index int,
-	totalCIDRs int,
 	existingEgresses []netmaker.Egress,
 	network string,
-) error {
+	nodesByID map[string]netmaker.Node,
+	gated bool,
+	held bool,
+) (string, error) {
 	// Build index-based description: "Managed by kaput-not (DO NOT EDIT): index=<i>"
 	// or with cluster: "Managed by kaput-not (DO NOT EDIT): cluster=us-east index=<i>"
-	description := r.buildEgressDescription(index)
-
-	// Build human-friendly name: "node-name pods (1/2)"
-	name := buildEgressName(nodeName, index, totalCIDRs)
+	description := r.buildEgressDescription(index, hostID, labels)
+	metric := egressNodes[nodeID]
+	if metric != EgressMetric {
+		description += fmt.Sprintf(" metric=%d", metric)
+	}
+	if held {
+		description += " held=true"
+	}
 
 	// Search for existing egress rule with matching index AND node ID in nodes map
 	// Supports both old format (index=0) and new format (cluster=us-east index=0)
-	var existingEgress *netmaker.Egress
+	var existingEgress, staleEgress *netmaker.Egress
+	var existingMetadata, staleMetadata *egressMetadata
 	for i := range existingEgresses {
 		// Parse description to extract metadata
-		metadata := parseEgressDescription(existingEgresses[i].Description)
+		metadata := parseEgress(&existingEgresses[i])
 		if metadata == nil {
 			continue // Not a kaput-not managed egress
 		}
 
-		// Check if this egress belongs to our cluster
-		if !r.belongsToOurCluster(metadata) {
-			continue // Managed by another cluster or incompatible mode
+		// Check if this egress is a node rule of our cluster
+		if !r.isNodeEgress(metadata) {
+			continue // Managed by another cluster, incompatible mode or a ClusterEgressRule
 		}
 
 		// Check if index matches
@@ -161,39 +528,84 @@ func (r *Reconciler) reconcilePodCIDR(
 			continue
 		}
 
-		// Check if this egress belongs to our node (node ID in nodes map)
-		if _, hasNode := existingEgresses[i].Nodes[nodeID]; hasNode {
+		// Check if this egress belongs to our node (primary gateway in nodes map)
+		if isOwnedBy(&existingEgresses[i], nodeID) {
 			existingEgress = &existingEgresses[i]
+			existingMetadata = metadata
 			break
 		}
+
+		// Remember our rule left behind by a replaced node, in case we have none yet
+		if staleEgress == nil && existingEgresses[i].Name == name && hasStaleOwner(&existingEgresses[i], nodesByID) {
+			staleEgress = &existingEgresses[i]
+			staleMetadata = metadata
+		}
+	}
+
+	if existingEgress == nil && staleEgress != nil {
+		// Rewritten to the new node below (the gateways differ), keeping its ID
+		netmaker.Logf(ctx, "Egress rule %s (%q) in network %s is routed through Netmaker node %q, which no longer exists "+
+			"(node replaced?) - moving it to node %s", staleEgress.ID, name, r.DescribeNetwork(network), OwnerNodeID(staleEgress), nodeID)
+		existingEgress, existingMetadata = staleEgress, staleMetadata
 	}
 
 	if existingEgress != nil {
-		// Egress exists - check if CIDR matches
-		if existingEgress.Range == podCIDR {
+		// A gated rule must be off, an ungated one must not carry our gated marker
+		statusCorrect := !existingMetadata.gated
+		if gated {
+			statusCorrect = !existingEgress.Status
+		}
+
+		// Egress exists - check if CIDR, gateways and status match and the lease is still fresh
+		if cidr.Equal(existingEgress.Range, podCIDR) &&
+			egressNodesEqual(existingEgress.Nodes, egressNodes) &&
+			statusCorrect &&
+			existingMetadata.held == held &&
+			primaryMetric(existingMetadata) == metric &&
+			existingMetadata.host == hostID &&
+			(!r.options.NameMarker || existingEgress.Name == name) &&
+			existingMetadata.owner == "" && // Description stripped - restore it
+			maps.Equal(existingMetadata.labels, labels) &&
+			!r.leaseNeedsRefresh(existingMetadata) {
 			// Already correct - skip
-			return nil
+			return existingEgress.ID, nil
+		}
+
+		// Only mark rules we turn off ourselves - an operator's disabled rule stays off once the node is ungated
+		if gated && (existingEgress.Status || existingMetadata.gated) {
+			description += " gated=true"
 		}
 
-		// CIDR changed - update existing egress
+		// CIDR, gateways, status, hold, host ID, labels, name marker or lease changed - update existing egress
 		req := netmaker.EgressReq{
 			ID:          existingEgress.ID,
 			Name:        name,
 			Network:     existingEgress.Network,
-			Description: description,
+			Description: withNote(description, existingMetadata.note), // Keep the operator's note
 			Range:       podCIDR,
 			NAT:         false,
-			Nodes:       map[string]int{nodeID: EgressMetric},
-			Status:      true,
+			Nodes:       egressNodes,
+			Status:      !gated,
+			UpdatedAt:   existingEgress.UpdatedAt, // Lets the server reject the update if the rule changed since we read it
 		}
 
-		_, err := r.netmakerClient.UpdateEgress(ctx, req)
+		_, err := api.UpdateEgress(ctx, req)
 		if err != nil {
-			return fmt.Errorf("failed to update egress %s (old CIDR=%s, new CIDR=%s): %w",
+			return "", fmt.Errorf("failed to update egress %s (old CIDR=%s, new CIDR=%s): %w",
 				existingEgress.ID, existingEgress.Range, podCIDR, err)
 		}
 
-		return nil
+		return existingEgress.ID, nil
+	}
+
+	// Take over a matching hand-made rule instead of creating a duplicate next to it
+	if adoptable := r.findAdoptableEgress(existingEgresses, nodeID, podCIDR); adoptable != nil {
+		return r.adoptEgress(ctx, api, adoptable, name, description, egressNodes, gated)
+	}
+
+	// Gated nodes get no new rules
+	if gated {
+		return "", nil
 	}
 
 	// Egress doesn't exist - create new one
@@ -203,27 +615,80 @@ func (r *Reconciler) reconcilePodCIDR(
 		Description: description,
 		Range:       podCIDR,
 		NAT:         false,
-		Nodes:       map[string]int{nodeID: EgressMetric},
+		Nodes:       egressNodes,
 		Status:      true,
 	}
 
-	_, err := r.netmakerClient.CreateEgress(ctx, req)
+	created, err := api.CreateEgress(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to create egress for CIDR %s: %w", podCIDR, err)
+		return "", fmt.Errorf("failed to create egress for CIDR %s: %w", podCIDR, err)
 	}
 
+	return created.ID, nil
+}
+
+// findAdoptableEgress returns the unmanaged egress rule routing exactly podCIDR through nodeID
+// Returns nil unless AdoptExisting is set
+func (r *Reconciler) findAdoptableEgress(existingEgresses []netmaker.Egress, nodeID string, podCIDR string) *netmaker.Egress {
+	if !r.options.AdoptExisting {
+		return nil
+	}
+	for i := range existingEgresses {
+		if parseEgress(&existingEgresses[i]) != nil {
+			continue // Already managed (by us, another cluster or a ClusterEgressRule)
+		}
+		if _, hasNode := existingEgresses[i].Nodes[nodeID]; hasNode && cidr.Equal(existingEgresses[i].Range, podCIDR) {
+			return &existingEgresses[i]
+		}
+	}
 	return nil
 }
 
+// adoptEgress takes ownership of an unmanaged egress rule by rewriting it with our name, metadata and gateways
+// The previous description is kept as operator note; a gated node's adopted rule is turned off
+func (r *Reconciler) adoptEgress(ctx context.Context, api netmakerAPI, egress *netmaker.Egress, name string, description string, egressNodes map[string]int, gated bool) (string, error) {
+	if gated && egress.Status {
+		description += " gated=true"
+	}
+
+	req := netmaker.EgressReq{
+		ID:          egress.ID,
+		Name:        name,
+		Network:     egress.Network,
+		Description: withNote(description, egress.Description),
+		Range:       egress.Range,
+		NAT:         false,
+		Nodes:       egressNodes,
+		Status:      egress.Status && !gated,
+		UpdatedAt:   egress.UpdatedAt,
+	}
+	if _, err := api.UpdateEgress(ctx, req); err != nil {
+		return "", fmt.Errorf("failed to adopt egress %s (%q, CIDR=%s): %w", egress.ID, egress.Name, egress.Range, err)
+	}
+
+	if _, planning := api.(*planner); planning {
+		return egress.ID, nil // Recorded as a planned update
+	}
+	netmaker.Logf(ctx, "Adopted unmanaged egress rule %s (%q, CIDR=%s) in network %s as %s", egress.ID, egress.Name, egress.Range, r.DescribeNetwork(egress.Network), name)
+	return egress.ID, nil
+}
+
 // DeleteNode removes egress rules for a deleted node from all networks it participated in
+// Rules recorded in the state store are deleted first, by ID (works even if the Netmaker host is gone)
 // Networks are auto-discovered from the Netmaker nodes themselves
 // Searches for all egress rules that have this node ID in their nodes map
 func (r *Reconciler) DeleteNode(ctx context.Context, nodeName string) error {
-	// Get all Netmaker node IDs for this host (from host.Nodes field)
-	nodeIDs, err := r.netmakerClient.GetNodeIDsByHostname(ctx, nodeName)
+	if err := r.deleteRecordedEgresses(ctx, nodeName); err != nil {
+		return fmt.Errorf("failed to delete recorded egress rules for node %s: %w", nodeName, err)
+	}
+
+	// Get all Netmaker node IDs for this host (from host.Nodes field, or by host ID if it was renamed)
+	nodeIDs, err := r.hostNodeIDs(ctx, r.options.NetmakerClient, nodeName, nil)
 	if err != nil {
 		// If host doesn't exist, skip silently (nothing to delete)
 		if strings.Contains(err.Error(), "not found") {
+			r.hosts.forget(nodeName)
+			r.health.forget(nodeName)
 			return nil
 		}
 		return fmt.Errorf("failed to get node IDs for node %s: %w", nodeName, err)
@@ -231,11 +696,13 @@ func (r *Reconciler) DeleteNode(ctx context.Context, nodeName string) error {
 
 	if len(nodeIDs) == 0 {
 		// No nodes for this host - nothing to delete
+		r.hosts.forget(nodeName)
+		r.health.forget(nodeName)
 		return nil
 	}
 
 	// Get all nodes - each node contains its network
-	allNodes, err := r.netmakerClient.ListNodes(ctx)
+	allNodes, err := r.options.NetmakerClient.ListNodes(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list nodes: %w", err)
 	}
@@ -257,13 +724,80 @@ func (r *Reconciler) DeleteNode(ctx context.Context, nodeName string) error {
 		}
 
 		// Delete egress rules for this node in its network
-		if err := r.deleteNodeFromNetwork(ctx, n.ID, n.Network); err != nil {
+		if err := r.deleteNodeFromNetwork(ctx, r.options.NetmakerClient, n.ID, n.Network); err != nil {
 			deletionErrors = append(deletionErrors, fmt.Errorf("network %s: %w", n.Network, err))
 		}
 	}
 
 	if len(deletionErrors) > 0 {
-		return fmt.Errorf("failed to delete node %s from some networks: %v", nodeName, deletionErrors)
+		return fmt.Errorf("failed to delete node %s from some networks: %w", nodeName, errors.Join(deletionErrors...))
+	}
+
+	r.hosts.forget(nodeName)
+	r.health.forget(nodeName)
+	return nil
+}
+
+// deleteRecordedEgresses deletes the rules recorded in the state store for a node, then forgets the node
+// Each recorded rule is verified against the (cached) egress list of its network first, so stale
+// records and rules not managed by our cluster are never deleted
+func (r *Reconciler) deleteRecordedEgresses(ctx context.Context, nodeName string) error {
+	if r.options.StateStore == nil {
+		return nil
+	}
+
+	var deletionErrors []error
+	var deletable []netmaker.Egress
+	for _, ref := range r.options.StateStore.Get(nodeName) {
+		egresses, err := r.options.NetmakerClient.ListEgress(ctx, ref.Network)
+		if err != nil {
+			deletionErrors = append(deletionErrors, fmt.Errorf("failed to list egress rules in network %s: %w", ref.Network, err))
+			continue
+		}
+
+		for _, egress := range egresses {
+			metadata := parseEgress(&egress)
+			if egress.ID != ref.ID || !r.isNodeEgress(metadata) {
+				continue
+			}
+			if metadata.held {
+				log.Printf("Keeping held egress rule %s (%q) of deleted node %s in network %s", egress.ID, egress.Name, nodeName, ref.Network)
+				continue
+			}
+			egress.Network = ref.Network
+			deletable = append(deletable, egress)
+		}
+	}
+	deletionErrors = append(deletionErrors, r.deleteEgresses(ctx, r.options.NetmakerClient, deletable)...)
+
+	if len(deletionErrors) > 0 {
+		return fmt.Errorf("%v", deletionErrors)
+	}
+
+	r.options.StateStore.Delete(nodeName)
+	return nil
+}
+
+// CleanupRecordedEgresses deletes the recorded rules of nodes no longer present in Kubernetes
+// Covers nodes deleted while the controller was down whose Netmaker host is gone as well,
+// which CleanupOrphanedEgresses cannot find. No-op without a state store
+func (r *Reconciler) CleanupRecordedEgresses(ctx context.Context, managedNodes map[string]bool) error {
+	if r.options.StateStore == nil {
+		return nil
+	}
+
+	var cleanupErrors []error
+	for _, nodeName := range r.options.StateStore.NodeNames() {
+		if managedNodes[nodeName] {
+			continue
+		}
+		if err := r.deleteRecordedEgresses(ctx, nodeName); err != nil {
+			cleanupErrors = append(cleanupErrors, fmt.Errorf("node %s: %w", nodeName, err))
+		}
+	}
+
+	if len(cleanupErrors) > 0 {
+		return fmt.Errorf("failed to cleanup some recorded egress rules: %v", cleanupErrors)
 	}
 
 	return nil
@@ -272,33 +806,44 @@ func (r *Reconciler) DeleteNode(ctx context.Context, nodeName string) error {
 // deleteNodeFromNetwork removes egress rules for a node in a single network
 // nodeID is passed as parameter - no lookup needed
 // Only deletes egress rules that belong to this cluster
-func (r *Reconciler) deleteNodeFromNetwork(ctx context.Context, nodeID string, network string) error {
+func (r *Reconciler) deleteNodeFromNetwork(ctx context.Context, api netmakerAPI, nodeID string, network string) error {
+	ctx = netmaker.WithNetwork(ctx, network) // The network's timeout applies to the deletes too
 
 	// List all egress rules for this network
-	egresses, err := r.netmakerClient.ListEgress(ctx, network)
+	egresses, err := api.ListEgress(ctx, network)
 	if err != nil {
 		return fmt.Errorf("failed to list egress rules in network %s: %w", network, err)
 	}
 
-	// Find and delete all egress rules managed by kaput-not that contain this node ID
+	// Delete the node rules managed by kaput-not that this node ID owns: they route the node's own pod CIDRs
+	// Other rules of our cluster routed through it (as HA backup gateway, ClusterEgressRule or node pool gateway)
+	// only lose it as gateway, and are deleted once no gateway is left
 	var deletionErrors []error
-	for _, egress := range egresses {
-		// Parse description to extract metadata
-		metadata := parseEgressDescription(egress.Description)
-		if metadata == nil {
-			continue // Not a kaput-not managed egress
-		}
+	for i := range egresses {
+		egress := &egresses[i]
 
-		// Check if this egress belongs to our cluster
+		// Parse description to extract metadata
+		metadata := parseEgress(egress)
 		if !r.belongsToOurCluster(metadata) {
-			continue // Managed by another cluster or incompatible mode
+			continue // Not managed by kaput-not, or managed by another cluster or instance
+		}
+		if _, member := egress.Nodes[nodeID]; !member {
+			continue
 		}
 
-		// Check if this node ID is in the egress nodes map
-		if _, hasNode := egress.Nodes[nodeID]; hasNode {
-			if err := r.netmakerClient.DeleteEgress(ctx, egress.ID); err != nil {
+		// Check if this node ID is the primary gateway of a node rule
+		if r.isNodeEgress(metadata) && isOwnedBy(egress, nodeID) {
+			if metadata.held {
+				continue // Kept until the node is back or the rule is purged by hand
+			}
+			if err := api.DeleteEgress(ctx, egress.ID); err != nil {
 				deletionErrors = append(deletionErrors, fmt.Errorf("failed to delete egress %s in network %s: %w", egress.ID, network, err))
 			}
+			continue
+		}
+
+		if err := r.removeMembers(ctx, api, egress, nodeID); err != nil {
+			deletionErrors = append(deletionErrors, err)
 		}
 	}
 
@@ -312,56 +857,225 @@ func (r *Reconciler) deleteNodeFromNetwork(ctx context.Context, nodeID string, n
 // CleanupOrphanedEgresses removes egress rules for Netmaker nodes that don't have corresponding K8s nodes
 // This handles drift detection - egress rules created manually or left behind when the controller was down
 // validNodeIDs is the set of all Netmaker node IDs that should have egress rules
+// Also refreshes the held_egress_rules metric
 func (r *Reconciler) CleanupOrphanedEgresses(ctx context.Context, validNodeIDs map[string]bool) error {
+	err := r.cleanupOrphanedEgresses(ctx, r.options.NetmakerClient, validNodeIDs)
+	if heldErr := r.updateHeldEgressMetrics(ctx, validNodeIDs); heldErr != nil {
+		err = errors.Join(err, heldErr)
+	}
+	return err
+}
+
+// cleanupOrphanedEgresses removes orphaned egress rules through api (the cached client or a planner)
+// Orphans are cleaned up in batches of CleanupBatchSize; once a cycle exceeds CleanupTimeBudget the rest is left
+// to the next cycle, and cancellation of ctx is honored between batches so shutdown isn't delayed by a large backlog
+func (r *Reconciler) cleanupOrphanedEgresses(ctx context.Context, api netmakerAPI, validNodeIDs map[string]bool) error {
+	began := time.Now()
+
 	// Get all nodes across all networks
-	allNodes, err := r.netmakerClient.ListNodes(ctx)
+	allNodes, err := api.ListNodes(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list all nodes: %w", err)
 	}
 
-	// Group nodes by network for efficient cleanup
-	networkNodes := make(map[string][]string) // network -> []nodeID
+	// Find orphaned nodes (nodes in Netmaker but not in K8s)
+	var orphans []netmaker.Node
 	for _, node := range allNodes {
-		networkNodes[node.Network] = append(networkNodes[node.Network], node.ID)
+		if !validNodeIDs[node.ID] {
+			orphans = append(orphans, node)
+		}
 	}
 
-	// Clean up each network
+	// Delete egress rules for orphaned nodes, batch by batch
 	var cleanupErrors []error
-	for network, nodeIDs := range networkNodes {
-		// Find orphaned node IDs (nodes in Netmaker but not in K8s)
-		var orphanedNodeIDs []string
-		for _, nodeID := range nodeIDs {
-			if !validNodeIDs[nodeID] {
-				orphanedNodeIDs = append(orphanedNodeIDs, nodeID)
+	for start := 0; start < len(orphans); start += r.options.CleanupBatchSize {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("orphan cleanup interrupted with %d of %d orphaned nodes left: %w", len(orphans)-start, len(orphans), err)
+		}
+		if start > 0 && time.Since(began) > r.options.CleanupTimeBudget {
+			log.Printf("Orphan cleanup exceeded its time budget of %s: %d of %d orphaned nodes left for the next cycle",
+				r.options.CleanupTimeBudget, len(orphans)-start, len(orphans))
+			break
+		}
+
+		end := min(start+r.options.CleanupBatchSize, len(orphans))
+		cleanupErrors = append(cleanupErrors, r.deleteOrphans(ctx, api, orphans[start:end])...)
+	}
+
+	if len(cleanupErrors) > 0 {
+		return fmt.Errorf("failed to cleanup some orphaned egress rules: %v", cleanupErrors)
+	}
+
+	return nil
+}
+
+// deleteOrphans removes the egress rules of a batch of orphaned nodes, listing the rules of each network once
+// Node rules owned by the orphans are deleted CleanupDeleteConcurrency at a time; other rules of our cluster lose
+// all orphans among their gateways in a single update (see deleteNodeFromNetwork for a single node)
+// Returns an error for each rule that could not be cleaned up
+func (r *Reconciler) deleteOrphans(ctx context.Context, api netmakerAPI, orphans []netmaker.Node) []error {
+	orphansByNetwork := make(map[string]map[string]bool)
+	for _, node := range orphans {
+		if orphansByNetwork[node.Network] == nil {
+			orphansByNetwork[node.Network] = make(map[string]bool)
+		}
+		orphansByNetwork[node.Network][node.ID] = true
+	}
+
+	var cleanupErrors []error
+	var owned []netmaker.Egress
+	for _, network := range sortedKeys(orphansByNetwork) {
+		orphanIDs := orphansByNetwork[network]
+		networkCtx := netmaker.WithNetwork(ctx, network) // The network's timeout applies to the updates too
+
+		egresses, err := api.ListEgress(networkCtx, network)
+		if err != nil {
+			cleanupErrors = append(cleanupErrors, fmt.Errorf("failed to list egress rules in network %s: %w", network, err))
+			continue
+		}
+
+		for i := range egresses {
+			egress := &egresses[i]
+
+			metadata := parseEgress(egress)
+			if !r.belongsToOurCluster(metadata) {
+				continue // Not managed by kaput-not, or managed by another cluster or instance
+			}
+
+			if r.isNodeEgress(metadata) && orphanIDs[OwnerNodeID(egress)] {
+				if metadata.held {
+					continue // Kept until the node is back or the rule is purged by hand
+				}
+				egress.Network = network
+				owned = append(owned, *egress)
+				continue
 			}
+
+			var members []string
+			for _, nodeID := range sortedKeys(egress.Nodes) {
+				if orphanIDs[nodeID] {
+					members = append(members, nodeID)
+				}
+			}
+			if len(members) == 0 {
+				continue
+			}
+			if err := r.removeMembers(networkCtx, api, egress, members...); err != nil {
+				cleanupErrors = append(cleanupErrors, err)
+			}
+		}
+	}
+
+	return append(cleanupErrors, r.deleteEgresses(ctx, api, owned)...)
+}
+
+// CleanupExpiredEgresses removes managed egress rules whose lease expired more than LeaseGracePeriod ago
+// Unlike CleanupOrphanedEgresses this is NOT scoped to our cluster: an expired lease means the owning
+// controller stopped refreshing its rules (e.g. the cluster was decommissioned without running DeleteNode)
+// No-op when leases are disabled
+func (r *Reconciler) CleanupExpiredEgresses(ctx context.Context) error {
+	if r.options.LeaseDuration == 0 {
+		return nil
+	}
+
+	// Get all nodes - collect the set of networks in use
+	allNodes, err := r.options.NetmakerClient.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list all nodes: %w", err)
+	}
+
+	networks := make(map[string]bool)
+	for _, node := range allNodes {
+		networks[node.Network] = true
+	}
+
+	deadline := time.Now().Add(-r.options.LeaseGracePeriod).Unix()
+
+	var cleanupErrors []error
+	var expired []netmaker.Egress
+	for network := range networks {
+		egresses, err := r.options.NetmakerClient.ListEgress(ctx, network)
+		if err != nil {
+			cleanupErrors = append(cleanupErrors, fmt.Errorf("failed to list egress rules in network %s: %w", network, err))
+			continue
 		}
 
-		// Delete egress rules for orphaned nodes
-		for _, nodeID := range orphanedNodeIDs {
-			if err := r.deleteNodeFromNetwork(ctx, nodeID, network); err != nil {
-				cleanupErrors = append(cleanupErrors, fmt.Errorf("network %s, node %s: %w", network, nodeID, err))
+		for _, egress := range egresses {
+			metadata := parseEgress(&egress)
+			if metadata == nil || metadata.expires == 0 || metadata.held {
+				continue // Not managed, no lease or held for a node that is gone (its lease is no longer refreshed)
 			}
+
+			if metadata.expires > deadline {
+				continue // Lease still valid or within grace period
+			}
+
+			egress.Network = network
+			expired = append(expired, egress)
 		}
 	}
+	cleanupErrors = append(cleanupErrors, r.deleteEgresses(ctx, r.options.NetmakerClient, expired)...)
 
 	if len(cleanupErrors) > 0 {
-		return fmt.Errorf("failed to cleanup some orphaned egress rules: %v", cleanupErrors)
+		return fmt.Errorf("failed to cleanup some expired egress rules: %v", cleanupErrors)
 	}
 
 	return nil
 }
 
+// leaseNeedsRefresh checks if an egress rule's expiry timestamp must be rewritten
+// Refreshes once less than half the lease remains, and strips the expiry when leases are disabled
+func (r *Reconciler) leaseNeedsRefresh(metadata *egressMetadata) bool {
+	if r.options.LeaseDuration == 0 {
+		// Leases disabled - remove a leftover expiry so the janitor never deletes the rule
+		return metadata.expires != 0
+	}
+
+	if metadata.expires == 0 {
+		return true
+	}
+
+	remaining := time.Until(time.Unix(metadata.expires, 0))
+	return remaining < r.options.LeaseDuration/2
+}
+
 // egressMetadata holds parsed metadata from an egress description
 type egressMetadata struct {
-	cluster string // empty if not present (backwards compatible)
-	index   int
+	cluster  string // empty if not present (backwards compatible)
+	instance string // empty for the default instance
+	rule     string // ClusterEgressRule name, empty for node rules
+	pool     string // Node pool name, empty for node rules
+	host     string // Netmaker host ID of the owning node (node rules only), survives host renames
+	index    int
+	expires  int64  // Unix timestamp, zero if no lease
+	metric   int    // Primary metric of a node rule, zero for EgressMetric (see Options.NodeMetrics)
+	gated    bool   // Turned off by us while the node was gated (see Topology.Gated)
+	held     bool   // Kept when the node is deleted (see Topology.Held)
+	note     string // Free text appended by an operator after noteSeparator, preserved on updates
+	owner    string // Ownership key from the name marker of a rule without description metadata (see parseEgress)
+
+	labels map[string]string // Any other key=value fields (see Options.DescriptionLabels), nil if none
 }
 
+// noteSeparator separates our metadata from a free-text note in descriptions:
+// "Managed by kaput-not (DO NOT EDIT): index=0 | ticket NET-123"
+const noteSeparator = " | "
+
 // parseEgressDescription parses the egress description to extract metadata
 // Supports both formats:
 //   - New: "Managed by kaput-not (DO NOT EDIT): cluster=us-east index=0"
 //   - Old: "Managed by kaput-not (DO NOT EDIT): index=0"
 //
+// Either format may carry an optional lease: "... index=0 expires=1767225600"
+// Node rules turned off while their node is gated are marked: "... index=0 gated=true"
+// Node rules held for a deleted node are marked: "... index=0 held=true"
+// Node rules with another primary metric than EgressMetric carry it: "... index=0 metric=400"
+// Rules of a non-default instance carry its ID: "... cluster=us-east instance=team-a index=0"
+// Rules of a ClusterEgressRule carry its name: "... cluster=us-east rule=office-lan index=0"
+// Rules of a node pool carry its name: "... cluster=us-east pool=spot-workers index=0"
+// Node rules may carry labels of their node: "... index=0 host=<host ID> environment=prod team=payments"
+// Anything after noteSeparator is an operator note and never parsed as metadata: "... index=0 | ticket NET-123"
+//
 // Returns nil if description doesn't match expected format
 func parseEgressDescription(description string) *egressMetadata {
 	// Check if it starts with our marker
@@ -369,11 +1083,11 @@ func parseEgressDescription(description string) *egressMetadata {
 		return nil
 	}
 
-	// Extract metadata part after the marker
-	metadataPart := strings.TrimPrefix(description, EgressMarker+": ")
+	// Extract metadata part after the marker, splitting off an operator note
+	metadataPart, note, _ := strings.Cut(strings.TrimPrefix(description, EgressMarker+": "), noteSeparator)
 
 	// Parse space-separated key=value pairs
-	metadata := &egressMetadata{}
+	metadata := &egressMetadata{note: strings.TrimSpace(note)}
 	fields := strings.Fields(metadataPart)
 
 	for _, field := range fields {
@@ -385,9 +1099,32 @@ func parseEgressDescription(description string) *egressMetadata {
 		switch kv[0] {
 		case "cluster":
 			metadata.cluster = kv[1]
+		case "instance":
+			metadata.instance = kv[1]
+		case "rule":
+			metadata.rule = kv[1]
+		case "pool":
+			metadata.pool = kv[1]
+		case "host":
+			metadata.host = kv[1]
 		case "index":
 			// Ignore error - if parsing fails, index stays at zero value
 			_, _ = fmt.Sscanf(kv[1], "%d", &metadata.index)
+		case "expires":
+			// Ignore error - if parsing fails, the rule is treated as having no lease
+			_, _ = fmt.Sscanf(kv[1], "%d", &metadata.expires)
+		case "metric":
+			// Ignore error - if parsing fails, the rule's primary gateway is expected at EgressMetric
+			_, _ = fmt.Sscanf(kv[1], "%d", &metadata.metric)
+		case "gated":
+			metadata.gated = kv[1] == "true"
+		case "held":
+			metadata.held = kv[1] == "true"
+		default:
+			if metadata.labels == nil {
+				metadata.labels = make(map[string]string)
+			}
+			metadata.labels[kv[0]] = kv[1]
 		}
 	}
 
@@ -399,13 +1136,20 @@ func parseEgressDescription(description string) *egressMetadata {
 //   - If our cluster name is empty: all kaput-not egress rules belong to us (single-cluster mode)
 //   - If our cluster name is set: only egress rules with matching cluster name belong to us
 //   - Egress rules without cluster name are skipped in multi-cluster mode (migration safety)
+//   - In either mode, only rules of our instance belong to us (no instance ID for the default instance)
 func (r *Reconciler) belongsToOurCluster(metadata *egressMetadata) bool {
 	if metadata == nil {
 		return false // Not a kaput-not managed egress
 	}
+	if metadata.owner != "" {
+		return metadata.ownedBy(r.options.ClusterName, r.options.InstanceID) // Description stripped, see parseEgress
+	}
+	if metadata.instance != r.options.InstanceID {
+		return false // Another kaput-not instance in the same cluster
+	}
 
 	// Single-cluster mode (no cluster name configured)
-	if r.clusterName == "" {
+	if r.options.ClusterName == "" {
 		// If egress has a cluster name, it's from another cluster
 		// Only manage egress rules without cluster name (backwards compatibility)
 		return metadata.cluster == ""
@@ -413,21 +1157,74 @@ func (r *Reconciler) belongsToOurCluster(metadata *egressMetadata) bool {
 
 	// Multi-cluster mode (cluster name configured)
 	// Only manage egress rules with our cluster name
-	return metadata.cluster == r.clusterName
+	return metadata.cluster == r.options.ClusterName
+}
+
+// ManagedBy checks if an egress rule is managed by the kaput-not instance instanceID of clusterName
+// (both empty by default), e.g. to tell its rules apart from hand-made and other instances' ones
+func ManagedBy(egress netmaker.Egress, clusterName string, instanceID string) bool {
+	metadata := parseEgress(&egress)
+	return metadata != nil && metadata.ownedBy(clusterName, instanceID)
+}
+
+// isNodeEgress checks if an egress rule is a node (pod CIDR) rule of our cluster
+// ClusterEgressRule and node pool rules also use EgressMetric for their first gateway, so node paths must skip them
+func (r *Reconciler) isNodeEgress(metadata *egressMetadata) bool {
+	return r.belongsToOurCluster(metadata) && metadata.rule == "" && metadata.pool == ""
+}
+
+// buildEgressDescription builds the index-based description of a node rule
+// Format with cluster: "Managed by kaput-not (DO NOT EDIT): cluster=us-east index=0 host=<host ID>"
+// Format without: "Managed by kaput-not (DO NOT EDIT): index=0 host=<host ID>"
+// With leases enabled an expiry is appended: "... index=0 host=<host ID> expires=1767225600"
+// Description labels of the node follow the host ID: "... index=0 host=<host ID> team=payments"
+func (r *Reconciler) buildEgressDescription(index int, hostID string, labels map[string]string) string {
+	return r.buildDescription("", "", hostID, index, labels)
+}
+
+// buildDescription builds a description, with the rule group (ClusterEgressRule or node pool) name if name is set,
+// the Netmaker host ID of the owning node if hostID is set and the given description labels
+func (r *Reconciler) buildDescription(kind groupKind, name string, hostID string, index int, labels map[string]string) string {
+	var fields []string
+	if r.options.ClusterName != "" {
+		fields = append(fields, "cluster="+r.options.ClusterName)
+	}
+	if r.options.InstanceID != "" {
+		fields = append(fields, "instance="+r.options.InstanceID)
+	}
+	if name != "" {
+		fields = append(fields, string(kind)+"="+name)
+	}
+	fields = append(fields, fmt.Sprintf("index=%d", index))
+	if hostID != "" {
+		fields = append(fields, "host="+hostID)
+	}
+	fields = append(fields, labelFields(labels)...)
+	if r.options.LeaseDuration > 0 {
+		fields = append(fields, fmt.Sprintf("expires=%d", time.Now().Add(r.options.LeaseDuration).Unix()))
+	}
+	return fmt.Sprintf("%s: %s", EgressMarker, strings.Join(fields, " "))
 }
 
-// buildEgressDescription builds the index-based description
-// Format with cluster: "Managed by kaput-not (DO NOT EDIT): cluster=us-east index=0"
-// Format without: "Managed by kaput-not (DO NOT EDIT): index=0"
-func (r *Reconciler) buildEgressDescription(index int) string {
-	if r.clusterName != "" {
-		return fmt.Sprintf("%s: cluster=%s index=%d", EgressMarker, r.clusterName, index)
+// withNote appends an operator note to a description we built (unchanged if note is empty)
+func withNote(description string, note string) string {
+	if note == "" {
+		return description
 	}
-	return fmt.Sprintf("%s: index=%d", EgressMarker, index)
+	return description + noteSeparator + note
 }
 
 // buildEgressName builds the human-friendly egress name
-// Format: "node-name pods (1/2)"
-func buildEgressName(nodeName string, index int, totalCIDRs int) string {
+// Format: "node-name pods (1/2)", or "node-name cluster pods (1/1)" in aggregated mode
+func buildEgressName(nodeName string, index int, totalCIDRs int, aggregated bool) string {
+	if aggregated {
+		return fmt.Sprintf("%s cluster pods (%d/%d)", nodeName, index+1, totalCIDRs)
+	}
 	return fmt.Sprintf("%s pods (%d/%d)", nodeName, index+1, totalCIDRs)
 }
+
+// buildClusterNetworkEgressName builds the egress name for a cluster network CIDR published by a gateway
+// Format: "node-name cluster network (1/2)"
+func buildClusterNetworkEgressName(nodeName string, index int, totalCIDRs int) string {
+	return fmt.Sprintf("%s cluster network (%d/%d)", nodeName, index+1, totalCIDRs)
+}
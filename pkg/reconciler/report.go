@@ -0,0 +1,170 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// Report summarizes the managed egress rules of our cluster identity by description labels (see the report command)
+type Report struct {
+	// Keys are the description keys the rules are grouped by
+	Keys []string `json:"keys"`
+
+	// Groups hold one entry per distinct combination of label values, sorted by those values
+	Groups []ReportGroup `json:"groups"`
+
+	// Rules is the number of managed egress rules across all groups
+	Rules int `json:"rules"`
+
+	// Held is the number of held rules across all groups (see ReportGroup.Held)
+	Held int `json:"held"`
+}
+
+// ReportGroup counts the managed egress rules sharing the same description label values
+type ReportGroup struct {
+	// Labels holds the value of each key; empty for rules without the label (e.g. ClusterEgressRule rules)
+	Labels map[string]string `json:"labels"`
+
+	// Rules is the number of egress rules
+	Rules int `json:"rules"`
+
+	// Disabled is the number of rules turned off (gated nodes, unhealthy gateways or by an operator)
+	Disabled int `json:"disabled"`
+
+	// Held is the number of node rules marked held=true, kept even if their node is deleted
+	Held int `json:"held"`
+
+	// Hosts is the number of distinct Netmaker hosts owning node rules
+	Hosts int `json:"hosts"`
+
+	// Networks are the networks holding the rules
+	Networks []string `json:"networks"`
+}
+
+// Report lists the managed egress rules in all networks and groups them by the values of the given description keys
+func (r *Reconciler) Report(ctx context.Context, keys []string) (*Report, error) {
+	egresses, err := r.ManagedEgresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildReport(egresses, keys), nil
+}
+
+// buildReport groups managed egress rules by the values of the given description keys
+func buildReport(egresses []netmaker.Egress, keys []string) *Report {
+	type group struct {
+		ReportGroup
+		hosts    map[string]bool
+		networks map[string]bool
+	}
+
+	report := &Report{Keys: keys, Groups: []ReportGroup{}}
+	groups := make(map[string]*group)
+	for _, egress := range egresses {
+		metadata := parseEgress(&egress)
+		if metadata == nil {
+			continue
+		}
+
+		values := make([]string, len(keys))
+		for i, key := range keys {
+			values[i] = metadata.labels[key]
+		}
+		id := strings.Join(values, "\x00")
+
+		g, ok := groups[id]
+		if !ok {
+			labels := make(map[string]string, len(keys))
+			for i, key := range keys {
+				labels[key] = values[i]
+			}
+			g = &group{ReportGroup: ReportGroup{Labels: labels}, hosts: make(map[string]bool), networks: make(map[string]bool)}
+			groups[id] = g
+		}
+
+		g.Rules++
+		if !egress.Status {
+			g.Disabled++
+		}
+		if metadata.held {
+			g.Held++
+			report.Held++
+		}
+		if metadata.host != "" {
+			g.hosts[metadata.host] = true
+		}
+		g.networks[egress.Network] = true
+		report.Rules++
+	}
+
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		g := groups[id]
+		g.Hosts = len(g.hosts)
+		for network := range g.networks {
+			g.Networks = append(g.Networks, network)
+		}
+		sort.Strings(g.Networks)
+		report.Groups = append(report.Groups, g.ReportGroup)
+	}
+	return report
+}
+
+// NodeEgresses returns the node rules of our cluster identity in Netmaker by the name of their owning host
+// (the Kubernetes node name), sorted by network and index; rules whose owner has no host are keyed by its node ID
+// These are the current rules - PlanNodes computes the desired ones
+func (r *Reconciler) NodeEgresses(ctx context.Context) (map[string][]netmaker.Egress, error) {
+	egresses, err := r.ManagedEgresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := r.options.NetmakerClient.ListHosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	hostnames := make(map[string]string) // node ID -> host name
+	for _, host := range hosts {
+		for _, id := range host.Nodes {
+			hostnames[id] = host.Name
+		}
+	}
+
+	type indexed struct {
+		index  int
+		egress netmaker.Egress
+	}
+	owned := make(map[string][]indexed)
+	for i := range egresses {
+		metadata := parseEgress(&egresses[i])
+		if !r.isNodeEgress(metadata) {
+			continue
+		}
+		owner := OwnerNodeID(&egresses[i])
+		if hostname, ok := hostnames[owner]; ok {
+			owner = hostname
+		}
+		owned[owner] = append(owned[owner], indexed{index: metadata.index, egress: egresses[i]})
+	}
+
+	byNode := make(map[string][]netmaker.Egress, len(owned))
+	for node, rules := range owned {
+		sort.Slice(rules, func(i, j int) bool {
+			if rules[i].egress.Network != rules[j].egress.Network {
+				return rules[i].egress.Network < rules[j].egress.Network
+			}
+			return rules[i].index < rules[j].index
+		})
+		for _, rule := range rules {
+			byNode[node] = append(byNode[node], rule.egress)
+		}
+	}
+	return byNode, nil
+}
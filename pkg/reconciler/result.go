@@ -0,0 +1,244 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/statestore"
+)
+
+// Mutation is a change made to a Netmaker egress rule while reconciling a node
+type Mutation struct {
+	Action   string // ChangeCreate, ChangeUpdate or ChangeDelete
+	Network  string
+	EgressID string
+	Name     string // Empty for deletes
+	Range    string // Empty for deletes
+}
+
+// NodeResult describes what reconciling a node did
+// Also returned with an error, holding the mutations made before the failure
+type NodeResult struct {
+	// RequestID correlates the reconcile with its Netmaker requests (see netmaker.RequestIDHeader)
+	RequestID string
+
+	// Networks are the Netmaker networks the node was reconciled in (empty if it has no host or CIDRs)
+	Networks []string
+
+	// Mutations are the egress rule changes, in the order they were made (empty if nothing changed)
+	Mutations []Mutation
+
+	// Counts summarizes the outcome per network (only networks with any rule or change)
+	Counts map[string]NetworkCounts
+
+	// Collisions are the unmanaged egress rules overlapping the node's rules (empty if none, or if the reconcile failed)
+	Collisions []Collision
+
+	// HostWithoutNetwork is true if the node's Netmaker host is in no network and wasn't joined to one, so no
+	// egress rules are published for the node (see Options.DefaultNetwork)
+	HostWithoutNetwork bool
+
+	// JoinedNetwork is the network the node's host was joined to because it was in none (see Options.DefaultNetwork)
+	JoinedNetwork string
+}
+
+// NetworkCounts are the egress rule outcomes of a node reconcile in one network
+type NetworkCounts struct {
+	Created int
+	Updated int
+	Deleted int
+
+	// Skipped are the node's rules that were already in sync (unknown, thus 0, if the reconcile failed)
+	Skipped int
+}
+
+// Changed reports whether any rule was created, updated or deleted
+func (c NetworkCounts) Changed() bool {
+	return c.Created+c.Updated+c.Deleted > 0
+}
+
+// newNodeResult builds the result of a node reconcile from its recorded requests and the rules it has now (nil on failure)
+func newNodeResult(requestID string, networks []string, recorder *recordingAPI, applied []statestore.EgressRef) NodeResult {
+	mutations := recorder.mutations
+	counts := make(map[string]NetworkCounts)
+	changed := make(map[string]bool) // Created or updated egress IDs - not skipped
+	for _, mutation := range mutations {
+		count := counts[mutation.Network]
+		switch mutation.Action {
+		case ChangeCreate:
+			count.Created++
+			changed[mutation.EgressID] = true
+		case ChangeUpdate:
+			count.Updated++
+			changed[mutation.EgressID] = true
+		case ChangeDelete:
+			count.Deleted++
+		}
+		counts[mutation.Network] = count
+	}
+	for _, ref := range applied {
+		if !changed[ref.ID] {
+			count := counts[ref.Network]
+			count.Skipped++
+			counts[ref.Network] = count
+		}
+	}
+
+	return NodeResult{
+		RequestID:          requestID,
+		Networks:           networks,
+		Mutations:          mutations,
+		Counts:             counts,
+		Collisions:         recorder.collisions(applied),
+		HostWithoutNetwork: recorder.hostWithoutNetwork && recorder.joinedNetwork == "",
+		JoinedNetwork:      recorder.joinedNetwork,
+	}
+}
+
+// NetworkError is the failure to reconcile a node in one network
+// Node reconcile errors join one per failed network, see FailedNetworks
+type NetworkError struct {
+	Network string
+	Err     error
+}
+
+// Error implements error
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network %s: %v", e.Network, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// FailedNetworks returns the networks a node reconcile error failed in (nil if it failed before reaching any)
+func FailedNetworks(err error) []string {
+	var networks []string
+	var walk func(error)
+	walk = func(err error) {
+		if networkErr, ok := err.(*NetworkError); ok {
+			networks = append(networks, networkErr.Network)
+			return
+		}
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range wrapped.Unwrap() {
+				walk(e)
+			}
+		case interface{ Unwrap() error }:
+			walk(wrapped.Unwrap())
+		}
+	}
+	if err != nil {
+		walk(err)
+	}
+	return networks
+}
+
+// recordingAPI records the egress rule mutations made through a netmakerAPI
+type recordingAPI struct {
+	netmakerAPI
+
+	egressNetwork map[string]string            // egress ID -> network, learned from ListEgress (deletes only carry the ID)
+	listed        map[string][]netmaker.Egress // network -> egress rules as last listed
+	mutations     []Mutation
+
+	hostWithoutNetwork bool   // A host was found by name without any node
+	joinedNetwork      string // Network a host was joined to
+}
+
+// newRecordingAPI wraps api to record mutations
+func newRecordingAPI(api netmakerAPI) *recordingAPI {
+	return &recordingAPI{netmakerAPI: api, egressNetwork: make(map[string]string), listed: make(map[string][]netmaker.Egress)}
+}
+
+// ListEgress lists egress rules and remembers them and their network
+func (a *recordingAPI) ListEgress(ctx context.Context, network string) ([]netmaker.Egress, error) {
+	egresses, err := a.netmakerAPI.ListEgress(ctx, network)
+	for _, egress := range egresses {
+		a.egressNetwork[egress.ID] = network
+	}
+	if err == nil {
+		a.listed[network] = egresses
+	}
+	return egresses, err
+}
+
+// collisions returns the unmanaged egress rules overlapping the rules in applied, as of the listings seen
+func (a *recordingAPI) collisions(applied []statestore.EgressRef) []Collision {
+	var collisions []Collision
+	for _, ref := range applied {
+		if rule, ok := a.rule(ref); ok {
+			collisions = append(collisions, unmanagedCollisions(ref.Network, []netmaker.Egress{rule}, a.listed[ref.Network])...)
+		}
+	}
+	return collisions
+}
+
+// rule returns the ID, name and range of a rule as last written, or as listed if it wasn't
+func (a *recordingAPI) rule(ref statestore.EgressRef) (netmaker.Egress, bool) {
+	for i := len(a.mutations) - 1; i >= 0; i-- {
+		if mutation := a.mutations[i]; mutation.EgressID == ref.ID && mutation.Action != ChangeDelete {
+			return netmaker.Egress{ID: mutation.EgressID, Name: mutation.Name, Range: mutation.Range}, true
+		}
+	}
+	for _, egress := range a.listed[ref.Network] {
+		if egress.ID == ref.ID {
+			return egress, true
+		}
+	}
+	return netmaker.Egress{}, false
+}
+
+// GetNodeIDsByHostname looks up the node IDs of a host and remembers if it has none
+func (a *recordingAPI) GetNodeIDsByHostname(ctx context.Context, hostname string) ([]string, error) {
+	nodeIDs, err := a.netmakerAPI.GetNodeIDsByHostname(ctx, hostname)
+	if err == nil && len(nodeIDs) == 0 {
+		a.hostWithoutNetwork = true
+	}
+	return nodeIDs, err
+}
+
+// AddHostToNetwork joins a host to a network and records it
+func (a *recordingAPI) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	if err := a.netmakerAPI.AddHostToNetwork(ctx, hostID, network); err != nil {
+		return err
+	}
+	a.joinedNetwork = network
+	return nil
+}
+
+// CreateEgress creates an egress rule and records it
+func (a *recordingAPI) CreateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	created, err := a.netmakerAPI.CreateEgress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	a.mutations = append(a.mutations, Mutation{
+		Action: ChangeCreate, Network: req.Network, EgressID: created.ID, Name: req.Name, Range: req.Range,
+	})
+	return created, nil
+}
+
+// UpdateEgress updates an egress rule and records it
+func (a *recordingAPI) UpdateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	updated, err := a.netmakerAPI.UpdateEgress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	a.mutations = append(a.mutations, Mutation{
+		Action: ChangeUpdate, Network: req.Network, EgressID: req.ID, Name: req.Name, Range: req.Range,
+	})
+	return updated, nil
+}
+
+// DeleteEgress deletes an egress rule and records it
+func (a *recordingAPI) DeleteEgress(ctx context.Context, egressID string) error {
+	if err := a.netmakerAPI.DeleteEgress(ctx, egressID); err != nil {
+		return err
+	}
+	a.mutations = append(a.mutations, Mutation{Action: ChangeDelete, Network: a.egressNetwork[egressID], EgressID: egressID})
+	return nil
+}
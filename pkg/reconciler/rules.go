@@ -0,0 +1,223 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bsure-analytics/kaput-not/pkg/cidr"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// groupKind is the metadata key naming the group of egress rules routed via several gateways
+type groupKind string
+
+const (
+	ruleGroup groupKind = "rule" // ClusterEgressRules
+	poolGroup groupKind = "pool" // Node pools
+)
+
+// groupName returns the name of the group of the given kind an egress rule belongs to (empty for none)
+func (m *egressMetadata) groupName(kind groupKind) string {
+	if kind == poolGroup {
+		return m.pool
+	}
+	return m.rule
+}
+
+// EgressRule is a ClusterEgressRule or node pool resolved by the controller: CIDRs routed via selected nodes
+type EgressRule struct {
+	// Name is the ClusterEgressRule or node pool name, recorded in the rule descriptions
+	Name string
+
+	// CIDRs are published as one Netmaker egress rule each, in every network of the gateways
+	CIDRs []string
+
+	// NAT enables masquerading on the gateways
+	NAT bool
+
+	// GatewayNodes are the Kubernetes node names routing the CIDRs, in order of preference
+	// The first gateway in each network gets EgressMetric, later ones get increasing metrics
+	GatewayNodes []string
+}
+
+// ReconcileRule syncs a ClusterEgressRule to Netmaker
+// Creates or updates one egress rule per CIDR in every network a gateway participates in, and deletes
+// the rule's egresses in networks without gateways and beyond its CIDR list
+func (r *Reconciler) ReconcileRule(ctx context.Context, rule EgressRule) error {
+	return r.reconcileGroup(ctx, ruleGroup, rule)
+}
+
+// reconcileGroup syncs a ClusterEgressRule or node pool to Netmaker (see ReconcileRule)
+func (r *Reconciler) reconcileGroup(ctx context.Context, kind groupKind, rule EgressRule) error {
+	allNodes, err := r.options.NetmakerClient.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	gateways, err := r.resolveGateways(ctx, r.options.NetmakerClient, "", rule.GatewayNodes, allNodes)
+	if err != nil {
+		return err
+	}
+
+	var reconcileErrors []error
+	for _, network := range nodeNetworks(allNodes) {
+		if err := r.reconcileRuleInNetwork(ctx, kind, rule, gateways[network], network); err != nil {
+			reconcileErrors = append(reconcileErrors, fmt.Errorf("network %s: %w", network, err))
+		}
+	}
+
+	if len(reconcileErrors) > 0 {
+		return fmt.Errorf("failed to reconcile %s %s in some networks: %w", kind, rule.Name, errors.Join(reconcileErrors...))
+	}
+
+	return nil
+}
+
+// reconcileRuleInNetwork reconciles a ClusterEgressRule or node pool in a single network
+// gatewayIDs are the Netmaker node IDs of the gateways in this network (none deletes the rule's egresses)
+func (r *Reconciler) reconcileRuleInNetwork(ctx context.Context, kind groupKind, rule EgressRule, gatewayIDs []string, network string) error {
+	ctx = netmaker.WithNetwork(ctx, network) // The network's timeout applies to the deletes too
+	existingEgresses, err := r.options.NetmakerClient.ListEgress(ctx, network)
+	if err != nil {
+		return fmt.Errorf("failed to list egress rules in network %s: %w", network, err)
+	}
+
+	// Index the rule's existing egresses; duplicates and surplus indexes are deleted below
+	existing := make(map[int]*netmaker.Egress)
+	existingMetadata := make(map[int]*egressMetadata)
+	var surplus []string
+	for i := range existingEgresses {
+		metadata := parseEgress(&existingEgresses[i])
+		if !r.belongsToOurCluster(metadata) || metadata.groupName(kind) != rule.Name {
+			continue
+		}
+		if len(gatewayIDs) == 0 || metadata.index >= len(rule.CIDRs) || existing[metadata.index] != nil {
+			surplus = append(surplus, existingEgresses[i].ID)
+			continue
+		}
+		existing[metadata.index] = &existingEgresses[i]
+		existingMetadata[metadata.index] = metadata
+	}
+
+	var ours []netmaker.Egress // The rule's egresses as written, for collisions with unmanaged rules
+	if len(gatewayIDs) > 0 {
+		egressNodes := buildEgressNodes(gatewayIDs[0], EgressMetric, gatewayIDs[1:])
+		for index, ruleCIDR := range rule.CIDRs {
+			ruleCIDR = cidr.NormalizeOrKeep(ruleCIDR)
+			req := netmaker.EgressReq{
+				Name:        r.markName(buildGroupEgressName(kind, rule.Name, index, len(rule.CIDRs)), index, EgressMetric),
+				Network:     network,
+				Description: r.buildDescription(kind, rule.Name, "", index, nil),
+				Range:       ruleCIDR,
+				NAT:         rule.NAT,
+				Nodes:       egressNodes,
+				Status:      true,
+			}
+
+			egress := existing[index]
+			if egress == nil {
+				created, err := r.options.NetmakerClient.CreateEgress(ctx, req)
+				if err != nil {
+					return fmt.Errorf("failed to create egress for CIDR %s (index=%d): %w", ruleCIDR, index, err)
+				}
+				ours = append(ours, netmaker.Egress{ID: created.ID, Name: req.Name, Range: req.Range})
+				continue
+			}
+			ours = append(ours, netmaker.Egress{ID: egress.ID, Name: req.Name, Range: req.Range})
+
+			if cidr.Equal(egress.Range, ruleCIDR) && egress.NAT == rule.NAT && egress.Name == req.Name &&
+				egressNodesEqual(egress.Nodes, egressNodes) && existingMetadata[index].owner == "" &&
+				!r.leaseNeedsRefresh(existingMetadata[index]) {
+				continue // Already correct
+			}
+
+			req.ID = egress.ID
+			req.UpdatedAt = egress.UpdatedAt
+			req.Description = withNote(req.Description, existingMetadata[index].note) // Keep the operator's note
+			if _, err := r.options.NetmakerClient.UpdateEgress(ctx, req); err != nil {
+				return fmt.Errorf("failed to update egress %s (index=%d): %w", egress.ID, index, err)
+			}
+		}
+	}
+
+	reportCollisions(ctx, unmanagedCollisions(network, ours, existingEgresses))
+
+	var deletionErrors []error
+	for _, egressID := range surplus {
+		if err := r.options.NetmakerClient.DeleteEgress(ctx, egressID); err != nil {
+			deletionErrors = append(deletionErrors, fmt.Errorf("failed to delete egress %s: %w", egressID, err))
+		}
+	}
+
+	return errors.Join(deletionErrors...)
+}
+
+// DeleteRule removes all egress rules of a ClusterEgressRule from every network
+func (r *Reconciler) DeleteRule(ctx context.Context, name string) error {
+	return r.deleteGroups(ctx, ruleGroup, func(rule string) bool { return rule == name })
+}
+
+// CleanupRules removes egress rules of ClusterEgressRules that no longer exist
+// validRules is the set of all ClusterEgressRule names; node rules are never touched
+func (r *Reconciler) CleanupRules(ctx context.Context, validRules map[string]bool) error {
+	return r.deleteGroups(ctx, ruleGroup, func(rule string) bool { return !validRules[rule] })
+}
+
+// deleteGroups deletes our cluster's egresses of the given group kind whose group name matches
+func (r *Reconciler) deleteGroups(ctx context.Context, kind groupKind, match func(name string) bool) error {
+	allNodes, err := r.options.NetmakerClient.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var deletionErrors []error
+	var matching []netmaker.Egress
+	for _, network := range nodeNetworks(allNodes) {
+		egresses, err := r.options.NetmakerClient.ListEgress(ctx, network)
+		if err != nil {
+			deletionErrors = append(deletionErrors, fmt.Errorf("failed to list egress rules in network %s: %w", network, err))
+			continue
+		}
+
+		for _, egress := range egresses {
+			metadata := parseEgress(&egress)
+			if !r.belongsToOurCluster(metadata) || metadata.groupName(kind) == "" || !match(metadata.groupName(kind)) {
+				continue
+			}
+			egress.Network = network
+			matching = append(matching, egress)
+		}
+	}
+	deletionErrors = append(deletionErrors, r.deleteEgresses(ctx, r.options.NetmakerClient, matching)...)
+
+	if len(deletionErrors) > 0 {
+		return fmt.Errorf("failed to delete some %s egresses: %w", kind, errors.Join(deletionErrors...))
+	}
+
+	return nil
+}
+
+// nodeNetworks returns the sorted set of networks the Netmaker nodes participate in
+func nodeNetworks(allNodes []netmaker.Node) []string {
+	seen := make(map[string]bool)
+	var networks []string
+	for _, n := range allNodes {
+		if !seen[n.Network] {
+			seen[n.Network] = true
+			networks = append(networks, n.Network)
+		}
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// buildGroupEgressName builds the egress name for a ClusterEgressRule or node pool CIDR
+// Format: "rule-name rule (1/2)" or "pool-name pool pods (1/2)"
+func buildGroupEgressName(kind groupKind, name string, index int, totalCIDRs int) string {
+	if kind == poolGroup {
+		return fmt.Sprintf("%s pool pods (%d/%d)", name, index+1, totalCIDRs)
+	}
+	return fmt.Sprintf("%s rule (%d/%d)", name, index+1, totalCIDRs)
+}
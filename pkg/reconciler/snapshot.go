@@ -0,0 +1,298 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+)
+
+// netmakerAPI is the Netmaker access needed to reconcile a node
+// Implemented by *netmaker.CachedClient (TTL cache), *snapshot (one listing per resync cycle)
+// and *planner (records mutations instead of applying them)
+type netmakerAPI interface {
+	GetNodeIDsByHostname(ctx context.Context, hostname string) ([]string, error)
+	ListHosts(ctx context.Context) ([]netmaker.Host, error)
+	ListNodes(ctx context.Context) ([]netmaker.Node, error)
+	ListEgress(ctx context.Context, network string) ([]netmaker.Egress, error)
+	CreateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error)
+	UpdateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error)
+	DeleteEgress(ctx context.Context, egressID string) error
+	Invalidate(kind netmaker.CacheKind, network string) error
+	AddHostToNetwork(ctx context.Context, hostID, network string) error
+}
+
+// Ensure all implementations satisfy the interface
+var (
+	_ netmakerAPI = (*netmaker.CachedClient)(nil)
+	_ netmakerAPI = (*snapshot)(nil)
+	_ netmakerAPI = (*planner)(nil)
+)
+
+// NodeRequest is a node to reconcile in a resync, with its topology
+type NodeRequest struct {
+	Node     *corev1.Node
+	Topology Topology
+}
+
+// ResyncNodes reconciles many nodes against a single snapshot of Netmaker state
+// Hosts, nodes and the egress rules of every network are listed exactly once per call (the cache is
+// flushed first, so the snapshot is fresh), then each node is diffed against the snapshot
+// O(networks) list calls per cycle instead of O(nodes x networks) once the cache TTL expires mid-cycle
+// Returns the result of every node and the errors of nodes that failed, both keyed by node name
+// (errors nil if all succeeded); each node gets its own request ID
+func (r *Reconciler) ResyncNodes(ctx context.Context, requests []NodeRequest) (map[string]NodeResult, map[string]error, error) {
+	snap, err := newSnapshot(ctx, r.options.NetmakerClient, r.options.ResyncListConcurrency)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to snapshot Netmaker state: %w", err)
+	}
+
+	results := make(map[string]NodeResult, len(requests))
+	var nodeErrors map[string]error
+	for _, req := range requests {
+		requestID := netmaker.NewRequestID()
+		recorder := newRecordingAPI(snap)
+		nodeCtx := netmaker.WithRequestID(ctx, requestID)
+		applied, networks, err := r.reconcileNode(nodeCtx, recorder, req.Node, req.Topology)
+		results[req.Node.Name] = newNodeResult(requestID, networks, recorder, applied)
+		if err != nil {
+			if nodeErrors == nil {
+				nodeErrors = make(map[string]error)
+			}
+			nodeErrors[req.Node.Name] = err
+			continue
+		}
+		reportCollisions(nodeCtx, results[req.Node.Name].Collisions)
+		r.recordEgresses(req.Node.Name, applied)
+	}
+
+	return results, nodeErrors, nil
+}
+
+// snapshot is a point-in-time copy of Netmaker hosts, nodes and egress rules
+// Mutations go to the underlying client and are patched into the snapshot, so later nodes in the
+// same cycle see them without re-listing; only invalidated networks are listed again
+type snapshot struct {
+	client *netmaker.CachedClient
+
+	hostsMu     sync.RWMutex // Hosts and nodes are replaced when a host joins a network (see AddHostToNetwork)
+	hosts       []netmaker.Host
+	hostNodeIDs map[string][]string // hostname -> node IDs
+	nodes       []netmaker.Node
+
+	mu     sync.Mutex
+	egress map[string][]netmaker.Egress // network -> rules
+}
+
+// newSnapshot lists hosts, nodes and the egress rules of every network once
+// Egress rules of up to concurrency networks are listed in parallel
+func newSnapshot(ctx context.Context, client *netmaker.CachedClient, concurrency int) (*snapshot, error) {
+	_ = client.Invalidate(netmaker.CacheKindAll, "") // Valid kind - never fails
+
+	hosts, err := client.ListHosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	nodes, err := client.ListNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	snap := &snapshot{
+		client:      client,
+		hosts:       hosts,
+		hostNodeIDs: make(map[string][]string, len(hosts)),
+		nodes:       nodes,
+	}
+	for _, host := range hosts {
+		snap.hostNodeIDs[host.Name] = host.Nodes
+	}
+
+	var networks []string
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		if !seen[node.Network] {
+			seen[node.Network] = true
+			networks = append(networks, node.Network)
+		}
+	}
+	snap.egress, err = client.PrefetchEgress(ctx, networks, concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	return snap, nil
+}
+
+// GetNodeIDsByHostname returns the node IDs of a host from the snapshot
+func (s *snapshot) GetNodeIDsByHostname(_ context.Context, hostname string) ([]string, error) {
+	s.hostsMu.RLock()
+	defer s.hostsMu.RUnlock()
+
+	nodeIDs, exists := s.hostNodeIDs[hostname]
+	if !exists {
+		return nil, fmt.Errorf("host not found with name %s", hostname)
+	}
+	return nodeIDs, nil
+}
+
+// ListHosts returns the hosts from the snapshot
+func (s *snapshot) ListHosts(_ context.Context) ([]netmaker.Host, error) {
+	s.hostsMu.RLock()
+	defer s.hostsMu.RUnlock()
+	return s.hosts, nil
+}
+
+// ListNodes returns the nodes from the snapshot
+func (s *snapshot) ListNodes(_ context.Context) ([]netmaker.Node, error) {
+	s.hostsMu.RLock()
+	defer s.hostsMu.RUnlock()
+	return s.nodes, nil
+}
+
+// ListEgress returns the egress rules of a network, listing it again only after Invalidate
+func (s *snapshot) ListEgress(ctx context.Context, network string) ([]netmaker.Egress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if egresses, listed := s.egress[network]; listed {
+		return egresses, nil
+	}
+
+	egresses, err := s.client.ListEgress(ctx, network)
+	if err != nil {
+		return nil, err
+	}
+	s.egress[network] = egresses
+	return egresses, nil
+}
+
+// CreateEgress creates a rule and adds it to the snapshot
+func (s *snapshot) CreateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	created, err := s.client.CreateEgress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, listed := s.egress[req.Network]; listed {
+		s.egress[req.Network] = append(s.cloneEgress(req.Network), egressFromResponse(created, req))
+	}
+	return created, nil
+}
+
+// UpdateEgress updates a rule and replaces it in the snapshot
+func (s *snapshot) UpdateEgress(ctx context.Context, req netmaker.EgressReq) (*netmaker.Egress, error) {
+	updated, err := s.client.UpdateEgress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, listed := s.egress[req.Network]; listed {
+		egresses := s.cloneEgress(req.Network)
+		for i := range egresses {
+			if egresses[i].ID == req.ID {
+				egresses[i] = egressFromResponse(updated, req)
+			}
+		}
+		s.egress[req.Network] = egresses
+	}
+	return updated, nil
+}
+
+// DeleteEgress deletes a rule and removes it from the snapshot
+func (s *snapshot) DeleteEgress(ctx context.Context, egressID string) error {
+	if err := s.client.DeleteEgress(ctx, egressID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for network := range s.egress {
+		kept := make([]netmaker.Egress, 0, len(s.egress[network]))
+		for _, egress := range s.egress[network] {
+			if egress.ID != egressID {
+				kept = append(kept, egress)
+			}
+		}
+		s.egress[network] = kept
+	}
+	return nil
+}
+
+// Invalidate drops snapshot (and cache) entries, so the next read lists them again
+// Only egress rules can be re-listed - hosts and nodes change only when a host joins a network (see AddHostToNetwork)
+func (s *snapshot) Invalidate(kind netmaker.CacheKind, network string) error {
+	if err := s.client.Invalidate(kind, network); err != nil {
+		return err
+	}
+
+	if kind != netmaker.CacheKindEgress && kind != netmaker.CacheKindAll {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if network == "" {
+		s.egress = make(map[string][]netmaker.Egress)
+	} else {
+		delete(s.egress, network)
+	}
+	return nil
+}
+
+// AddHostToNetwork joins a host to a network and lists hosts and nodes again, so the host's new node is seen
+func (s *snapshot) AddHostToNetwork(ctx context.Context, hostID, network string) error {
+	if err := s.client.AddHostToNetwork(ctx, hostID, network); err != nil {
+		return err
+	}
+
+	hosts, err := s.client.ListHosts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list hosts: %w", err)
+	}
+	nodes, err := s.client.ListNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	hostNodeIDs := make(map[string][]string, len(hosts))
+	for _, host := range hosts {
+		hostNodeIDs[host.Name] = host.Nodes
+	}
+
+	s.hostsMu.Lock()
+	defer s.hostsMu.Unlock()
+	s.hosts, s.hostNodeIDs, s.nodes = hosts, hostNodeIDs, nodes
+	return nil
+}
+
+// cloneEgress copies a network's rules, so slices handed out earlier are never modified
+// Must be called with mu held
+func (s *snapshot) cloneEgress(network string) []netmaker.Egress {
+	return append([]netmaker.Egress(nil), s.egress[network]...)
+}
+
+// egressFromResponse returns the rule from a create/update response, falling back to the request
+// if the server returned no rule
+func egressFromResponse(egress *netmaker.Egress, req netmaker.EgressReq) netmaker.Egress {
+	if egress != nil && egress.ID != "" {
+		return *egress
+	}
+	return netmaker.Egress{
+		ID:          req.ID,
+		Name:        req.Name,
+		Network:     req.Network,
+		Description: req.Description,
+		Range:       req.Range,
+		NAT:         req.NAT,
+		Nodes:       req.Nodes,
+		Status:      req.Status,
+	}
+}
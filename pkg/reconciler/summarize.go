@@ -0,0 +1,78 @@
+package reconciler
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// SummarizeCIDRs computes the minimal set of CIDRs covering exactly the given CIDRs
+// Duplicates and CIDRs contained in others are dropped, and sibling prefixes are merged
+// into their parent (e.g. 10.0.0.0/24 + 10.0.1.0/24 = 10.0.0.0/23) until nothing changes
+// Never widens coverage beyond the input, so unrelated address space is not advertised
+// IPv4 and IPv6 CIDRs may be mixed; the result is sorted (IPv4 first)
+func SummarizeCIDRs(cidrs []string) ([]string, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	for {
+		prefixes = removeCoveredPrefixes(prefixes)
+
+		merged := false
+		for i := 0; i+1 < len(prefixes); i++ {
+			a, b := prefixes[i], prefixes[i+1]
+			if a.Bits() != b.Bits() || a.Bits() == 0 {
+				continue
+			}
+
+			parent := netip.PrefixFrom(a.Addr(), a.Bits()-1).Masked()
+			if parent != netip.PrefixFrom(b.Addr(), b.Bits()-1).Masked() {
+				continue
+			}
+
+			// Siblings - replace both with their parent
+			prefixes[i] = parent
+			prefixes = append(prefixes[:i+1], prefixes[i+2:]...)
+			merged = true
+		}
+
+		if !merged {
+			break
+		}
+	}
+
+	result := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		result = append(result, prefix.String())
+	}
+	return result, nil
+}
+
+// removeCoveredPrefixes sorts prefixes and drops duplicates and prefixes contained in another
+func removeCoveredPrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	// Sort by address, shorter prefixes first, so a covering prefix precedes what it covers
+	sort.Slice(prefixes, func(i, j int) bool {
+		if c := prefixes[i].Addr().Compare(prefixes[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return prefixes[i].Bits() < prefixes[j].Bits()
+	})
+
+	kept := prefixes[:0]
+	for _, prefix := range prefixes {
+		if len(kept) > 0 {
+			last := kept[len(kept)-1]
+			if last.Bits() <= prefix.Bits() && last.Contains(prefix.Addr()) {
+				continue
+			}
+		}
+		kept = append(kept, prefix)
+	}
+	return kept
+}
@@ -0,0 +1,247 @@
+// Package runtimeconfig watches the singleton KaputNotConfig custom resource for settings that can be
+// changed without a restart (dry-run, node filters, metrics options) and reports in its status
+// whether they were applied
+package runtimeconfig
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/bsure-analytics/kaput-not/pkg/apis/v1alpha1"
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+// Settings are the runtime settings, resolved from the KaputNotConfig spec and the defaults
+type Settings struct {
+	DryRun                     bool
+	PublisherSelector          string
+	IncludeWindowsNodes        bool
+	InformerCacheWarnThreshold int
+	EgressCacheWarnThreshold   int
+}
+
+// Options contains configuration for the watcher
+type Options struct {
+	// DynamicClient reads the KaputNotConfig and writes its status
+	DynamicClient dynamic.Interface
+
+	// Name is the name of the KaputNotConfig to watch (an instance ID for non-default instances)
+	// Default: v1alpha1.KaputNotConfigName
+	Name string
+
+	// Defaults are the settings used for fields the KaputNotConfig leaves unset, and when it doesn't exist
+	// (usually the values configured by environment variables)
+	Defaults Settings
+
+	// OnChange applies changed settings; an error marks the KaputNotConfig as not applied (required)
+	OnChange func(Settings) error
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.DynamicClient == nil {
+		return fmt.Errorf("DynamicClient is required")
+	}
+	if o.OnChange == nil {
+		return fmt.Errorf("OnChange is required")
+	}
+	return nil
+}
+
+// Watcher applies the KaputNotConfig named Options.Name whenever it changes
+type Watcher struct {
+	options  *Options
+	informer cache.SharedIndexInformer
+	synced   cache.InformerSynced
+
+	mu      sync.Mutex
+	applied Settings
+}
+
+// New creates a new watcher
+// Returns error for validation failures, never panics
+func New(opts *Options) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+
+	if opts.Name == "" {
+		opts.Name = v1alpha1.KaputNotConfigName
+	}
+
+	w := &Watcher{
+		options: opts,
+		applied: opts.Defaults,
+	}
+
+	// Only our instance's KaputNotConfig is watched
+	w.informer = dynamicinformer.NewFilteredDynamicInformer(
+		opts.DynamicClient,
+		v1alpha1.KaputNotConfigResource,
+		metav1.NamespaceAll,
+		0,
+		cache.Indexers{},
+		func(listOptions *metav1.ListOptions) {
+			listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", opts.Name).String()
+		},
+	).Informer()
+
+	registration, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { w.apply(obj) },
+		UpdateFunc: func(_, newObj interface{}) { w.apply(newObj) },
+		DeleteFunc: func(interface{}) { w.reset() },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add event handler: %w", err)
+	}
+	w.synced = registration.HasSynced
+
+	return w, nil
+}
+
+// Run starts the informer and blocks until the context is canceled
+func (w *Watcher) Run(ctx context.Context) {
+	defer runtime.HandleCrash()
+	w.informer.Run(ctx.Done())
+}
+
+// WaitForSync blocks until the KaputNotConfig (if any) has been applied or ctx is canceled
+// Returns false if ctx was canceled first
+func (w *Watcher) WaitForSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), w.synced)
+}
+
+// Settings returns the settings in effect
+func (w *Watcher) Settings() Settings {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.applied
+}
+
+// apply resolves and applies a KaputNotConfig, then reports the outcome in its status
+func (w *Watcher) apply(obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	config, err := v1alpha1.KaputNotConfigFromUnstructured(u.Object)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("failed to decode KaputNotConfig %s: %w", u.GetName(), err))
+		return
+	}
+
+	status := v1alpha1.KaputNotConfigStatus{
+		ObservedGeneration: config.Generation,
+		Conditions:         append([]metav1.Condition(nil), config.Status.Conditions...),
+	}
+	if err := w.update(resolve(config.Spec, w.options.Defaults)); err != nil {
+		runtime.HandleError(fmt.Errorf("KaputNotConfig %s not applied: %w", config.Name, err))
+		setCondition(&status, v1alpha1.ConditionApplied, false, "Invalid", err.Error(), config.Generation)
+		setCondition(&status, v1alpha1.ConditionDegraded, true, "Invalid", "The previous settings stay in effect", config.Generation)
+	} else {
+		setCondition(&status, v1alpha1.ConditionApplied, true, "Applied", "Settings are in effect", config.Generation)
+		setCondition(&status, v1alpha1.ConditionDegraded, false, "Applied", "Settings are in effect", config.Generation)
+	}
+	w.updateStatus(u, config, status)
+}
+
+// reset restores the defaults after the KaputNotConfig was deleted
+func (w *Watcher) reset() {
+	if err := w.update(w.options.Defaults); err != nil {
+		runtime.HandleError(fmt.Errorf("failed to restore default settings: %w", err))
+	}
+}
+
+// update calls OnChange if the settings differ from the applied ones
+func (w *Watcher) update(settings Settings) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if settings == w.applied {
+		return nil
+	}
+	if err := w.options.OnChange(settings); err != nil {
+		return err
+	}
+	w.applied = settings
+	log.Printf("Runtime settings changed: %+v", settings)
+	return nil
+}
+
+// resolve overlays the fields set in a spec on the defaults
+func resolve(spec v1alpha1.KaputNotConfigSpec, defaults Settings) Settings {
+	settings := defaults
+	settings.DryRun = spec.DryRun
+	if spec.PublisherSelector != nil {
+		settings.PublisherSelector = *spec.PublisherSelector
+	}
+	if spec.IncludeWindowsNodes != nil {
+		settings.IncludeWindowsNodes = *spec.IncludeWindowsNodes
+	}
+	if spec.Metrics.InformerCacheWarnThreshold != nil {
+		settings.InformerCacheWarnThreshold = *spec.Metrics.InformerCacheWarnThreshold
+	}
+	if spec.Metrics.EgressCacheWarnThreshold != nil {
+		settings.EgressCacheWarnThreshold = *spec.Metrics.EgressCacheWarnThreshold
+	}
+	return settings
+}
+
+// setCondition sets a condition in a KaputNotConfig status
+// The transition time only changes when the condition's status flips
+func setCondition(status *v1alpha1.KaputNotConfigStatus, conditionType string, value bool, reason, message string, generation int64) {
+	conditionStatus := metav1.ConditionFalse
+	if value {
+		conditionStatus = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// updateStatus writes the KaputNotConfig status if it changed
+// Every replica applies the settings and computes the same status, so conflicts are ignored
+func (w *Watcher) updateStatus(u *unstructured.Unstructured, config *v1alpha1.KaputNotConfig, status v1alpha1.KaputNotConfigStatus) {
+	if equality.Semantic.DeepEqual(config.Status, status) {
+		return
+	}
+
+	statusObj, err := v1alpha1.KaputNotConfigStatusToUnstructured(&status)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("failed to encode status of KaputNotConfig %s: %w", config.Name, err))
+		return
+	}
+
+	updated := u.DeepCopy()
+	updated.Object["status"] = statusObj
+	_, err = w.options.DynamicClient.Resource(v1alpha1.KaputNotConfigResource).UpdateStatus(context.Background(), updated, metav1.UpdateOptions{})
+	if err != nil && !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+		runtime.HandleError(fmt.Errorf("failed to update status of KaputNotConfig %s: %w", config.Name, err))
+	}
+}
+
+// Permissions returns the RBAC rules the watcher needs
+func (o *Options) Permissions() []rbac.Permission {
+	group := v1alpha1.KaputNotConfigResource.Group
+	return []rbac.Permission{
+		{Rule: rbac.Rule(group, v1alpha1.KaputNotConfigResource.Resource, "list", "watch")},
+		{Rule: rbac.Rule(group, v1alpha1.KaputNotConfigResource.Resource+"/status", "update")},
+	}
+}
@@ -0,0 +1,252 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+// ConfigMapOptions contains configuration for the ConfigMap-backed store
+type ConfigMapOptions struct {
+	// KubeClient is the Kubernetes client
+	KubeClient kubernetes.Interface
+
+	// Name is the name of the ConfigMap
+	Name string
+
+	// Namespace is the namespace of the ConfigMap
+	Namespace string
+
+	// FlushInterval is how often pending changes are written to the ConfigMap
+	// Default: 10 seconds
+	FlushInterval time.Duration
+}
+
+// Validate validates the options
+func (o *ConfigMapOptions) Validate() error {
+	if o.KubeClient == nil {
+		return fmt.Errorf("KubeClient is required")
+	}
+	if o.Name == "" {
+		return fmt.Errorf("Name is required")
+	}
+	if o.Namespace == "" {
+		return fmt.Errorf("Namespace is required")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *ConfigMapOptions) ApplyDefaults() {
+	if o.FlushInterval == 0 {
+		o.FlushInterval = 10 * time.Second
+	}
+}
+
+// ConfigMapStore keeps the mapping in memory and periodically writes it to a ConfigMap
+// Each node is one data key holding a JSON array of egress references
+// Only the leader should Load and Run the store - observers never write it
+type ConfigMapStore struct {
+	options *ConfigMapOptions
+
+	mu    sync.RWMutex
+	refs  map[string][]EgressRef
+	dirty bool
+}
+
+// NewConfigMapStore creates a ConfigMap-backed store
+// Returns error for validation failures, never panics
+func NewConfigMapStore(opts *ConfigMapOptions) (*ConfigMapStore, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	return &ConfigMapStore{
+		options: opts,
+		refs:    make(map[string][]EgressRef),
+	}, nil
+}
+
+// Get implements Store
+func (s *ConfigMapStore) Get(nodeName string) []EgressRef {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refs[nodeName]
+}
+
+// Set implements Store
+func (s *ConfigMapStore) Set(nodeName string, refs []EgressRef) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if refsEqual(s.refs[nodeName], refs) {
+		return
+	}
+	s.refs[nodeName] = refs
+	s.dirty = true
+}
+
+// Delete implements Store
+func (s *ConfigMapStore) Delete(nodeName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.refs[nodeName]; !exists {
+		return
+	}
+	delete(s.refs, nodeName)
+	s.dirty = true
+}
+
+// NodeNames implements Store
+func (s *ConfigMapStore) NodeNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.refs))
+	for name := range s.refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load replaces the in-memory state with the ConfigMap contents
+// A missing ConfigMap is not an error (first start) - it is created on the next flush
+// Unparseable entries are skipped; they are rediscovered by the regular reconcile
+func (s *ConfigMapStore) Load(ctx context.Context) error {
+	cm, err := s.options.KubeClient.CoreV1().ConfigMaps(s.options.Namespace).Get(ctx, s.options.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get state ConfigMap %s/%s: %w", s.options.Namespace, s.options.Name, err)
+	}
+
+	refs := make(map[string][]EgressRef, len(cm.Data))
+	for nodeName, value := range cm.Data {
+		var nodeRefs []EgressRef
+		if err := json.Unmarshal([]byte(value), &nodeRefs); err != nil {
+			log.Printf("WARNING: Ignoring unparseable state for node %s: %v", nodeName, err)
+			continue
+		}
+		refs[nodeName] = nodeRefs
+	}
+
+	s.mu.Lock()
+	s.refs = refs
+	s.dirty = false
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Run flushes pending changes every FlushInterval until the context is canceled
+// A final flush is attempted on shutdown so a clean leadership handover loses nothing
+func (s *ConfigMapStore) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.Flush(ctx); err != nil {
+			log.Printf("Failed to flush state ConfigMap: %v", err)
+		}
+	}, s.options.FlushInterval)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Flush(shutdownCtx); err != nil {
+		log.Printf("Failed to flush state ConfigMap on shutdown: %v", err)
+	}
+}
+
+// Flush writes the in-memory state to the ConfigMap if it changed since the last flush
+func (s *ConfigMapStore) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	data := make(map[string]string, len(s.refs))
+	for nodeName, refs := range s.refs {
+		value, err := json.Marshal(refs)
+		if err != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("failed to marshal state for node %s: %w", nodeName, err)
+		}
+		data[nodeName] = string(value)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err := s.write(ctx, data); err != nil {
+		// Retry on the next flush
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// write creates or replaces the ConfigMap data
+func (s *ConfigMapStore) write(ctx context.Context, data map[string]string) error {
+	configMaps := s.options.KubeClient.CoreV1().ConfigMaps(s.options.Namespace)
+
+	cm, err := configMaps.Get(ctx, s.options.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.options.Name,
+				Namespace: s.options.Namespace,
+			},
+			Data: data,
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create state ConfigMap %s/%s: %w", s.options.Namespace, s.options.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get state ConfigMap %s/%s: %w", s.options.Namespace, s.options.Name, err)
+	}
+
+	cm.Data = data
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update state ConfigMap %s/%s: %w", s.options.Namespace, s.options.Name, err)
+	}
+
+	return nil
+}
+
+// refsEqual checks if two reference lists are identical (order-sensitive)
+func refsEqual(a, b []EgressRef) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Permissions returns the RBAC rules the store needs (the ConfigMap is created on first flush)
+func (o *ConfigMapOptions) Permissions() []rbac.Permission {
+	return []rbac.Permission{
+		{Namespace: o.Namespace, Rule: rbac.Rule("", "configmaps", "create")},
+		{Namespace: o.Namespace, Rule: rbac.NamedRule("", "configmaps", []string{o.Name}, "get", "update")},
+	}
+}
@@ -0,0 +1,25 @@
+// Package statestore persists which Netmaker egress rules were applied for each Kubernetes node
+// so a restarted controller can address known rules by ID instead of re-discovering them
+package statestore
+
+// EgressRef identifies an applied Netmaker egress rule
+type EgressRef struct {
+	ID      string `json:"id"`
+	Network string `json:"network"`
+}
+
+// Store maps Kubernetes node names to the egress rules applied for them
+// Implementations must be safe for concurrent use
+type Store interface {
+	// Get returns the egress rules recorded for a node (nil if unknown)
+	Get(nodeName string) []EgressRef
+
+	// Set replaces the egress rules recorded for a node
+	Set(nodeName string, refs []EgressRef)
+
+	// Delete forgets a node
+	Delete(nodeName string)
+
+	// NodeNames returns all nodes with recorded egress rules
+	NodeNames() []string
+}
@@ -0,0 +1,305 @@
+// Package trash keeps copies of the Netmaker egress rules kaput-not deletes in a ConfigMap, bounded in size and age,
+// so operators can restore rules removed by accident (see "kaput-not restore")
+package trash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/bsure-analytics/kaput-not/pkg/hooks"
+	"github.com/bsure-analytics/kaput-not/pkg/netmaker"
+	"github.com/bsure-analytics/kaput-not/pkg/rbac"
+)
+
+// maxDataBytes bounds the entries kept in the ConfigMap, well below the 1 MiB object size limit
+const maxDataBytes = 768 << 10
+
+// Options contains configuration for the trash bin
+type Options struct {
+	// KubeClient is the Kubernetes client
+	KubeClient kubernetes.Interface
+
+	// Name is the name of the ConfigMap
+	Name string
+
+	// Namespace is the namespace of the ConfigMap
+	Namespace string
+
+	// MaxEntries is how many deleted rules are kept; the oldest are dropped first
+	// Default: 200
+	MaxEntries int
+
+	// TTL is how long deleted rules are kept
+	// Default: 7 days
+	TTL time.Duration
+
+	// FlushInterval is how often deleted rules are written to the ConfigMap
+	// Default: 10 seconds
+	FlushInterval time.Duration
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.KubeClient == nil {
+		return fmt.Errorf("KubeClient is required")
+	}
+	if o.Name == "" {
+		return fmt.Errorf("Name is required")
+	}
+	if o.Namespace == "" {
+		return fmt.Errorf("Namespace is required")
+	}
+	if o.MaxEntries < 0 {
+		return fmt.Errorf("MaxEntries must not be negative")
+	}
+	if o.TTL < 0 {
+		return fmt.Errorf("TTL must not be negative")
+	}
+	return nil
+}
+
+// ApplyDefaults applies default values to options
+func (o *Options) ApplyDefaults() {
+	if o.MaxEntries == 0 {
+		o.MaxEntries = 200
+	}
+	if o.TTL == 0 {
+		o.TTL = 7 * 24 * time.Hour
+	}
+	if o.FlushInterval == 0 {
+		o.FlushInterval = 10 * time.Second
+	}
+}
+
+// Permissions returns the RBAC rules the bin needs (the ConfigMap is created on first flush)
+func (o *Options) Permissions() []rbac.Permission {
+	return []rbac.Permission{
+		{Namespace: o.Namespace, Rule: rbac.Rule("", "configmaps", "create")},
+		{Namespace: o.Namespace, Rule: rbac.NamedRule("", "configmaps", []string{o.Name}, "get", "update")},
+	}
+}
+
+// Entry is a deleted egress rule
+type Entry struct {
+	// Egress is the rule as last listed before its deletion
+	Egress netmaker.Egress `json:"egress"`
+
+	// DeletedAt is when kaput-not deleted the rule
+	DeletedAt time.Time `json:"deletedAt"`
+
+	// RequestID is the correlation ID of the reconcile that deleted the rule (see netmaker.RequestIDHeader)
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Bin records deleted egress rules as a mutation hook and writes them to a ConfigMap every FlushInterval
+// Each rule is one data key (its egress ID) holding the JSON Entry; flushes only add and prune entries, so
+// rules removed by "kaput-not restore" meanwhile are not written back
+// Only the leader should Run the bin - observers never delete rules
+type Bin struct {
+	options *Options
+
+	mu      sync.Mutex
+	pending []Entry // Recorded, not flushed yet
+}
+
+// New creates a trash bin
+// Returns error for validation failures, never panics
+func New(opts *Options) (*Bin, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	opts.ApplyDefaults()
+
+	return &Bin{options: opts}, nil
+}
+
+// Name implements hooks.Hook
+func (b *Bin) Name() string {
+	return "trash"
+}
+
+// Before implements hooks.Hook; never rejects a mutation
+func (b *Bin) Before(ctx context.Context, m hooks.Mutation) error {
+	return nil
+}
+
+// After implements hooks.Hook, recording successfully deleted rules
+func (b *Bin) After(ctx context.Context, m hooks.Mutation) error {
+	if m.Action != hooks.ActionDelete || m.Error != "" {
+		return nil
+	}
+	if m.Egress == nil {
+		return fmt.Errorf("egress rule %s was deleted without being listed first, it cannot be restored", m.EgressID)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, Entry{Egress: *m.Egress, DeletedAt: time.Now().UTC(), RequestID: m.RequestID})
+	return nil
+}
+
+// Run flushes recorded rules every FlushInterval until the context is canceled
+// A final flush is attempted on shutdown so a clean leadership handover loses nothing
+func (b *Bin) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := b.Flush(ctx); err != nil {
+			log.Printf("Failed to flush trash ConfigMap: %v", err)
+		}
+	}, b.options.FlushInterval)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Flush(shutdownCtx); err != nil {
+		log.Printf("Failed to flush trash ConfigMap on shutdown: %v", err)
+	}
+}
+
+// Flush adds the recorded rules to the ConfigMap and drops expired entries and the oldest beyond the limits
+func (b *Bin) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+	if len(pending) == 0 {
+		return nil // Expired entries are pruned with the next deletion and never listed
+	}
+
+	err := b.modify(ctx, func(data map[string]string) error {
+		for _, entry := range pending {
+			value, err := json.Marshal(entry)
+			if err != nil {
+				return fmt.Errorf("failed to marshal deleted egress rule %s: %w", entry.Egress.ID, err)
+			}
+			data[entry.Egress.ID] = string(value)
+		}
+		b.prune(data, time.Now())
+		return nil
+	})
+	if err != nil {
+		// Retry on the next flush
+		b.mu.Lock()
+		b.pending = append(pending, b.pending...)
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// prune drops expired and unparseable entries, then the oldest ones beyond MaxEntries and maxDataBytes
+func (b *Bin) prune(data map[string]string, now time.Time) {
+	entries := make([]Entry, 0, len(data))
+	size := 0
+	for id, value := range data {
+		var entry Entry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			log.Printf("WARNING: Dropping unparseable trash entry %s: %v", id, err)
+			delete(data, id)
+			continue
+		}
+		if now.Sub(entry.DeletedAt) > b.options.TTL {
+			delete(data, id)
+			continue
+		}
+		entries = append(entries, entry)
+		size += len(id) + len(value)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+	})
+	for _, entry := range entries {
+		if len(data) <= b.options.MaxEntries && size <= maxDataBytes {
+			break
+		}
+		size -= len(entry.Egress.ID) + len(data[entry.Egress.ID])
+		delete(data, entry.Egress.ID)
+	}
+}
+
+// List returns the deleted rules in the ConfigMap, most recently deleted first
+// Expired and unparseable entries are skipped
+func (b *Bin) List(ctx context.Context) ([]Entry, error) {
+	cm, err := b.options.KubeClient.CoreV1().ConfigMaps(b.options.Namespace).Get(ctx, b.options.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash ConfigMap %s/%s: %w", b.options.Namespace, b.options.Name, err)
+	}
+
+	entries := make([]Entry, 0, len(cm.Data))
+	for _, value := range cm.Data {
+		var entry Entry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil || time.Since(entry.DeletedAt) > b.options.TTL {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+	return entries, nil
+}
+
+// Remove drops a deleted rule from the ConfigMap, e.g. once it was restored
+func (b *Bin) Remove(ctx context.Context, egressID string) error {
+	return b.modify(ctx, func(data map[string]string) error {
+		delete(data, egressID)
+		return nil
+	})
+}
+
+// modify applies a change to the ConfigMap data, creating the ConfigMap if needed and retrying on conflicts
+func (b *Bin) modify(ctx context.Context, change func(data map[string]string) error) error {
+	configMaps := b.options.KubeClient.CoreV1().ConfigMaps(b.options.Namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, b.options.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			data := make(map[string]string)
+			if err := change(data); err != nil {
+				return err
+			}
+			_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      b.options.Name,
+					Namespace: b.options.Namespace,
+				},
+				Data: data,
+			}, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				return apierrors.NewConflict(corev1.Resource("configmaps"), b.options.Name, err) // Created meanwhile
+			}
+			if err != nil {
+				return fmt.Errorf("failed to create trash ConfigMap %s/%s: %w", b.options.Namespace, b.options.Name, err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get trash ConfigMap %s/%s: %w", b.options.Namespace, b.options.Name, err)
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		if err := change(cm.Data); err != nil {
+			return err
+		}
+		if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update trash ConfigMap %s/%s: %w", b.options.Namespace, b.options.Name, err)
+		}
+		return nil
+	})
+}
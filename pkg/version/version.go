@@ -0,0 +1,64 @@
+// Package version holds the build information of the kaput-not binary
+// Version, Commit and NetmakerAPI are injected at build time, e.g.
+//
+//	go build -ldflags "-X github.com/bsure-analytics/kaput-not/pkg/version.Version=v1.2.3" ./cmd/kaput-not
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Version is the release version (e.g. v1.2.3), "dev" for local builds
+	Version = "dev"
+
+	// Commit is the git commit the binary was built from
+	// Default: the VCS revision recorded by the Go toolchain, if any
+	Commit = ""
+
+	// NetmakerAPI is the range of Netmaker server versions the client is known to work with
+	NetmakerAPI = ">=v0.30.0"
+)
+
+// Info describes a kaput-not build
+type Info struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	GoVersion   string `json:"goVersion"`
+	Platform    string `json:"platform"`
+	NetmakerAPI string `json:"netmakerAPI"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:     Version,
+		Commit:      commit(),
+		GoVersion:   runtime.Version(),
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		NetmakerAPI: NetmakerAPI,
+	}
+}
+
+// String formats the build information in the style of operator-sdk version
+func (i Info) String() string {
+	return fmt.Sprintf("kaput-not version: %q, commit: %q, go version: %q, platform: %q, netmaker api: %q",
+		i.Version, i.Commit, i.GoVersion, i.Platform, i.NetmakerAPI)
+}
+
+// commit returns the injected commit, falling back to the VCS revision embedded by go build
+func commit() string {
+	if Commit != "" {
+		return Commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}